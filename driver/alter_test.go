@@ -0,0 +1,292 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestAlterTable(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestAlterTable")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE post (id BIGINT PRIMARY KEY, account_id BIGINT REFERENCES account, title TEXT)`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@foo.com')`,
+		`INSERT INTO post (id, account_id, title) VALUES (1, 1, 'hello')`,
+		`ALTER TABLE account ADD COLUMN age INT DEFAULT 18`,
+		`ALTER TABLE account ADD score BIGINT`,
+		`ALTER TABLE account ADD COLUMN IF NOT EXISTS age INT DEFAULT 30`,
+		`ALTER TABLE IF EXISTS nope ADD COLUMN foo TEXT`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	// Existing rows are filled with the default value
+	var age int64
+	var score sql.NullInt64
+	err = db.QueryRow(`SELECT age, score FROM account WHERE id = 2`).Scan(&age, &score)
+	if err != nil {
+		t.Fatalf("Cannot select added columns: %s", err)
+	}
+	if age != 18 || score.Valid {
+		t.Fatalf("Expected default values 18 and NULL, got %d and %v", age, score)
+	}
+
+	batch = []string{
+		`ALTER TABLE account RENAME COLUMN email TO mail`,
+		`ALTER TABLE account ALTER COLUMN age SET DEFAULT 21, ALTER COLUMN score SET NOT NULL`,
+		`INSERT INTO account (id, mail, score) VALUES (3, 'baz@foo.com', 10)`,
+		`ALTER TABLE account ALTER COLUMN age DROP DEFAULT`,
+		`ALTER TABLE account DROP COLUMN score`,
+		`INSERT INTO account (id, mail) VALUES (4, 'qux@foo.com')`,
+		`ALTER TABLE account ALTER COLUMN age TYPE TEXT`,
+		`ALTER TABLE post RENAME TO article`,
+		`ALTER TABLE article ALTER COLUMN account_id TYPE INT`,
+	}
+	_, err = db.Exec(`UPDATE account SET score = 0 WHERE id < 3`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var mail, ageText string
+	err = db.QueryRow(`SELECT mail, age FROM account WHERE id = 3`).Scan(&mail, &ageText)
+	if err != nil {
+		t.Fatalf("Cannot select altered columns: %s", err)
+	}
+	if mail != "baz@foo.com" || ageText != "21" {
+		t.Fatalf("Expected baz@foo.com and 21, got %s and %s", mail, ageText)
+	}
+
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM article WHERE account_id = 1`).Scan(&n)
+	if err != nil {
+		t.Fatalf("Cannot select from renamed table: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("Expected 1 article, got %d", n)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`SELECT email FROM account`, `email`},
+		{`SELECT score FROM account`, `score`},
+		{`SELECT * FROM post`, `post`},
+		{`ALTER TABLE nope ADD COLUMN foo TEXT`, `relation "nope" does not exist`},
+		{`ALTER TABLE account ADD COLUMN mail TEXT`, `column "mail" of relation "account" already exists`},
+		{`ALTER TABLE account DROP COLUMN nope`, `column "nope" of relation "account" does not exist`},
+		{`ALTER TABLE account ADD COLUMN code TEXT NOT NULL`, `null value in column "code" violates not-null constraint`},
+		{`ALTER TABLE account ALTER COLUMN age SET NOT NULL`, `null value in column "age" violates not-null constraint`},
+		{`ALTER TABLE account ALTER COLUMN id DROP NOT NULL`, `column "id" is in a primary key`},
+		{`ALTER TABLE account ALTER COLUMN mail TYPE BIGINT`, `invalid input syntax for type bigint`},
+		{`ALTER TABLE account DROP COLUMN id`, `cannot drop column id of table account because other objects depend on it`},
+		{`ALTER TABLE account DROP CONSTRAINT account_pkey`, `cannot drop constraint account_pkey on table account because other objects depend on it`},
+		{`ALTER TABLE account DROP CONSTRAINT nope`, `constraint "nope" of relation "account" does not exist`},
+		{`ALTER TABLE account RENAME TO article`, `relation "article" already exists`},
+		{`ALTER TABLE account ALTER COLUMN id TYPE TEXT`, `foreign key constraint "post_account_id_fkey" cannot be implemented`},
+		{`ALTER TABLE article ALTER COLUMN account_id TYPE TEXT`, `key columns "account_id" and "id" are of incompatible types: text and bigint`},
+		{`CREATE TABLE comment (id BIGINT, account_id TEXT REFERENCES account)`, `foreign key constraint "comment_account_id_fkey" cannot be implemented`},
+		{`ALTER TABLE article ADD FOREIGN KEY (title) REFERENCES account (id)`, `are of incompatible types: text and bigint`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Constraints are checked against existing rows when added
+	_, err = db.Exec(`UPDATE account SET mail = 'foo@bar.com' WHERE id = 3`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`ALTER TABLE account ADD CONSTRAINT account_mail_key UNIQUE (mail)`)
+	if err == nil || !strings.Contains(err.Error(), `duplicate key value violates unique constraint "account_mail_key"`) {
+		t.Fatalf("Expected unique violation, got %v", err)
+	}
+	_, err = db.Exec(`UPDATE account SET mail = 'baz@foo.com' WHERE id = 3`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	batch = []string{
+		`ALTER TABLE account ADD CONSTRAINT account_mail_key UNIQUE (mail)`,
+		`ALTER TABLE account ADD CONSTRAINT account_id_check CHECK (id < 100)`,
+		`ALTER TABLE article ADD CONSTRAINT article_title_key UNIQUE (title)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	violations = []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (id, mail) VALUES (5, 'foo@bar.com')`, `duplicate key value violates unique constraint "account_mail_key"`},
+		{`INSERT INTO account (id, mail) VALUES (100, 'new@foo.com')`, `violates check constraint "account_id_check"`},
+		{`INSERT INTO article (id, account_id, title) VALUES (2, 5, 'world')`, `violates foreign key constraint "post_account_id_fkey"`},
+		{`INSERT INTO article (id, account_id, title) VALUES (2, 1, 'hello')`, `duplicate key value violates unique constraint "article_title_key"`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Renamed columns are still checked
+	_, err = db.Exec(`ALTER TABLE account RENAME id TO account_id`)
+	if err != nil {
+		t.Fatalf("Cannot rename column: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO account (account_id, mail) VALUES (100, 'new@foo.com')`)
+	if err == nil || !strings.Contains(err.Error(), `violates check constraint "account_id_check"`) {
+		t.Fatalf("Expected check violation, got %v", err)
+	}
+
+	// Dropping constraints and columns
+	batch = []string{
+		`ALTER TABLE account DROP CONSTRAINT account_id_check`,
+		`ALTER TABLE account DROP CONSTRAINT IF EXISTS account_id_check`,
+		`ALTER TABLE account DROP COLUMN IF EXISTS score`,
+		`ALTER TABLE account DROP CONSTRAINT account_pkey CASCADE`,
+		`INSERT INTO article (id, account_id, title) VALUES (2, 5, 'world')`,
+		`ALTER TABLE account DROP COLUMN account_id`,
+		`INSERT INTO account (mail) VALUES ('new@foo.com')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// Unique constraint follows the attribute moved by the dropped column
+	_, err = db.Exec(`INSERT INTO account (mail) VALUES ('new@foo.com')`)
+	if err == nil || !strings.Contains(err.Error(), `duplicate key value violates unique constraint "account_mail_key"`) {
+		t.Fatalf("Expected unique violation, got %v", err)
+	}
+
+	// Quoted default values
+	batch = []string{
+		`ALTER TABLE article ALTER COLUMN title SET DEFAULT 'zz'`,
+		`ALTER TABLE article ADD COLUMN x TEXT DEFAULT 'yy'`,
+		`ALTER TABLE article ADD COLUMN published DATE DEFAULT '2020-01-02'`,
+		`INSERT INTO article (id, account_id) VALUES (3, 1)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	var title, x string
+	var published time.Time
+	err = db.QueryRow(`SELECT title, x, published FROM article WHERE id = 3`).Scan(&title, &x, &published)
+	if err != nil {
+		t.Fatalf("Cannot select default values: %s", err)
+	}
+	if title != "zz" || x != "yy" || !published.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected default values zz, yy and 2020-01-02, got %s, %s and %s", title, x, published)
+	}
+
+	// USING computes values of existing rows
+	_, err = db.Exec(`ALTER TABLE article ALTER COLUMN x DROP DEFAULT, ALTER COLUMN x TYPE INT USING length(x) * 10`)
+	if err != nil {
+		t.Fatalf("Cannot alter column type using expression: %s", err)
+	}
+	var length int64
+	err = db.QueryRow(`SELECT x FROM article WHERE id = 3`).Scan(&length)
+	if err != nil || length != 20 {
+		t.Fatalf("Expected 20, got %d (%v)", length, err)
+	}
+	_, err = db.Exec(`ALTER TABLE article ALTER COLUMN title TYPE INT USING nope`)
+	if err == nil || !strings.Contains(err.Error(), `column "nope" does not exist`) {
+		t.Fatalf("Expected unknown column error, got %v", err)
+	}
+}
+
+func TestAlterTableRollback(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestAlterTableRollback")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot begin transaction: %s", err)
+	}
+	batch = []string{
+		`ALTER TABLE account ADD COLUMN age INT DEFAULT 18`,
+		`ALTER TABLE account DROP COLUMN email`,
+		`ALTER TABLE account RENAME TO member`,
+		`INSERT INTO member (id, age) VALUES (2, 30)`,
+	}
+	for _, b := range batch {
+		_, err = tx.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("Cannot rollback: %s", err)
+	}
+
+	var email string
+	var n int
+	err = db.QueryRow(`SELECT email FROM account WHERE id = 1`).Scan(&email)
+	if err != nil {
+		t.Fatalf("Cannot select from restored table: %s", err)
+	}
+	if email != "foo@bar.com" {
+		t.Fatalf("Expected foo@bar.com, got %s", email)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 row, got %d (%v)", n, err)
+	}
+	_, err = db.Exec(`SELECT age FROM account`)
+	if err == nil {
+		t.Fatalf("Expected added column to be rolled back")
+	}
+}
@@ -92,3 +92,62 @@ func TestOtherAutoIncrementSimple(t *testing.T) {
 		t.Fatalf("Last insterted id should be 2, not %d", lastID)
 	}
 }
+
+func TestAutoIncrementAfterDelete(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestAutoIncrementAfterDelete")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY AUTO_INCREMENT, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@foo.com')`,
+		`INSERT INTO account (email) VALUES ('baz@foo.com')`,
+		`DELETE FROM account WHERE id = 1`,
+		`DELETE FROM account WHERE id = 3`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// Ids of deleted rows are never given again
+	res, err := db.Exec(`INSERT INTO account (email) VALUES ('qux@foo.com')`)
+	if err != nil {
+		t.Fatalf("Cannot insert into table account: %s", err)
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil || lastID != 4 {
+		t.Fatalf("Last inserted id should be 4, not %d (%v)", lastID, err)
+	}
+
+	// Explicit values are kept, and never generated afterwards
+	batch = []string{
+		`INSERT INTO account (id, email) VALUES (10, 'ten@foo.com')`,
+		`INSERT INTO account (id, email) VALUES (NULL, 'eleven@foo.com')`,
+		`INSERT INTO account (id, email) VALUES (DEFAULT, 'twelve@foo.com')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var email string
+	err = db.QueryRow(`SELECT email FROM account WHERE id = 12`).Scan(&email)
+	if err != nil || email != "twelve@foo.com" {
+		t.Fatalf("Expected twelve@foo.com with id 12, got %s (%v)", email, err)
+	}
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&n)
+	if err != nil || n != 5 {
+		t.Fatalf("Expected 5 rows, got %d (%v)", n, err)
+	}
+}
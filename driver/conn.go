@@ -1,7 +1,10 @@
 package ramsql
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"sync"
 
 	"github.com/kokizzu/ramsql/engine/log"
@@ -10,8 +13,7 @@ import (
 
 // Conn implements sql/driver Conn interface
 type Conn struct {
-	// Mutex is locked when a Statement is created
-	// then released on Statement.Exec or Statement.Query
+	// Mutex serializes requests to the engine
 	mutex sync.Mutex
 
 	// Socket is the network connection to RamSQL engine
@@ -29,10 +31,127 @@ func newConn(conn protocol.DriverConn, parent *Server) driver.Conn {
 
 // Prepare returns a prepared statement, bound to this connection.
 func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return prepareStatement(context.Background(), c, query)
+}
+
+// PrepareContext returns a prepared statement, bound to this connection.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return prepareStatement(ctx, c, query)
+}
+
+// ExecContext executes a query that doesn't return rows without
+// preparing it first.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	log.Info("Exec <%s>\n", query)
+
+	return c.exec(ctx, func() error {
+		return c.conn.WriteExec(ctx, query, namedArguments(args))
+	})
+}
+
+// QueryContext executes a query that may return rows without
+// preparing it first.
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	log.Info("Query < %s >\n", query)
+
+	return c.query(ctx, func() error {
+		return c.conn.WriteQuery(ctx, query, namedArguments(args))
+	})
+}
+
+// Ping checks the connection to the engine is still alive.
+func (c *Conn) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Engine answers an empty query with an empty result
+	err := c.conn.WriteExec(ctx, "", nil)
+	if err != nil {
+		return driver.ErrBadConn
+	}
+
+	_, _, err = c.conn.ReadResult()
+	if err != nil {
+		return driver.ErrBadConn
+	}
+
+	return nil
+}
+
+// exec sends a statement with given write function and reads its result.
+// If ctx is done meanwhile, the engine aborts the statement and ctx error
+// is returned.
+func (c *Conn) exec(ctx context.Context, write func() error) (r driver.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fatalf error: %s", r)
+			return
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Send query to server
+	err = write()
+	if err != nil {
+		log.Warning("Exec: Cannot send query to server: %s", err)
+		return nil, fmt.Errorf("Cannot send query to server: %s", err)
+	}
+
+	// Get answer from server
+	lastInsertedID, rowsAffected, err := c.conn.ReadResult()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	// Create a driver.Result
+	return newResult(lastInsertedID, rowsAffected), nil
+}
+
+// query sends a statement with given write function and reads rows it returns.
+// If ctx is done meanwhile, the engine aborts the statement and ctx error
+// is returned.
+func (c *Conn) query(ctx context.Context, write func() error) (r driver.Rows, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fatalf error: %s", r)
+			return
+		}
+	}()
 
-	stmt := prepareStatement(c, query)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err = write()
+	if err != nil {
+		return nil, err
+	}
+
+	columns, rowsChannel, err := c.conn.ReadRows()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
 
-	return stmt, nil
+	return newRows(ctx, columns, rowsChannel), nil
 }
 
 // Close invalidates and potentially stops any current
@@ -56,6 +175,47 @@ func (c *Conn) Close() error {
 
 // Begin starts and returns a new transaction.
 func (c *Conn) Begin() (driver.Tx, error) {
+	return c.begin("", false)
+}
+
+// BeginTx starts and returns a new transaction with given isolation level
+// and access mode.
+//
+// READ UNCOMMITTED is handled as READ COMMITTED and SNAPSHOT
+// as REPEATABLE READ.
+func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var level string
+
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		level = "READ UNCOMMITTED"
+	case sql.LevelReadCommitted:
+		level = "READ COMMITTED"
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		level = "REPEATABLE READ"
+	case sql.LevelSerializable:
+		level = "SERIALIZABLE"
+	default:
+		return nil, fmt.Errorf("isolation level %s is not supported", sql.IsolationLevel(opts.Isolation))
+	}
+
+	return c.begin(level, opts.ReadOnly)
+}
+
+func (c *Conn) begin(isolationLevel string, readOnly bool) (driver.Tx, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.conn.WriteBegin(isolationLevel, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = c.conn.ReadResult()
+	if err != nil {
+		return nil, err
+	}
 
 	tx := Tx{
 		conn: c,
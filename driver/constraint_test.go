@@ -0,0 +1,596 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestPrimaryKey(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestPrimaryKey")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE membership (account_id BIGINT, team TEXT, role TEXT, CONSTRAINT membership_pk PRIMARY KEY (account_id, team))`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@foo.com')`,
+		`INSERT INTO membership (account_id, team, role) VALUES (1, 'core', 'admin')`,
+		`INSERT INTO membership (account_id, team, role) VALUES (1, 'docs', 'member')`,
+		`INSERT INTO membership (account_id, team, role) VALUES (2, 'core', 'member')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (id, email) VALUES (1, 'baz@foo.com')`, `duplicate key value violates unique constraint "account_pkey"`},
+		{`INSERT INTO account (email) VALUES ('baz@foo.com')`, `doesn't have a default value`},
+		{`INSERT INTO account (id, email) VALUES (NULL, 'baz@foo.com')`, `null value in column "id" violates not-null constraint`},
+		{`UPDATE account SET id = 2 WHERE id = 1`, `duplicate key value violates unique constraint "account_pkey"`},
+		{`INSERT INTO membership (account_id, team, role) VALUES (1, 'core', 'member')`, `duplicate key value violates unique constraint "membership_pk"`},
+		{`UPDATE membership SET team = 'core' WHERE team = 'docs'`, `duplicate key value violates unique constraint "membership_pk"`},
+		{`CREATE TABLE invalid (id BIGINT PRIMARY KEY, name TEXT, PRIMARY KEY (name))`, `multiple primary keys for table "invalid" are not allowed`},
+		{`CREATE TABLE invalid (id BIGINT, PRIMARY KEY (name))`, `column "name" named in key does not exist`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Updating a row without changing its key is fine
+	_, err = db.Exec(`UPDATE account SET id = 1, email = 'baz@foo.com' WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot update row: %s", err)
+	}
+
+	// Key is available again once its row is deleted
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot begin transaction: %s", err)
+	}
+	_, err = tx.Exec(`DELETE FROM membership WHERE account_id = 2`)
+	if err != nil {
+		t.Fatalf("Cannot delete row: %s", err)
+	}
+	_, err = tx.Exec(`INSERT INTO membership (account_id, team, role) VALUES (2, 'core', 'admin')`)
+	if err != nil {
+		t.Fatalf("Cannot insert row with deleted key: %s", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("Cannot commit: %s", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM membership WHERE account_id = 2`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Cannot count rows: %s", err)
+	}
+	if count != 1 {
+		t.Fatalf("Expected 1 row, got %d", count)
+	}
+
+	// Key columns are NOT NULL
+	rows, err := db.Query(`SELECT account_id, team, role FROM membership`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s", err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("Cannot get column types: %s", err)
+	}
+	for i, expected := range []bool{false, false, true} {
+		nullable, ok := types[i].Nullable()
+		if !ok || nullable != expected {
+			t.Fatalf("Expected column %s nullable to be %v, got %v", types[i].Name(), expected, nullable)
+		}
+	}
+}
+
+func TestUniqueConstraints(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestUniqueConstraints")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT UNIQUE, nickname TEXT, CONSTRAINT nickname_taken UNIQUE (nickname))`,
+		`CREATE TABLE address (id BIGINT, account_id BIGINT, street TEXT, town TEXT, UNIQUE KEY street_town (street, town))`,
+		`CREATE TABLE tag (id BIGINT, a TEXT, b TEXT, UNIQUE (a, b))`,
+		`INSERT INTO account (id, email, nickname) VALUES (1, 'foo@bar.com', 'foo')`,
+		`INSERT INTO account (id, email, nickname) VALUES (2, 'bar@foo.com', 'bar')`,
+		`INSERT INTO address (id, account_id, street, town) VALUES (1, 1, 'Rue de Rivoli', 'Paris')`,
+		`INSERT INTO address (id, account_id, street, town) VALUES (2, 2, 'Rue de Rivoli', 'Lyon')`,
+		`INSERT INTO tag (id, a, b) VALUES (1, 'x', 'y')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (id, email, nickname) VALUES (3, 'foo@bar.com', 'baz')`, `duplicate key value violates unique constraint "account_email_key"`},
+		{`UPDATE account SET email = 'foo@bar.com' WHERE id = 2`, `duplicate key value violates unique constraint "account_email_key"`},
+		{`INSERT INTO account (id, email, nickname) VALUES (3, 'baz@bar.com', 'foo')`, `duplicate key value violates unique constraint "nickname_taken"`},
+		{`UPDATE account SET nickname = 'bar' WHERE id = 1`, `duplicate key value violates unique constraint "nickname_taken"`},
+		{`INSERT INTO address (id, account_id, street, town) VALUES (3, 1, 'Rue de Rivoli', 'Lyon')`, `duplicate key value violates unique constraint "street_town"`},
+		{`UPDATE address SET town = 'Paris' WHERE id = 2`, `duplicate key value violates unique constraint "street_town"`},
+		{`INSERT INTO tag (id, a, b) VALUES (2, 'x', 'y')`, `duplicate key value violates unique constraint "tag_a_b_key"`},
+		{`CREATE TABLE invalid (id BIGINT, UNIQUE (name))`, `column "name" named in key does not exist`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// NULL values are distinct
+	batch = []string{
+		`INSERT INTO account (id, email, nickname) VALUES (3, NULL, 'baz')`,
+		`INSERT INTO account (id, email, nickname) VALUES (4, NULL, 'qux')`,
+		`INSERT INTO tag (id, a, b) VALUES (2, 'x', NULL)`,
+		`INSERT INTO tag (id, a, b) VALUES (3, 'x', NULL)`,
+		`UPDATE account SET email = NULL WHERE id = 1`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+
+	// A value freed by an update can be reused
+	_, err = db.Exec(`INSERT INTO account (id, email, nickname) VALUES (5, 'foo@bar.com', 'foo2')`)
+	if err != nil {
+		t.Fatalf("Cannot reuse freed value: %s", err)
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Cannot count rows: %s", err)
+	}
+	if count != 5 {
+		t.Fatalf("Expected 5 rows, got %d", count)
+	}
+}
+
+func TestForeignKey(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestForeignKey")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT UNIQUE)`,
+		`CREATE TABLE address (id BIGINT PRIMARY KEY, account_id BIGINT REFERENCES account ON DELETE CASCADE ON UPDATE CASCADE, street TEXT)`,
+		`CREATE TABLE parcel (id BIGINT PRIMARY KEY, address_id BIGINT, CONSTRAINT parcel_address FOREIGN KEY (address_id) REFERENCES address (id) ON DELETE SET NULL)`,
+		`CREATE TABLE invoice (id BIGINT PRIMARY KEY, account_id BIGINT DEFAULT 3, FOREIGN KEY (account_id) REFERENCES account (id) ON DELETE SET DEFAULT)`,
+		`CREATE TABLE newsletter (id BIGINT PRIMARY KEY, email TEXT REFERENCES account (email) ON DELETE RESTRICT)`,
+		`CREATE TABLE payment (id BIGINT PRIMARY KEY, invoice_id BIGINT, FOREIGN KEY (invoice_id) REFERENCES invoice (id))`,
+		`CREATE TABLE employee (id BIGINT PRIMARY KEY, manager_id BIGINT REFERENCES employee (id) ON DELETE CASCADE)`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@foo.com')`,
+		`INSERT INTO account (id, email) VALUES (3, 'nobody@bar.com')`,
+		`INSERT INTO address (id, account_id, street) VALUES (1, 1, 'Rue de Rivoli')`,
+		`INSERT INTO address (id, account_id, street) VALUES (2, 2, 'Champs Elysees')`,
+		`INSERT INTO address (id, account_id, street) VALUES (3, NULL, 'Rue du Bac')`,
+		`INSERT INTO parcel (id, address_id) VALUES (1, 1)`,
+		`INSERT INTO invoice (id, account_id) VALUES (1, 2)`,
+		`INSERT INTO newsletter (id, email) VALUES (1, 'foo@bar.com')`,
+		`INSERT INTO payment (id, invoice_id) VALUES (1, 1)`,
+		`INSERT INTO employee (id, manager_id) VALUES (1, NULL)`,
+		`INSERT INTO employee (id, manager_id) VALUES (2, 1)`,
+		`INSERT INTO employee (id, manager_id) VALUES (3, 2)`,
+		`INSERT INTO employee (id, manager_id) VALUES (4, 4)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO address (id, account_id, street) VALUES (4, 42, 'Nowhere')`, `insert or update on table "address" violates foreign key constraint "address_account_id_fkey"`},
+		{`UPDATE parcel SET address_id = 42 WHERE id = 1`, `insert or update on table "parcel" violates foreign key constraint "parcel_address"`},
+		{`DELETE FROM invoice WHERE id = 1`, `update or delete on table "invoice" violates foreign key constraint "payment_invoice_id_fkey" on table "payment"`},
+		{`DELETE FROM account WHERE id = 1`, `update or delete on table "account" violates foreign key constraint "newsletter_email_fkey" on table "newsletter"`},
+		{`INSERT INTO newsletter (id, email) VALUES (2, 'baz@bar.com')`, `insert or update on table "newsletter" violates foreign key constraint "newsletter_email_fkey"`},
+		{`UPDATE invoice SET id = 2 WHERE id = 1`, `update or delete on table "invoice" violates foreign key constraint "payment_invoice_id_fkey" on table "payment"`},
+		{`DROP TABLE invoice`, `cannot drop table invoice because other objects depend on it`},
+		{`CREATE TABLE invalid (id BIGINT, account_id BIGINT REFERENCES nowhere (id))`, `relation "nowhere" does not exist`},
+		{`CREATE TABLE invalid (id BIGINT, street TEXT REFERENCES address (street))`, `there is no unique constraint matching given keys for referenced table "address"`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	count := func(query string, expected int) {
+		var n int
+		err := db.QueryRow(query).Scan(&n)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", query, err)
+		}
+		if n != expected {
+			t.Fatalf("Expected %d executing %s, got %d", expected, query, n)
+		}
+	}
+
+	// ON UPDATE CASCADE
+	_, err = db.Exec(`UPDATE account SET id = 10 WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot update referenced key: %s", err)
+	}
+	count(`SELECT COUNT(*) FROM address WHERE account_id = 10`, 1)
+
+	// ON DELETE CASCADE, then SET NULL on rows referencing deleted addresses
+	_, err = db.Exec(`DELETE FROM newsletter`)
+	if err != nil {
+		t.Fatalf("Cannot delete referencing rows: %s", err)
+	}
+	_, err = db.Exec(`DELETE FROM account WHERE id = 10`)
+	if err != nil {
+		t.Fatalf("Cannot delete referenced row: %s", err)
+	}
+	count(`SELECT COUNT(*) FROM address`, 2)
+	count(`SELECT COUNT(*) FROM parcel WHERE address_id IS NULL`, 1)
+
+	// ON DELETE SET DEFAULT
+	_, err = db.Exec(`DELETE FROM account WHERE id = 2`)
+	if err != nil {
+		t.Fatalf("Cannot delete referenced row: %s", err)
+	}
+	count(`SELECT COUNT(*) FROM invoice WHERE account_id = 3`, 1)
+
+	// Default value must reference an existing row too
+	_, err = db.Exec(`DELETE FROM account WHERE id = 3`)
+	if err == nil {
+		t.Fatalf("Expected an error deleting row referenced by default value")
+	}
+	count(`SELECT COUNT(*) FROM account`, 1)
+
+	// Self reference
+	_, err = db.Exec(`DELETE FROM employee WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot delete referenced row: %s", err)
+	}
+	count(`SELECT COUNT(*) FROM employee`, 1)
+
+	// DROP TABLE ... CASCADE drops referencing constraints only
+	_, err = db.Exec(`DROP TABLE invoice CASCADE`)
+	if err != nil {
+		t.Fatalf("Cannot drop referenced table: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO payment (id, invoice_id) VALUES (2, 42)`)
+	if err != nil {
+		t.Fatalf("Cannot insert once referenced table is dropped: %s", err)
+	}
+}
+
+func TestForeignKeyConcurrency(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestForeignKeyConcurrency")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE parent (id INT PRIMARY KEY)`,
+		`CREATE TABLE child (id SERIAL PRIMARY KEY, pid INT REFERENCES parent)`,
+		`INSERT INTO parent (id) VALUES (1)`,
+		`INSERT INTO parent (id) VALUES (2)`,
+		`INSERT INTO parent (id) VALUES (3)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+
+	// Parent being deleted cannot be referenced by a concurrent insert
+	tx1, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx1.Rollback()
+	tx2, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx2.Rollback()
+
+	_, err = tx1.Exec(`DELETE FROM parent WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot delete parent: %s", err)
+	}
+	_, err = tx2.Exec(`INSERT INTO child (pid) VALUES (1)`)
+	if err == nil || !strings.Contains(err.Error(), `could not obtain lock on row in relation "parent"`) {
+		t.Fatalf("Expected lock error inserting child of deleted parent, got %v", err)
+	}
+
+	// Nor deleted while a concurrent insert references it
+	_, err = tx2.Exec(`INSERT INTO child (pid) VALUES (2)`)
+	if err != nil {
+		t.Fatalf("Cannot insert child: %s", err)
+	}
+	_, err = tx1.Exec(`DELETE FROM parent WHERE id = 2`)
+	if err == nil || !strings.Contains(err.Error(), `could not obtain lock on row in relation "child"`) {
+		t.Fatalf("Expected lock error deleting referenced parent, got %v", err)
+	}
+	if err := tx1.Rollback(); err != nil {
+		t.Fatalf("Cannot rollback: %s", err)
+	}
+	if err := tx2.Commit(); err != nil {
+		t.Fatalf("Cannot commit: %s", err)
+	}
+
+	// Children committed after the snapshot of a REPEATABLE READ
+	// transaction are not missed
+	tx3, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx3.Rollback()
+	var n int
+	err = tx3.QueryRow(`SELECT COUNT(*) FROM child`).Scan(&n)
+	if err != nil {
+		t.Fatalf("Cannot count children: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO child (pid) VALUES (3)`)
+	if err != nil {
+		t.Fatalf("Cannot insert child: %s", err)
+	}
+	_, err = tx3.Exec(`DELETE FROM parent WHERE id = 3`)
+	if err == nil || !strings.Contains(err.Error(), `could not serialize access due to concurrent update`) {
+		t.Fatalf("Expected serialization error deleting referenced parent, got %v", err)
+	}
+	tx3.Rollback()
+
+	// No child is left without its parent
+	var joined int
+	err = db.QueryRow(`SELECT COUNT(*) FROM child`).Scan(&n)
+	if err != nil {
+		t.Fatalf("Cannot count children: %s", err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM child JOIN parent ON child.pid = parent.id`).Scan(&joined)
+	if err != nil {
+		t.Fatalf("Cannot count children with parent: %s", err)
+	}
+	if n != 2 || joined != n {
+		t.Fatalf("Expected 2 children with their parent, got %d of %d", joined, n)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestCheck")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE product (
+			id BIGINT PRIMARY KEY,
+			price INT CHECK (price > 0),
+			stock INT,
+			status TEXT,
+			CHECK (stock >= 0),
+			CONSTRAINT valid_status CHECK (status IN ('draft', 'published') OR status IS NULL)
+		)`,
+		`INSERT INTO product (id, price, stock, status) VALUES (1, 10, 5, 'draft')`,
+		`INSERT INTO product (id, price, stock, status) VALUES (2, NULL, NULL, NULL)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+
+	violations := []struct {
+		query string
+		args  []interface{}
+		err   string
+	}{
+		{`INSERT INTO product (id, price, stock, status) VALUES (3, 0, 5, 'draft')`, nil, `new row for relation "product" violates check constraint "product_price_check"`},
+		{`INSERT INTO product (id, price, stock, status) VALUES (3, 10, $1, 'draft')`, []interface{}{-1}, `new row for relation "product" violates check constraint "product_stock_check"`},
+		{`INSERT INTO product (id, price, stock, status) VALUES (3, 10, 5, 'archived')`, nil, `new row for relation "product" violates check constraint "valid_status"`},
+		{`UPDATE product SET price = $1 WHERE id = 1`, []interface{}{-10}, `new row for relation "product" violates check constraint "product_price_check"`},
+		{`UPDATE product SET status = 'deleted' WHERE id = 2`, nil, `new row for relation "product" violates check constraint "valid_status"`},
+		{`CREATE TABLE invalid (id BIGINT, CHECK (price > 0))`, nil, `attribute price does not exist in table invalid`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query, v.args...)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	_, err = db.Exec(`UPDATE product SET stock = 0, status = 'published' WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot update product: %s", err)
+	}
+
+	var stock int64
+	err = db.QueryRow(`SELECT stock FROM product WHERE id = 1`).Scan(&stock)
+	if err != nil {
+		t.Fatalf("Cannot select product: %s", err)
+	}
+	if stock != 0 {
+		t.Fatalf("Expected stock 0, got %d", stock)
+	}
+
+	// Failed CREATE TABLE leaves nothing behind
+	_, err = db.Exec(`CREATE TABLE invalid (id BIGINT)`)
+	if err != nil {
+		t.Fatalf("Cannot create table: %s", err)
+	}
+
+	// Only a false condition is a violation, NULL making it unknown unless
+	// another part decides it. Columns may be compared with each other.
+	_, err = db.Exec(`CREATE TABLE period (a INT, b INT, lo INT, hi INT, CHECK (a > 0 AND b > 0), CHECK (lo < hi))`)
+	if err != nil {
+		t.Fatalf("Cannot create table: %s", err)
+	}
+	batch = []string{
+		`INSERT INTO period (a, b, lo, hi) VALUES (1, 1, 1, 2)`,
+		`INSERT INTO period (a, b, lo, hi) VALUES (NULL, 1, NULL, 2)`,
+		`INSERT INTO period (a, b, lo, hi) VALUES (NULL, NULL, 1, NULL)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+	violations = []struct {
+		query string
+		args  []interface{}
+		err   string
+	}{
+		{`INSERT INTO period (a, b, lo, hi) VALUES (NULL, 0, 1, 2)`, nil, `violates check constraint "period_check"`},
+		{`INSERT INTO period (a, b, lo, hi) VALUES (0, NULL, 1, 2)`, nil, `violates check constraint "period_check"`},
+		{`INSERT INTO period (a, b, lo, hi) VALUES (1, 1, 2, 1)`, nil, `violates check constraint "period_check1"`},
+		{`UPDATE period SET hi = lo WHERE a = 1`, nil, `violates check constraint "period_check1"`},
+		{`CREATE TABLE invalid_check (price INT CHECK (price + 1))`, nil, `argument of CHECK must be type boolean`},
+		{`CREATE TABLE invalid_check (lo INT, CHECK (lo < hi))`, nil, `attribute hi does not exist in table invalid_check`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query, v.args...)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Unnamed checks are told apart by a number once their default name
+	// is taken, whether given with the column, the table or added later
+	batch = []string{
+		`CREATE TABLE stock (qty INT CHECK (qty >= 0), CHECK (qty < 100), CHECK (qty <= 90), lo INT, hi INT, CHECK (lo < hi), CHECK (lo > 0 OR hi > 0))`,
+		`ALTER TABLE stock ADD CHECK (qty <= 80)`,
+		`ALTER TABLE stock ADD CHECK (hi - lo < 10)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+	violations = []struct {
+		query string
+		args  []interface{}
+		err   string
+	}{
+		{`INSERT INTO stock (qty, lo, hi) VALUES ($1, 1, 2)`, []interface{}{-1}, `violates check constraint "stock_qty_check"`},
+		{`INSERT INTO stock (qty, lo, hi) VALUES (100, 1, 2)`, nil, `violates check constraint "stock_qty_check1"`},
+		{`INSERT INTO stock (qty, lo, hi) VALUES (95, 1, 2)`, nil, `violates check constraint "stock_qty_check2"`},
+		{`INSERT INTO stock (qty, lo, hi) VALUES (85, 1, 2)`, nil, `violates check constraint "stock_qty_check3"`},
+		{`INSERT INTO stock (qty, lo, hi) VALUES (1, 2, 1)`, nil, `violates check constraint "stock_check"`},
+		{`INSERT INTO stock (qty, lo, hi) VALUES (1, $1, $2)`, []interface{}{-2, -1}, `violates check constraint "stock_check1"`},
+		{`INSERT INTO stock (qty, lo, hi) VALUES (1, 1, 11)`, nil, `violates check constraint "stock_check2"`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query, v.args...)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+}
+
+func TestColumnConstraintName(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestColumnConstraintName")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE team (id BIGINT PRIMARY KEY)`,
+		`CREATE TABLE member (
+			id BIGINT CONSTRAINT member_id PRIMARY KEY,
+			email TEXT CONSTRAINT email_required NOT NULL CONSTRAINT email_taken UNIQUE,
+			age INT CONSTRAINT adult CHECK (age >= 18),
+			team_id BIGINT CONSTRAINT member_team REFERENCES team (id)
+		)`,
+		`INSERT INTO team (id) VALUES (1)`,
+		`INSERT INTO member (id, email, age, team_id) VALUES (1, 'foo@bar.com', 20, 1)`,
+		`ALTER TABLE member ADD COLUMN nick TEXT CONSTRAINT short_nick CHECK (length(nick) < 8)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot execute %s: %s", b, err)
+		}
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO member (id, email, age, team_id) VALUES (1, 'bar@bar.com', 20, 1)`, `violates unique constraint "member_id"`},
+		{`INSERT INTO member (id, email, age, team_id) VALUES (2, NULL, 20, 1)`, `null value in column "email" violates not-null constraint`},
+		{`INSERT INTO member (id, email, age, team_id) VALUES (2, 'foo@bar.com', 20, 1)`, `violates unique constraint "email_taken"`},
+		{`INSERT INTO member (id, email, age, team_id) VALUES (2, 'bar@bar.com', 10, 1)`, `violates check constraint "adult"`},
+		{`INSERT INTO member (id, email, age, team_id) VALUES (2, 'bar@bar.com', 20, 2)`, `violates foreign key constraint "member_team"`},
+		{`UPDATE member SET nick = 'nicknamed'`, `violates check constraint "short_nick"`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	_, err = db.Exec(`ALTER TABLE member DROP CONSTRAINT adult`)
+	if err != nil {
+		t.Fatalf("Cannot drop named column constraint: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO member (id, email, age, team_id) VALUES (2, 'bar@bar.com', 10, 1)`)
+	if err != nil {
+		t.Fatalf("Cannot insert once constraint dropped: %s", err)
+	}
+}
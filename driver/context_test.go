@@ -0,0 +1,116 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+// cancelAfter is a context cancelled once its Err method has been called n times,
+// so statements are aborted deterministically while the engine walks rows
+type cancelAfter struct {
+	context.Context
+	n      int32
+	cancel context.CancelFunc
+}
+
+func newCancelAfter(n int32) *cancelAfter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancelAfter{Context: ctx, n: n, cancel: cancel}
+}
+
+func (c *cancelAfter) Err() error {
+	if atomic.AddInt32(&c.n, -1) == 0 {
+		c.cancel()
+	}
+	return c.Context.Err()
+}
+
+func TestContext(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestContext")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	err = db.PingContext(context.Background())
+	if err != nil {
+		t.Fatalf("Cannot ping: %s", err)
+	}
+
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, `CREATE TABLE account (id BIGINT, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.ExecContext(ctx, `CREATE TABLE address (id BIGINT, account_id BIGINT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	for i := 0; i < 200; i++ {
+		_, err = db.ExecContext(ctx, `INSERT INTO account (id, email) VALUES ($1, $2)`, i, "foo@bar.com")
+		if err != nil {
+			t.Fatalf("Cannot insert: %s", err)
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO address (id, account_id) VALUES ($1, $1)`, i)
+		if err != nil {
+			t.Fatalf("Cannot insert: %s", err)
+		}
+	}
+
+	// Already expired context
+	expired, cancel := context.WithTimeout(ctx, time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+
+	_, err = db.QueryContext(expired, `SELECT * FROM account`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded error, got %v", err)
+	}
+
+	err = db.PingContext(expired)
+	if err == nil {
+		t.Fatalf("Expected an error pinging with an expired context")
+	}
+
+	// Statement cancelled while running is rolled back
+	_, err = db.ExecContext(newCancelAfter(100), `DELETE FROM account WHERE email = 'foo@bar.com'`)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled error, got %v", err)
+	}
+
+	_, err = db.ExecContext(newCancelAfter(100), `UPDATE account SET email = 'bar@foo.com' WHERE email = 'foo@bar.com'`)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled error, got %v", err)
+	}
+
+	// Rows are streamed, so cancellation shows once they are read
+	rows, err := db.QueryContext(newCancelAfter(100), `SELECT * FROM account JOIN address ON address.account_id = account.id`)
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled error, got %v", err)
+	}
+
+	// Connection is still usable afterward
+	var count int64
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM account WHERE email = $1`, "foo@bar.com").Scan(&count)
+	if err != nil {
+		t.Fatalf("Cannot count rows: %s", err)
+	}
+	if count != 200 {
+		t.Fatalf("Expected 200 rows, got %d", count)
+	}
+}
@@ -0,0 +1,101 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestDeleteUsing(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestDeleteUsing")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE users (id INT PRIMARY KEY, name TEXT, disabled BOOLEAN)`,
+		`CREATE TABLE sessions (id INT PRIMARY KEY, user_id INT, token TEXT)`,
+		`INSERT INTO users (id, name, disabled) VALUES (1, 'foo', false)`,
+		`INSERT INTO users (id, name, disabled) VALUES (2, 'bar', true)`,
+		`INSERT INTO sessions (id, user_id, token) VALUES (1, 1, 'a')`,
+		`INSERT INTO sessions (id, user_id, token) VALUES (2, 2, 'b')`,
+		`INSERT INTO sessions (id, user_id, token) VALUES (3, 2, 'c')`,
+		`INSERT INTO sessions (id, user_id, token) VALUES (4, 3, 'd')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot exec query %s: %s", b, err)
+		}
+	}
+
+	res, err := db.Exec(`DELETE FROM sessions USING users WHERE sessions.user_id = users.id AND users.disabled`)
+	if err != nil {
+		t.Fatalf("Cannot delete using: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 2 {
+		t.Fatalf("Expected 2 rows affected, got %d", ra)
+	}
+
+	// Joined values may be returned
+	var token, name string
+	err = db.QueryRow(`DELETE FROM sessions USING users WHERE users.id = sessions.user_id AND users.name = 'foo' RETURNING sessions.token, users.name`).Scan(&token, &name)
+	if err != nil || token != "a" || name != "foo" {
+		t.Fatalf("Expected session a of foo, got %s and %s (%v)", token, name, err)
+	}
+
+	// The table rows are deleted from may be aliased
+	_, err = db.Exec(`INSERT INTO sessions (id, user_id, token) VALUES (5, 2, 'e')`)
+	if err != nil {
+		t.Fatalf("Cannot insert: %s", err)
+	}
+	res, err = db.Exec(`DELETE FROM sessions s USING users u WHERE s.user_id = u.id AND u.disabled`)
+	if err != nil {
+		t.Fatalf("Cannot delete using aliases: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 1 {
+		t.Fatalf("Expected 1 row affected, got %d", ra)
+	}
+	res, err = db.Exec(`DELETE FROM sessions AS s WHERE s.token = 'nope'`)
+	if err != nil {
+		t.Fatalf("Cannot delete using alias: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 0 {
+		t.Fatalf("Expected no row affected, got %d", ra)
+	}
+
+	// Without WHERE, every row is joined with every user
+	res, err = db.Exec(`DELETE FROM sessions USING users`)
+	if err != nil {
+		t.Fatalf("Cannot delete using: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 1 {
+		t.Fatalf("Expected 1 row affected, got %d", ra)
+	}
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count)
+	if err != nil || count != 0 {
+		t.Fatalf("Expected no session left, got %d (%v)", count, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`DELETE FROM sessions USING nope WHERE sessions.user_id = nope.id`, `nope`},
+		{`DELETE FROM sessions USING users WHERE users.nope = sessions.user_id`, `column "nope" does not exist`},
+		{`DELETE FROM sessions USING users WHERE id = 1`, `column reference "id" is ambiguous`},
+		{`DELETE FROM sessions USING sessions`, `specified more than once`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+}
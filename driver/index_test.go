@@ -0,0 +1,274 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestIndex(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestIndex")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT, age INT, KEY account_age (age))`,
+		`CREATE TABLE post (id BIGINT PRIMARY KEY, account_id BIGINT, title TEXT)`,
+		`CREATE INDEX ON post (account_id)`,
+		`CREATE UNIQUE INDEX account_email ON account USING HASH (email)`,
+		`CREATE INDEX IF NOT EXISTS account_email ON account (email)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	for i := 1; i <= 1000; i++ {
+		_, err = db.Exec(`INSERT INTO account (id, email, age) VALUES (?, ?, ?)`, i, fmt.Sprintf("user%d@foo.com", i), i%50)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+		_, err = db.Exec(`INSERT INTO post (id, account_id, title) VALUES (?, ?, ?)`, i, (i%10)+1, fmt.Sprintf("post %d", i))
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	batch = []string{
+		`UPDATE account SET age = 100 WHERE id = 500`,
+		`UPDATE account SET email = 'new@foo.com' WHERE email = 'user2@foo.com'`,
+		`DELETE FROM account WHERE id = 3`,
+		`DELETE FROM post WHERE account_id = 10`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	counts := []struct {
+		query string
+		count int
+	}{
+		{`SELECT COUNT(*) FROM account WHERE id = 500`, 1},
+		{`SELECT COUNT(*) FROM account WHERE id = 3`, 0},
+		{`SELECT COUNT(*) FROM account WHERE email = 'user2@foo.com'`, 0},
+		{`SELECT COUNT(*) FROM account WHERE email = 'new@foo.com'`, 1},
+		{`SELECT COUNT(*) FROM account WHERE age = 100`, 1},
+		{`SELECT COUNT(*) FROM account WHERE age = 0`, 19},
+		{`SELECT COUNT(*) FROM account WHERE age = 0 AND id > 500`, 10},
+		{`SELECT COUNT(*) FROM account WHERE id > 10 AND id <= 20`, 10},
+		{`SELECT COUNT(*) FROM account WHERE id >= 1 AND id < 5`, 3},
+		{`SELECT COUNT(*) FROM account WHERE id IN (1, 2, 3, 4)`, 3},
+		{`SELECT COUNT(*) FROM account WHERE age = 1 OR age = 2`, 40},
+		{`SELECT COUNT(*) FROM post WHERE account_id = 1`, 100},
+		{`SELECT COUNT(*) FROM post WHERE account_id = 10`, 0},
+		{`SELECT COUNT(*) FROM account JOIN post ON post.account_id = account.id WHERE account.id < 5`, 300},
+	}
+	for _, c := range counts {
+		var n int
+		err = db.QueryRow(c.query).Scan(&n)
+		if err != nil {
+			t.Fatalf("Cannot run %s: %s", c.query, err)
+		}
+		if n != c.count {
+			t.Fatalf("Expected %d rows for %s, got %d", c.count, c.query, n)
+		}
+	}
+
+	// Rows found through an index keep the table order
+	rows, err := db.Query(`SELECT id FROM account WHERE age = 7`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	last := int64(0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		if id <= last {
+			t.Fatalf("Expected ids in ascending order, got %d after %d", id, last)
+		}
+		last = id
+	}
+	rows.Close()
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (id, email, age) VALUES (1001, 'user1@foo.com', 1)`, `duplicate key value violates unique constraint "account_email"`},
+		{`CREATE INDEX account_email ON account (age)`, `relation "account_email" already exists`},
+		{`CREATE INDEX account ON post (title)`, `relation "account" already exists`},
+		{`CREATE INDEX ON nope (id)`, `relation "nope" does not exist`},
+		{`CREATE INDEX ON account (nope)`, `column "nope" does not exist`},
+		{`CREATE UNIQUE INDEX ON account (age)`, `duplicate key value violates unique constraint "account_age_idx"`},
+		{`DROP INDEX nope`, `index "nope" does not exist`},
+		{`DROP INDEX account_pkey`, `cannot drop index account_pkey because constraint account_pkey on table account requires it`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	batch = []string{
+		`DROP INDEX account_email`,
+		`DROP INDEX IF EXISTS account_email`,
+		`DROP INDEX account_age ON account`,
+		`INSERT INTO account (id, email, age) VALUES (1001, 'user1@foo.com', 1)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM account WHERE email = 'user1@foo.com'`).Scan(&n)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows once unique index is dropped, got %d (%v)", n, err)
+	}
+}
+
+func TestIndexTransaction(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestIndexTransaction")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT)`,
+		`CREATE INDEX account_email ON account (email)`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@foo.com')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// Older snapshots still find rows by their previous values
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	tx1, err := db.BeginTx(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx1.Rollback()
+
+	var id int64
+	err = tx1.QueryRow(`SELECT id FROM account WHERE email = 'foo@bar.com'`).Scan(&id)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+
+	_, err = db.Exec(`UPDATE account SET email = 'baz@bar.com' WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("cannot update row: %s\n", err)
+	}
+
+	err = tx1.QueryRow(`SELECT id FROM account WHERE email = 'foo@bar.com'`).Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("Expected row 1 in snapshot, got %d (%v)", id, err)
+	}
+	err = tx1.QueryRow(`SELECT id FROM account WHERE email = 'baz@bar.com'`).Scan(&id)
+	if err != sql.ErrNoRows {
+		t.Fatalf("Expected no row in snapshot, got %v", err)
+	}
+	tx1.Rollback()
+
+	err = db.QueryRow(`SELECT id FROM account WHERE email = 'baz@bar.com'`).Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("Expected updated row 1, got %d (%v)", id, err)
+	}
+
+	// Index creation and row changes are cancelled by rollback
+	tx2, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	batch = []string{
+		`CREATE UNIQUE INDEX account_email_key ON account (email)`,
+		`INSERT INTO account (id, email) VALUES (3, 'qux@bar.com')`,
+		`UPDATE account SET email = 'new@bar.com' WHERE id = 2`,
+		`DROP INDEX account_email`,
+	}
+	for _, b := range batch {
+		_, err = tx2.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	err = tx2.Rollback()
+	if err != nil {
+		t.Fatalf("Cannot rollback: %s", err)
+	}
+
+	batch = []string{
+		`INSERT INTO account (id, email) VALUES (3, 'baz@bar.com')`,
+		`CREATE INDEX account_email_key ON account (email)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	counts := []struct {
+		query string
+		count int
+	}{
+		{`SELECT COUNT(*) FROM account WHERE email = 'qux@bar.com'`, 0},
+		{`SELECT COUNT(*) FROM account WHERE email = 'new@bar.com'`, 0},
+		{`SELECT COUNT(*) FROM account WHERE email = 'bar@foo.com'`, 1},
+		{`SELECT COUNT(*) FROM account WHERE email = 'baz@bar.com'`, 2},
+	}
+	for _, c := range counts {
+		var n int
+		err = db.QueryRow(c.query).Scan(&n)
+		if err != nil {
+			t.Fatalf("Cannot run %s: %s", c.query, err)
+		}
+		if n != c.count {
+			t.Fatalf("Expected %d rows for %s, got %d", c.count, c.query, n)
+		}
+	}
+
+	// Indexes follow columns moved by ALTER TABLE
+	batch = []string{
+		`ALTER TABLE account DROP COLUMN id`,
+		`ALTER TABLE account ADD COLUMN id BIGINT DEFAULT 0`,
+		`ALTER TABLE account ALTER COLUMN email TYPE VARCHAR(255)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM account WHERE email = 'bar@foo.com'`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 row once altered, got %d (%v)", n, err)
+	}
+}
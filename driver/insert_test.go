@@ -0,0 +1,411 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestInsertSelect(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestInsertSelect")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE legacy_account (id INT, email TEXT, active BOOLEAN)`,
+		`CREATE TABLE account (id SERIAL PRIMARY KEY, legacy_id INT UNIQUE, email TEXT NOT NULL)`,
+		`INSERT INTO legacy_account (id, email, active) VALUES (10, 'foo@bar.com', true)`,
+		`INSERT INTO legacy_account (id, email, active) VALUES (20, 'bar@bar.com', false)`,
+		`INSERT INTO legacy_account (id, email, active) VALUES (30, 'baz@bar.com', true)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	res, err := db.Exec(`INSERT INTO account (legacy_id, email) SELECT id, email FROM legacy_account WHERE active = true`)
+	if err != nil {
+		t.Fatalf("Cannot insert from select: %s", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows affected, got %d (%v)", n, err)
+	}
+
+	var email string
+	err = db.QueryRow(`SELECT email FROM account WHERE legacy_id = 30`).Scan(&email)
+	if err != nil || email != "baz@bar.com" {
+		t.Fatalf("Expected baz@bar.com, got %s (%v)", email, err)
+	}
+
+	// Identity columns are computed for each row
+	var id int64
+	err = db.QueryRow(`SELECT id FROM account WHERE legacy_id = 30`).Scan(&id)
+	if err != nil || id != 2 {
+		t.Fatalf("Expected id 2, got %d (%v)", id, err)
+	}
+
+	// Rows may be selected from the table they are inserted in
+	_, err = db.Exec(`INSERT INTO legacy_account (id, email) SELECT legacy_id, email FROM account`)
+	if err != nil {
+		t.Fatalf("Cannot insert from same table: %s", err)
+	}
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM legacy_account`).Scan(&count)
+	if err != nil || count != 5 {
+		t.Fatalf("Expected 5 legacy accounts, got %d (%v)", count, err)
+	}
+
+	// Without column list, values are those of all columns in table order
+	_, err = db.Exec(`INSERT INTO legacy_account SELECT id, email FROM account WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot insert without column list: %s", err)
+	}
+	var active sql.NullBool
+	err = db.QueryRow(`SELECT email, active FROM legacy_account WHERE id = 1`).Scan(&email, &active)
+	if err != nil || email != "foo@bar.com" || active.Valid {
+		t.Fatalf("Expected foo@bar.com and NULL, got %s and %v (%v)", email, active, err)
+	}
+	_, err = db.Exec(`DELETE FROM legacy_account WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// Rows are all inserted or none
+	_, err = db.Exec(`INSERT INTO account (legacy_id, email) SELECT id, email FROM legacy_account`)
+	if err == nil {
+		t.Fatalf("Expected unique violation")
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 accounts after failed insert, got %d (%v)", count, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (legacy_id) SELECT id, email FROM legacy_account`, `INSERT has more expressions than target columns`},
+		{`INSERT INTO account (legacy_id, email) SELECT id FROM legacy_account`, `INSERT has more target columns than expressions`},
+		{`INSERT INTO account (legacy_id, email) SELECT id, email FROM nowhere`, `nowhere`},
+		{`INSERT INTO legacy_account SELECT id, email, legacy_id, id FROM account`, `INSERT has more expressions than target columns`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+}
+
+func TestCreateTableAs(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestCreateTableAs")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id INT PRIMARY KEY, email TEXT, created_at TIMESTAMP, active BOOLEAN)`,
+		`INSERT INTO account (id, email, created_at, active) VALUES (1, 'foo@bar.com', '2020-01-01 10:00:00', true)`,
+		`INSERT INTO account (id, email, created_at, active) VALUES (2, 'bar@bar.com', '2021-01-01 10:00:00', false)`,
+		`INSERT INTO account (id, email, created_at, active) VALUES (3, 'baz@bar.com', '2022-01-01 10:00:00', true)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	res, err := db.Exec(`CREATE TABLE active_account AS SELECT id, email, created_at FROM account WHERE active = true`)
+	if err != nil {
+		t.Fatalf("Cannot create table as select: %s", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows affected, got %d (%v)", n, err)
+	}
+
+	// Column types are those of the result
+	rows, err := db.Query(`SELECT id, email, created_at FROM active_account ORDER BY id ASC`)
+	if err != nil {
+		t.Fatalf("Cannot select from created table: %s", err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("Cannot get column types: %s", err)
+	}
+	var names []string
+	for _, ct := range types {
+		names = append(names, ct.DatabaseTypeName())
+	}
+	if strings.Join(names, ",") != "INT,TEXT,TIMESTAMP" {
+		t.Fatalf("Expected INT,TEXT,TIMESTAMP columns, got %v", names)
+	}
+	var emails []string
+	for rows.Next() {
+		var id int64
+		var email string
+		var createdAt interface{}
+		if err := rows.Scan(&id, &email, &createdAt); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		emails = append(emails, email)
+	}
+	rows.Close()
+	if strings.Join(emails, ",") != "foo@bar.com,baz@bar.com" {
+		t.Fatalf("Expected foo@bar.com,baz@bar.com, got %v", emails)
+	}
+
+	// Created table is independent from the selected one
+	batch = []string{
+		`INSERT INTO active_account (id, email) VALUES (4, 'qux@bar.com')`,
+		`DELETE FROM account WHERE id = 1`,
+		`CREATE TEMP TABLE recent ON COMMIT PRESERVE ROWS AS SELECT id FROM account WHERE id > 1`,
+		`CREATE TABLE country (code VARCHAR(2), name TEXT)`,
+		`INSERT INTO country VALUES ('FR', 'FRA')`,
+		`CREATE TABLE country_code AS SELECT code FROM country`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM active_account`).Scan(&count)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected 3 active accounts, got %d (%v)", count, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`CREATE TABLE active_account AS SELECT id FROM account`, `already exists`},
+		{`INSERT INTO country_code SELECT name FROM country`, `value too long for type character varying(2)`},
+		{`CREATE TABLE missing AS SELECT id FROM nowhere`, `nowhere`},
+		{`CREATE TABLE missing AS INSERT INTO account (id) VALUES (5)`, `Syntax error`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM missing`).Scan(&count)
+	if err == nil {
+		t.Fatalf("Expected table not to be created")
+	}
+}
+
+func TestInsertMultipleRows(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestInsertMultipleRows")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id SERIAL PRIMARY KEY, email TEXT UNIQUE, age INT)`)
+	if err != nil {
+		t.Fatalf("Cannot create table: %s", err)
+	}
+
+	res, err := db.Exec(`INSERT INTO account (email, age) VALUES ('foo@bar.com', 20), ('bar@bar.com', 30), ($1, $2)`, "baz@bar.com", 40)
+	if err != nil {
+		t.Fatalf("Cannot insert rows: %s", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 rows affected, got %d (%v)", n, err)
+	}
+
+	var age int64
+	err = db.QueryRow(`SELECT age FROM account WHERE email = 'baz@bar.com'`).Scan(&age)
+	if err != nil || age != 40 {
+		t.Fatalf("Expected age 40, got %d (%v)", age, err)
+	}
+
+	// RETURNING produces a row per inserted tuple
+	rows, err := db.Query(`INSERT INTO account (email, age) VALUES ('qux@bar.com', DEFAULT), ('quux@bar.com', 50) RETURNING id`)
+	if err != nil {
+		t.Fatalf("Cannot insert rows returning id: %s", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 || ids[0] != 4 || ids[1] != 5 {
+		t.Fatalf("Expected ids 4 and 5, got %v", ids)
+	}
+
+	// Rows are all inserted or none
+	_, err = db.Exec(`INSERT INTO account (email, age) VALUES ('new@bar.com', 60), ('foo@bar.com', 70)`)
+	if err == nil {
+		t.Fatalf("Expected unique violation")
+	}
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil || count != 5 {
+		t.Fatalf("Expected 5 accounts after failed insert, got %d (%v)", count, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (email, age) VALUES ('a@bar.com', 1), ('b@bar.com')`, `INSERT has more target columns than expressions`},
+		{`INSERT INTO account (email) VALUES ('a@bar.com'), ('b@bar.com', 2)`, `INSERT has more expressions than target columns`},
+		{`INSERT INTO account (email) VALUES ('a@bar.com'),`, `Syntax error`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestUpsert")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id SERIAL PRIMARY KEY, email TEXT, visits INT, CONSTRAINT account_email_key UNIQUE (email))`,
+		`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 1), ('bar@bar.com', 1)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	checks := []struct {
+		query    string
+		affected int64
+		email    string
+		visits   int64
+	}{
+		// PostgreSQL
+		{`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 5) ON CONFLICT DO NOTHING`, 0, "foo@bar.com", 1},
+		{`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 5), ('baz@bar.com', 1) ON CONFLICT (email) DO NOTHING`, 1, "baz@bar.com", 1},
+		{`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 5) ON CONFLICT (email) DO UPDATE SET visits = EXCLUDED.visits`, 1, "foo@bar.com", 5},
+		{`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 6) ON CONFLICT ON CONSTRAINT account_email_key DO UPDATE SET visits = 7`, 1, "foo@bar.com", 7},
+		{`INSERT INTO account (id, email, visits) VALUES (2, 'qux@bar.com', 3) ON CONFLICT (id) DO UPDATE SET email = EXCLUDED.email, visits = EXCLUDED.visits`, 1, "qux@bar.com", 3},
+		// MySQL
+		{`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 8) ON DUPLICATE KEY UPDATE visits = VALUES(visits)`, 2, "foo@bar.com", 8},
+		{`INSERT INTO account (email, visits) VALUES ('foo@bar.com', 8) ON DUPLICATE KEY UPDATE visits = VALUES(visits)`, 0, "foo@bar.com", 8},
+		{`INSERT INTO account (email, visits) VALUES ('quux@bar.com', 1) ON DUPLICATE KEY UPDATE visits = 2`, 1, "quux@bar.com", 1},
+		{`REPLACE INTO account (id, email, visits) VALUES (1, 'foo@bar.com', 9)`, 2, "foo@bar.com", 9},
+		{`REPLACE INTO account (id, email, visits) VALUES (1, 'baz@bar.com', 10)`, 3, "baz@bar.com", 10},
+		// Assigned values are expressions of the existing and inserted rows
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (email) DO UPDATE SET visits = account.visits + 1`, 1, "qux@bar.com", 4},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 2) ON CONFLICT (email) DO UPDATE SET visits = visits + EXCLUDED.visits`, 1, "qux@bar.com", 6},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 2) ON CONFLICT (email) DO UPDATE SET visits = 0 WHERE account.visits > 10`, 0, "qux@bar.com", 6},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 2) ON CONFLICT (email) DO UPDATE SET visits = visits * excluded.visits WHERE excluded.visits > 1`, 1, "qux@bar.com", 12},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 2) ON DUPLICATE KEY UPDATE visits = visits + 1`, 2, "qux@bar.com", 13},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 2) ON DUPLICATE KEY UPDATE visits = visits + VALUES(visits)`, 2, "qux@bar.com", 15},
+	}
+	for _, c := range checks {
+		res, err := db.Exec(c.query)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", c.query, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil || n != c.affected {
+			t.Fatalf("Expected %d rows affected by %s, got %d (%v)", c.affected, c.query, n, err)
+		}
+		var visits int64
+		err = db.QueryRow(`SELECT visits FROM account WHERE email = $1`, c.email).Scan(&visits)
+		if err != nil || visits != c.visits {
+			t.Fatalf("Expected %d visits for %s after %s, got %d (%v)", c.visits, c.email, c.query, visits, err)
+		}
+	}
+
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected 3 accounts, got %d (%v)", count, err)
+	}
+
+	// RETURNING produces inserted and updated rows, not skipped ones
+	rows, err := db.Query(`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 4), ('new@bar.com', 1) ON CONFLICT (email) DO UPDATE SET visits = EXCLUDED.visits RETURNING id`)
+	if err != nil {
+		t.Fatalf("Cannot upsert returning id: %s", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	var id int64
+	err = db.QueryRow(`SELECT id FROM account WHERE email = 'new@bar.com'`).Scan(&id)
+	if err != nil {
+		t.Fatalf("Cannot select inserted account: %s", err)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != id {
+		t.Fatalf("Expected ids 2 and %d, got %v", id, ids)
+	}
+	rows, err = db.Query(`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 4) ON CONFLICT DO NOTHING RETURNING id`)
+	if err != nil {
+		t.Fatalf("Cannot upsert returning id: %s", err)
+	}
+	if rows.Next() {
+		t.Fatalf("Expected no row returned")
+	}
+	rows.Close()
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO account (email, visits) VALUES ('a@bar.com', 1), ('a@bar.com', 2) ON CONFLICT (email) DO UPDATE SET visits = EXCLUDED.visits`, `ON CONFLICT DO UPDATE command cannot affect row a second time`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (visits) DO NOTHING`, `there is no unique or exclusion constraint matching the ON CONFLICT specification`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT ON CONSTRAINT nope DO NOTHING`, `constraint "nope" for table "account" does not exist`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT DO UPDATE SET visits = 1`, `ON CONFLICT DO UPDATE requires inference specification or constraint name`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (email) DO UPDATE SET nope = 1`, `column "nope" of relation "account" does not exist`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (email) DO UPDATE SET visits = EXCLUDED.nope`, `column "nope" does not exist`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (email) DO UPDATE SET visits = other.visits`, `missing FROM-clause entry for table "other"`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (email) DO UPDATE SET visits = 1 WHERE nope > 1`, `column "nope" does not exist`},
+		{`INSERT INTO account (id, email, visits) VALUES (1, 'zzz@bar.com', 1) ON CONFLICT (email) DO NOTHING`, `duplicate key value violates unique constraint`},
+		{`INSERT INTO account (email, visits) VALUES ('qux@bar.com', 1) ON CONFLICT (email) DO`, `Syntax error`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM account WHERE email = 'a@bar.com'`).Scan(&count)
+	if err != nil || count != 0 {
+		t.Fatalf("Expected failed upsert to be rolled back, got %d (%v)", count, err)
+	}
+}
@@ -2,6 +2,7 @@ package ramsql
 
 import (
 	"database/sql"
+	"strings"
 	"testing"
 
 	"github.com/go-gorp/gorp"
@@ -121,3 +122,98 @@ func TestJoin(t *testing.T) {
 		t.Fatalf("Expected 2 projects, got %d", len(projects))
 	}
 }
+
+func TestJoinAlias(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestJoinAlias")
+	if err != nil {
+		t.Fatalf("sql.Open failed: %s", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id INT PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE orders (id INT PRIMARY KEY, account_id INT, amount INT)`,
+		`INSERT INTO account (id, name) VALUES (1, 'foo')`,
+		`INSERT INTO account (id, name) VALUES (2, 'bar')`,
+		`INSERT INTO orders (id, account_id, amount) VALUES (1, 1, 5)`,
+		`INSERT INTO orders (id, account_id, amount) VALUES (2, 2, 8)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// Tables are aliased in FROM, with or without AS
+	var id int64
+	for _, q := range []string{
+		`SELECT id FROM account AS a WHERE name = 'bar'`,
+		`SELECT a.id FROM account AS a WHERE a.name = 'bar'`,
+		`SELECT a.id FROM account a WHERE a.name = 'bar'`,
+	} {
+		err = db.QueryRow(q).Scan(&id)
+		if err != nil || id != 2 {
+			t.Fatalf("Expected 2 from %s, got %d (%v)", q, id, err)
+		}
+	}
+
+	// And in JOIN, the joined table being filtered by its alias
+	var name string
+	var amount int64
+	for _, q := range []string{
+		`SELECT a.name, o.amount FROM account AS a JOIN orders AS o ON o.account_id = a.id WHERE o.amount > 6`,
+		`SELECT a.name, o.amount FROM account a INNER JOIN orders o ON o.account_id = a.id WHERE o.amount > 6`,
+	} {
+		err = db.QueryRow(q).Scan(&name, &amount)
+		if err != nil || name != "bar" || amount != 8 {
+			t.Fatalf("Expected bar 8 from %s, got %s %d (%v)", q, name, amount, err)
+		}
+	}
+
+	// Self-joins tell the tables apart by their alias
+	batch = []string{
+		`CREATE TABLE employee (id INT PRIMARY KEY, manager_id INT, name TEXT)`,
+		`INSERT INTO employee (id, manager_id, name) VALUES (1, 0, 'alice')`,
+		`INSERT INTO employee (id, manager_id, name) VALUES (2, 1, 'bob')`,
+		`INSERT INTO employee (id, manager_id, name) VALUES (3, 1, 'carol')`,
+		`INSERT INTO employee (id, manager_id, name) VALUES (4, 3, 'dave')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	var manager string
+	for _, q := range []string{
+		`SELECT e.name, m.name FROM employee AS e JOIN employee AS m ON e.manager_id = m.id WHERE e.name = 'dave'`,
+		`SELECT employee.name, m.name FROM employee JOIN employee m ON employee.manager_id = m.id WHERE m.id = 3`,
+	} {
+		err = db.QueryRow(q).Scan(&name, &manager)
+		if err != nil || name != "dave" || manager != "carol" {
+			t.Fatalf("Expected dave carol from %s, got %s %s (%v)", q, name, manager, err)
+		}
+	}
+	var n int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM employee e JOIN employee m ON e.manager_id = m.id WHERE m.name = 'alice'`).Scan(&n)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 employees managed by alice, got %d (%v)", n, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`SELECT account.id FROM account JOIN account ON account.id = account.id`, `table name "account" specified more than once`},
+		{`SELECT orders.id FROM account AS orders JOIN account AS b ON b.id = orders.id`, `alias "orders" of table "account" conflicts with relation "orders"`},
+	}
+	for _, v := range violations {
+		_, err = db.Query(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestReturning(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestReturning")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE item (id SERIAL PRIMARY KEY, name TEXT NOT NULL, price INT, quantity INT DEFAULT 1, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP)`)
+	if err != nil {
+		t.Fatalf("Cannot create table: %s", err)
+	}
+
+	// Defaults are returned
+	rows, err := db.Query(`INSERT INTO item (name, price) VALUES ('foo', 10), ('bar', 20) RETURNING *`)
+	if err != nil {
+		t.Fatalf("Cannot insert returning *: %s", err)
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("Cannot get columns: %s", err)
+	}
+	if strings.Join(columns, ",") != "id,name,price,quantity,updated_at" {
+		t.Fatalf("Expected every column, got %v", columns)
+	}
+	var names []string
+	for rows.Next() {
+		var id, price, quantity int64
+		var name string
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &name, &price, &quantity, &updatedAt); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		if quantity != 1 || updatedAt.IsZero() {
+			t.Fatalf("Expected default values, got %d and %v", quantity, updatedAt)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if strings.Join(names, ",") != "foo,bar" {
+		t.Fatalf("Expected foo and bar, got %v", names)
+	}
+
+	// Expressions and aliases
+	var id, total int64
+	var label string
+	err = db.QueryRow(`INSERT INTO item (name, price, quantity) VALUES ($1, 5, 3) RETURNING id, price * quantity AS total, 'item ' || name label`, "baz").Scan(&id, &total, &label)
+	if err != nil {
+		t.Fatalf("Cannot insert returning expressions: %s", err)
+	}
+	if id != 3 || total != 15 || label != "item baz" {
+		t.Fatalf("Expected 3, 15 and 'item baz', got %d, %d and '%s'", id, total, label)
+	}
+
+	// Updated rows are returned once ON UPDATE values are set
+	var before time.Time
+	err = db.QueryRow(`SELECT updated_at FROM item WHERE id = 1`).Scan(&before)
+	if err != nil {
+		t.Fatalf("Cannot select: %s", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	rows, err = db.Query(`UPDATE item SET price = 12 WHERE price < 15 RETURNING item.name, price - 2, updated_at`)
+	if err != nil {
+		t.Fatalf("Cannot update returning: %s", err)
+	}
+	columns, err = rows.Columns()
+	if err != nil {
+		t.Fatalf("Cannot get columns: %s", err)
+	}
+	if strings.Join(columns, ",") != "name,?column?,updated_at" {
+		t.Fatalf("Expected name,?column?,updated_at columns, got %v", columns)
+	}
+	names = nil
+	for rows.Next() {
+		var name string
+		var price int64
+		var updatedAt time.Time
+		if err := rows.Scan(&name, &price, &updatedAt); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		if price != 10 {
+			t.Fatalf("Expected updated price, got %d", price)
+		}
+		if name == "foo" && !updatedAt.After(before) {
+			t.Fatalf("Expected updated_at to be set on update, got %v (was %v)", updatedAt, before)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 updated rows, got %v", names)
+	}
+
+	// UPDATE without WHERE updates every row
+	rows, err = db.Query(`UPDATE item SET quantity = 2 RETURNING id`)
+	if err != nil {
+		t.Fatalf("Cannot update returning: %s", err)
+	}
+	var n int
+	for rows.Next() {
+		n++
+	}
+	rows.Close()
+	if n != 3 {
+		t.Fatalf("Expected 3 updated rows, got %d", n)
+	}
+
+	// Deleted rows are returned
+	err = db.QueryRow(`DELETE FROM item WHERE id = 2 RETURNING name, price / 3.0`).Scan(&label, new(float64))
+	if err != nil || label != "bar" {
+		t.Fatalf("Expected bar to be deleted, got %s (%v)", label, err)
+	}
+	rows, err = db.Query(`DELETE FROM item RETURNING id`)
+	if err != nil {
+		t.Fatalf("Cannot delete returning: %s", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Fatalf("Expected ids 1 and 3, got %v", ids)
+	}
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM item`).Scan(&count)
+	if err != nil || count != 0 {
+		t.Fatalf("Expected no item left, got %d (%v)", count, err)
+	}
+
+	_, err = db.Exec(`INSERT INTO item (name, price) VALUES ('qux', 1)`)
+	if err != nil {
+		t.Fatalf("Cannot insert: %s", err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`INSERT INTO item (name) VALUES ('qux') RETURNING nope`, `column "nope" does not exist`},
+		{`INSERT INTO item (name) VALUES ('qux') RETURNING other.id`, `missing FROM-clause entry for table "other"`},
+		{`INSERT INTO item (name, price) VALUES ('qux', 1) RETURNING price / 0`, `division by zero`},
+		{`UPDATE item SET price = 1 RETURNING other.*`, `missing FROM-clause entry for table "other"`},
+		{`UPDATE item SET price = 2 RETURNING nope + 1`, `column "nope" does not exist`},
+		{`DELETE FROM item RETURNING name - 1`, `operator does not exist`},
+	}
+	for _, v := range violations {
+		_, err = db.Query(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Rows are not written if their values cannot be returned
+	var price int64
+	err = db.QueryRow(`SELECT price FROM item WHERE name = 'qux'`).Scan(&price)
+	if err != nil || price != 1 {
+		t.Fatalf("Expected qux to be left unchanged, got %d (%v)", price, err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM item`).Scan(&count)
+	if err != nil || count != 1 {
+		t.Fatalf("Expected a single item, got %d (%v)", count, err)
+	}
+}
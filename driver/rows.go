@@ -1,33 +1,35 @@
 package ramsql
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"sync"
+	"time"
 
-	"github.com/kokizzu/ramsql/engine/log"
-	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
 // Rows implements the sql/driver Rows interface
 type Rows struct {
-	rowsChannel chan []string
-	columns     []string
+	rowsChannel chan []interface{}
+	columns     []protocol.Column
+	// ctx of the query, aborted by the engine once done
+	ctx context.Context
 
 	sync.Mutex
 }
 
-func newRows(channel chan []string) *Rows {
-	r := &Rows{rowsChannel: channel}
-	c, ok := <-channel
-	if !ok {
-		log.Critical("Cannot receive column names from channel")
-		return nil
+func newRows(ctx context.Context, columns []protocol.Column, channel chan []interface{}) *Rows {
+	r := &Rows{
+		rowsChannel: channel,
+		columns:     columns,
+		ctx:         ctx,
 	}
 
-	r.columns = c
 	return r
 }
 
@@ -36,7 +38,42 @@ func newRows(channel chan []string) *Rows {
 // slice.  If a particular column name isn't known, an empty
 // string should be returned for that entry.
 func (r *Rows) Columns() []string {
-	return r.columns
+	names := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		names[i] = c.Name
+	}
+
+	return names
+}
+
+// ColumnTypeDatabaseTypeName returns the database system type name
+// of the column, as declared in table definition.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.columns[index].TypeName
+}
+
+// ColumnTypeScanType returns the Go type of column values.
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	return r.columns[index].ScanType
+}
+
+// ColumnTypeNullable reports whether the column may be null.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.columns[index].Nullable, true
+}
+
+// ColumnTypeLength returns the length of variable length column types
+// such as VARCHAR(n), TEXT or BYTEA. Unbounded types have a length of math.MaxInt64.
+func (r *Rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	length = r.columns[index].Length
+	return length, length > 0
+}
+
+// ColumnTypePrecisionScale returns the precision and scale of decimal types,
+// i.e NUMERIC(10, 2).
+func (r *Rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	c := r.columns[index]
+	return c.Precision, c.Scale, c.Precision > 0
 }
 
 // Close closes the rows iterator.
@@ -48,13 +85,11 @@ func (r *Rows) Close() error {
 		return nil
 	}
 
-	_, ok := <-r.rowsChannel
-	if !ok {
-		return nil
+	// Drain remaining rows so the engine is done with the statement
+	// before the connection is used again
+	for range r.rowsChannel {
 	}
 
-	// Tells UnlimitedRowsChannel to close itself
-	//r.rowsChannel <- []string{}
 	r.rowsChannel = nil
 	return nil
 }
@@ -63,9 +98,9 @@ func (r *Rows) Close() error {
 // the provided slice. The provided slice will be the same
 // size as the Columns() are wide.
 //
-// The dest slice may be populated only with
-// a driver Value type, but excluding string.
-// All string values must be converted to []byte.
+// The dest slice is populated with values typed after
+// their column: int64, float64, bool, string, []byte,
+// time.Time or nil.
 //
 // Next should return io.EOF when there are no more rows.
 func (r *Rows) Next(dest []driver.Value) (err error) {
@@ -79,6 +114,10 @@ func (r *Rows) Next(dest []driver.Value) (err error) {
 	value, ok := <-r.rowsChannel
 	if !ok {
 		r.rowsChannel = nil
+		// Rows were cut short by query cancellation
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
 		return io.EOF
 	}
 
@@ -87,27 +126,23 @@ func (r *Rows) Next(dest []driver.Value) (err error) {
 	}
 
 	for i, v := range value {
-		if v == "<nil>" || v == "null" {
-			dest[i] = nil
-			continue
-		}
-
-		// TODO: make rowsChannel send virtualRows,
-		// so we have the type and don't blindy try to parse date here
-		dest[i] = nil
-		if t, err := parser.ParseDate(string(v)); err == nil {
-			if t != nil {
-				dest[i] = *t
-			}
-		} else {
-			dest[i] = []byte(v)
+		switch v := v.(type) {
+		case nil, int64, float64, bool, string, time.Time:
+			dest[i] = v
+		case []byte:
+			// Do not let the caller modify stored value
+			b := make([]byte, len(v))
+			copy(b, v)
+			dest[i] = b
+		default:
+			return fmt.Errorf("unsupported value type %T", v)
 		}
 	}
 
 	return nil
 }
 
-func (r *Rows) setColumns(columns []string) {
+func (r *Rows) setColumns(columns []protocol.Column) {
 	r.columns = columns
 }
 
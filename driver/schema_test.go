@@ -0,0 +1,201 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestSchema(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestSchema")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE SCHEMA billing`,
+		`CREATE SCHEMA auth`,
+		`CREATE SCHEMA IF NOT EXISTS billing`,
+		`CREATE TABLE auth.users (id SERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE billing.invoices (id SERIAL PRIMARY KEY, user_id INT REFERENCES auth.users (id), total INT)`,
+		`CREATE INDEX invoices_total_idx ON billing.invoices (total)`,
+		`CREATE TABLE invoices (id INT, note TEXT)`,
+		`INSERT INTO auth.users (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO billing.invoices (user_id, total) VALUES (1, 100)`,
+		`INSERT INTO billing.invoices (user_id, total) VALUES (1, 50)`,
+		`INSERT INTO public.invoices (id, note) VALUES (1, 'public')`,
+		`UPDATE billing.invoices SET total = 150 WHERE invoices.total = 50`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var total int64
+	err = db.QueryRow(`SELECT billing.invoices.total FROM billing.invoices WHERE invoices.id = 2`).Scan(&total)
+	if err != nil || total != 150 {
+		t.Fatalf("Expected total 150, got %d (%v)", total, err)
+	}
+
+	err = db.QueryRow(`SELECT invoices.total FROM billing.invoices JOIN auth.users ON users.id = invoices.user_id WHERE users.email = 'foo@bar.com' AND invoices.id = 1`).Scan(&total)
+	if err != nil || total != 100 {
+		t.Fatalf("Expected total 100 joining schemas, got %d (%v)", total, err)
+	}
+
+	// Unqualified names are looked up in public schema by default
+	var note string
+	err = db.QueryRow(`SELECT note FROM invoices WHERE id = 1`).Scan(&note)
+	if err != nil || note != "public" {
+		t.Fatalf("Expected public invoice, got %s (%v)", note, err)
+	}
+
+	// Foreign keys reference tables of other schemas, and are named after
+	// their table out of its schema
+	_, err = db.Exec(`INSERT INTO billing.invoices (user_id, total) VALUES (42, 1)`)
+	if err == nil || !strings.Contains(err.Error(), `violates foreign key constraint "invoices_user_id_fkey"`) {
+		t.Fatalf("Expected foreign key violation, got %v", err)
+	}
+
+	// Sequences of identity columns belong to the schema of their table,
+	// the failed insert used value 3
+	var id int64
+	err = db.QueryRow(`SELECT nextval('billing.invoices_id_seq')`).Scan(&id)
+	if err != nil || id != 4 {
+		t.Fatalf("Expected next invoice id 4, got %d (%v)", id, err)
+	}
+
+	// search_path is a setting of the connection
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer conn.Close()
+
+	batch = []string{
+		`SET search_path TO billing, public`,
+		`CREATE TABLE payments (id INT, amount INT)`,
+		`INSERT INTO payments (id, amount) VALUES (1, 10)`,
+	}
+	for _, b := range batch {
+		_, err = conn.ExecContext(ctx, b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	var n int64
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM invoices`).Scan(&n)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 invoices of billing schema, got %d (%v)", n, err)
+	}
+	// Tables not found are reported as written
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n)
+	if err == nil || !strings.Contains(err.Error(), `relation "users" does not exist`) {
+		t.Fatalf("Expected users not to be found out of search_path, got %v", err)
+	}
+	err = db.QueryRow(`SELECT amount FROM billing.payments WHERE id = 1`).Scan(&n)
+	if err != nil || n != 10 {
+		t.Fatalf("Expected payment created in billing schema, got %d (%v)", n, err)
+	}
+
+	// SET is undone with the transaction
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Cannot begin: %s", err)
+	}
+	if _, err = tx.Exec(`SET search_path = auth`); err != nil {
+		t.Fatalf("Cannot set search_path: %s", err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("Cannot rollback: %s", err)
+	}
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM payments`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected search_path to be restored, got %d (%v)", n, err)
+	}
+
+	// Tables of public schema are hidden once it is out of search_path
+	for _, path := range []string{"auth", "nowhere"} {
+		_, err = conn.ExecContext(ctx, `SET search_path TO `+path)
+		if err != nil {
+			t.Fatalf("Cannot set search_path to %s: %s", path, err)
+		}
+		err = conn.QueryRowContext(ctx, `SELECT note FROM invoices WHERE id = 1`).Scan(&note)
+		if err == nil || !strings.Contains(err.Error(), `relation "invoices" does not exist`) {
+			t.Fatalf("Expected public invoices not to be found with search_path %s, got %v", path, err)
+		}
+		for _, q := range []string{
+			`INSERT INTO invoices (id, note) VALUES (2, 'hidden')`,
+			`UPDATE invoices SET note = 'hidden'`,
+			`DELETE FROM invoices`,
+			`TRUNCATE invoices`,
+			`ALTER TABLE invoices ADD COLUMN hidden TEXT`,
+		} {
+			_, err = conn.ExecContext(ctx, q)
+			if err == nil || !strings.Contains(err.Error(), `relation "invoices" does not exist`) {
+				t.Fatalf("Expected public invoices not to be written by %s with search_path %s, got %v", q, path, err)
+			}
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, `SET search_path TO DEFAULT`)
+	if err != nil {
+		t.Fatalf("Cannot reset search_path: %s", err)
+	}
+	err = conn.QueryRowContext(ctx, `SELECT note FROM invoices WHERE id = 1`).Scan(&note)
+	if err != nil || note != "public" {
+		t.Fatalf("Expected public invoice once search_path reset, got %s (%v)", note, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`CREATE SCHEMA billing`, `schema "billing" already exists`},
+		{`CREATE TABLE nowhere.invoices (id INT)`, `schema "nowhere" does not exist`},
+		{`DROP SCHEMA nowhere`, `schema "nowhere" does not exist`},
+		{`DROP SCHEMA auth`, `cannot drop schema auth because other objects depend on it`},
+		{`SET search_path TO`, `syntax error`},
+		{`SET timezone TO 'UTC'`, `syntax error`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(strings.ToLower(err.Error()), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Dropping a schema drops its tables, and foreign keys referencing them
+	batch = []string{
+		`DROP SCHEMA IF EXISTS nowhere`,
+		`DROP SCHEMA auth CASCADE`,
+		`INSERT INTO billing.invoices (user_id, total) VALUES (42, 1)`,
+		`DELETE FROM billing.invoices WHERE invoices.user_id = 1`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM auth.users`).Scan(&n)
+	if err == nil {
+		t.Fatalf("Expected auth.users to be dropped")
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM billing.invoices`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 invoice left, got %d (%v)", n, err)
+	}
+	_, err = db.Exec(`CREATE TABLE auth.users (id INT)`)
+	if err == nil {
+		t.Fatalf("Expected auth schema to be dropped")
+	}
+}
@@ -0,0 +1,264 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestSequence(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestSequence")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE SEQUENCE invoice_seq START WITH 100 INCREMENT BY 10`,
+		`CREATE SEQUENCE IF NOT EXISTS invoice_seq`,
+		`CREATE TABLE invoice (id BIGINT DEFAULT nextval('invoice_seq') PRIMARY KEY, amount INT)`,
+		`INSERT INTO invoice (amount) VALUES (1)`,
+		`INSERT INTO invoice (id, amount) VALUES (nextval('invoice_seq'), 2)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// A connection is pinned so currval sees its own nextval calls
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer conn.Close()
+
+	values := []struct {
+		query string
+		value int64
+	}{
+		{`SELECT nextval('invoice_seq')`, 120},
+		{`SELECT currval('invoice_seq')`, 120},
+		{`SELECT setval('invoice_seq', 500)`, 500},
+		{`SELECT nextval('invoice_seq')`, 510},
+		{`SELECT setval('invoice_seq', 1000, false)`, 1000},
+		{`SELECT currval('invoice_seq')`, 510},
+		{`SELECT nextval('invoice_seq')`, 1000},
+	}
+	for _, v := range values {
+		var n int64
+		err = conn.QueryRowContext(ctx, v.query).Scan(&n)
+		if err != nil {
+			t.Fatalf("Cannot run %s: %s", v.query, err)
+		}
+		if n != v.value {
+			t.Fatalf("Expected %d from %s, got %d", v.value, v.query, n)
+		}
+	}
+
+	var id int64
+	err = db.QueryRow(`SELECT id FROM invoice WHERE amount = 2`).Scan(&id)
+	if err != nil || id != 110 {
+		t.Fatalf("Expected invoice 110, got %d (%v)", id, err)
+	}
+
+	// Restarted sequence starts over
+	_, err = db.Exec(`ALTER SEQUENCE invoice_seq RESTART WITH 5`)
+	if err != nil {
+		t.Fatalf("Cannot restart sequence: %s", err)
+	}
+	err = db.QueryRow(`SELECT nextval('invoice_seq')`).Scan(&id)
+	if err != nil || id != 5 {
+		t.Fatalf("Expected 5 once restarted, got %d (%v)", id, err)
+	}
+
+	batch = []string{
+		`CREATE SEQUENCE tiny_seq MAXVALUE 2`,
+		`CREATE SEQUENCE loop_seq MAXVALUE 2 CYCLE`,
+		`CREATE SEQUENCE unused_seq`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	var tiny, loop int64
+	for i := 0; i < 3; i++ {
+		err = db.QueryRow(`SELECT nextval('tiny_seq'), nextval('loop_seq')`).Scan(&tiny, &loop)
+		if i < 2 && err != nil {
+			t.Fatalf("Cannot call nextval: %s", err)
+		}
+	}
+	if err == nil || tiny != 2 || loop != 2 {
+		t.Fatalf("Expected max value reached at 2, got %d and %d (%v)", tiny, loop, err)
+	}
+	err = db.QueryRow(`SELECT nextval('loop_seq')`).Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("Expected cycling sequence to return 1, got %d (%v)", id, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`SELECT nextval('tiny_seq')`, `nextval: reached maximum value of sequence "tiny_seq" (2)`},
+		{`SELECT currval('unused_seq')`, `currval of sequence "unused_seq" is not yet defined in this session`},
+		{`SELECT nextval('nope')`, `relation "nope" does not exist`},
+		{`SELECT setval('tiny_seq', 3)`, `setval: value 3 is out of bounds for sequence "tiny_seq" (1..2)`},
+		{`CREATE SEQUENCE invoice_seq`, `relation "invoice_seq" already exists`},
+		{`CREATE SEQUENCE other_seq MINVALUE 10 START 5`, `START value (5) cannot be less than MINVALUE (10)`},
+		{`CREATE SEQUENCE other_seq INCREMENT BY 0`, `INCREMENT must not be zero`},
+		{`ALTER SEQUENCE tiny_seq RESTART WITH 3`, `RESTART value (3) cannot be greater than MAXVALUE (2)`},
+		{`ALTER SEQUENCE nope RESTART`, `relation "nope" does not exist`},
+		{`DROP SEQUENCE invoice_seq`, `cannot drop sequence invoice_seq because other objects depend on it`},
+		{`DROP SEQUENCE nope`, `sequence "nope" does not exist`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error '%s' executing %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	batch = []string{
+		`DROP SEQUENCE tiny_seq`,
+		`DROP SEQUENCE IF EXISTS tiny_seq`,
+		`DROP SEQUENCE invoice_seq CASCADE`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO invoice (amount) VALUES (3)`)
+	if err == nil {
+		t.Fatalf("Expected error inserting without id once sequence is dropped")
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestIdentity")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id SERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE post (id BIGINT GENERATED ALWAYS AS IDENTITY, title TEXT)`,
+		`CREATE TABLE tag (id SMALLINT GENERATED BY DEFAULT AS IDENTITY (START WITH 100 INCREMENT BY 5), name TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@foo.com')`,
+		`INSERT INTO post (title) VALUES ('hello')`,
+		`INSERT INTO post (id, title) VALUES (DEFAULT, 'world')`,
+		`INSERT INTO tag (name) VALUES ('go')`,
+		`INSERT INTO tag (id, name) VALUES (200, 'sql')`,
+		`INSERT INTO tag (name) VALUES ('ram')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	ids := []struct {
+		query string
+		id    int64
+	}{
+		{`SELECT id FROM account WHERE email = 'bar@foo.com'`, 2},
+		{`SELECT id FROM post WHERE title = 'world'`, 2},
+		{`SELECT id FROM tag WHERE name = 'go'`, 100},
+		{`SELECT id FROM tag WHERE name = 'ram'`, 205},
+	}
+	for _, i := range ids {
+		var id int64
+		err = db.QueryRow(i.query).Scan(&id)
+		if err != nil {
+			t.Fatalf("Cannot run %s: %s", i.query, err)
+		}
+		if id != i.id {
+			t.Fatalf("Expected id %d from %s, got %d", i.id, i.query, id)
+		}
+	}
+
+	_, err = db.Exec(`INSERT INTO post (id, title) VALUES (10, 'nope')`)
+	if err == nil || !strings.Contains(err.Error(), `cannot insert a non-DEFAULT value into column "id"`) {
+		t.Fatalf("Expected error inserting into GENERATED ALWAYS column, got %v", err)
+	}
+
+	// Ids are not given back by rollback, but are by TRUNCATE ... RESTART IDENTITY
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot begin transaction: %s", err)
+	}
+	_, err = tx.Exec(`INSERT INTO account (email) VALUES ('baz@foo.com')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	tx.Rollback()
+
+	res, err := db.Exec(`INSERT INTO account (email) VALUES ('qux@foo.com')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil || lastID != 4 {
+		t.Fatalf("Expected id 4 after rollback, got %d (%v)", lastID, err)
+	}
+
+	batch = []string{
+		`TRUNCATE account`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`TRUNCATE TABLE post RESTART IDENTITY`,
+		`INSERT INTO post (title) VALUES ('hello')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var id int64
+	err = db.QueryRow(`SELECT id FROM account`).Scan(&id)
+	if err != nil || id != 5 {
+		t.Fatalf("Expected id 5 after TRUNCATE, got %d (%v)", id, err)
+	}
+	err = db.QueryRow(`SELECT id FROM post`).Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("Expected id 1 after TRUNCATE RESTART IDENTITY, got %d (%v)", id, err)
+	}
+
+	// Sequences of identity columns are dropped with them
+	batch = []string{
+		`ALTER TABLE tag DROP COLUMN id`,
+		`ALTER TABLE tag ADD COLUMN id SERIAL`,
+		`DROP TABLE account`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM tag WHERE id = 3`).Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("Expected existing rows to be numbered, got %d (%v)", id, err)
+	}
+	_, err = db.Exec(`SELECT nextval('account_id_seq')`)
+	if err == nil || !strings.Contains(err.Error(), `relation "account_id_seq" does not exist`) {
+		t.Fatalf("Expected sequence to be dropped with its table, got %v", err)
+	}
+}
@@ -1,60 +1,72 @@
 package ramsql
 
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
 
 	"github.com/kokizzu/ramsql/engine/log"
+	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
 // Stmt implements the Statement interface of sql/driver
 type Stmt struct {
 	conn     *Conn
+	id       int64
 	query    string
 	numInput int
 }
 
-func countArguments(query string) int {
-	for id := 1; id > 0; id++ {
-		sep := fmt.Sprintf("$%d", id)
-		if strings.Count(query, sep) == 0 {
-			return id - 1
-		}
+// prepareStatement parses the query once on the engine, which keeps it
+// until the statement is closed. Parameters are either Postgres ($n),
+// ODBC (?) or named (:name, @name) markers, bound by the engine on execution.
+func prepareStatement(ctx context.Context, c *Conn, query string) (*Stmt, error) {
+	if query == "" {
+		return nil, fmt.Errorf("empty statement")
 	}
 
-	return -1
-}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-func prepareStatement(c *Conn, query string) *Stmt {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	// Parse number of arguments here
-	// Should handler either Postgres ($*) or ODBC (?) parameter markers
-	numInput := strings.Count(query, "?")
-	// if numInput == 0, maybe it's Postgres format
-	if numInput == 0 {
-		numInput = countArguments(query)
+	err := c.conn.WritePrepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	id, numInput, err := c.conn.ReadPrepared()
+	if err != nil {
+		return nil, err
 	}
 
-	// Create statement
 	stmt := &Stmt{
 		conn:     c,
+		id:       id,
 		query:    query,
 		numInput: numInput,
 	}
 
-	stmt.conn.mutex.Lock()
-	return stmt
+	return stmt, nil
 }
 
-// Close closes the statement.
+// Close closes the statement, releasing it on the engine.
 //
 // As of Go 1.1, a Stmt will not be closed if it's in use
 // by any queries.
 func (s *Stmt) Close() error {
-	return fmt.Errorf("Not implemented")
+	s.conn.mutex.Lock()
+	defer s.conn.mutex.Unlock()
+
+	err := s.conn.conn.WriteClose(s.id)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = s.conn.conn.ReadResult()
+	return err
 }
 
 // NumInput returns the number of placeholder parameters.
@@ -72,131 +84,60 @@ func (s *Stmt) NumInput() int {
 
 // Exec executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
-func (s *Stmt) Exec(args []driver.Value) (r driver.Result, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("fatalf error: %s", r)
-			return
-		}
-	}()
-	defer s.conn.mutex.Unlock()
-
-	if s.query == "" {
-		return nil, fmt.Errorf("empty statement")
-	}
-
-	var finalQuery string
-
-	// replace $* by arguments in query string
-	finalQuery = replaceArguments(s.query, args)
-	log.Info("Exec <%s>\n", finalQuery)
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.exec(context.Background(), arguments(args))
+}
 
-	// Send query to server
-	err = s.conn.conn.WriteExec(finalQuery)
-	if err != nil {
-		log.Warning("Exec: Cannot send query to server: %s", err)
-		return nil, fmt.Errorf("Cannot send query to server: %s", err)
-	}
+// ExecContext executes a query that doesn't return rows, such
+// as an INSERT or UPDATE, with named or positional arguments.
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.exec(ctx, namedArguments(args))
+}
 
-	// Get answer from server
-	lastInsertedID, rowsAffected, err := s.conn.conn.ReadResult()
-	if err != nil {
-		return nil, err
-	}
+func (s *Stmt) exec(ctx context.Context, args []protocol.Argument) (driver.Result, error) {
+	log.Info("Exec <%s>\n", s.query)
 
-	// Create a driver.Result
-	return newResult(lastInsertedID, rowsAffected), nil
+	return s.conn.exec(ctx, func() error {
+		return s.conn.conn.WriteExecute(ctx, s.id, args)
+	})
 }
 
 // Query executes a query that may return rows, such as a
 // SELECT.
-func (s *Stmt) Query(args []driver.Value) (r driver.Rows, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("fatalf error: %s", r)
-			return
-		}
-	}()
-	defer s.conn.mutex.Unlock()
-
-	if s.query == "" {
-		return nil, fmt.Errorf("empty statement")
-	}
-
-	finalQuery := replaceArguments(s.query, args)
-	log.Info("Query < %s >\n", finalQuery)
-	err = s.conn.conn.WriteQuery(finalQuery)
-	if err != nil {
-		return nil, err
-	}
-
-	rowsChannel, err := s.conn.conn.ReadRows()
-	if err != nil {
-		return nil, err
-	}
-
-	r = newRows(rowsChannel)
-	return r, nil
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.queryRows(context.Background(), arguments(args))
 }
 
-// replace $* by arguments in query string
-func replaceArguments(query string, args []driver.Value) string {
+// QueryContext executes a query that may return rows, such as a
+// SELECT, with named or positional arguments.
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.queryRows(ctx, namedArguments(args))
+}
 
-	holder := regexp.MustCompile(`[^\$]\$[0-9]+`)
-	replacedQuery := ""
+func (s *Stmt) queryRows(ctx context.Context, args []protocol.Argument) (driver.Rows, error) {
+	log.Info("Query < %s >\n", s.query)
 
-	if strings.Count(query, "?") == len(args) {
-		return replaceArgumentsODBC(query, args)
-	}
+	return s.conn.query(ctx, func() error {
+		return s.conn.conn.WriteExecute(ctx, s.id, args)
+	})
+}
 
-	allloc := holder.FindAllIndex([]byte(query), -1)
-	queryB := []byte(query)
-	for i, loc := range allloc {
-		match := queryB[loc[0]+1 : loc[1]]
-
-		index, err := strconv.Atoi(string(match[1:]))
-		if err != nil {
-			log.Warning("Matched %s as a placeholder but cannot get index: %s\n", match, err)
-			return query
-		}
-
-		var v string
-		if args[index-1] == nil {
-			v = "null"
-		} else {
-			v = fmt.Sprintf("$$%v$$", args[index-1])
-		}
-		if i == 0 {
-			replacedQuery = fmt.Sprintf("%s%s%s", replacedQuery, string(queryB[:loc[0]+1]), v)
-		} else {
-			replacedQuery = fmt.Sprintf("%s%s%s", replacedQuery, string(queryB[allloc[i-1][1]:loc[0]+1]), v)
-		}
+// arguments binds values to positional parameters
+func arguments(args []driver.Value) []protocol.Argument {
+	bound := make([]protocol.Argument, len(args))
+	for i, v := range args {
+		bound[i] = protocol.Argument{Ordinal: i + 1, Value: v}
 	}
-	// add remaining query
-	replacedQuery = fmt.Sprintf("%s%s", replacedQuery, string(queryB[allloc[len(allloc)-1][1]:]))
 
-	return replacedQuery
+	return bound
 }
 
-func replaceArgumentsODBC(query string, args []driver.Value) string {
-	var finalQuery string
-
-	queryParts := strings.Split(query, "?")
-	finalQuery = queryParts[0]
-	for i := range args {
-		if args[i] == nil {
-			finalQuery += "null"
-		} else {
-			arg := fmt.Sprintf("%v", args[i])
-			_, ok := args[i].(string)
-			if ok && !strings.HasSuffix(query, "'") {
-				arg = "$$" + arg + "$$"
-			}
-			finalQuery += arg
-		}
-
-		finalQuery += queryParts[i+1]
+// namedArguments binds values to either positional or named parameters
+func namedArguments(args []driver.NamedValue) []protocol.Argument {
+	bound := make([]protocol.Argument, len(args))
+	for i, v := range args {
+		bound[i] = protocol.Argument{Name: v.Name, Ordinal: v.Ordinal, Value: v.Value}
 	}
 
-	return finalQuery
+	return bound
 }
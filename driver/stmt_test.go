@@ -1,122 +1,224 @@
 package ramsql
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
 )
 
 func TestNumInputQuestionMarker(t *testing.T) {
+	n := numInput(t, "TestNumInputQuestionMarker", "SELECT * FROM account WHERE email = ? AND name = '?'")
 
-	// Create a new stub Conn
-	c := &Conn{}
-
-	stmt := prepareStatement(c, "SELECT * FROM account WHERE email = '?'")
-	if stmt == nil {
-		t.Fatal("prepareStatement should not return nil")
+	if n != 1 {
+		t.Fatalf("prepareStatement expected 1 input, got %d", n)
 	}
+}
+
+func TestNumInputPostgreMarker(t *testing.T) {
+	n := numInput(t, "TestNumInputPostgreMarker", "SELECT * FROM account WHERE email = '$1' AND foo = $2 LIMIT $2")
 
-	if stmt.numInput != 1 {
-		t.Fatalf("prepareStatement expected 1 input, got %d", stmt.numInput)
+	if n != 2 {
+		t.Fatalf("prepareStatement expected 2 input, got %d", n)
 	}
 }
 
-func TestNumInputPostgreMarker(t *testing.T) {
+func TestNumInputNamedMarker(t *testing.T) {
+	n := numInput(t, "TestNumInputNamedMarker", "SELECT * FROM account WHERE email = :email AND backup = @email AND name = :name")
 
-	// Create a new stub Conn
-	c := &Conn{}
+	if n != 2 {
+		t.Fatalf("prepareStatement expected 2 input, got %d", n)
+	}
+}
 
-	stmt := prepareStatement(c, "SELECT * FROM account WHERE email = '$1' AND foo = $2 LIMIT $2")
-	if stmt == nil {
-		t.Fatal("prepareStatement should not return nil")
+// numInput prepares query on a new engine and returns its number of parameters
+func numInput(t *testing.T, dsn string, query string) int {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
 	}
+	defer db.Close()
 
-	if stmt.numInput != 2 {
-		t.Fatalf("prepareStatement expected 2 input, got %d", stmt.numInput)
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer conn.Close()
+
+	var n int
+	err = conn.Raw(func(driverConn interface{}) error {
+		stmt, err := prepareStatement(context.Background(), driverConn.(*Conn), query)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		n = stmt.NumInput()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Cannot prepare statement: %s", err)
 	}
+
+	return n
 }
 
-func TestReplaceArgument(t *testing.T) {
-	query := `SELECT * FROM account WHERE email = $1`
-	wantedQuery := `SELECT * FROM account WHERE email = $$foo@bar.com$$`
-	args := []driver.Value{
-		driver.Value("foo@bar.com"),
+func TestParameterBinding(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestParameterBinding")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
 	}
+	defer db.Close()
 
-	testReplaceArguments(t, query, args, wantedQuery)
-}
+	_, err = db.Exec(`CREATE TABLE item (id BIGINT, name TEXT, payload BYTEA)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
 
-func TestReplaceTwoArguments(t *testing.T) {
-	query := `SELECT * FROM account WHERE email = $1 AND password = $2`
-	wantedQuery := `SELECT * FROM account WHERE email = $$foo@bar.com$$ AND password = $$ewfewgwewggew$$`
-	args := []driver.Value{
-		driver.Value("foo@bar.com"),
-		driver.Value("ewfewgwewggew"),
+	names := []string{
+		`$$ quoted $$`,
+		`what?`,
+		`it's $1`,
+		`'); DROP TABLE item; --`,
+		"multi\nline",
 	}
+	payload := []byte{0, 1, '$', '$', '\'', 255}
 
-	testReplaceArguments(t, query, args, wantedQuery)
-}
+	for i, name := range names {
+		_, err = db.Exec(`INSERT INTO item (id, name, payload) VALUES ($1, $2, $3)`, i, name, payload)
+		if err != nil {
+			t.Fatalf("Cannot insert %q: %s", name, err)
+		}
+	}
 
-func TestReplaceTwoArgumentsTwice(t *testing.T) {
-	query := `SELECT * FROM account WHERE email = $1 OR
-	 email_backup = $1 AND password = $2 OR foo = $2`
-	wantedQuery := `SELECT * FROM account WHERE email = $$foo@bar.com$$ OR
-	 email_backup = $$foo@bar.com$$ AND password = $$ewfewgwewggew$$ OR foo = $$ewfewgwewggew$$`
-	args := []driver.Value{
-		driver.Value("foo@bar.com"),
-		driver.Value("ewfewgwewggew"),
+	for i, name := range names {
+		var got string
+		var gotPayload []byte
+		err = db.QueryRow(`SELECT name, payload FROM item WHERE id = ?`, i).Scan(&got, &gotPayload)
+		if err != nil {
+			t.Fatalf("Cannot select %q: %s", name, err)
+		}
+		if got != name {
+			t.Fatalf("Expected name %q, got %q", name, got)
+		}
+		if string(gotPayload) != string(payload) {
+			t.Fatalf("Expected payload %v, got %v", payload, gotPayload)
+		}
 	}
 
-	testReplaceArguments(t, query, args, wantedQuery)
-}
+	// Placeholders in literals are left as is
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM item WHERE name = 'what?' OR name = $1`, `it's $1`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Cannot count: %s", err)
+	}
+	if count != 2 {
+		t.Fatalf("Expected 2 rows, got %d", count)
+	}
 
-func TestReplaceEnd(t *testing.T) {
-	query := `SELECT * FROM account WHERE email = $1`
-	wantedQuery := `SELECT * FROM account WHERE email = $$foo@bar.com$$`
-	args := []driver.Value{
-		driver.Value("foo@bar.com"),
+	// Named parameters
+	var id int64
+	err = db.QueryRow(`SELECT id FROM item WHERE name = :name`, sql.Named("name", "what?")).Scan(&id)
+	if err != nil {
+		t.Fatalf("Cannot select with named parameter: %s", err)
+	}
+	if id != 1 {
+		t.Fatalf("Expected id 1, got %d", id)
 	}
 
-	testReplaceArguments(t, query, args, wantedQuery)
+	err = db.QueryRow(`SELECT id FROM item WHERE name = @name`, sql.Named("name", `it's $1`)).Scan(&id)
+	if err != nil {
+		t.Fatalf("Cannot select with named parameter: %s", err)
+	}
+	if id != 2 {
+		t.Fatalf("Expected id 2, got %d", id)
+	}
+
+	_, err = db.Exec(`DELETE FROM item WHERE name = :name`, sql.Named("other", "foo"))
+	if err == nil {
+		t.Fatalf("Expected an error with missing named parameter")
+	}
 }
 
-func TestReplaceEndODBC(t *testing.T) {
-	query := `SELECT * FROM account WHERE email = ?`
-	wantedQuery := `SELECT * FROM account WHERE email = $$foo@bar.com$$`
-	args := []driver.Value{
-		driver.Value("foo@bar.com"),
+func TestPreparedStatement(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestPreparedStatement")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
 	}
+	defer db.Close()
 
-	testReplaceArguments(t, query, args, wantedQuery)
-}
+	_, err = db.Exec(`CREATE TABLE account (id BIGINT, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
 
-func TestReplaceALot(t *testing.T) {
-	query := `INSERT INTO "human" ("id","birth_date","first_name","middle_name","last_name","size","weight","gender","version","goal","hair_color","foo","bar","team") values ($1, $2, $3, $4, $5, $6, $7, $8, $9 ,$10,$11,$12,$13,$14)`
-
-	wantedQuery := `INSERT INTO "human" ("id","birth_date","first_name","middle_name","last_name","size","weight","gender","version","goal","hair_color","foo","bar","team") values ($$1$$, $$2$$, $$3$$, $$4$$, $$5$$, $$6$$, $$7$$, $$8$$, $$9$$ ,$$10$$,$$11$$,$$12$$,$$13$$,$$14$$)`
-
-	args := []driver.Value{
-		driver.Value("1"),
-		driver.Value("2"),
-		driver.Value("3"),
-		driver.Value("4"),
-		driver.Value("5"),
-		driver.Value("6"),
-		driver.Value("7"),
-		driver.Value("8"),
-		driver.Value("9"),
-		driver.Value("10"),
-		driver.Value("11"),
-		driver.Value("12"),
-		driver.Value("13"),
-		driver.Value("14"),
-	}
-
-	testReplaceArguments(t, query, args, wantedQuery)
-}
+	insert, err := db.Prepare(`INSERT INTO account (id, email) VALUES ($1, $2)`)
+	if err != nil {
+		t.Fatalf("Cannot prepare insert: %s", err)
+	}
+	for i := 0; i < 100; i++ {
+		_, err = insert.Exec(i, fmt.Sprintf("user%d@example.com", i))
+		if err != nil {
+			t.Fatalf("Cannot execute prepared insert: %s", err)
+		}
+	}
+	err = insert.Close()
+	if err != nil {
+		t.Fatalf("Cannot close prepared insert: %s", err)
+	}
 
-func testReplaceArguments(t *testing.T, query string, args []driver.Value, wantedQuery string) {
-	finalQuery := replaceArguments(query, args)
-	if finalQuery != wantedQuery {
-		t.Fatalf("Expected <%s>, got <%s>", wantedQuery, finalQuery)
+	// Cached plan must not be altered by executions
+	query, err := db.Prepare(`SELECT id FROM account WHERE account.email = $1`)
+	if err != nil {
+		t.Fatalf("Cannot prepare query: %s", err)
+	}
+	defer query.Close()
+	for _, i := range []int64{42, 7, 99} {
+		var id int64
+		err = query.QueryRow(fmt.Sprintf("user%d@example.com", i)).Scan(&id)
+		if err != nil {
+			t.Fatalf("Cannot execute prepared query: %s", err)
+		}
+		if id != i {
+			t.Fatalf("Expected id %d, got %d", i, id)
+		}
+	}
+
+	_, err = db.Prepare(`SELECT FROM WHERE`)
+	if err == nil {
+		t.Fatalf("Expected an error when preparing an invalid statement")
+	}
+
+	// Closed statements are released on the engine
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		stmt, err := prepareStatement(context.Background(), driverConn.(*Conn), `DELETE FROM account WHERE id = $1`)
+		if err != nil {
+			return err
+		}
+		if err = stmt.Close(); err != nil {
+			return err
+		}
+		if _, err = stmt.Exec([]driver.Value{int64(1)}); err == nil {
+			return fmt.Errorf("expected an error executing a closed statement")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
 	}
 }
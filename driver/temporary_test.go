@@ -0,0 +1,185 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestTemporaryTable(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTemporaryTable")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE staging (id INT, note TEXT)`)
+	if err != nil {
+		t.Fatalf("Cannot create table: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO staging (id, note) VALUES (1, 'permanent')`)
+	if err != nil {
+		t.Fatalf("Cannot insert: %s", err)
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer conn.Close()
+
+	batch := []string{
+		`CREATE TEMPORARY TABLE staging (id INT, note TEXT)`,
+		`CREATE TEMP TABLE scratch (id SERIAL PRIMARY KEY, note TEXT)`,
+		`INSERT INTO staging (id, note) VALUES (1, 'temporary')`,
+		`INSERT INTO scratch (note) VALUES ('foo')`,
+	}
+	for _, b := range batch {
+		_, err = conn.ExecContext(ctx, b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// Temporary tables shadow permanent ones for their connection only
+	var note string
+	err = conn.QueryRowContext(ctx, `SELECT note FROM staging WHERE id = 1`).Scan(&note)
+	if err != nil || note != "temporary" {
+		t.Fatalf("Expected temporary row, got %s (%v)", note, err)
+	}
+	err = db.QueryRow(`SELECT note FROM staging WHERE id = 1`).Scan(&note)
+	if err != nil || note != "permanent" {
+		t.Fatalf("Expected permanent row from other connection, got %s (%v)", note, err)
+	}
+	var n int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM scratch`).Scan(&n)
+	if err == nil {
+		t.Fatalf("Expected temporary table not to be visible from other connection")
+	}
+
+	_, err = conn.ExecContext(ctx, `DROP TABLE staging`)
+	if err != nil {
+		t.Fatalf("Cannot drop temporary table: %s", err)
+	}
+	err = conn.QueryRowContext(ctx, `SELECT note FROM staging WHERE id = 1`).Scan(&note)
+	if err != nil || note != "permanent" {
+		t.Fatalf("Expected permanent row once temporary table dropped, got %s (%v)", note, err)
+	}
+
+	// ON COMMIT DROP tables last until the end of the transaction
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("Cannot begin: %s", err)
+	}
+	batch = []string{
+		`CREATE TEMPORARY TABLE batch (id INT) ON COMMIT DROP`,
+		`INSERT INTO batch (id) VALUES (1)`,
+		`INSERT INTO batch (id) VALUES (2)`,
+	}
+	for _, b := range batch {
+		_, err = tx.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	err = tx.QueryRow(`SELECT COUNT(*) FROM batch`).Scan(&n)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows in transaction, got %d (%v)", n, err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Cannot commit: %s", err)
+	}
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM batch`).Scan(&n)
+	if err == nil {
+		t.Fatalf("Expected temporary table to be dropped on commit")
+	}
+
+	// ON COMMIT PRESERVE ROWS is the default
+	_, err = conn.ExecContext(ctx, `CREATE TEMP TABLE kept (id INT) ON COMMIT PRESERVE ROWS`)
+	if err != nil {
+		t.Fatalf("Cannot create temporary table: %s", err)
+	}
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM kept`).Scan(&n)
+	if err != nil || n != 0 {
+		t.Fatalf("Expected temporary table to be kept, got %d (%v)", n, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`CREATE TABLE batch (id INT) ON COMMIT DROP`, `ON COMMIT can only be used on temporary tables`},
+		{`CREATE TEMP TABLE public.batch (id INT)`, `cannot create temporary relation in non-temporary schema`},
+		{`CREATE TEMP TABLE scratch (id INT)`, `already exists`},
+		{`CREATE TEMP TABLE batch (id INT) ON COMMIT DELETE`, `Syntax error`},
+	}
+	for _, v := range violations {
+		_, err = conn.ExecContext(ctx, v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+}
+
+func TestTemporaryView(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTemporaryView")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	c1, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer c1.Close()
+	c2, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Cannot get connection: %s", err)
+	}
+	defer c2.Close()
+
+	batch := []string{
+		`CREATE TEMP TABLE secret (id INT)`,
+		`INSERT INTO secret (id) VALUES (1)`,
+		`INSERT INTO secret (id) VALUES (2)`,
+		`INSERT INTO secret (id) VALUES (3)`,
+		`CREATE VIEW pv AS SELECT id FROM secret`,
+	}
+	for _, b := range batch {
+		_, err = c1.ExecContext(ctx, b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// The view selects from a temporary table, so it is temporary as well
+	var n int64
+	err = c1.QueryRowContext(ctx, `SELECT COUNT(*) FROM pv`).Scan(&n)
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 rows from view, got %d (%v)", n, err)
+	}
+	err = c2.QueryRowContext(ctx, `SELECT COUNT(*) FROM pv`).Scan(&n)
+	if err == nil {
+		t.Fatalf("Expected view not to be visible from other connection, got %d rows", n)
+	}
+	_, err = c1.ExecContext(ctx, `CREATE VIEW public.leak AS SELECT id FROM secret`)
+	if err == nil || !strings.Contains(err.Error(), `cannot create temporary relation in non-temporary schema`) {
+		t.Fatalf("Expected error creating view over temporary table in public schema, got %v", err)
+	}
+
+	// Its name is not taken in the public schema either
+	_, err = c2.ExecContext(ctx, `CREATE TABLE pv (id INT)`)
+	if err != nil {
+		t.Fatalf("Expected view not to take its name in public schema: %s", err)
+	}
+}
@@ -1,9 +1,5 @@
 package ramsql
 
-import (
-	"fmt"
-)
-
 // Tx implements SQL transaction method
 type Tx struct {
 	conn *Conn
@@ -11,11 +7,28 @@ type Tx struct {
 
 // Commit the transaction on server
 func (t *Tx) Commit() error {
-	// TODO: Not implemented
-	return nil
+	t.conn.mutex.Lock()
+	defer t.conn.mutex.Unlock()
+
+	err := t.conn.conn.WriteCommit()
+	if err != nil {
+		return err
+	}
+
+	_, _, err = t.conn.conn.ReadResult()
+	return err
 }
 
 // Rollback all changes
 func (t *Tx) Rollback() error {
-	return fmt.Errorf("Not implemented")
+	t.conn.mutex.Lock()
+	defer t.conn.mutex.Unlock()
+
+	err := t.conn.conn.WriteRollback()
+	if err != nil {
+		return err
+	}
+
+	_, _, err = t.conn.conn.ReadResult()
+	return err
 }
@@ -1,7 +1,10 @@
 package ramsql
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/kokizzu/ramsql/engine/log"
@@ -56,6 +59,509 @@ func TestTransaction(t *testing.T) {
 	// Select count
 }
 
+func TestTransactionRollback(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionRollback")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id INT, email TEXT)`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@bar.com')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+
+	queries := []string{
+		`INSERT INTO account (id, email) VALUES (3, 'baz@bar.com')`,
+		`UPDATE account SET email = 'foo@baz.com' WHERE id = 1`,
+		`DELETE FROM account WHERE id = 2`,
+	}
+	for _, q := range queries {
+		_, err = tx.Exec(q)
+		if err != nil {
+			t.Fatalf("cannot exec in tx: %s", err)
+		}
+	}
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM account WHERE id = 3").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row in tx: %s\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected inserted row to be visible in tx, got %d rows", count)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("cannot rollback tx: %s", err)
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM account").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rows after rollback, got %d", count)
+	}
+
+	var email string
+	err = db.QueryRow("SELECT email FROM account WHERE id = 1").Scan(&email)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if email != "foo@bar.com" {
+		t.Fatalf("expected update to be rolled back, got %s", email)
+	}
+}
+
+func TestTransactionIsolation(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionIsolation")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id INT, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`)
+	if err != nil {
+		t.Fatalf("cannot exec in tx: %s", err)
+	}
+
+	// Uncommitted row must not be visible from another connection
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM account").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected uncommitted row to be invisible, got %d rows", count)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("cannot commit tx: %s", err)
+	}
+
+	err = db.QueryRow("SELECT COUNT(*) FROM account").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected committed row to be visible, got %d rows", count)
+	}
+}
+
+func TestTransactionStatements(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionStatements")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	queries := []string{
+		`CREATE TABLE account (id INT, email TEXT)`,
+		`BEGIN`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`ROLLBACK`,
+		`BEGIN TRANSACTION`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@bar.com')`,
+		`COMMIT`,
+	}
+	for _, q := range queries {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	var id int
+	err = db.QueryRow("SELECT id FROM account").Scan(&id)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if id != 2 {
+		t.Fatalf("expected only committed row, got id %d", id)
+	}
+}
+
+func TestTransactionSavepoint(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionSavepoint")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id INT, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+
+	queries := []string{
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`SAVEPOINT first`,
+		`INSERT INTO account (id, email) VALUES (2, 'bar@bar.com')`,
+		`SAVEPOINT second`,
+		`UPDATE account SET email = 'foo@baz.com' WHERE id = 1`,
+		`RELEASE SAVEPOINT second`,
+		`ROLLBACK TO SAVEPOINT first`,
+		`INSERT INTO account (id, email) VALUES (3, 'baz@bar.com')`,
+		`SAVEPOINT third`,
+		`DELETE FROM account WHERE id = 3`,
+		`ROLLBACK TO third`,
+	}
+	for _, q := range queries {
+		_, err = tx.Exec(q)
+		if err != nil {
+			t.Fatalf("cannot exec '%s' in tx: %s", q, err)
+		}
+	}
+
+	_, err = tx.Exec(`ROLLBACK TO SAVEPOINT second`)
+	if err == nil {
+		t.Fatalf("expected an error rolling back to a released savepoint")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("cannot commit tx: %s", err)
+	}
+
+	rows, err := db.Query(`SELECT id, email FROM account`)
+	if err != nil {
+		t.Fatalf("cannot query rows: %s", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		var email string
+		if err = rows.Scan(&id, &email); err != nil {
+			t.Fatalf("cannot scan row: %s", err)
+		}
+		if id == 1 && email != "foo@bar.com" {
+			t.Fatalf("expected update to be rolled back, got %s", email)
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Fatalf("expected rows 1 and 3, got %v", ids)
+	}
+
+	_, err = db.Exec(`SAVEPOINT foo`)
+	if err == nil {
+		t.Fatalf("expected an error defining a savepoint outside of a transaction")
+	}
+}
+
+func TestTransactionReadOnly(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionReadOnly")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id INT, email TEXT)`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com')`,
+		`CREATE SEQUENCE account_id_seq`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM account").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row in read-only tx: %s\n", err)
+	}
+
+	queries := []string{
+		`INSERT INTO account (id, email) VALUES (2, 'bar@bar.com')`,
+		`UPDATE account SET email = 'foo@baz.com' WHERE id = 1`,
+		`DELETE FROM account WHERE id = 1`,
+	}
+	for _, q := range queries {
+		_, err = tx.Exec(q)
+		if err == nil {
+			t.Fatalf("expected an error executing '%s' in read-only tx", q)
+		}
+		if !strings.Contains(err.Error(), "read-only transaction") {
+			t.Fatalf("unexpected error executing '%s' in read-only tx: %s", q, err)
+		}
+	}
+
+	// Sequence functions changing the sequence cannot be selected either
+	sequences := []struct {
+		query string
+		err   string
+	}{
+		{`SELECT nextval('account_id_seq')`, `cannot execute nextval() in a read-only transaction`},
+		{`SELECT setval('account_id_seq', 10)`, `cannot execute setval() in a read-only transaction`},
+	}
+	for _, q := range sequences {
+		var v int64
+		err = tx.QueryRow(q.query).Scan(&v)
+		if err == nil || !strings.Contains(err.Error(), q.err) {
+			t.Fatalf("expected error '%s' executing '%s' in read-only tx, got %v", q.err, q.query, err)
+		}
+	}
+	tx.Rollback()
+
+	var id int64
+	err = db.QueryRow(`SELECT nextval('account_id_seq')`).Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("expected sequence untouched by read-only tx, got %d (%v)", id, err)
+	}
+}
+
+func TestTransactionRepeatableRead(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionRepeatableRead")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id INT, balance INT)`,
+		`INSERT INTO account (id, balance) VALUES (1, 100)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	tx1, err := db.BeginTx(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx1.Rollback()
+
+	tx2, err := db.BeginTx(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx2.Rollback()
+
+	var b1, b2 int
+	err = tx1.QueryRow("SELECT balance FROM account WHERE id = 1").Scan(&b1)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	err = tx2.QueryRow("SELECT balance FROM account WHERE id = 1").Scan(&b2)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+
+	_, err = tx1.Exec(fmt.Sprintf("UPDATE account SET balance = %d WHERE id = 1", b1+10))
+	if err != nil {
+		t.Fatalf("cannot update row: %s\n", err)
+	}
+	err = tx1.Commit()
+	if err != nil {
+		t.Fatalf("cannot commit tx: %s", err)
+	}
+
+	// Snapshot must not change
+	err = tx2.QueryRow("SELECT balance FROM account WHERE id = 1").Scan(&b2)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if b2 != 100 {
+		t.Fatalf("expected snapshot balance 100, got %d", b2)
+	}
+
+	// And lost update is prevented
+	_, err = tx2.Exec(fmt.Sprintf("UPDATE account SET balance = %d WHERE id = 1", b2+20))
+	if err == nil {
+		t.Fatalf("expected a serialization error on concurrent update")
+	}
+
+	var balance int
+	err = db.QueryRow("SELECT balance FROM account WHERE id = 1").Scan(&balance)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if balance != 110 {
+		t.Fatalf("expected balance 110, got %d", balance)
+	}
+}
+
+func TestTransactionReadCommitted(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionReadCommitted")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id INT, balance INT)`,
+		`INSERT INTO account (id, balance) VALUES (1, 100)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM account").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO account (id, balance) VALUES (2, 0)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	err = tx.QueryRow("SELECT COUNT(*) FROM account").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected committed row to be visible, got %d rows", count)
+	}
+}
+
+func TestTransactionSerializable(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTransactionSerializable")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE doctor (name TEXT, on_call INT)`,
+		`INSERT INTO doctor (name, on_call) VALUES ('alice', 1)`,
+		`INSERT INTO doctor (name, on_call) VALUES ('bob', 1)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	tx1, err := db.BeginTx(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx1.Rollback()
+
+	tx2, err := db.BeginTx(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Cannot create tx: %s", err)
+	}
+	defer tx2.Rollback()
+
+	// Write skew: each transaction checks there is another doctor on call
+	// before leaving
+	for _, tx := range []*sql.Tx{tx1, tx2} {
+		var count int
+		err = tx.QueryRow("SELECT COUNT(*) FROM doctor WHERE on_call = 1").Scan(&count)
+		if err != nil {
+			t.Fatalf("cannot query row: %s\n", err)
+		}
+		if count != 2 {
+			t.Fatalf("expected 2 doctors on call, got %d", count)
+		}
+	}
+
+	_, err = tx1.Exec(`UPDATE doctor SET on_call = 0 WHERE name = 'alice'`)
+	if err != nil {
+		t.Fatalf("cannot update row: %s\n", err)
+	}
+	_, err = tx2.Exec(`UPDATE doctor SET on_call = 0 WHERE name = 'bob'`)
+	if err != nil {
+		t.Fatalf("cannot update row: %s\n", err)
+	}
+
+	err = tx1.Commit()
+	if err != nil {
+		t.Fatalf("cannot commit tx: %s", err)
+	}
+
+	err = tx2.Commit()
+	if err == nil {
+		t.Fatalf("expected a serialization error on commit")
+	}
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM doctor WHERE on_call = 1").Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query row: %s\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 doctor on call, got %d", count)
+	}
+}
+
 func TestCheckAttributes(t *testing.T) {
 	log.UseTestLogger(t)
 
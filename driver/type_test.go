@@ -0,0 +1,291 @@
+package ramsql
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestTypeValidation(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTypeValidation")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE item (id INT, price DOUBLE PRECISION, available BOOLEAN, name VARCHAR(8), created_at TIMESTAMP, release DATE, payload BYTEA, qty SMALLINT, total BIGINT, code CHAR(2))`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO item (id, price, available, name, created_at, release, payload) VALUES (1, 9.99, 't', 'foo', '2019-10-01 10:11:12', '2019-10-01', 'bar')`)
+	if err != nil {
+		t.Fatalf("Cannot insert valid row: %s", err)
+	}
+
+	invalids := map[string]string{
+		`INSERT INTO item (id) VALUES ('one')`:                       `invalid input syntax for type integer: "one"`,
+		`INSERT INTO item (price) VALUES ('cheap')`:                  `invalid input syntax for type double precision: "cheap"`,
+		`INSERT INTO item (available) VALUES ('maybe')`:              `invalid input syntax for type boolean: "maybe"`,
+		`INSERT INTO item (name) VALUES ('way too long')`:            `value too long for type character varying(8)`,
+		`INSERT INTO item (created_at) VALUES ('yesterday')`:         `invalid input syntax for type timestamp: "yesterday"`,
+		`INSERT INTO item (release) VALUES ('soon')`:                 `invalid input syntax for type date: "soon"`,
+		`UPDATE item SET id = 'two' WHERE id = 1`:                    `invalid input syntax for type integer: "two"`,
+		`UPDATE item SET name = 'still too long' WHERE name = 'foo'`: `value too long for type character varying(8)`,
+		`INSERT INTO item (code) VALUES ('FRA')`:                     `value too long for type character(2)`,
+		`INSERT INTO item (id) VALUES (2147483648)`:                  `integer out of range`,
+		`UPDATE item SET id = 0 - 2147483649 WHERE id = 1`:           `integer out of range`,
+		`UPDATE item SET id = id + 2147483647 WHERE id = 1`:          `integer out of range`,
+		`INSERT INTO item (qty) VALUES (40000)`:                      `smallint out of range`,
+		`INSERT INTO item (total) VALUES (9223372036854775808)`:      `bigint out of range`,
+	}
+	for query, expected := range invalids {
+		_, err = db.Exec(query)
+		if err == nil {
+			t.Fatalf("Expected an error with query '%s'", query)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error '%s' with query '%s', got '%s'", expected, query, err)
+		}
+	}
+
+	// Bounds of integer types are valid
+	_, err = db.Exec(`INSERT INTO item (id, qty, total) VALUES (2147483647, 32767, 9223372036854775807)`)
+	if err != nil {
+		t.Fatalf("Cannot insert integer bounds: %s", err)
+	}
+	_, err = db.Exec(`DELETE FROM item WHERE id = 2147483647`)
+	if err != nil {
+		t.Fatalf("Cannot delete integer bounds: %s", err)
+	}
+
+	// Typed values are compared according to column type
+	var count int
+	queries := []string{
+		`SELECT COUNT(*) FROM item WHERE id = '1'`,
+		`SELECT COUNT(*) FROM item WHERE price = 9.990`,
+		`SELECT COUNT(*) FROM item WHERE available = true`,
+		`SELECT COUNT(*) FROM item WHERE created_at = '2019-10-01T10:11:12Z'`,
+		`SELECT COUNT(*) FROM item WHERE release = '2019-10-01'`,
+	}
+	for _, query := range queries {
+		err = db.QueryRow(query).Scan(&count)
+		if err != nil {
+			t.Fatalf("Cannot query '%s': %s", query, err)
+		}
+		if count != 1 {
+			t.Fatalf("Expected 1 row with query '%s', got %d", query, count)
+		}
+	}
+
+	var id int
+	var price float64
+	var available bool
+	var name string
+	err = db.QueryRow(`SELECT id, price, available, name FROM item`).Scan(&id, &price, &available, &name)
+	if err != nil {
+		t.Fatalf("Cannot query row: %s", err)
+	}
+	if id != 1 || price != 9.99 || !available || name != "foo" {
+		t.Fatalf("Unexpected row: %d %f %v %s", id, price, available, name)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTypedValues")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE item (id BIGINT, price REAL, available BOOLEAN, name TEXT, created_at TIMESTAMP, payload BYTEA, comment TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO item (id, price, available, name, created_at, payload) VALUES (1, 9.5, false, '2006-01-02', '2019-10-01 10:11:12', 'bar')`)
+	if err != nil {
+		t.Fatalf("Cannot insert row: %s", err)
+	}
+
+	values := make([]interface{}, 7)
+	dest := make([]interface{}, 7)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	err = db.QueryRow(`SELECT id, price, available, name, created_at, payload, comment FROM item`).Scan(dest...)
+	if err != nil {
+		t.Fatalf("Cannot query row: %s", err)
+	}
+
+	if v, ok := values[0].(int64); !ok || v != 1 {
+		t.Fatalf("Expected int64 1, got %T %v", values[0], values[0])
+	}
+	if v, ok := values[1].(float64); !ok || v != 9.5 {
+		t.Fatalf("Expected float64 9.5, got %T %v", values[1], values[1])
+	}
+	if v, ok := values[2].(bool); !ok || v {
+		t.Fatalf("Expected bool false, got %T %v", values[2], values[2])
+	}
+	if v, ok := values[3].(string); !ok || v != "2006-01-02" {
+		t.Fatalf("Expected string 2006-01-02, got %T %v", values[3], values[3])
+	}
+	if v, ok := values[4].(time.Time); !ok || !v.Equal(time.Date(2019, 10, 1, 10, 11, 12, 0, time.UTC)) {
+		t.Fatalf("Expected time.Time 2019-10-01 10:11:12, got %T %v", values[4], values[4])
+	}
+	if v, ok := values[5].([]byte); !ok || string(v) != "bar" {
+		t.Fatalf("Expected []byte bar, got %T %v", values[5], values[5])
+	}
+	if values[6] != nil {
+		t.Fatalf("Expected nil, got %T %v", values[6], values[6])
+	}
+
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM item`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Cannot count rows: %s", err)
+	}
+	if count != 1 {
+		t.Fatalf("Expected 1 row, got %d", count)
+	}
+}
+
+func TestTypedArguments(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestTypedArguments")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE item (id BIGINT, payload BYTEA, created_at TIMESTAMP)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// Looks like a hex literal, but arguments are raw bytes
+	payload := []byte(`\x41`)
+	created := time.Date(2019, 10, 1, 10, 11, 12, 123456789, time.FixedZone("CEST", 2*3600))
+
+	_, err = db.Exec(`INSERT INTO item (id, payload, created_at) VALUES (1, $1, $2)`, payload, created)
+	if err != nil {
+		t.Fatalf("Cannot insert row: %s", err)
+	}
+
+	var id int64
+	var b []byte
+	var c time.Time
+	err = db.QueryRow(`SELECT id, payload, created_at FROM item WHERE payload = $1`, payload).Scan(&id, &b, &c)
+	if err != nil {
+		t.Fatalf("Cannot query row: %s", err)
+	}
+	if string(b) != `\x41` {
+		t.Fatalf("Expected payload \\x41, got %q", b)
+	}
+	if !c.Equal(created) {
+		t.Fatalf("Expected created_at %s, got %s", created, c)
+	}
+
+	_, err = db.Exec(`UPDATE item SET payload = $1 WHERE created_at = $2`, []byte(`\x42`), created)
+	if err != nil {
+		t.Fatalf("Cannot update row: %s", err)
+	}
+
+	err = db.QueryRow(`SELECT payload FROM item WHERE id = 1`).Scan(&b)
+	if err != nil {
+		t.Fatalf("Cannot query row: %s", err)
+	}
+	if string(b) != `\x42` {
+		t.Fatalf("Expected payload \\x42, got %q", b)
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestColumnTypes")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGSERIAL, name VARCHAR(32) NOT NULL, balance NUMERIC(10, 2), bio TEXT)`,
+		`CREATE TABLE address (account_id BIGINT, street TEXT, since TIMESTAMP)`,
+		`INSERT INTO account (name, balance, bio) VALUES ('foo', 12.5, 'bar')`,
+		`INSERT INTO address (account_id, street, since) VALUES (1, 'Main street', '2019-10-01 10:11:12')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT account.name AS owner, balance, bio, address.street, address.since FROM account
+		JOIN address ON address.account_id = account.id`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("Cannot get column types: %s", err)
+	}
+
+	expected := []struct {
+		name      string
+		typeName  string
+		scanType  reflect.Type
+		nullable  bool
+		length    int64
+		precision int64
+		scale     int64
+	}{
+		{"owner", "VARCHAR", reflect.TypeOf(""), false, 32, 0, 0},
+		{"balance", "NUMERIC", reflect.TypeOf(float64(0)), true, 0, 10, 2},
+		{"bio", "TEXT", reflect.TypeOf(""), true, math.MaxInt64, 0, 0},
+		{"street", "TEXT", reflect.TypeOf(""), true, math.MaxInt64, 0, 0},
+		{"since", "TIMESTAMP", reflect.TypeOf(time.Time{}), true, 0, 0, 0},
+	}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected %d columns, got %d", len(expected), len(types))
+	}
+
+	for i, e := range expected {
+		c := types[i]
+		if c.Name() != e.name {
+			t.Fatalf("Expected column %d to be named %s, got %s", i, e.name, c.Name())
+		}
+		if c.DatabaseTypeName() != e.typeName {
+			t.Fatalf("Expected column %s to be of type %s, got %s", e.name, e.typeName, c.DatabaseTypeName())
+		}
+		if c.ScanType() != e.scanType {
+			t.Fatalf("Expected column %s scan type to be %s, got %s", e.name, e.scanType, c.ScanType())
+		}
+		nullable, ok := c.Nullable()
+		if !ok || nullable != e.nullable {
+			t.Fatalf("Expected column %s nullable to be %v, got %v", e.name, e.nullable, nullable)
+		}
+		length, ok := c.Length()
+		if length != e.length || ok != (e.length > 0) {
+			t.Fatalf("Expected column %s length to be %d, got %d", e.name, e.length, length)
+		}
+		precision, scale, ok := c.DecimalSize()
+		if precision != e.precision || scale != e.scale || ok != (e.precision > 0) {
+			t.Fatalf("Expected column %s decimal size to be (%d, %d), got (%d, %d)", e.name, e.precision, e.scale, precision, scale)
+		}
+	}
+}
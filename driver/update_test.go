@@ -0,0 +1,387 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestUpdateExpressions(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestUpdateExpressions")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE counter (id INT PRIMARY KEY, name TEXT, n INT, price FLOAT, qty INT, total FLOAT, label TEXT, updated_at TIMESTAMP)`,
+		`CREATE TABLE bonus (id INT PRIMARY KEY, amount INT)`,
+		`INSERT INTO counter (id, name, n, price, qty) VALUES (1, 'Foo', 1, 2.5, 4)`,
+		`INSERT INTO counter (id, name, n, price, qty) VALUES (2, 'bar', 10, 1.0, 3)`,
+		`INSERT INTO counter (id, name, n, price, qty) VALUES (3, NULL, NULL, 3.0, 1)`,
+		`INSERT INTO bonus (id, amount) VALUES (1, 100)`,
+		`INSERT INTO bonus (id, amount) VALUES (2, 5)`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot exec query %s: %s", b, err)
+		}
+	}
+
+	// Column references and arithmetic
+	res, err := db.Exec(`UPDATE counter SET n = n + 1, total = price * qty, updated_at = NOW() WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot update counter: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 1 {
+		t.Fatalf("Expected 1 row affected, got %d", ra)
+	}
+	var n int64
+	var total float64
+	var updatedAt time.Time
+	err = db.QueryRow(`SELECT n, total, updated_at FROM counter WHERE id = 1`).Scan(&n, &total, &updatedAt)
+	if err != nil {
+		t.Fatalf("Cannot select counter: %s", err)
+	}
+	if n != 2 || total != 10 || updatedAt.IsZero() {
+		t.Fatalf("Expected 2, 10 and a timestamp, got %d, %f and %v", n, total, updatedAt)
+	}
+
+	// Every expression sees the row as it was before the update
+	_, err = db.Exec(`UPDATE counter SET n = qty, qty = n WHERE id = 2`)
+	if err != nil {
+		t.Fatalf("Cannot swap columns: %s", err)
+	}
+	var qty int64
+	err = db.QueryRow(`SELECT n, qty FROM counter WHERE id = 2`).Scan(&n, &qty)
+	if err != nil {
+		t.Fatalf("Cannot select counter: %s", err)
+	}
+	if n != 3 || qty != 10 {
+		t.Fatalf("Expected swapped values 3 and 10, got %d and %d", n, qty)
+	}
+
+	// Functions, CASE and NULL values
+	_, err = db.Exec(`UPDATE counter SET n = COALESCE(n, 0) * 2, label = CASE WHEN n IS NULL THEN 'none' WHEN n > 2 AND qty >= 10 THEN 'many ' || lower(name) ELSE upper(name) END`)
+	if err != nil {
+		t.Fatalf("Cannot update with functions: %s", err)
+	}
+	rows, err := db.Query(`SELECT id, n, label FROM counter ORDER BY id ASC`)
+	if err != nil {
+		t.Fatalf("Cannot select counters: %s", err)
+	}
+	var labels []string
+	for rows.Next() {
+		var id int64
+		var label string
+		if err := rows.Scan(&id, &n, &label); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		if n != []int64{4, 6, 0}[id-1] {
+			t.Fatalf("Unexpected n %d for counter %d", n, id)
+		}
+		labels = append(labels, label)
+	}
+	rows.Close()
+	if strings.Join(labels, ",") != "FOO,many bar,none" {
+		t.Fatalf("Expected FOO, many bar and none, got %v", labels)
+	}
+
+	// Simple CASE
+	_, err = db.Exec(`UPDATE counter SET label = CASE id WHEN 1 THEN 'one' WHEN 2 THEN 'two' END`)
+	if err != nil {
+		t.Fatalf("Cannot update with simple CASE: %s", err)
+	}
+	var label sql.NullString
+	err = db.QueryRow(`SELECT label FROM counter WHERE id = 3`).Scan(&label)
+	if err != nil || label.Valid {
+		t.Fatalf("Expected NULL label, got %v (%v)", label, err)
+	}
+
+	// Scalar subqueries
+	_, err = db.Exec(`UPDATE counter SET n = (SELECT amount FROM bonus WHERE id = 1) + n, qty = (SELECT COUNT(*) FROM counter) WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot update with subqueries: %s", err)
+	}
+	err = db.QueryRow(`SELECT n, qty FROM counter WHERE id = 1`).Scan(&n, &qty)
+	if err != nil {
+		t.Fatalf("Cannot select counter: %s", err)
+	}
+	if n != 104 || qty != 3 {
+		t.Fatalf("Expected 104 and 3, got %d and %d", n, qty)
+	}
+	_, err = db.Exec(`UPDATE counter SET n = (SELECT amount FROM bonus WHERE id = 42) WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot update with empty subquery: %s", err)
+	}
+	var nullable sql.NullInt64
+	err = db.QueryRow(`SELECT n FROM counter WHERE id = 1`).Scan(&nullable)
+	if err != nil || nullable.Valid {
+		t.Fatalf("Expected NULL, got %v (%v)", nullable, err)
+	}
+
+	// Parameters
+	_, err = db.Exec(`UPDATE counter SET qty = qty + $1 WHERE id = $2`, 5, 2)
+	if err != nil {
+		t.Fatalf("Cannot update with parameters: %s", err)
+	}
+	err = db.QueryRow(`SELECT qty FROM counter WHERE id = 2`).Scan(&qty)
+	if err != nil || qty != 15 {
+		t.Fatalf("Expected 15, got %d (%v)", qty, err)
+	}
+
+	// Numbers are rounded when assigned to an integer column
+	_, err = db.Exec(`UPDATE counter SET n = price * qty WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot assign a number to an integer: %s", err)
+	}
+	err = db.QueryRow(`SELECT n FROM counter WHERE id = 1`).Scan(&n)
+	if err != nil || n != 8 {
+		t.Fatalf("Expected 2.5 * 3 rounded to 8, got %d (%v)", n, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`UPDATE counter SET n = nope + 1`, `column "nope" does not exist`},
+		{`UPDATE counter SET n = 9223372036854775807 + 1`, `bigint out of range`},
+		{`UPDATE counter SET n = 0 - 9223372036854775807 - 2`, `bigint out of range`},
+		{`UPDATE counter SET n = 4611686018427387904 * 2`, `bigint out of range`},
+		{`UPDATE counter SET n = price * 1000000000000`, `integer out of range`},
+		{`UPDATE counter SET n = (SELECT amount FROM bonus WHERE bonus.id = counter.id)`, `correlated subqueries are not supported, column "counter.id" references the outer query`},
+		{`UPDATE counter SET n = (SELECT amount FROM bonus WHERE counter.id = 1)`, `correlated subqueries are not supported`},
+		{`UPDATE counter SET nope = 1`, `column "nope" of relation "counter" does not exist`},
+		{`UPDATE counter SET n = 1, n = 2`, `multiple assignments to same column "n"`},
+		{`UPDATE counter SET n = qty / 0`, `division by zero`},
+		{`UPDATE counter SET n = foo(qty)`, `function foo() does not exist`},
+		{`UPDATE counter SET n = (SELECT amount FROM bonus)`, `more than one row returned by a subquery`},
+		{`UPDATE counter SET n = (SELECT * FROM bonus WHERE id = 1)`, `subquery must return only one column`},
+		{`UPDATE counter SET n = CASE WHEN qty THEN 1 END`, `argument of CASE/WHEN must be type boolean`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Failed updates leave rows unchanged
+	err = db.QueryRow(`SELECT qty FROM counter WHERE id = 2`).Scan(&qty)
+	if err != nil || qty != 15 {
+		t.Fatalf("Expected 15, got %d (%v)", qty, err)
+	}
+}
+
+func TestUpdateNow(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestUpdateNow")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE event (id INT PRIMARY KEY, created_at TIMESTAMP DEFAULT NOW(), seen_at TIMESTAMP)`,
+		`INSERT INTO event (id, seen_at) VALUES (1, NOW()), (2, NOW()), (3, NOW())`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot exec query %s: %s", b, err)
+		}
+	}
+
+	times := func() []time.Time {
+		rows, err := db.Query(`SELECT created_at, seen_at FROM event ORDER BY id ASC`)
+		if err != nil {
+			t.Fatalf("Cannot select events: %s", err)
+		}
+		defer rows.Close()
+		var times []time.Time
+		for rows.Next() {
+			var created, seen time.Time
+			if err := rows.Scan(&created, &seen); err != nil {
+				t.Fatalf("Cannot scan event: %s", err)
+			}
+			times = append(times, created, seen)
+		}
+		return times
+	}
+
+	// Every row of a statement holds the same time, defaults included
+	inserted := times()
+	for _, at := range inserted {
+		if !at.Equal(inserted[0]) {
+			t.Fatalf("Expected rows inserted at the same time, got %v", inserted)
+		}
+	}
+
+	// NOW() is the time the transaction started
+	time.Sleep(2 * time.Millisecond)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot begin: %s", err)
+	}
+	batch = []string{
+		`UPDATE event SET seen_at = NOW()`,
+		`INSERT INTO event (id, seen_at) VALUES (4, NOW())`,
+	}
+	for _, b := range batch {
+		time.Sleep(2 * time.Millisecond)
+		_, err = tx.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot exec query %s: %s", b, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Cannot commit: %s", err)
+	}
+
+	updated := times()
+	if !updated[0].Equal(inserted[0]) {
+		t.Fatalf("Expected creation time unchanged, got %v", updated[0])
+	}
+	if !updated[1].After(inserted[0]) {
+		t.Fatalf("Expected update time after insertion time, got %v", updated)
+	}
+	// Seen times, and creation time of the inserted row
+	for _, at := range []time.Time{updated[3], updated[5], updated[6], updated[7]} {
+		if !at.Equal(updated[1]) {
+			t.Fatalf("Expected rows written by the transaction at the same time, got %v", updated)
+		}
+	}
+}
+
+func TestUpdateFrom(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestUpdateFrom")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE customers (id INT PRIMARY KEY, name TEXT, discount INT, vip BOOLEAN)`,
+		`CREATE TABLE orders (id INT PRIMARY KEY, customer_id INT, amount INT, status TEXT)`,
+		`INSERT INTO customers (id, name, discount, vip) VALUES (1, 'foo', 10, true)`,
+		`INSERT INTO customers (id, name, discount, vip) VALUES (2, 'bar', 20, false)`,
+		`INSERT INTO orders (id, customer_id, amount, status) VALUES (1, 1, 100, 'new')`,
+		`INSERT INTO orders (id, customer_id, amount, status) VALUES (2, 1, 200, 'new')`,
+		`INSERT INTO orders (id, customer_id, amount, status) VALUES (3, 2, 300, 'new')`,
+		`INSERT INTO orders (id, customer_id, amount, status) VALUES (4, 3, 400, 'new')`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot exec query %s: %s", b, err)
+		}
+	}
+
+	amounts := func() []int64 {
+		rows, err := db.Query(`SELECT amount FROM orders ORDER BY id ASC`)
+		if err != nil {
+			t.Fatalf("Cannot select orders: %s", err)
+		}
+		defer rows.Close()
+		var amounts []int64
+		for rows.Next() {
+			var amount int64
+			if err := rows.Scan(&amount); err != nil {
+				t.Fatalf("Cannot scan: %s", err)
+			}
+			amounts = append(amounts, amount)
+		}
+		return amounts
+	}
+
+	// PostgreSQL style, values computed from joined rows
+	res, err := db.Exec(`UPDATE orders SET amount = amount - customers.discount, status = 'discounted' FROM customers WHERE orders.customer_id = customers.id AND customers.vip`)
+	if err != nil {
+		t.Fatalf("Cannot update from: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 2 {
+		t.Fatalf("Expected 2 rows affected, got %d", ra)
+	}
+	if a := amounts(); a[0] != 90 || a[1] != 190 || a[2] != 300 || a[3] != 400 {
+		t.Fatalf("Expected orders of vip customers to be discounted, got %v", a)
+	}
+
+	// Rows without any joined row are left unchanged
+	res, err = db.Exec(`UPDATE orders SET amount = 0 FROM customers WHERE customers.id = orders.customer_id AND customers.name = 'nobody'`)
+	if err != nil {
+		t.Fatalf("Cannot update from: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 0 {
+		t.Fatalf("Expected no row affected, got %d", ra)
+	}
+
+	// MySQL style
+	res, err = db.Exec(`UPDATE orders JOIN customers ON orders.customer_id = customers.id SET orders.amount = orders.amount + customers.discount WHERE customers.name = 'bar'`)
+	if err != nil {
+		t.Fatalf("Cannot update join: %s", err)
+	}
+	if ra, _ := res.RowsAffected(); ra != 1 {
+		t.Fatalf("Expected 1 row affected, got %d", ra)
+	}
+	if a := amounts(); a[2] != 320 {
+		t.Fatalf("Expected order 3 to be updated, got %v", a)
+	}
+
+	// The updated table may be aliased
+	batch = []string{
+		`UPDATE orders o JOIN customers c ON o.customer_id = c.id SET o.amount = o.amount + c.discount WHERE c.name = 'bar'`,
+		`UPDATE orders AS o SET amount = o.amount - c.discount FROM customers c WHERE o.customer_id = c.id AND c.name = 'bar'`,
+		`UPDATE orders AS o SET amount = o.amount + 1 WHERE o.id = 3`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("Cannot exec query %s: %s", b, err)
+		}
+	}
+	if a := amounts(); a[0] != 90 || a[1] != 190 || a[2] != 321 || a[3] != 400 {
+		t.Fatalf("Expected order 3 to be updated through aliases, got %v", a)
+	}
+
+	// Joined values may be returned
+	var id int64
+	var name string
+	err = db.QueryRow(`UPDATE orders SET status = 'shipped' FROM customers WHERE orders.customer_id = customers.id AND orders.id = 3 RETURNING orders.id, customers.name`).Scan(&id, &name)
+	if err != nil || id != 3 || name != "bar" {
+		t.Fatalf("Expected order 3 of bar, got %d and %s (%v)", id, name, err)
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`UPDATE orders SET amount = 1 FROM nope WHERE orders.id = nope.id`, `nope`},
+		{`UPDATE orders SET amount = customers.nope FROM customers`, `column "nope" does not exist`},
+		{`UPDATE orders SET amount = 1 FROM customers WHERE other.id = 1`, `missing FROM-clause entry for table "other"`},
+		{`UPDATE orders SET amount = id FROM customers`, `column reference "id" is ambiguous`},
+		{`UPDATE orders SET amount = 1 FROM orders`, `specified more than once`},
+		{`UPDATE orders JOIN customers ON orders.customer_id = customers.id SET customers.name = 'baz'`, `cannot update column "name" of joined table "customers", only columns of "orders" can be set`},
+		{`UPDATE orders JOIN customers c ON orders.customer_id = c.id SET c.name = 'baz'`, `cannot update column "name" of joined table "customers"`},
+		{`UPDATE orders JOIN customers ON orders.customer_id = customers.id SET discount = 0`, `cannot update column "discount" of joined table "customers"`},
+		{`UPDATE orders LEFT JOIN customers ON orders.customer_id = customers.id SET amount = 1`, `LEFT JOIN is not supported in UPDATE, only [INNER] JOIN is`},
+		{`UPDATE orders LEFT OUTER JOIN customers ON orders.customer_id = customers.id SET amount = 1`, `LEFT JOIN is not supported in UPDATE`},
+		{`UPDATE orders RIGHT JOIN customers ON orders.customer_id = customers.id SET amount = 1`, `RIGHT JOIN is not supported in UPDATE`},
+		{`UPDATE orders JOIN customers ON orders.nope = customers.id SET amount = 1`, `column "nope" does not exist`},
+		{`UPDATE orders o SET amount = 1 FROM customers WHERE x.id = 1`, `missing FROM-clause entry for table "x"`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+}
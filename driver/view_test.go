@@ -0,0 +1,268 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/kokizzu/ramsql/engine/log"
+)
+
+func TestView(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestView")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id INT PRIMARY KEY, email TEXT, active BOOLEAN)`,
+		`CREATE TABLE address (id INT PRIMARY KEY, account_id INT, city TEXT)`,
+		`INSERT INTO account (id, email, active) VALUES (1, 'foo@bar.com', true)`,
+		`INSERT INTO account (id, email, active) VALUES (2, 'bar@bar.com', false)`,
+		`INSERT INTO account (id, email, active) VALUES (3, 'baz@bar.com', true)`,
+		`INSERT INTO address (id, account_id, city) VALUES (1, 1, 'Paris')`,
+		`INSERT INTO address (id, account_id, city) VALUES (2, 2, 'Lyon')`,
+		`INSERT INTO address (id, account_id, city) VALUES (3, 3, 'Nantes')`,
+		`CREATE VIEW active_account AS SELECT id, email FROM account WHERE active = true`,
+		`CREATE VIEW active_city (account_id, city) AS SELECT active_account.id, address.city FROM active_account JOIN address ON active_account.id = address.account_id`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var n int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM active_account`).Scan(&n)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 active accounts, got %d (%v)", n, err)
+	}
+
+	var email string
+	err = db.QueryRow(`SELECT email FROM active_account WHERE id = 3`).Scan(&email)
+	if err != nil || email != "baz@bar.com" {
+		t.Fatalf("Expected baz@bar.com, got %s (%v)", email, err)
+	}
+
+	// Views are joined like tables
+	var city string
+	err = db.QueryRow(`SELECT address.city FROM active_account JOIN address ON active_account.id = address.account_id WHERE active_account.email = 'foo@bar.com'`).Scan(&city)
+	if err != nil || city != "Paris" {
+		t.Fatalf("Expected Paris, got %s (%v)", city, err)
+	}
+
+	// Views filtering on a joined table can be selected from repeatedly
+	_, err = db.Exec(`CREATE VIEW nantes_email AS SELECT account.email FROM account JOIN address ON address.account_id = account.id WHERE address.city = 'Nantes'`)
+	if err != nil {
+		t.Fatalf("Cannot create join view: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		err = db.QueryRow(`SELECT * FROM nantes_email`).Scan(&email)
+		if err != nil || email != "baz@bar.com" {
+			t.Fatalf("Expected baz@bar.com from select %d, got %s (%v)", i+1, email, err)
+		}
+	}
+	_, err = db.Exec(`DROP VIEW nantes_email`)
+	if err != nil {
+		t.Fatalf("Cannot drop join view: %s", err)
+	}
+
+	// View of a view, with renamed columns, sees rows inserted since
+	_, err = db.Exec(`UPDATE account SET active = true WHERE id = 2`)
+	if err != nil {
+		t.Fatalf("Cannot update account: %s", err)
+	}
+	rows, err := db.Query(`SELECT account_id, city FROM active_city ORDER BY account_id ASC`)
+	if err != nil {
+		t.Fatalf("Cannot select from view: %s", err)
+	}
+	var cities []string
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id, &city); err != nil {
+			t.Fatalf("Cannot scan: %s", err)
+		}
+		cities = append(cities, city)
+	}
+	rows.Close()
+	if strings.Join(cities, ",") != "Paris,Lyon,Nantes" {
+		t.Fatalf("Expected Paris,Lyon,Nantes, got %v", cities)
+	}
+
+	// OR REPLACE changes the query
+	_, err = db.Exec(`CREATE OR REPLACE VIEW active_account AS SELECT id, email FROM account WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("Cannot replace view: %s", err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM active_city`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 city once replaced, got %d (%v)", n, err)
+	}
+
+	// Views created in a rolled back transaction are gone
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot begin: %s", err)
+	}
+	_, err = tx.Exec(`CREATE VIEW paris AS SELECT * FROM address WHERE city = 'Paris'`)
+	if err != nil {
+		t.Fatalf("Cannot create view in transaction: %s", err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("Cannot rollback: %s", err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM paris`).Scan(&n)
+	if err == nil {
+		t.Fatalf("Expected view to be rolled back")
+	}
+
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`CREATE VIEW active_account AS SELECT * FROM account`, `relation "active_account" already exists`},
+		{`CREATE TABLE active_account (id INT)`, `already exists`},
+		{`CREATE OR REPLACE VIEW account AS SELECT * FROM address`, `"account" is not a view`},
+		{`CREATE VIEW missing AS SELECT * FROM nowhere`, `nowhere`},
+		{`CREATE VIEW two_ids AS SELECT account.id, address.id FROM account JOIN address ON account.id = address.account_id`, `column "id" specified more than once`},
+		{`CREATE OR REPLACE VIEW active_account AS SELECT * FROM active_city`, `infinite recursion`},
+		{`DROP TABLE account`, `cannot drop table account because other objects depend on it`},
+		{`DROP VIEW active_account`, `cannot drop view active_account because other objects depend on it`},
+		{`DROP VIEW account`, `"account" is not a view`},
+		{`DROP TABLE active_account`, `"active_account" is not a table`},
+		{`DROP VIEW nowhere`, `view "nowhere" does not exist`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	batch = []string{
+		`DROP VIEW IF EXISTS nowhere`,
+		`DROP VIEW active_account CASCADE`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+	for _, name := range []string{"active_account", "active_city"} {
+		err = db.QueryRow(`SELECT COUNT(*) FROM ` + name).Scan(&n)
+		if err == nil {
+			t.Fatalf("Expected view %s to be dropped", name)
+		}
+	}
+	_, err = db.Exec(`DROP TABLE account`)
+	if err != nil {
+		t.Fatalf("Cannot drop table once views are dropped: %s", err)
+	}
+}
+
+func TestViewAlterTable(t *testing.T) {
+	log.UseTestLogger(t)
+
+	db, err := sql.Open("ramsql", "TestViewAlterTable")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE item (id INT, c TEXT, qty INT, note TEXT)`,
+		`CREATE TABLE stock (item_id INT, c TEXT)`,
+		`INSERT INTO item (id, c, qty, note) VALUES (1, 'a', 5, 'x')`,
+		`INSERT INTO item (id, c, qty, note) VALUES (2, 'b', 0, 'y')`,
+		`INSERT INTO stock (item_id, c) VALUES (1, 'shelf')`,
+		`CREATE VIEW available AS SELECT id, c FROM item WHERE qty > 0 ORDER BY c`,
+		`CREATE VIEW stocked AS SELECT item.c, stock.c AS place FROM item JOIN stock ON item.id = stock.item_id`,
+		`CREATE VIEW everything AS SELECT * FROM item`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	// Columns read by views can't be dropped or changed
+	violations := []struct {
+		query string
+		err   string
+	}{
+		{`ALTER TABLE item DROP COLUMN qty`, `cannot drop column qty of table item because other objects depend on it`},
+		{`ALTER TABLE item DROP COLUMN note`, `cannot drop column note of table item because other objects depend on it`},
+		{`ALTER TABLE item ALTER COLUMN qty TYPE BIGINT`, `cannot alter type of a column used by a view or rule`},
+	}
+	for _, v := range violations {
+		_, err = db.Exec(v.query)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Fatalf("Expected error containing '%s' from %s, got %v", v.err, v.query, err)
+		}
+	}
+
+	// Renames are followed by views, which keep their column names
+	batch = []string{
+		`DROP VIEW everything`,
+		`ALTER TABLE item DROP COLUMN note`,
+		`ALTER TABLE item RENAME COLUMN c TO code`,
+		`ALTER TABLE item RENAME COLUMN qty TO quantity`,
+		`ALTER TABLE item RENAME TO product`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(b)
+		if err != nil {
+			t.Fatalf("sql.Exec %s: Error: %s\n", b, err)
+		}
+	}
+
+	var id int64
+	var c string
+	err = db.QueryRow(`SELECT id, c FROM available WHERE c = 'a'`).Scan(&id, &c)
+	if err != nil || id != 1 || c != "a" {
+		t.Fatalf("Expected item 1 from view, got %d %s (%v)", id, c, err)
+	}
+	var place string
+	err = db.QueryRow(`SELECT c, place FROM stocked`).Scan(&c, &place)
+	if err != nil || c != "a" || place != "shelf" {
+		t.Fatalf("Expected stocked item from view, got %s %s (%v)", c, place, err)
+	}
+
+	// CASCADE drops views reading the column
+	_, err = db.Exec(`ALTER TABLE product DROP COLUMN quantity CASCADE`)
+	if err != nil {
+		t.Fatalf("Cannot drop column with CASCADE: %s", err)
+	}
+	var n int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM available`).Scan(&n)
+	if err == nil {
+		t.Fatalf("Expected view to be dropped with column")
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM stocked`).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected view not reading column to be kept, got %d (%v)", n, err)
+	}
+
+	// Rolled back renames restore views
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Cannot begin: %s", err)
+	}
+	_, err = tx.Exec(`ALTER TABLE stock RENAME COLUMN c TO shelf`)
+	if err != nil {
+		t.Fatalf("Cannot rename column in transaction: %s", err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("Cannot rollback: %s", err)
+	}
+	err = db.QueryRow(`SELECT c, place FROM stocked`).Scan(&c, &place)
+	if err != nil || c != "a" || place != "shelf" {
+		t.Fatalf("Expected view to be restored, got %s %s (%v)", c, place, err)
+	}
+}
@@ -0,0 +1,677 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+func alterExecutor(e *Engine, tx *Transaction, alterDecl *parser.Decl, conn protocol.EngineConn) error {
+	if len(alterDecl.Decl) > 0 && alterDecl.Decl[0].Token == parser.SequenceToken {
+		return alterSequenceExecutor(e, tx, alterDecl, conn)
+	}
+
+	// Required: TABLE [IF EXISTS] <TABLE-NAME> <ACTION> [, <ACTION>]*
+	if len(alterDecl.Decl) < 2 ||
+		alterDecl.Decl[0].Token != parser.TableToken ||
+		len(alterDecl.Decl[0].Decl) == 0 {
+		return fmt.Errorf("parsing failed, malformed ALTER TABLE query")
+	}
+
+	tableDecl := alterDecl.Decl[0]
+	name := tableDecl.Decl[len(tableDecl.Decl)-1].Lexeme
+	r := e.relation(name)
+	if r == nil {
+		if tableDecl.Decl[0].Token == parser.IfToken {
+			return conn.WriteResult(0, 0)
+		}
+		return fmt.Errorf("relation \"%s\" does not exist", name)
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	// Definitions of the table and of tables referencing it are restored
+	// as a whole if tx rolls back, so are indexes once rows are
+	indexes := r.indexes
+	tx.onRollback(func() {
+		r.indexes = indexes
+		r.reindex()
+	})
+	tx.saveTable(r.table)
+	for c := range e.referencing(r) {
+		if c != r {
+			tx.saveTable(c.table)
+		}
+	}
+
+	for _, actionDecl := range alterDecl.Decl[1:] {
+		var err error
+		switch actionDecl.Token {
+		case parser.AddToken:
+			err = alterAdd(e, tx, r, actionDecl)
+		case parser.DropToken:
+			err = alterDrop(e, tx, r, actionDecl)
+		case parser.RenameToken:
+			err = alterRename(e, tx, r, actionDecl)
+		case parser.AlterToken:
+			err = alterColumn(tx, r, actionDecl)
+		default:
+			err = fmt.Errorf("unexpected alter table action %s", actionDecl.Lexeme)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Row values may have been rewritten
+	r.syncKeyIndexes()
+	r.reindex()
+
+	return conn.WriteResult(0, 1)
+}
+
+// alterAdd adds a column, filled with its default value in existing rows,
+// or a table constraint existing rows must satisfy
+func alterAdd(e *Engine, tx *Transaction, r *Relation, addDecl *parser.Decl) error {
+	t := r.table
+	if len(addDecl.Decl) != 1 {
+		return fmt.Errorf("malformed ADD on table %s", t.name)
+	}
+
+	if addDecl.Decl[0].Token != parser.ColumnToken {
+		if err := addConstraint(e, t, addDecl.Decl[0]); err != nil {
+			return err
+		}
+		return r.validate(tx)
+	}
+
+	// ADD COLUMN [IF NOT EXISTS] <COLUMN-DEFINITION>
+	d := addDecl.Decl[0]
+	if len(d.Decl) == 0 || len(d.Decl) > 2 {
+		return fmt.Errorf("malformed ADD COLUMN on table %s", t.name)
+	}
+	columnDecl := d.Decl[len(d.Decl)-1]
+	attr, err := parseAttribute(columnDecl)
+	if err != nil {
+		return err
+	}
+	if t.attributeIndex(attr.name) >= 0 {
+		if d.Decl[0].Token == parser.IfToken {
+			return nil
+		}
+		return fmt.Errorf("column \"%s\" of relation \"%s\" already exists", attr.name, t.name)
+	}
+	if err := t.AddAttribute(attr); err != nil {
+		return err
+	}
+	if err := tx.createIdentities(e, r, []*parser.Decl{columnDecl}); err != nil {
+		return err
+	}
+	attr = t.attributes[len(t.attributes)-1]
+
+	// Existing rows get the default value, or the next value of the
+	// sequence of the attribute
+	var generated []interface{}
+	if attr.sequence != "" {
+		for range r.rows {
+			n, err := tx.nextval(attr.sequence)
+			if err != nil {
+				return err
+			}
+			v, err := coerce(attr, n)
+			if err != nil {
+				return err
+			}
+			generated = append(generated, v)
+		}
+	}
+	err = tx.rewrite(r, func(i int, row *Tuple) ([]interface{}, error) {
+		v, err := attr.computeDefault(tx)
+		if err != nil {
+			return nil, err
+		}
+		if generated != nil {
+			v = generated[i]
+		}
+		values := make([]interface{}, len(row.Values), len(row.Values)+1)
+		copy(values, row.Values)
+		return append(values, v), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Column constraints
+	for _, d := range columnDecl.Decl[1:] {
+		switch d.Token {
+		case parser.PrimaryToken:
+			err = t.setPrimaryKey("", []string{attr.name})
+		case parser.UniqueToken:
+			err = t.addUnique("", []string{attr.name})
+		case parser.ReferencesToken:
+			err = t.addForeignKey(e, "", []string{attr.name}, d)
+		case parser.CheckToken:
+			err = t.addCheck("", d)
+		case parser.ConstraintToken:
+			err = addColumnConstraint(e, t, attr.name, d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.validate(tx)
+}
+
+// alterDrop drops a column or a constraint:
+//
+//	|-> drop
+//	    |-> { column | constraint }
+//	        |-> if
+//	            |-> exists
+//	        |-> <NAME>
+//	        |-> cascade
+func alterDrop(e *Engine, tx *Transaction, r *Relation, dropDecl *parser.Decl) error {
+	if len(dropDecl.Decl) != 1 {
+		return fmt.Errorf("malformed DROP on table %s", r.table.name)
+	}
+
+	var name string
+	var ifExists, cascade bool
+	for _, d := range dropDecl.Decl[0].Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifExists = true
+		case parser.CascadeToken:
+			cascade = true
+		case parser.RestrictToken:
+		default:
+			name = d.Lexeme
+		}
+	}
+
+	if dropDecl.Decl[0].Token == parser.ConstraintToken {
+		found, err := r.dropConstraint(e, name, cascade)
+		if err != nil {
+			return err
+		}
+		if !found && !ifExists {
+			return fmt.Errorf("constraint \"%s\" of relation \"%s\" does not exist", name, r.table.name)
+		}
+		return nil
+	}
+
+	i := r.table.attributeIndex(name)
+	if i < 0 {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("column \"%s\" of relation \"%s\" does not exist", name, r.table.name)
+	}
+
+	// Views reading the column are dropped with CASCADE
+	for _, v := range e.dependentViews(r.table.name) {
+		if !e.uses(v, r.table.name, name) || e.view(v.name) != v {
+			continue
+		}
+		if !cascade {
+			return fmt.Errorf("cannot drop column %s of table %s because other objects depend on it", name, r.table.name)
+		}
+		tx.dropView(e, v)
+		tx.dropViews(e, v.name)
+	}
+
+	return tx.dropColumn(e, r, i, cascade)
+}
+
+// dropConstraint removes constraint name of relation r and returns true if
+// it exists. Foreign keys of other tables referencing a dropped key are
+// dropped as well with CASCADE.
+func (r *Relation) dropConstraint(e *Engine, name string, cascade bool) (bool, error) {
+	t := r.table
+
+	if t.primaryKey != nil && t.primaryKey.name == name {
+		if err := r.dropKeyReferences(e, t.primaryKey, cascade); err != nil {
+			return true, err
+		}
+		t.primaryKey = nil
+		return true, nil
+	}
+
+	for i, k := range t.uniqueKeys {
+		if k.name == name {
+			if err := r.dropKeyReferences(e, k, cascade); err != nil {
+				return true, err
+			}
+			t.uniqueKeys = append(t.uniqueKeys[:i:i], t.uniqueKeys[i+1:]...)
+			return true, nil
+		}
+	}
+
+	for i, fk := range t.foreignKeys {
+		if fk.name == name {
+			t.foreignKeys = append(t.foreignKeys[:i:i], t.foreignKeys[i+1:]...)
+			return true, nil
+		}
+	}
+
+	for i, c := range t.checks {
+		if c.name == name {
+			t.checks = append(t.checks[:i:i], t.checks[i+1:]...)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// dropKeyReferences removes foreign keys referencing key k of relation r,
+// or returns an error if cascade is false
+func (r *Relation) dropKeyReferences(e *Engine, k *uniqueKey, cascade bool) error {
+	for c, fks := range e.referencing(r) {
+		for _, fk := range fks {
+			if r.table.uniqueKey(fk.references) != k {
+				continue
+			}
+			if !cascade {
+				return fmt.Errorf("cannot drop constraint %s on table %s because other objects depend on it", k.name, r.table.name)
+			}
+			c.table.foreignKeys = removeForeignKey(c.table.foreignKeys, fk)
+		}
+	}
+
+	return nil
+}
+
+// dropColumn removes attribute i of relation r from its definition and its
+// rows. Constraints involving the attribute are dropped, foreign keys of
+// other tables referencing it only with CASCADE.
+func (tx *Transaction) dropColumn(e *Engine, r *Relation, i int, cascade bool) error {
+	t := r.table
+	name := t.attributes[i].name
+
+	// Foreign keys referencing the attribute
+	for c := range e.referencing(r) {
+		var fks []*foreignKey
+		for _, fk := range c.table.foreignKeys {
+			if fk.table == t.name && hasAttribute(fk.references, i) {
+				if !cascade && !(c == r && hasAttribute(fk.attributes, i)) {
+					return fmt.Errorf("cannot drop column %s of table %s because other objects depend on it", name, t.name)
+				}
+				continue
+			}
+			if fk.table == t.name {
+				n := *fk
+				n.references = shiftAttributes(fk.references, i)
+				fk = &n
+			}
+			fks = append(fks, fk)
+		}
+		c.table.foreignKeys = fks
+	}
+
+	// Constraints of the table
+	if t.primaryKey != nil {
+		t.primaryKey = t.primaryKey.without(i)
+	}
+	var keys []*uniqueKey
+	for _, k := range t.uniqueKeys {
+		if k = k.without(i); k != nil {
+			keys = append(keys, k)
+		}
+	}
+	t.uniqueKeys = keys
+
+	var fks []*foreignKey
+	for _, fk := range t.foreignKeys {
+		if hasAttribute(fk.attributes, i) {
+			continue
+		}
+		n := *fk
+		n.attributes = shiftAttributes(fk.attributes, i)
+		fks = append(fks, &n)
+	}
+	t.foreignKeys = fks
+
+	var checks []*checkConstraint
+	for _, c := range t.checks {
+		involved := false
+		for _, a := range c.columns {
+			if a == name {
+				involved = true
+			}
+		}
+		if !involved {
+			checks = append(checks, c)
+		}
+	}
+	t.checks = checks
+
+	// Indexes involving the attribute, unique ones losing their key above
+	var indexes []*index
+	for _, idx := range r.indexes {
+		if hasAttribute(idx.attributes, i) {
+			continue
+		}
+		n := newIndex(idx.name, shiftAttributes(idx.attributes, i), idx.method)
+		n.unique, n.constraint = idx.unique, idx.constraint
+		indexes = append(indexes, n)
+	}
+	r.indexes = indexes
+
+	// Sequence of an identity column
+	if s := e.sequences[t.attributes[i].sequence]; s != nil && s.owner == r && t.attributes[i].autoIncrement {
+		tx.dropSequence(e, s)
+	}
+
+	t.attributes = append(t.attributes[:i:i], t.attributes[i+1:]...)
+
+	return tx.rewrite(r, func(_ int, row *Tuple) ([]interface{}, error) {
+		return append(row.Values[:i:i], row.Values[i+1:]...), nil
+	})
+}
+
+// alterRename renames a column or the table:
+//
+//	|-> rename
+//	    |-> column
+//	        |-> <COLUMN-NAME>
+//	        |-> <NEW-COLUMN-NAME>
+//
+//	|-> rename
+//	    |-> to
+//	        |-> <NEW-TABLE-NAME>
+func alterRename(e *Engine, tx *Transaction, r *Relation, renameDecl *parser.Decl) error {
+	t := r.table
+	if len(renameDecl.Decl) != 1 {
+		return fmt.Errorf("malformed RENAME on table %s", t.name)
+	}
+	d := renameDecl.Decl[0]
+
+	if d.Token == parser.ToToken {
+		if len(d.Decl) != 1 {
+			return fmt.Errorf("malformed RENAME TO on table %s", t.name)
+		}
+		// The table stays in its schema
+		old, name := t.name, qualifiedName(schemaOf(t.name), d.Decl[0].Lexeme)
+		if e.relation(name) != nil || e.view(name) != nil {
+			return fmt.Errorf("relation \"%s\" already exists", name)
+		}
+
+		// Foreign keys referencing the table, including its own
+		for c := range e.referencing(r) {
+			var fks []*foreignKey
+			for _, fk := range c.table.foreignKeys {
+				if fk.table == old {
+					n := *fk
+					n.table = name
+					fk = &n
+				}
+				fks = append(fks, fk)
+			}
+			c.table.foreignKeys = fks
+		}
+		t.renameChecks(old, "", name, "")
+		for _, v := range e.dependentViews(old) {
+			tx.alterView(v, func(query *parser.Decl) {
+				renameViewTable(query, old, name)
+			})
+		}
+		t.name = name
+
+		e.Lock()
+		delete(e.relations, old)
+		e.relations[name] = r
+		e.Unlock()
+		tx.onRollback(func() {
+			delete(e.relations, name)
+			e.relations[old] = r
+		})
+		return nil
+	}
+
+	if len(d.Decl) != 2 {
+		return fmt.Errorf("malformed RENAME COLUMN on table %s", t.name)
+	}
+	old, name := d.Decl[0].Lexeme, d.Decl[1].Lexeme
+	i := t.attributeIndex(old)
+	if i < 0 {
+		return fmt.Errorf("column \"%s\" does not exist", old)
+	}
+	if t.attributeIndex(name) >= 0 {
+		return fmt.Errorf("column \"%s\" of relation \"%s\" already exists", name, t.name)
+	}
+
+	for _, v := range e.dependentViews(t.name) {
+		if e.uses(v, t.name, old) {
+			tx.alterView(v, func(query *parser.Decl) {
+				e.renameViewColumn(query, t.name, old, name)
+			})
+		}
+	}
+	t.attributes[i].name = name
+	t.renameChecks(t.name, old, t.name, name)
+	return nil
+}
+
+// alterColumn changes the type, default value or nullability of a column:
+//
+//	|-> alter
+//	    |-> <COLUMN-NAME>
+//	        |-> { set | drop }
+//	            |-> default
+//	                |-> <VALUE>
+//	        |-> { set | drop }
+//	            |-> not
+//	                |-> null
+//	        |-> type
+//	            |-> <TYPE>
+//	            |-> using
+//	                |-> <EXPRESSION>
+func alterColumn(tx *Transaction, r *Relation, alterDecl *parser.Decl) error {
+	t := r.table
+	if len(alterDecl.Decl) != 1 || len(alterDecl.Decl[0].Decl) != 1 || len(alterDecl.Decl[0].Decl[0].Decl) == 0 {
+		return fmt.Errorf("malformed ALTER COLUMN on table %s", t.name)
+	}
+
+	name := alterDecl.Decl[0].Lexeme
+	i := t.attributeIndex(name)
+	if i < 0 {
+		return fmt.Errorf("column \"%s\" of relation \"%s\" does not exist", name, t.name)
+	}
+	attr := &t.attributes[i]
+	d := alterDecl.Decl[0].Decl[0]
+
+	switch d.Token {
+	case parser.TypeToken:
+		var using *parser.Decl
+		if len(d.Decl) == 2 && len(d.Decl[1].Decl) == 1 {
+			using = d.Decl[1].Decl[0]
+		}
+		return tx.alterType(r, i, d.Decl[0], using)
+	case parser.SetToken, parser.DropToken:
+		switch d.Decl[0].Token {
+		case parser.DefaultToken:
+			if d.Token == parser.DropToken {
+				attr.defaultValue = nil
+				attr.sequence = ""
+				attr.generatedAlways = false
+				return nil
+			}
+			if len(d.Decl[0].Decl) != 1 {
+				return fmt.Errorf("malformed SET DEFAULT on column %s", name)
+			}
+			return attr.setDefault(d.Decl[0].Decl[0])
+		case parser.NotToken:
+			if d.Token == parser.SetToken {
+				attr.isNullable = false
+				return r.validate(tx)
+			}
+			if t.primaryKey != nil && hasAttribute(t.primaryKey.attributes, i) {
+				return fmt.Errorf("column \"%s\" is in a primary key", name)
+			}
+			attr.isNullable = true
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected alteration of column %s", name)
+}
+
+// alterType changes the type of attribute i of relation r, converting
+// values of existing rows and the default value. Values of existing rows
+// are computed by expression using if not nil.
+func (tx *Transaction) alterType(r *Relation, i int, typeDecl *parser.Decl, using *parser.Decl) error {
+	t := r.table
+	attr := &t.attributes[i]
+	for _, v := range tx.e.dependentViews(t.name) {
+		if tx.e.uses(v, t.name, attr.name) {
+			return fmt.Errorf("cannot alter type of a column used by a view or rule")
+		}
+	}
+	if err := attr.setType(typeDecl); err != nil {
+		return err
+	}
+
+	// Foreign keys of the table, and those referencing it, must still
+	// compare columns of compatible types
+	for _, fk := range t.foreignKeys {
+		parent := t
+		if p := tx.e.relation(fk.table); p != nil {
+			parent = p.table
+		}
+		if err := fk.checkTypes(t, parent); err != nil {
+			return err
+		}
+	}
+	for c, fks := range tx.e.referencing(r) {
+		for _, fk := range fks {
+			if err := fk.checkTypes(c.table, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	converted := *attr
+	err := tx.rewrite(r, func(_ int, row *Tuple) ([]interface{}, error) {
+		values := make([]interface{}, len(row.Values))
+		copy(values, row.Values)
+		v := values[i]
+		var err error
+		if using != nil {
+			v, err = evaluate(using, r.virtualRow(row))
+		}
+		if err == nil {
+			v, err = coerce(converted, v)
+		}
+		if err != nil {
+			// Versions no transaction sees anymore do not matter
+			if row.deletedBy != nil && row.deletedBy.state == txCommitted {
+				v = nil
+			} else {
+				return nil, err
+			}
+		}
+		values[i] = v
+		return values, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, ok := attr.defaultValue.(func(*Transaction) interface{}); !ok {
+		if attr.defaultValue, err = coerce(converted, attr.defaultValue); err != nil {
+			return err
+		}
+	}
+	if _, ok := attr.onUpdateValue.(func(*Transaction) interface{}); !ok {
+		if attr.onUpdateValue, err = coerce(converted, attr.onUpdateValue); err != nil {
+			return err
+		}
+	}
+
+	return r.validate(tx)
+}
+
+// renameChecks replaces check constraints of table t by copies reading
+// attribute attr of table as attribute newAttr of table newTable
+func (t *Table) renameChecks(table, attr, newTable, newAttr string) {
+	var checks []*checkConstraint
+	for _, c := range t.checks {
+		n := *c
+		n.condition = copyDecl(c.condition)
+		renameColumns(n.condition, table, attr, newTable, newAttr)
+		n.columns = conditionColumns(n.condition)
+		checks = append(checks, &n)
+	}
+	t.checks = checks
+}
+
+// renameColumns renames in expression decl columns of table, or attribute
+// attr of table if not empty, as columns of newTable, or attribute newAttr
+func renameColumns(decl *parser.Decl, table, attr, newTable, newAttr string) {
+	if decl.Token == parser.ColumnToken {
+		if len(decl.Decl) > 0 {
+			if decl.Decl[0].Lexeme != table {
+				return
+			}
+			decl.Decl[0].Lexeme = newTable
+		}
+		if attr != "" && decl.Lexeme == attr {
+			decl.Lexeme = newAttr
+		}
+		return
+	}
+
+	for _, d := range decl.Decl {
+		renameColumns(d, table, attr, newTable, newAttr)
+	}
+}
+
+// without returns a copy of key k once attribute i is dropped, nil if the
+// key involves it
+func (k *uniqueKey) without(i int) *uniqueKey {
+	if hasAttribute(k.attributes, i) {
+		return nil
+	}
+
+	return &uniqueKey{name: k.name, attributes: shiftAttributes(k.attributes, i)}
+}
+
+// removeForeignKey returns a copy of fks without foreign key fk
+func removeForeignKey(fks []*foreignKey, fk *foreignKey) []*foreignKey {
+	var left []*foreignKey
+	for _, f := range fks {
+		if f != fk {
+			left = append(left, f)
+		}
+	}
+
+	return left
+}
+
+// hasAttribute returns true if attributes holds attribute i
+func hasAttribute(attributes []int, i int) bool {
+	for _, a := range attributes {
+		if a == i {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shiftAttributes returns a copy of attributes where those following
+// dropped attribute i are moved back by one
+func shiftAttributes(attributes []int, i int) []int {
+	shifted := make([]int, len(attributes))
+	for j, a := range attributes {
+		if a > i {
+			a--
+		}
+		shifted[j] = a
+	}
+
+	return shifted
+}
@@ -2,11 +2,13 @@ package engine
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/kokizzu/ramsql/engine/log"
 	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
 // Attribute (aka Field, Column) is a named column of a relation
@@ -14,11 +16,18 @@ type Attribute struct {
 	name          string
 	selectAs      string
 	typeName      string
+	typeSize      int64
+	typeScale     int64
 	defaultValue  interface{}
 	onUpdateValue interface{}
 	autoIncrement bool // TODO: rename to isAutoIncrement
 	unique        bool // TODO: rename to isUnique
 	isNullable    bool
+	// sequence gives default values of the attribute, i.e of a SERIAL
+	// or an identity column
+	sequence string
+	// generatedAlways forbids explicit values of an identity column
+	generatedAlways bool
 }
 
 // NewAttribute initialize a new Attribute struct
@@ -41,6 +50,45 @@ func (u Attribute) Alias() string {
 	return nameFields[len(nameFields)-1]
 }
 
+// column describes the attribute as a column of a result set
+func (u Attribute) column() protocol.Column {
+	c := protocol.Column{
+		Name:     u.Alias(),
+		TypeName: strings.ToUpper(u.typeName),
+		Nullable: u.isNullable,
+		ScanType: scanType(u.typeName),
+	}
+
+	switch typeKind(u.typeName) {
+	case textType, byteaType:
+		c.Length = math.MaxInt64
+		if u.typeSize > 0 {
+			c.Length = u.typeSize
+		}
+	case floatType:
+		c.Precision = u.typeSize
+		c.Scale = u.typeScale
+	}
+
+	return c
+}
+
+// computeDefault returns the default value of attribute in transaction tx,
+// calling functions like NOW()
+func (u Attribute) computeDefault(tx *Transaction) (interface{}, error) {
+	if f, ok := u.defaultValue.(func(*Transaction) interface{}); ok {
+		return coerce(u, f(tx))
+	}
+
+	return u.defaultValue, nil
+}
+
+// transactionTime is the default or ON UPDATE value of columns set to
+// NOW() or CURRENT_TIMESTAMP
+func transactionTime(tx *Transaction) interface{} {
+	return tx.now
+}
+
 // TranslateDecl traverses a Decl tree translating token sequences into Attribute settings
 // TODO func (u *Attribute) TranslateDecl(decl *parser.Decl) error {...}
 
@@ -60,7 +108,9 @@ func parseAttribute(decl *parser.Decl) (Attribute, error) {
 	if decl.Decl[0].Token != parser.StringToken {
 		return attr, fmt.Errorf("engine: expected attribute type, got %v:%v", decl.Decl[0].Token, decl.Decl[0].Lexeme)
 	}
-	attr.typeName = decl.Decl[0].Lexeme
+	if err := attr.setType(decl.Decl[0]); err != nil {
+		return attr, err
+	}
 
 	// Maybe domain and special thing like primary key
 	otherDecl := decl.Decl[1:]
@@ -69,6 +119,11 @@ func parseAttribute(decl *parser.Decl) (Attribute, error) {
 		switch otherDecl[i].Token {
 		case parser.AutoincrementToken: // AUTOINCREMENT
 			attr.autoIncrement = true
+		case parser.GeneratedToken: // GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY
+			attr.autoIncrement = true
+			if len(otherDecl[i].Decl) > 0 && otherDecl[i].Decl[0].Token == parser.AlwaysToken {
+				attr.generatedAlways = true
+			}
 		case parser.UniqueToken: // UNIQUE
 			attr.unique = true
 		case parser.NotToken: // NOT NULL
@@ -79,6 +134,10 @@ func parseAttribute(decl *parser.Decl) (Attribute, error) {
 			case parser.NullToken:
 				attr.isNullable = false
 			}
+		case parser.ConstraintToken: // CONSTRAINT <CONSTRAINT-NAME> NOT NULL
+			if len(otherDecl[i].Decl) == 2 && otherDecl[i].Decl[1].Token == parser.NotToken {
+				attr.isNullable = false
+			}
 		case parser.NullToken: // NULL
 			if len(otherDecl[i].Decl) != 0 {
 				return attr, fmt.Errorf("Attribute %s has NULL constraint with extra arguments", attr.name)
@@ -86,30 +145,82 @@ func parseAttribute(decl *parser.Decl) (Attribute, error) {
 			attr.isNullable = true
 		case parser.DefaultToken: // DEFAULT <VALUE>
 			log.Debug("we get a default value for %s: %s!\n", attr.name, otherDecl[i].Decl[0].Lexeme)
-			switch otherDecl[i].Decl[0].Token {
-			case parser.LocalTimestampToken, parser.NowToken:
-				log.Debug("Setting default value to NOW() func !\n")
-				attr.defaultValue = func() interface{} { return time.Now().Format(parser.DateLongFormat) }
-			default:
-				log.Debug("Setting default value to '%v'\n", otherDecl[i].Decl[0].Lexeme)
-				attr.defaultValue = otherDecl[i].Decl[0].Lexeme
+			if err := attr.setDefault(otherDecl[i].Decl[0]); err != nil {
+				return attr, err
 			}
 		case parser.OnToken: // ON UPDATE <VALUE>
 			log.Debug("we get a on update value for %s: %s!\n", attr.name, otherDecl[i].Decl[0].Decl[0].Lexeme)
 			switch otherDecl[i].Decl[0].Decl[0].Token {
 			case parser.LocalTimestampToken, parser.NowToken:
 				log.Debug("Setting on update value to NOW() func !\n")
-				attr.onUpdateValue = func() interface{} { return time.Now().Format(parser.DateLongFormat) }
+				attr.onUpdateValue = transactionTime
 			default:
 				log.Debug("Setting on update value to '%v'\n", otherDecl[i].Decl[0].Decl[0].Lexeme)
-				attr.onUpdateValue = otherDecl[i].Decl[0].Decl[0].Lexeme
+				v, err := coerce(attr, declValue(otherDecl[i].Decl[0].Decl[0]))
+				if err != nil {
+					return attr, err
+				}
+				attr.onUpdateValue = v
 			}
 		}
 	}
 
-	if strings.ToLower(attr.typeName) == "bigserial" {
+	switch strings.ToLower(attr.typeName) {
+	case "serial", "smallserial", "bigserial", "serial2", "serial4", "serial8":
 		attr.autoIncrement = true
 	}
 
 	return attr, nil
 }
+
+// setType sets attribute type from given type declaration, with its size,
+// i.e VARCHAR(255), or precision and scale, i.e NUMERIC(10, 2)
+func (u *Attribute) setType(typeDecl *parser.Decl) error {
+	u.typeName = typeDecl.Lexeme
+	u.typeSize = 0
+	u.typeScale = 0
+
+	for i, d := range typeDecl.Decl {
+		if d.Token != parser.NumberToken {
+			continue
+		}
+		size, err := strconv.ParseInt(d.Lexeme, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size for type %s: %s", u.typeName, d.Lexeme)
+		}
+		if i == 0 {
+			u.typeSize = size
+		} else {
+			u.typeScale = size
+		}
+	}
+
+	return nil
+}
+
+// setDefault sets attribute default value from given value declaration
+func (u *Attribute) setDefault(valueDecl *parser.Decl) error {
+	u.sequence = ""
+
+	switch valueDecl.Token {
+	case parser.NextvalToken:
+		if len(valueDecl.Decl) != 1 {
+			return fmt.Errorf("malformed default value of %s", u.name)
+		}
+		log.Debug("Setting default value to sequence %s\n", valueDecl.Decl[0].Lexeme)
+		u.defaultValue = nil
+		u.sequence = valueDecl.Decl[0].Lexeme
+	case parser.LocalTimestampToken, parser.NowToken:
+		log.Debug("Setting default value to NOW() func !\n")
+		u.defaultValue = transactionTime
+	default:
+		log.Debug("Setting default value to '%v'\n", valueDecl.Lexeme)
+		v, err := coerce(*u, declValue(valueDecl))
+		if err != nil {
+			return err
+		}
+		u.defaultValue = v
+	}
+
+	return nil
+}
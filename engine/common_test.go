@@ -13,7 +13,7 @@ func parseAndExecuteQuery(t *testing.T, e *Engine, query string) error {
 		return fmt.Errorf("Cannot parse SQL %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		return fmt.Errorf("Cannot execute SQL: %s", err)
 	}
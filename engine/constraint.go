@@ -0,0 +1,373 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// uniqueKey is a set of attributes whose values identify a single row,
+// i.e a PRIMARY KEY or a UNIQUE constraint
+type uniqueKey struct {
+	name       string
+	attributes []int
+}
+
+// values returns the key values of row t
+func (k *uniqueKey) values(t *Tuple) []interface{} {
+	values := make([]interface{}, len(k.attributes))
+	for i, a := range k.attributes {
+		values[i] = t.Values[a]
+	}
+	return values
+}
+
+// addConstraint adds a table constraint, optionally named:
+//
+//	|-> constraint
+//	    |-> <CONSTRAINT-NAME>
+//	    |-> primary
+//	        |-> key
+//	            |-> id
+//
+//	|-> unique
+//	    |-> index
+//	        |-> <INDEX-NAME>
+//	        |-> key
+//	            |-> email
+//
+//	|-> foreign
+//	    |-> key
+//	        |-> account_id
+//	    |-> references
+//
+//	|-> check
+//	    |-> price
+//	        |-> >
+//	        |-> 0
+func addConstraint(e *Engine, t *Table, decl *parser.Decl) error {
+	var name string
+
+	if decl.Token == parser.ConstraintToken {
+		if len(decl.Decl) == 0 {
+			return fmt.Errorf("malformed constraint on table %s", t.name)
+		}
+		if decl.Decl[0].Token == parser.StringToken {
+			name = decl.Decl[0].Lexeme
+		}
+		decl = decl.Decl[len(decl.Decl)-1]
+	}
+
+	switch decl.Token {
+	case parser.PrimaryToken:
+		if len(decl.Decl) != 1 {
+			return fmt.Errorf("malformed primary key on table %s", t.name)
+		}
+		var columns []string
+		for _, d := range decl.Decl[0].Decl {
+			columns = append(columns, d.Lexeme)
+		}
+		return t.setPrimaryKey(name, columns)
+	case parser.UniqueToken:
+		if len(decl.Decl) != 1 || len(decl.Decl[0].Decl) == 0 {
+			return fmt.Errorf("malformed unique constraint on table %s", t.name)
+		}
+		indexDecl := decl.Decl[0]
+		if name == "" && indexDecl.Decl[0].Token == parser.StringToken {
+			name = indexDecl.Decl[0].Lexeme
+		}
+		var columns []string
+		for _, d := range indexDecl.Decl[len(indexDecl.Decl)-1].Decl {
+			columns = append(columns, d.Lexeme)
+		}
+		return t.addUnique(name, columns)
+	case parser.ForeignToken:
+		if len(decl.Decl) != 2 {
+			return fmt.Errorf("malformed foreign key on table %s", t.name)
+		}
+		var columns []string
+		for _, d := range decl.Decl[0].Decl {
+			columns = append(columns, d.Lexeme)
+		}
+		return t.addForeignKey(e, name, columns, decl.Decl[1])
+	case parser.CheckToken:
+		return t.addCheck(name, decl)
+	}
+
+	// Other constraints are not enforced
+	return nil
+}
+
+// addColumnConstraint adds a named constraint on attribute attr of table t.
+// NOT NULL constraints are set on the attribute, see parseAttribute.
+//
+//	|-> constraint
+//	    |-> <CONSTRAINT-NAME>
+//	    |-> { primary | unique | references | check | not }
+func addColumnConstraint(e *Engine, t *Table, attr string, decl *parser.Decl) error {
+	if len(decl.Decl) != 2 {
+		return fmt.Errorf("malformed constraint on column %s of table %s", attr, t.name)
+	}
+
+	name, d := decl.Decl[0].Lexeme, decl.Decl[1]
+	switch d.Token {
+	case parser.PrimaryToken:
+		return t.setPrimaryKey(name, []string{attr})
+	case parser.UniqueToken:
+		return t.addUnique(name, []string{attr})
+	case parser.ReferencesToken:
+		return t.addForeignKey(e, name, []string{attr}, d)
+	case parser.CheckToken:
+		return t.addCheck(name, d)
+	}
+
+	return nil
+}
+
+// setPrimaryKey defines the primary key of table t. Key attributes
+// cannot be NULL.
+func (t *Table) setPrimaryKey(name string, columns []string) error {
+	if t.primaryKey != nil {
+		return fmt.Errorf("multiple primary keys for table \"%s\" are not allowed", t.name)
+	}
+
+	if name == "" {
+		name = t.name + "_pkey"
+	}
+
+	k := &uniqueKey{name: name}
+	for _, c := range columns {
+		i := t.attributeIndex(c)
+		if i < 0 {
+			return fmt.Errorf("column \"%s\" named in key does not exist", c)
+		}
+		t.attributes[i].isNullable = false
+		k.attributes = append(k.attributes, i)
+	}
+
+	t.primaryKey = k
+	return nil
+}
+
+// addUnique adds a unique constraint on given attributes of table t.
+// Default name follows Postgres: <table>_<column>_key.
+func (t *Table) addUnique(name string, columns []string) error {
+	if name == "" {
+		name = t.name + "_" + strings.Join(columns, "_") + "_key"
+	}
+
+	k := &uniqueKey{name: name}
+	for _, c := range columns {
+		i := t.attributeIndex(c)
+		if i < 0 {
+			return fmt.Errorf("column \"%s\" named in key does not exist", c)
+		}
+		k.attributes = append(k.attributes, i)
+	}
+
+	t.uniqueKeys = append(t.uniqueKeys, k)
+	return nil
+}
+
+// checkConstraint is a condition every row of a table must satisfy
+type checkConstraint struct {
+	name string
+	// condition must not be false, an unknown one satisfying the
+	// constraint
+	condition *parser.Decl
+	// columns holds the names of attributes read by condition
+	columns []string
+}
+
+// addCheck adds a check constraint on table t. Default name follows
+// Postgres: <table>_<column>_check if a single column is involved,
+// <table>_check otherwise, numbered if already taken, out of the schema of
+// the table.
+func (t *Table) addCheck(name string, checkDecl *parser.Decl) error {
+	if len(checkDecl.Decl) != 1 {
+		return fmt.Errorf("malformed check constraint on table %s", t.name)
+	}
+
+	c := &checkConstraint{
+		condition: checkDecl.Decl[0],
+		columns:   conditionColumns(checkDecl.Decl[0]),
+	}
+	for _, column := range c.columns {
+		if t.attributeIndex(column) < 0 {
+			return fmt.Errorf("attribute %s does not exist in table %s", column, t.name)
+		}
+	}
+	attr, err := expressionAttribute(c.condition, []*Table{t})
+	if err != nil {
+		return err
+	}
+	if k := typeKind(attr.typeName); k != booleanType && k != unknownType {
+		return fmt.Errorf("argument of CHECK must be type boolean, not type %s", attr.typeName)
+	}
+
+	if name == "" {
+		_, table := splitName(t.name)
+		base := table + "_check"
+		if len(c.columns) == 1 {
+			base = table + "_" + c.columns[0] + "_check"
+		}
+		name = base
+		for n := 1; t.check(name) != nil; n++ {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+	}
+	c.name = name
+
+	t.checks = append(t.checks, c)
+	return nil
+}
+
+// check returns the check constraint of table t named name, nil if none
+func (t *Table) check(name string) *checkConstraint {
+	for _, c := range t.checks {
+		if c.name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// conditionColumns returns the names of columns read by condition decl,
+// once each
+func conditionColumns(decl *parser.Decl) []string {
+	var columns []string
+	if decl.Token == parser.ColumnToken {
+		return append(columns, decl.Lexeme)
+	}
+
+	for _, d := range decl.Decl {
+		for _, column := range conditionColumns(d) {
+			found := false
+			for _, c := range columns {
+				found = found || c == column
+			}
+			if !found {
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	return columns
+}
+
+// check returns an error if row t, replacing row old if any, violates
+// a constraint of relation r
+func (r *Relation) check(tx *Transaction, t *Tuple, old *Tuple) error {
+	for i, attr := range r.table.attributes {
+		if t.Values[i] == nil && !attr.isNullable {
+			return fmt.Errorf("null value in column \"%s\" violates not-null constraint", attr.name)
+		}
+	}
+
+	for _, c := range r.table.checks {
+		if err := r.checkCondition(c, t); err != nil {
+			return err
+		}
+	}
+
+	if r.table.primaryKey != nil {
+		if err := r.checkUnique(tx, r.table.primaryKey, t, old); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range r.table.uniqueKeys {
+		if err := r.checkUnique(tx, k, t, old); err != nil {
+			return err
+		}
+	}
+
+	for _, fk := range r.table.foreignKeys {
+		if err := r.checkReference(tx, fk, t, old); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate returns an error if a row of relation r violates one of its
+// constraints, i.e once they are altered
+func (r *Relation) validate(tx *Transaction) error {
+	for _, row := range r.rows {
+		if row = tx.visible(row); row == nil {
+			continue
+		}
+		if err := r.check(tx, row, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkCondition returns an error if row t does not satisfy check
+// constraint c, that is makes its condition false
+func (r *Relation) checkCondition(c *checkConstraint, t *Tuple) error {
+	v, err := evaluateTruth(c.condition, r.virtualRow(t))
+	if err != nil {
+		return err
+	}
+	if v == false {
+		return fmt.Errorf("new row for relation \"%s\" violates check constraint \"%s\"", r.table.name, c.name)
+	}
+
+	return nil
+}
+
+// checkUnique returns an error if another row of relation r holds the
+// same key values as row t
+func (r *Relation) checkUnique(tx *Transaction, k *uniqueKey, t *Tuple, old *Tuple) error {
+	if r.duplicate(tx, k, t, old) != nil {
+		return fmt.Errorf("duplicate key value violates unique constraint \"%s\"", k.name)
+	}
+
+	return nil
+}
+
+// duplicate returns the row of relation r, other than old, holding the same
+// key values as row t, or nil if none. Rows deleted by a running transaction
+// still hold their key until it commits.
+func (r *Relation) duplicate(tx *Transaction, k *uniqueKey, t *Tuple, old *Tuple) *Tuple {
+	values := k.values(t)
+	for _, v := range values {
+		// NULL values are all distinct
+		if v == nil {
+			return nil
+		}
+	}
+
+	rows := r.rows
+	if positions, ok := r.search(k.attributes, values); ok {
+		rows = r.heads(positions)
+	}
+
+	for _, row := range rows {
+		if row == old || row == t {
+			continue
+		}
+		if row.deletedBy != nil && (row.deletedBy == tx || row.deletedBy.state == txCommitted) {
+			continue
+		}
+
+		duplicate := true
+		for i, a := range k.attributes {
+			if !equal(row.Values[a], values[i]) {
+				duplicate = false
+				break
+			}
+		}
+		if duplicate {
+			return row
+		}
+	}
+
+	return nil
+}
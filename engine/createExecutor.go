@@ -7,7 +7,7 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func createExecutor(e *Engine, createDecl *parser.Decl, conn protocol.EngineConn) error {
+func createExecutor(e *Engine, tx *Transaction, createDecl *parser.Decl, conn protocol.EngineConn) error {
 	if len(createDecl.Decl) == 0 {
 		return errors.New("Parsing failed, no declaration after CREATE")
 	}
@@ -16,5 +16,5 @@ func createExecutor(e *Engine, createDecl *parser.Decl, conn protocol.EngineConn
 		return errors.New("Parsing failed, after CREATE unkown token " + createDecl.Decl[0].Lexeme)
 	}
 
-	return e.opsExecutors[createDecl.Decl[0].Token](e, createDecl.Decl[0], conn)
+	return e.opsExecutors[createDecl.Decl[0].Token](e, tx, createDecl.Decl[0], conn)
 }
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// createIndexExecutor creates an index on an existing table:
+//
+//	|-> index
+//	    |-> unique
+//	    |-> if
+//	        |-> not
+//	            |-> exists
+//	    |-> <INDEX-NAME>
+//	    |-> on
+//	        |-> <TABLE-NAME>
+//	    |-> using
+//	        |-> { btree | hash }
+//	    |-> key
+//	        |-> <COLUMN-NAME>
+func createIndexExecutor(e *Engine, tx *Transaction, indexDecl *parser.Decl, conn protocol.EngineConn) error {
+	var table string
+	for _, d := range indexDecl.Decl {
+		if d.Token == parser.OnToken && len(d.Decl) == 1 {
+			table = d.Decl[0].Lexeme
+		}
+	}
+	if table == "" {
+		return fmt.Errorf("parsing failed, malformed CREATE INDEX query")
+	}
+
+	r := e.relation(table)
+	if r == nil {
+		return fmt.Errorf("relation \"%s\" does not exist", table)
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	if err := tx.createIndex(e, r, indexDecl); err != nil {
+		return err
+	}
+
+	return conn.WriteResult(0, 1)
+}
+
+// createIndex builds an index on relation r, dropped if tx rolls back.
+// A unique index enforces a unique constraint of the same name.
+// Default name follows Postgres: <table>_<column>_idx.
+func (tx *Transaction) createIndex(e *Engine, r *Relation, indexDecl *parser.Decl) error {
+	var name string
+	var columns []string
+	var unique, ifNotExists bool
+	method := btreeIndex
+
+	for _, d := range indexDecl.Decl {
+		switch d.Token {
+		case parser.UniqueToken:
+			unique = true
+		case parser.IfToken:
+			ifNotExists = true
+		case parser.OnToken:
+		case parser.UsingToken:
+			if len(d.Decl) == 1 && d.Decl[0].Token == parser.HashToken {
+				method = hashIndex
+			}
+		case parser.KeyToken:
+			for _, c := range d.Decl {
+				columns = append(columns, c.Lexeme)
+			}
+		default:
+			name = d.Lexeme
+		}
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("malformed index on table %s", r.table.name)
+	}
+
+	// Indexes belong to the schema of their table
+	if name == "" {
+		name = e.indexName(r.table.name + "_" + strings.Join(columns, "_") + "_idx")
+	} else {
+		name = qualifiedName(schemaOf(r.table.name), name)
+	}
+	if _, i := e.findIndex(name); i != nil || e.relation(name) != nil || e.view(name) != nil {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("relation \"%s\" already exists", name)
+	}
+
+	var attributes []int
+	for _, c := range columns {
+		a := r.table.attributeIndex(c)
+		if a < 0 {
+			return fmt.Errorf("column \"%s\" does not exist", c)
+		}
+		attributes = append(attributes, a)
+	}
+
+	i := newIndex(name, attributes, method)
+	i.unique = unique
+	r.build(i)
+	r.indexes = append(r.indexes, i)
+	tx.onRollback(func() {
+		r.removeIndex(i)
+	})
+
+	if unique {
+		tx.saveTable(r.table)
+		if err := r.table.addUnique(name, columns); err != nil {
+			return err
+		}
+		return r.validate(tx)
+	}
+
+	return nil
+}
+
+// indexName returns given index name, followed by a number if an index
+// or a relation already uses it
+func (e *Engine) indexName(name string) string {
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate += strconv.Itoa(n)
+		}
+		if _, i := e.findIndex(candidate); i == nil && e.relation(candidate) == nil {
+			return candidate
+		}
+	}
+}
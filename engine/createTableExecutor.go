@@ -7,7 +7,7 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func createTableExecutor(e *Engine, tableDecl *parser.Decl, conn protocol.EngineConn) error {
+func createTableExecutor(e *Engine, tx *Transaction, tableDecl *parser.Decl, conn protocol.EngineConn) error {
 	if len(tableDecl.Decl) == 0 {
 		return fmt.Errorf("parsing failed, malformed CREATE TABLE query")
 	}
@@ -16,7 +16,7 @@ func createTableExecutor(e *Engine, tableDecl *parser.Decl, conn protocol.Engine
 	i := 0
 	for i < len(tableDecl.Decl) {
 		if e.opsExecutors[tableDecl.Decl[i].Token] != nil {
-			if err := e.opsExecutors[tableDecl.Decl[i].Token](e, tableDecl.Decl[i], conn); err != nil {
+			if err := e.opsExecutors[tableDecl.Decl[i].Token](e, tx, tableDecl.Decl[i], conn); err != nil {
 				return err
 			}
 		} else {
@@ -28,16 +28,54 @@ func createTableExecutor(e *Engine, tableDecl *parser.Decl, conn protocol.Engine
 
 	// Check if table does not exists
 	r := e.relation(tableDecl.Decl[i].Lexeme)
-	if r != nil {
+	if r != nil || e.view(tableDecl.Decl[i].Lexeme) != nil {
 		return fmt.Errorf("table %s already exists", tableDecl.Decl[i].Lexeme)
 	}
 
 	// Fetch table name
 	t := NewTable(tableDecl.Decl[i].Lexeme)
 
-	// Fetch attributes
+	// Fetch attributes, then table constraints once all attributes are known
+	var columns []*parser.Decl
+	var constraints []*parser.Decl
+	var indexes []*parser.Decl
+	var keys []string
+	var references = make(map[string]*parser.Decl)
+	var named = make(map[string][]*parser.Decl)
+	var temporary, onCommit, dropOnCommit bool
+	var query *parser.Decl
 	i++
 	for i < len(tableDecl.Decl) {
+		switch tableDecl.Decl[i].Token {
+		case parser.ConstraintToken, parser.PrimaryToken, parser.UniqueToken, parser.ForeignToken, parser.CheckToken:
+			constraints = append(constraints, tableDecl.Decl[i])
+			i++
+			continue
+		case parser.IndexToken:
+			indexes = append(indexes, tableDecl.Decl[i])
+			i++
+			continue
+		case parser.TemporaryToken:
+			temporary = true
+			i++
+			continue
+		case parser.OnToken:
+			// ON COMMIT { PRESERVE ROWS | DROP }
+			onCommit = true
+			d := tableDecl.Decl[i]
+			dropOnCommit = len(d.Decl) == 1 && len(d.Decl[0].Decl) == 1 && d.Decl[0].Decl[0].Token == parser.DropToken
+			i++
+			continue
+		case parser.AsToken:
+			// AS <SELECT>
+			if len(tableDecl.Decl[i].Decl) != 1 {
+				return fmt.Errorf("parsing failed, malformed CREATE TABLE AS query")
+			}
+			query = tableDecl.Decl[i].Decl[0]
+			i++
+			continue
+		}
+
 		attr, err := parseAttribute(tableDecl.Decl[i])
 		if err != nil {
 			return err
@@ -46,10 +84,118 @@ func createTableExecutor(e *Engine, tableDecl *parser.Decl, conn protocol.Engine
 		if err != nil {
 			return err
 		}
+		columns = append(columns, tableDecl.Decl[i])
+
+		// Column constraints PRIMARY KEY, REFERENCES and CHECK, and
+		// those named. Checks are added with table constraints so their
+		// default names are numbered in the order they are declared.
+		for _, d := range tableDecl.Decl[i].Decl[1:] {
+			switch d.Token {
+			case parser.PrimaryToken:
+				keys = append(keys, attr.name)
+			case parser.ReferencesToken:
+				references[attr.name] = d
+			case parser.CheckToken:
+				constraints = append(constraints, d)
+			case parser.ConstraintToken:
+				named[attr.name] = append(named[attr.name], d)
+			}
+		}
 		i++
 	}
 
-	e.relations[t.name] = NewRelation(t)
+	if onCommit && !temporary {
+		return fmt.Errorf("ON COMMIT can only be used on temporary tables")
+	}
+
+	// Columns of a table created AS SELECT are those of the result,
+	// its rows being inserted once the table exists
+	var rows [][]interface{}
+	if query != nil {
+		result := &queryResult{}
+		if err := selectExecutor(e, tx, query, result); err != nil {
+			return err
+		}
+		if err := addResultColumns(t, nil, result.columns); err != nil {
+			return err
+		}
+		rows = result.rows
+	}
+
+	// Relation exists before constraints are added since check
+	// conditions resolve attributes through the engine
+	r = NewRelation(t)
+	e.relations[t.name] = r
+	tx.onRollback(func() {
+		e.drop(t.name)
+	})
+
+	// Sequences of identity columns
+	if err := tx.createIdentities(e, r, columns); err != nil {
+		return err
+	}
+
+	if len(keys) > 0 {
+		if err := t.setPrimaryKey("", keys); err != nil {
+			return err
+		}
+	}
+	for _, attr := range t.attributes {
+		if attr.unique {
+			if err := t.addUnique("", []string{attr.name}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range constraints {
+		if err := addConstraint(e, t, c); err != nil {
+			return err
+		}
+	}
+	for _, attr := range t.attributes {
+		if d, ok := references[attr.name]; ok {
+			if err := t.addForeignKey(e, "", []string{attr.name}, d); err != nil {
+				return err
+			}
+		}
+		for _, d := range named[attr.name] {
+			if err := addColumnConstraint(e, t, attr.name, d); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Indexes backing keys, then those declared with INDEX or KEY
+	r.syncKeyIndexes()
+	for _, d := range indexes {
+		if err := tx.createIndex(e, r, d); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		if err := tx.insert(r, NewTuple(row...)); err != nil {
+			return err
+		}
+	}
+
+	// Temporary tables created ON COMMIT DROP last until the end of
+	// the transaction, unless dropped before
+	if dropOnCommit {
+		tx.onCommit(func() {
+			if e.relation(t.name) != r {
+				return
+			}
+			r.Lock()
+			tx.dropTable(e, r)
+			r.Unlock()
+		})
+	}
+
+	if query != nil {
+		return conn.WriteResult(0, int64(len(rows)))
+	}
+
 	conn.WriteResult(0, 1)
 	return nil
 }
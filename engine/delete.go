@@ -3,10 +3,11 @@ package engine
 import (
 	"fmt"
 
+	"github.com/kokizzu/ramsql/engine/parser"
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func deleteRows(e *Engine, tables []*Table, conn protocol.EngineConn, predicates []Predicate) error {
+func deleteRows(e *Engine, tx *Transaction, deleteDecl *parser.Decl, tables []*Table, conn protocol.EngineConn, predicates []Predicate) error {
 	var rowsDeleted int64
 
 	r := e.relation(tables[0].name)
@@ -15,15 +16,44 @@ func deleteRows(e *Engine, tables []*Table, conn protocol.EngineConn, predicates
 	}
 	r.Lock()
 	defer r.Unlock()
+	tx.reads(r)
+
+	// Rows may be joined with those of other tables, filtered by WHERE
+	var joinedTables []*Table
+	j, err := newJoined(e, r, deleteDecl)
+	if err != nil {
+		return err
+	}
+	if j != nil {
+		defer j.lock(tx)()
+		joinedTables = j.tables[1:]
+	}
+
+	// Values of deleted rows may be returned
+	ret, err := newReturning(r, deleteDecl, joinedTables...)
+	if err != nil {
+		return err
+	}
+
+	conditions := make([]*Predicate, len(predicates))
+	for i := range predicates {
+		conditions[i] = &predicates[i]
+	}
 
 	var ok, res bool
-	var err error
-	lenRows := len(r.rows)
-	for i := 0; i < lenRows; i++ {
+	err = r.scan(conditions, func(_ int, t *Tuple) error {
+		if err := tx.interrupted(); err != nil {
+			return err
+		}
+		if t = tx.visible(t); t == nil {
+			return nil
+		}
+
 		ok = true
 		// If the row validate all predicates, write it
 		for _, predicate := range predicates {
-			if res, err = predicate.Evaluate(r.rows[i], r.table); err != nil {
+			var err error
+			if res, err = predicate.Evaluate(t, r.table); err != nil {
 				return err
 			}
 			if res == false {
@@ -32,20 +62,31 @@ func deleteRows(e *Engine, tables []*Table, conn protocol.EngineConn, predicates
 			}
 		}
 
-		if ok {
-			switch i {
-			case 0:
-				r.rows = r.rows[1:]
-			case lenRows - 1:
-				r.rows = r.rows[:lenRows-1]
-			default:
-				r.rows = append(r.rows[:i], r.rows[i+1:]...)
-				i--
+		if !ok {
+			return nil
+		}
+
+		// Joined rows are deleted once
+		var row virtualRow
+		if j != nil {
+			var err error
+			if row, err = j.match(tx, r, t); err != nil || row == nil {
+				return err
 			}
-			lenRows--
-			rowsDeleted++
 		}
+
+		rowsDeleted++
+		if err := tx.delete(r, t); err != nil {
+			return err
+		}
+		return ret.addJoined(t, row)
+	})
+	if err != nil {
+		return err
 	}
 
+	if ret != nil {
+		return ret.write(conn)
+	}
 	return conn.WriteResult(0, rowsDeleted)
 }
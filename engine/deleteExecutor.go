@@ -6,7 +6,7 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func deleteExecutor(e *Engine, deleteDecl *parser.Decl, conn protocol.EngineConn) error {
+func deleteExecutor(e *Engine, tx *Transaction, deleteDecl *parser.Decl, conn protocol.EngineConn) error {
 	log.Debug("deleteExecutor")
 
 	// get tables to be deleted
@@ -14,7 +14,12 @@ func deleteExecutor(e *Engine, deleteDecl *parser.Decl, conn protocol.EngineConn
 
 	// If len is 1, it means no predicates so truncate table
 	if len(deleteDecl.Decl) == 1 {
-		return truncateTable(e, tables[0], conn)
+		return truncateTable(e, tx, tables[0], conn)
+	}
+
+	// Rows joined with those of other tables are filtered while joined
+	if deleteDecl.Decl[1].Token == parser.UsingToken {
+		return deleteRows(e, tx, deleteDecl, tables, conn, nil)
 	}
 
 	// get WHERE declaration
@@ -24,5 +29,5 @@ func deleteExecutor(e *Engine, deleteDecl *parser.Decl, conn protocol.EngineConn
 	}
 
 	// and delete
-	return deleteRows(e, tables, conn, predicates)
+	return deleteRows(e, tx, deleteDecl, tables, conn, predicates)
 }
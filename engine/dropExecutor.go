@@ -7,19 +7,31 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func dropExecutor(e *Engine, dropDecl *parser.Decl, conn protocol.EngineConn) error {
+func dropExecutor(e *Engine, tx *Transaction, dropDecl *parser.Decl, conn protocol.EngineConn) error {
 	// Action Parameters
 	var allowNotFound = false
 	var tableName string
 
 	// Process Decls
 
-	// Required: TABLE
+	// Required: TABLE | INDEX | SEQUENCE | VIEW | SCHEMA
 	if dropDecl.Decl == nil ||
 		len(dropDecl.Decl) != 1 ||
-		dropDecl.Decl[0].Token != parser.TableToken {
+		(dropDecl.Decl[0].Token != parser.TableToken && dropDecl.Decl[0].Token != parser.IndexToken && dropDecl.Decl[0].Token != parser.SequenceToken && dropDecl.Decl[0].Token != parser.ViewToken && dropDecl.Decl[0].Token != parser.SchemaToken) {
 		return fmt.Errorf("unexpected drop arguments")
 	}
+	if dropDecl.Decl[0].Token == parser.IndexToken {
+		return dropIndex(e, tx, dropDecl.Decl[0], conn)
+	}
+	if dropDecl.Decl[0].Token == parser.SequenceToken {
+		return dropSequence(e, tx, dropDecl.Decl[0], conn)
+	}
+	if dropDecl.Decl[0].Token == parser.ViewToken {
+		return dropViewExecutor(e, tx, dropDecl.Decl[0], conn)
+	}
+	if dropDecl.Decl[0].Token == parser.SchemaToken {
+		return dropSchema(e, tx, dropDecl.Decl[0], conn)
+	}
 
 	// Optional: IF EXISTS
 	tableNameTokenIndex := 0
@@ -34,6 +46,9 @@ func dropExecutor(e *Engine, dropDecl *parser.Decl, conn protocol.EngineConn) er
 	// Pre-Action/s
 	r := e.relation(tableName)
 	if r == nil {
+		if e.view(tableName) != nil {
+			return fmt.Errorf("\"%s\" is not a table", tableName)
+		}
 		if allowNotFound {
 			return conn.WriteResult(0, 1)
 		}
@@ -41,11 +56,97 @@ func dropExecutor(e *Engine, dropDecl *parser.Decl, conn protocol.EngineConn) er
 		return fmt.Errorf("relation '%s' not found", tableName)
 	}
 
+	// Views selecting from the table and foreign keys referencing it are
+	// dropped with CASCADE
+	cascade := dropDecl.Decl[0].Decl[len(dropDecl.Decl[0].Decl)-1].Token == parser.CascadeToken
+	if len(e.dependentViews(tableName)) > 0 && !cascade {
+		return fmt.Errorf("cannot drop table %s because other objects depend on it", tableName)
+	}
+	for c := range e.referencing(r) {
+		if c != r && !cascade {
+			return fmt.Errorf("cannot drop table %s because other objects depend on it", tableName)
+		}
+	}
+
 	// Action/s
-	e.drop(tableName)
+	tx.dropTable(e, r)
+
+	return conn.WriteResult(0, 1)
+}
+
+// dropTable drops relation r with the sequences it owns, the views
+// selecting from it and the foreign keys referencing it
+func (tx *Transaction) dropTable(e *Engine, r *Relation) {
+	name := r.table.name
+	tx.dropReferences(r)
+	e.drop(name)
+	tx.onRollback(func() {
+		e.relations[name] = r
+	})
+
+	tx.dropOwnedSequences(e, r)
+	tx.dropViews(e, name)
+}
+
+// dropIndex drops an index, and the unique constraint it enforces if any.
+// Indexes backing a constraint are dropped with it.
+//
+//	|-> index
+//	    |-> if
+//	        |-> exists
+//	    |-> <INDEX-NAME>
+//	    |-> on
+//	        |-> <TABLE-NAME>
+//	    |-> cascade
+func dropIndex(e *Engine, tx *Transaction, indexDecl *parser.Decl, conn protocol.EngineConn) error {
+	var name, table string
+	var ifExists, cascade bool
+	for _, d := range indexDecl.Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifExists = true
+		case parser.OnToken:
+			if len(d.Decl) == 1 {
+				table = d.Decl[0].Lexeme
+			}
+		case parser.CascadeToken:
+			cascade = true
+		case parser.RestrictToken:
+		default:
+			name = d.Lexeme
+		}
+	}
+
+	r, i := e.findIndex(name)
+	if i == nil || (table != "" && r.table.name != table) {
+		if ifExists {
+			return conn.WriteResult(0, 1)
+		}
+		return fmt.Errorf("index \"%s\" does not exist", name)
+	}
+	if i.constraint {
+		return fmt.Errorf("cannot drop index %s because constraint %s on table %s requires it", name, name, r.table.name)
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	if i.unique {
+		tx.saveTable(r.table)
+		for c := range e.referencing(r) {
+			if c != r {
+				tx.saveTable(c.table)
+			}
+		}
+		if _, err := r.dropConstraint(e, name, cascade); err != nil {
+			return err
+		}
+	}
 
-	// Post-Action/s
-	// None
+	r.removeIndex(i)
+	tx.onRollback(func() {
+		r.build(i)
+		r.indexes = append(r.indexes, i)
+	})
 
 	return conn.WriteResult(0, 1)
 }
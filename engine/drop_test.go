@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/kokizzu/ramsql/engine/log"
+	"github.com/kokizzu/ramsql/engine/parser"
 )
 
 func TestDropAfterCreate(t *testing.T) {
@@ -51,3 +52,36 @@ func TestDropIfExistsWithoutCreate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestDropTemporaryOnClose(t *testing.T) {
+	log.UseTestLogger(t)
+
+	e := testEngine(t)
+	defer e.Stop()
+
+	s := newSession()
+	for _, query := range []string{
+		"CREATE TABLE account (id INT, email TEXT)",
+		"CREATE TEMPORARY TABLE account (id SERIAL, email TEXT)",
+		"INSERT INTO account (email) VALUES ('foo@bar.com')",
+	} {
+		i, err := parser.ParseInstruction(query)
+		if err != nil {
+			t.Fatalf("Cannot parse SQL %s : %s", query, err)
+		}
+		if err = e.executeQuery(s, i[0], &TestEngineConn{}); err != nil {
+			t.Fatalf("Cannot execute SQL %s : %s", query, err)
+		}
+	}
+	if len(e.relations) != 2 {
+		t.Fatalf("Expected 2 relations, got %d", len(e.relations))
+	}
+
+	e.closeSession(s)
+	if len(e.relations) != 1 || e.relation("account") == nil {
+		t.Fatalf("Expected only permanent table to be left, got %v", e.relations)
+	}
+	if len(e.sequences) != 0 || e.schemas[s.temp] {
+		t.Fatalf("Expected temporary schema to be dropped")
+	}
+}
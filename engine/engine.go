@@ -1,9 +1,11 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 
 	"github.com/kokizzu/ramsql/engine/log"
@@ -11,13 +13,25 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-type executor func(*Engine, *parser.Decl, protocol.EngineConn) error
+type executor func(*Engine, *Transaction, *parser.Decl, protocol.EngineConn) error
 
 // Engine is the root struct of RamSQL server
 type Engine struct {
 	endpoint     protocol.EngineEndpoint
+	schemas      map[string]bool
 	relations    map[string]*Relation
+	sequences    map[string]*sequence
+	views        map[string]*view
 	opsExecutors map[int]executor
+	// temps counts temporary schemas, to name them
+	temps int
+
+	// Transactions bookkeeping, see Transaction
+	clock   int64
+	active  map[*Transaction]bool
+	garbage []rowVersion
+	// exec serializes statements execution
+	exec sync.Mutex
 
 	// Any value send to this channel (through Engine.stop)
 	// Will stop the listening loop
@@ -36,21 +50,36 @@ func New(endpoint protocol.EngineEndpoint) (e *Engine, err error) {
 	e.stop = make(chan bool)
 
 	e.opsExecutors = map[int]executor{
-		parser.CreateToken:   createExecutor,
-		parser.DeleteToken:   deleteExecutor,
-		parser.DropToken:     dropExecutor,
-		parser.ExistsToken:   existsExecutor,
-		parser.GrantToken:    grantExecutor,
-		parser.IfToken:       ifExecutor,
-		parser.InsertToken:   insertIntoTableExecutor,
-		parser.NotToken:      notExecutor,
-		parser.SelectToken:   selectExecutor,
-		parser.TableToken:    createTableExecutor,
-		parser.TruncateToken: truncateExecutor,
-		parser.UpdateToken:   updateExecutor,
+		parser.AlterToken:     alterExecutor,
+		parser.BeginToken:     beginExecutor,
+		parser.CommitToken:    commitExecutor,
+		parser.CreateToken:    createExecutor,
+		parser.DeleteToken:    deleteExecutor,
+		parser.DropToken:      dropExecutor,
+		parser.ExistsToken:    existsExecutor,
+		parser.GrantToken:     grantExecutor,
+		parser.IfToken:        ifExecutor,
+		parser.IndexToken:     createIndexExecutor,
+		parser.InsertToken:    insertIntoTableExecutor,
+		parser.NotToken:       notExecutor,
+		parser.ReleaseToken:   releaseExecutor,
+		parser.RollbackToken:  rollbackExecutor,
+		parser.SavepointToken: savepointExecutor,
+		parser.SchemaToken:    createSchemaExecutor,
+		parser.SelectToken:    selectExecutor,
+		parser.SequenceToken:  createSequenceExecutor,
+		parser.SetToken:       searchPathExecutor,
+		parser.TableToken:     createTableExecutor,
+		parser.TruncateToken:  truncateExecutor,
+		parser.UpdateToken:    updateExecutor,
+		parser.ViewToken:      createViewExecutor,
 	}
 
+	e.schemas = map[string]bool{publicSchema: true}
 	e.relations = make(map[string]*Relation)
+	e.sequences = make(map[string]*sequence)
+	e.views = make(map[string]*view)
+	e.active = make(map[*Transaction]bool)
 
 	err = e.start()
 	if err != nil {
@@ -122,6 +151,8 @@ func (e *Engine) listen() {
 }
 
 func (e *Engine) handleConnection(conn protocol.EngineConn) {
+	s := newSession()
+	defer e.closeSession(s)
 
 	for {
 		stmt, err := conn.ReadStatement()
@@ -134,13 +165,51 @@ func (e *Engine) handleConnection(conn protocol.EngineConn) {
 			return
 		}
 
-		instructions, err := parser.ParseInstruction(stmt)
+		if stmt.Close {
+			err = s.deallocate(stmt.ID)
+			if err != nil {
+				conn.WriteError(err)
+				continue
+			}
+			conn.WriteResult(0, 0)
+			continue
+		}
+
+		// Empty query does nothing, it is used to check the connection
+		if stmt.ID == 0 && !stmt.Prepare && strings.TrimSpace(stmt.Query) == "" {
+			conn.WriteResult(0, 0)
+			continue
+		}
+
+		// Prepared statements are only parsed once
+		var instructions []parser.Instruction
+		if stmt.ID != 0 {
+			instructions, err = s.statement(stmt.ID)
+		} else {
+			instructions, err = parser.ParseInstruction(stmt.Query)
+		}
 		if err != nil {
 			conn.WriteError(err)
 			continue
 		}
 
-		err = e.executeQueries(instructions, conn)
+		if stmt.Prepare {
+			conn.WritePrepared(s.prepare(instructions), parser.NumParameters(instructions))
+			continue
+		}
+
+		instructions, err = bind(instructions, stmt.Args)
+		if err != nil {
+			conn.WriteError(err)
+			continue
+		}
+
+		s.ctx = context.Background()
+		if stmt.Context != nil {
+			s.ctx = stmt.Context
+		}
+
+		err = e.executeQueries(s, instructions, conn)
 		if err != nil {
 			conn.WriteError(err)
 			continue
@@ -148,16 +217,17 @@ func (e *Engine) handleConnection(conn protocol.EngineConn) {
 	}
 }
 
-func (e *Engine) executeQueries(instructions []parser.Instruction, conn protocol.EngineConn) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("fatal error: %s", r)
-			return
-		}
-	}()
+func (e *Engine) closeSession(s *session) {
+	e.exec.Lock()
+	defer e.exec.Unlock()
 
+	s.close()
+	e.dropTempSchema(s)
+}
+
+func (e *Engine) executeQueries(s *session, instructions []parser.Instruction, conn protocol.EngineConn) (err error) {
 	for _, i := range instructions {
-		err = e.executeQuery(i, conn)
+		err = e.executeQuery(s, i, conn)
 		if err != nil {
 			return err
 		}
@@ -166,10 +236,43 @@ func (e *Engine) executeQueries(instructions []parser.Instruction, conn protocol
 	return nil
 }
 
-func (e *Engine) executeQuery(i parser.Instruction, conn protocol.EngineConn) error {
+// executeQuery runs given instruction in the session transaction.
+// Statements are executed one at a time.
+func (e *Engine) executeQuery(s *session, i parser.Instruction, conn protocol.EngineConn) (err error) {
+	e.exec.Lock()
+	defer e.exec.Unlock()
+
+	tx := s.transaction(e)
+	tx.ctx = s.ctx
+	tx.session = s
+	mark := len(tx.undo)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fatal error: %s", r)
+		}
+		err = s.end(tx, mark, err)
+	}()
+
+	if err := tx.interrupted(); err != nil {
+		return err
+	}
+
+	if err := tx.allows(i.Decls[0]); err != nil {
+		return err
+	}
+
+	if err := e.resolve(s, i.Decls[0]); err != nil {
+		return err
+	}
+
+	// Defaults of created and altered tables are computed as rows are
+	// written, views compute NOW() when selected from
+	if t := i.Decls[0].Token; t != parser.CreateToken && t != parser.AlterToken {
+		bindNow(i.Decls[0], tx.now)
+	}
 
 	if e.opsExecutors[i.Decls[0].Token] != nil {
-		return e.opsExecutors[i.Decls[0].Token](e, i.Decls[0], conn)
+		return e.opsExecutors[i.Decls[0].Token](e, tx, i.Decls[0], conn)
 	}
 
 	return errors.New("Not Implemented")
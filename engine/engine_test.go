@@ -9,8 +9,12 @@ import (
 type TestEngineConn struct {
 }
 
-func (conn *TestEngineConn) ReadStatement() (string, error) {
-	return "", nil
+func (conn *TestEngineConn) ReadStatement() (protocol.Statement, error) {
+	return protocol.Statement{}, nil
+}
+
+func (conn *TestEngineConn) WritePrepared(id int64, numInput int) error {
+	return nil
 }
 
 func (conn *TestEngineConn) WriteResult(lastInsertedID int64, rowsAffected int64) error {
@@ -21,11 +25,11 @@ func (conn *TestEngineConn) WriteError(err error) error {
 	return nil
 }
 
-func (conn *TestEngineConn) WriteRowHeader(header []string) error {
+func (conn *TestEngineConn) WriteRowHeader(header []protocol.Column) error {
 	return nil
 }
 
-func (conn *TestEngineConn) WriteRow(row []string) error {
+func (conn *TestEngineConn) WriteRow(row []interface{}) error {
 	return nil
 }
 
@@ -0,0 +1,572 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// expressionAttribute checks columns referenced by expression decl belong
+// to given tables, and returns the attribute describing its values
+func expressionAttribute(decl *parser.Decl, tables []*Table) (Attribute, error) {
+	switch decl.Token {
+	case parser.ColumnToken:
+		return columnAttribute(decl, tables)
+	case parser.PlusToken, parser.MinusToken, parser.StarToken, parser.SlashToken, parser.PercentToken:
+		if decl.Token == parser.StarToken && len(decl.Decl) != 2 {
+			return Attribute{}, fmt.Errorf("row expansion via \"*\" is not supported here")
+		}
+		if len(decl.Decl) == 0 || len(decl.Decl) > 2 {
+			return Attribute{}, fmt.Errorf("parsing failed, malformed expression")
+		}
+		attr, err := expressionAttribute(decl.Decl[0], tables)
+		if err != nil {
+			return Attribute{}, err
+		}
+		typeName := attr.typeName
+		if len(decl.Decl) == 2 {
+			other, err := expressionAttribute(decl.Decl[1], tables)
+			if err != nil {
+				return Attribute{}, err
+			}
+			typeName = arithmeticType(attr.typeName, other.typeName)
+		}
+		attr = NewAttribute("?column?", typeName, false)
+		attr.isNullable = true
+		return attr, nil
+	case parser.ConcatToken:
+		for _, d := range decl.Decl {
+			if _, err := expressionAttribute(d, tables); err != nil {
+				return Attribute{}, err
+			}
+		}
+		attr := NewAttribute("?column?", "text", false)
+		attr.isNullable = true
+		return attr, nil
+	case parser.NumberToken:
+		if _, err := toInteger(decl.Lexeme); err == nil {
+			return NewAttribute("?column?", "bigint", false), nil
+		}
+		return NewAttribute("?column?", "float", false), nil
+	case parser.StringToken:
+		return NewAttribute("?column?", "text", false), nil
+	case parser.TrueToken, parser.FalseToken:
+		return NewAttribute("?column?", "boolean", false), nil
+	case parser.ByteaToken:
+		return NewAttribute("?column?", "bytea", false), nil
+	case parser.TimestampToken:
+		return NewAttribute("?column?", "timestamp", false), nil
+	case parser.NowToken:
+		return NewAttribute("now", "timestamp", false), nil
+	case parser.LocalTimestampToken:
+		return NewAttribute(strings.ToLower(decl.Lexeme), "timestamp", false), nil
+	case parser.NullToken:
+		attr := NewAttribute("?column?", "text", false)
+		attr.isNullable = true
+		return attr, nil
+	case parser.FunctionToken:
+		return functionAttribute(decl, tables)
+	case parser.CaseToken:
+		var attr Attribute
+		for i, d := range decl.Decl {
+			if len(d.Decl) == 0 {
+				return Attribute{}, fmt.Errorf("parsing failed, malformed CASE expression")
+			}
+			result, err := expressionAttribute(d.Decl[len(d.Decl)-1], tables)
+			if err != nil {
+				return Attribute{}, err
+			}
+			if d.Token == parser.WhenToken {
+				cond, err := expressionAttribute(d.Decl[0], tables)
+				if err != nil {
+					return Attribute{}, err
+				}
+				if k := typeKind(cond.typeName); k != booleanType && k != unknownType {
+					return Attribute{}, fmt.Errorf("argument of CASE/WHEN must be type boolean, not type %s", cond.typeName)
+				}
+			}
+			if i == 0 {
+				attr = result
+			}
+		}
+		attr.name = "case"
+		attr.isNullable = true
+		return attr, nil
+	case parser.EqualityToken, parser.LeftDipleToken, parser.RightDipleToken, parser.LessOrEqualToken, parser.GreaterOrEqualToken,
+		parser.AndToken, parser.OrToken, parser.NotToken, parser.IsToken, parser.InToken:
+		for _, d := range decl.Decl {
+			if _, err := expressionAttribute(d, tables); err != nil {
+				return Attribute{}, err
+			}
+		}
+		return NewAttribute("?column?", "boolean", false), nil
+	}
+
+	return Attribute{}, fmt.Errorf("unsupported expression near \"%s\"", decl.Lexeme)
+}
+
+// functionAttribute checks the arguments of the scalar function called by
+// decl, and returns the attribute describing its values
+func functionAttribute(decl *parser.Decl, tables []*Table) (Attribute, error) {
+	var args []Attribute
+	for _, d := range decl.Decl {
+		attr, err := expressionAttribute(d, tables)
+		if err != nil {
+			return Attribute{}, err
+		}
+		args = append(args, attr)
+	}
+
+	var attr Attribute
+	switch decl.Lexeme {
+	case "coalesce":
+		if len(args) == 0 {
+			return Attribute{}, fmt.Errorf("function coalesce() requires at least one argument")
+		}
+		attr = args[0]
+	case "nullif":
+		if len(args) != 2 {
+			return Attribute{}, fmt.Errorf("function nullif() requires 2 arguments")
+		}
+		attr = args[0]
+	case "lower", "upper", "trim":
+		if len(args) != 1 {
+			return Attribute{}, fmt.Errorf("function %s() requires 1 argument", decl.Lexeme)
+		}
+		attr = NewAttribute("", "text", false)
+	case "length":
+		if len(args) != 1 {
+			return Attribute{}, fmt.Errorf("function length() requires 1 argument")
+		}
+		attr = NewAttribute("", "bigint", false)
+	case "abs":
+		if len(args) != 1 {
+			return Attribute{}, fmt.Errorf("function abs() requires 1 argument")
+		}
+		attr = NewAttribute("", arithmeticType(args[0].typeName, args[0].typeName), false)
+	default:
+		return Attribute{}, fmt.Errorf("function %s() does not exist", decl.Lexeme)
+	}
+
+	attr.name = decl.Lexeme
+	attr.selectAs = ""
+	attr.isNullable = true
+	return attr, nil
+}
+
+// columnAttribute returns the attribute of the table column referenced by
+// decl, qualified by its table name or not
+func columnAttribute(decl *parser.Decl, tables []*Table) (Attribute, error) {
+	var found []Attribute
+	matched := false
+	for _, t := range tables {
+		if len(decl.Decl) > 0 && t.name != decl.Decl[0].Lexeme {
+			continue
+		}
+		matched = true
+		if i := t.attributeIndex(decl.Lexeme); i >= 0 {
+			found = append(found, t.attributes[i])
+		}
+	}
+
+	if !matched {
+		return Attribute{}, fmt.Errorf("missing FROM-clause entry for table \"%s\"", decl.Decl[0].Lexeme)
+	}
+	if len(found) == 0 {
+		return Attribute{}, fmt.Errorf("column \"%s\" does not exist", decl.Lexeme)
+	}
+	if len(found) > 1 {
+		return Attribute{}, fmt.Errorf("column reference \"%s\" is ambiguous", decl.Lexeme)
+	}
+
+	return found[0], nil
+}
+
+// arithmeticType returns the type of the result of an arithmetic operation
+// between values of given types
+func arithmeticType(left string, right string) string {
+	if typeKind(left) == floatType || typeKind(right) == floatType {
+		return "float"
+	}
+
+	return "bigint"
+}
+
+// evaluate computes expression decl from a row, whose columns are named
+// after their table
+func evaluate(decl *parser.Decl, row virtualRow) (interface{}, error) {
+	switch decl.Token {
+	case parser.ColumnToken:
+		return row.column(decl)
+	case parser.PlusToken, parser.MinusToken, parser.StarToken, parser.SlashToken, parser.PercentToken, parser.ConcatToken:
+		var operands []interface{}
+		for _, d := range decl.Decl {
+			v, err := evaluate(d, row)
+			if err != nil {
+				return nil, err
+			}
+			// NULL operands give NULL
+			if v == nil {
+				return nil, nil
+			}
+			operands = append(operands, v)
+		}
+		if decl.Token == parser.ConcatToken {
+			return format(operands[0]) + format(operands[1]), nil
+		}
+		// Sign of a single operand
+		if len(operands) == 1 {
+			operands = []interface{}{int64(0), operands[0]}
+		}
+		return arithmetic(decl, operands[0], operands[1])
+	case parser.NumberToken:
+		if v, err := toInteger(decl.Lexeme); err == nil {
+			return v, nil
+		}
+		return toFloat(decl.Lexeme)
+	case parser.FunctionToken:
+		return function(decl, row)
+	case parser.CaseToken:
+		for _, d := range decl.Decl {
+			if d.Token == parser.ElseToken {
+				return evaluate(d.Decl[0], row)
+			}
+			ok, err := evaluateCondition(d.Decl[0], row)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return evaluate(d.Decl[1], row)
+			}
+		}
+		return nil, nil
+	case parser.EqualityToken, parser.LeftDipleToken, parser.RightDipleToken, parser.LessOrEqualToken, parser.GreaterOrEqualToken,
+		parser.AndToken, parser.OrToken, parser.NotToken, parser.IsToken, parser.InToken:
+		return evaluateTruth(decl, row)
+	case parser.SelectToken:
+		return nil, fmt.Errorf("subqueries are not supported here")
+	}
+
+	return declValue(decl), nil
+}
+
+// evaluateCondition tells whether condition decl holds for a row. An
+// unknown condition, i.e comparing NULL, does not hold.
+func evaluateCondition(decl *parser.Decl, row virtualRow) (bool, error) {
+	v, err := evaluateTruth(decl, row)
+	if err != nil || v == nil {
+		return false, err
+	}
+
+	return v.(bool), nil
+}
+
+// evaluateTruth computes condition decl for a row following three-valued
+// logic: it gives true, false, or nil if unknown, i.e comparing NULL
+func evaluateTruth(decl *parser.Decl, row virtualRow) (interface{}, error) {
+	switch decl.Token {
+	case parser.AndToken, parser.OrToken:
+		// FALSE AND x is FALSE, TRUE OR x is TRUE, whatever x
+		decisive := decl.Token == parser.OrToken
+		var result interface{} = !decisive
+		for _, d := range decl.Decl {
+			v, err := evaluateTruth(d, row)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				result = nil
+				continue
+			}
+			if v.(bool) == decisive {
+				return decisive, nil
+			}
+		}
+		return result, nil
+	case parser.NotToken:
+		v, err := evaluateTruth(decl.Decl[0], row)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return !v.(bool), nil
+	case parser.IsToken:
+		v, err := evaluate(decl.Decl[0], row)
+		return v == nil, err
+	case parser.EqualityToken, parser.LeftDipleToken, parser.RightDipleToken, parser.LessOrEqualToken, parser.GreaterOrEqualToken:
+		left, err := evaluate(decl.Decl[0], row)
+		if err != nil {
+			return nil, err
+		}
+		right, err := evaluate(decl.Decl[1], row)
+		if err != nil {
+			return nil, err
+		}
+		if left == nil || right == nil {
+			return nil, nil
+		}
+		op, err := NewOperator(decl.Token, decl.Lexeme)
+		if err != nil {
+			return nil, err
+		}
+		return op(Value{v: left}, Value{v: right, lexeme: format(right)}), nil
+	case parser.InToken:
+		// x IN (a, b) is x = a OR x = b
+		left, err := evaluate(decl.Decl[0], row)
+		if err != nil || left == nil {
+			return nil, err
+		}
+		var result interface{} = false
+		for _, d := range decl.Decl[1:] {
+			right, err := evaluate(d, row)
+			if err != nil {
+				return nil, err
+			}
+			if right == nil {
+				result = nil
+				continue
+			}
+			if equalityOperator(Value{v: left}, Value{v: right, lexeme: format(right)}) {
+				return true, nil
+			}
+		}
+		return result, nil
+	}
+
+	v, err := evaluate(decl, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	b, err := toBoolean(v)
+	if err != nil {
+		return nil, fmt.Errorf("argument of CASE/WHEN must be type boolean, not \"%s\"", format(v))
+	}
+	return b, nil
+}
+
+// function calls the scalar function of decl with the values of its
+// arguments computed from a row
+func function(decl *parser.Decl, row virtualRow) (interface{}, error) {
+	args := make([]interface{}, len(decl.Decl))
+	for i, d := range decl.Decl {
+		v, err := evaluate(d, row)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch decl.Lexeme {
+	case "coalesce":
+		for _, v := range args {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	case "nullif":
+		if args[0] != nil && equal(args[0], args[1]) {
+			return nil, nil
+		}
+		return args[0], nil
+	}
+
+	// Other functions give NULL from NULL
+	if args[0] == nil {
+		return nil, nil
+	}
+	switch decl.Lexeme {
+	case "lower":
+		return strings.ToLower(format(args[0])), nil
+	case "upper":
+		return strings.ToUpper(format(args[0])), nil
+	case "trim":
+		return strings.TrimSpace(format(args[0])), nil
+	case "length":
+		return int64(len([]rune(format(args[0])))), nil
+	case "abs":
+		if v, err := toInteger(args[0]); err == nil {
+			if v.(int64) < 0 {
+				return -v.(int64), nil
+			}
+			return v, nil
+		}
+		v, err := toFloat(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("function abs(%s) does not exist", format(args[0]))
+		}
+		return math.Abs(v.(float64)), nil
+	}
+
+	return nil, fmt.Errorf("function %s() does not exist", decl.Lexeme)
+}
+
+// evaluateSubqueries replaces the scalar subqueries found in decl by the
+// value they select, as parameters are bound. A subquery selecting no row
+// gives NULL. Subqueries being evaluated once, they cannot reference
+// columns of the outer tables.
+func evaluateSubqueries(e *Engine, tx *Transaction, decl *parser.Decl, outer []string) error {
+	for _, d := range decl.Decl {
+		if d.Token != parser.SelectToken {
+			if err := evaluateSubqueries(e, tx, d, outer); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if column := outerReference(d, selectedTables(d), outer); column != "" {
+			return fmt.Errorf("correlated subqueries are not supported, column \"%s\" references the outer query", column)
+		}
+
+		result := &queryResult{}
+		if err := selectExecutor(e, tx, d, result); err != nil {
+			return err
+		}
+		if len(result.columns) != 1 {
+			return fmt.Errorf("subquery must return only one column")
+		}
+		if len(result.rows) > 1 {
+			return fmt.Errorf("more than one row returned by a subquery used as an expression")
+		}
+		var v interface{}
+		if len(result.rows) == 1 {
+			v = result.rows[0][0]
+		}
+		*d = *argumentDecl(v)
+	}
+
+	return nil
+}
+
+// outerReference returns the first column found in decl qualified by one
+// of the outer tables rather than by one of the tables selected, empty if
+// there is none
+func outerReference(decl *parser.Decl, tables []string, outer []string) string {
+	switch decl.Token {
+	case parser.FromToken, parser.JoinToken:
+		return ""
+	case parser.StringToken, parser.ColumnToken:
+		if len(decl.Decl) > 0 && decl.Decl[0].Token == parser.StringToken {
+			table := decl.Decl[0].Lexeme
+			if !hasName(tables, table) && hasName(outer, table) {
+				return table + "." + decl.Lexeme
+			}
+		}
+	}
+
+	for _, d := range decl.Decl {
+		if column := outerReference(d, tables, outer); column != "" {
+			return column
+		}
+	}
+
+	return ""
+}
+
+// hasName returns true if name is one of names
+func hasName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// column returns the value of the column referenced by decl, qualified by
+// its table name or not
+func (row virtualRow) column(decl *parser.Decl) (interface{}, error) {
+	if len(decl.Decl) > 0 {
+		v, ok := row[decl.Decl[0].Lexeme+"."+decl.Lexeme]
+		if !ok {
+			return nil, fmt.Errorf("column %s.%s does not exist", decl.Decl[0].Lexeme, decl.Lexeme)
+		}
+		return v.v, nil
+	}
+
+	var found []Value
+	for name, v := range row {
+		if strings.HasSuffix(name, "."+decl.Lexeme) {
+			found = append(found, v)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("column \"%s\" does not exist", decl.Lexeme)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("column reference \"%s\" is ambiguous", decl.Lexeme)
+	}
+
+	return found[0].v, nil
+}
+
+// arithmetic applies operator decl to given values. Integers give an
+// integer, unless one of them is a float.
+func arithmetic(decl *parser.Decl, left interface{}, right interface{}) (interface{}, error) {
+	l, lerr := toInteger(left)
+	r, rerr := toInteger(right)
+	if _, ok := left.(bool); ok {
+		lerr = errInvalidInput
+	}
+	if _, ok := right.(bool); ok {
+		rerr = errInvalidInput
+	}
+
+	if lerr == nil && rerr == nil {
+		a, b := l.(int64), r.(int64)
+		errOverflow := fmt.Errorf("bigint out of range")
+		switch decl.Token {
+		case parser.PlusToken:
+			if b > 0 && a > math.MaxInt64-b || b < 0 && a < math.MinInt64-b {
+				return nil, errOverflow
+			}
+			return a + b, nil
+		case parser.MinusToken:
+			if b < 0 && a > math.MaxInt64+b || b > 0 && a < math.MinInt64+b {
+				return nil, errOverflow
+			}
+			return a - b, nil
+		case parser.StarToken:
+			c := a * b
+			if a != 0 && (c/a != b || a == -1 && b == math.MinInt64) {
+				return nil, errOverflow
+			}
+			return c, nil
+		}
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if decl.Token == parser.SlashToken {
+			if a == math.MinInt64 && b == -1 {
+				return nil, errOverflow
+			}
+			return a / b, nil
+		}
+		return a % b, nil
+	}
+
+	lf, lerr := toFloat(left)
+	rf, rerr := toFloat(right)
+	if lerr != nil || rerr != nil {
+		return nil, fmt.Errorf("operator does not exist: %s %s %s", format(left), decl.Lexeme, format(right))
+	}
+
+	a, b := lf.(float64), rf.(float64)
+	switch decl.Token {
+	case parser.PlusToken:
+		return a + b, nil
+	case parser.MinusToken:
+		return a - b, nil
+	case parser.StarToken:
+		return a * b, nil
+	}
+	if b == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	if decl.Token == parser.SlashToken {
+		return a / b, nil
+	}
+	return math.Mod(a, b), nil
+}
@@ -0,0 +1,351 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// Referential actions run when a referenced row is deleted or its key updated
+const (
+	noAction = iota
+	restrict
+	cascade
+	setNull
+	setDefault
+)
+
+// foreignKey links attributes of a table to a unique key of a
+// referenced table
+type foreignKey struct {
+	name       string
+	attributes []int
+	table      string
+	references []int
+	onDelete   int
+	onUpdate   int
+}
+
+// addForeignKey adds a foreign key on given attributes of table t,
+// referencing the table described by referencesDecl:
+//
+//	|-> references
+//	    |-> <TABLE-NAME>
+//	        |-> <KEY-PART>
+//	    |-> on
+//	        |-> delete
+//	            |-> <REFERENCE-OPTION>
+//
+// Default name follows Postgres: <table>_<column>_fkey, out of the schema
+// of the table.
+func (t *Table) addForeignKey(e *Engine, name string, columns []string, referencesDecl *parser.Decl) error {
+	if len(referencesDecl.Decl) == 0 {
+		return fmt.Errorf("malformed foreign key on table %s", t.name)
+	}
+
+	if name == "" {
+		_, table := splitName(t.name)
+		name = table + "_" + strings.Join(columns, "_") + "_fkey"
+	}
+
+	fk := &foreignKey{name: name, table: referencesDecl.Decl[0].Lexeme}
+	for _, c := range columns {
+		i := t.attributeIndex(c)
+		if i < 0 {
+			return fmt.Errorf("column \"%s\" referenced in foreign key constraint does not exist", c)
+		}
+		fk.attributes = append(fk.attributes, i)
+	}
+
+	// Table may reference itself
+	parent := t
+	if fk.table != t.name {
+		r := e.relation(fk.table)
+		if r == nil {
+			return fmt.Errorf("relation \"%s\" does not exist", fk.table)
+		}
+		parent = r.table
+	}
+
+	// Referenced columns default to primary key
+	var key *uniqueKey
+	if len(referencesDecl.Decl[0].Decl) == 0 {
+		if parent.primaryKey == nil {
+			return fmt.Errorf("there is no primary key for referenced table \"%s\"", parent.name)
+		}
+		key = parent.primaryKey
+		fk.references = key.attributes
+	} else {
+		for _, d := range referencesDecl.Decl[0].Decl {
+			i := parent.attributeIndex(d.Lexeme)
+			if i < 0 {
+				return fmt.Errorf("column \"%s\" referenced in foreign key constraint does not exist", d.Lexeme)
+			}
+			fk.references = append(fk.references, i)
+		}
+		key = parent.uniqueKey(fk.references)
+	}
+	if key == nil {
+		return fmt.Errorf("there is no unique constraint matching given keys for referenced table \"%s\"", parent.name)
+	}
+	if len(fk.references) != len(fk.attributes) {
+		return fmt.Errorf("number of referencing and referenced columns for foreign key disagree")
+	}
+	if err := fk.checkTypes(t, parent); err != nil {
+		return err
+	}
+
+	for _, d := range referencesDecl.Decl[1:] {
+		if d.Token != parser.OnToken || len(d.Decl) != 1 || len(d.Decl[0].Decl) != 1 {
+			continue
+		}
+		switch d.Decl[0].Token {
+		case parser.DeleteToken:
+			fk.onDelete = referentialAction(d.Decl[0].Decl[0])
+		case parser.UpdateToken:
+			fk.onUpdate = referentialAction(d.Decl[0].Decl[0])
+		}
+	}
+
+	t.foreignKeys = append(t.foreignKeys, fk)
+	return nil
+}
+
+func referentialAction(decl *parser.Decl) int {
+	switch decl.Token {
+	case parser.RestrictToken:
+		return restrict
+	case parser.CascadeToken:
+		return cascade
+	case parser.SetToken:
+		if len(decl.Decl) == 1 && decl.Decl[0].Token == parser.NullToken {
+			return setNull
+		}
+		return setDefault
+	}
+
+	return noAction
+}
+
+// uniqueKey returns the primary key or unique constraint made of exactly
+// given attributes, nil if there is none
+func (t *Table) uniqueKey(attributes []int) *uniqueKey {
+	keys := t.uniqueKeys
+	if t.primaryKey != nil {
+		keys = append([]*uniqueKey{t.primaryKey}, keys...)
+	}
+
+	for _, k := range keys {
+		if len(k.attributes) != len(attributes) {
+			continue
+		}
+		found := 0
+		for _, a := range attributes {
+			for _, b := range k.attributes {
+				if a == b {
+					found++
+					break
+				}
+			}
+		}
+		if found == len(attributes) {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// checkTypes returns an error if columns of foreign key fk of table t cannot
+// be compared with those they reference in table parent
+func (fk *foreignKey) checkTypes(t *Table, parent *Table) error {
+	for i, a := range fk.attributes {
+		attr, ref := t.attributes[a], parent.attributes[fk.references[i]]
+		kind := typeKind(attr.typeName)
+		if kind != typeKind(ref.typeName) || kind == unknownType && typeString(attr.typeName) != typeString(ref.typeName) {
+			return fmt.Errorf("foreign key constraint \"%s\" cannot be implemented: key columns \"%s\" and \"%s\" are of incompatible types: %s and %s",
+				fk.name, attr.name, ref.name, typeString(attr.typeName), typeString(ref.typeName))
+		}
+	}
+
+	return nil
+}
+
+// referencing returns the relations holding a foreign key to relation r
+func (e *Engine) referencing(r *Relation) map[*Relation][]*foreignKey {
+	refs := make(map[*Relation][]*foreignKey)
+	for _, c := range e.relations {
+		for _, fk := range c.table.foreignKeys {
+			if fk.table == r.table.name {
+				refs[c] = append(refs[c], fk)
+			}
+		}
+	}
+
+	return refs
+}
+
+// checkReference returns an error if row t, replacing row old if any, of
+// relation r references a row which does not exist. Like Postgres MATCH
+// SIMPLE, a key holding a NULL value references nothing.
+func (r *Relation) checkReference(tx *Transaction, fk *foreignKey, t *Tuple, old *Tuple) error {
+	values := make([]interface{}, len(fk.attributes))
+	changed := old == nil
+	for i, a := range fk.attributes {
+		if t.Values[a] == nil {
+			return nil
+		}
+		values[i] = t.Values[a]
+		if old != nil && !equal(old.Values[a], t.Values[a]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	parent := r
+	if fk.table != r.table.name {
+		parent = tx.e.relation(fk.table)
+	}
+	if parent == nil {
+		return fmt.Errorf("relation \"%s\" does not exist", fk.table)
+	}
+
+	// A row may reference itself
+	if parent == r && matches(t, fk.references, values) {
+		return nil
+	}
+
+	rows := parent.rows
+	if positions, ok := parent.search(fk.references, values); ok {
+		rows = parent.heads(positions)
+	}
+
+	// The referenced row must not be deleted or updated by a concurrent
+	// transaction
+	for _, row := range rows {
+		if row = tx.visible(row); row != nil && matches(row, fk.references, values) {
+			return tx.lock(parent, row)
+		}
+	}
+
+	return fmt.Errorf("insert or update on table \"%s\" violates foreign key constraint \"%s\"", r.table.name, fk.name)
+}
+
+// matches returns true if given attributes of row t hold values
+func matches(t *Tuple, attributes []int, values []interface{}) bool {
+	for i, a := range attributes {
+		if !equal(t.Values[a], values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// referentialActions runs foreign key actions of rows referencing row old
+// of relation r, deleted or replaced by row new. Statements being
+// serialized, referencing relations are not locked.
+func (tx *Transaction) referentialActions(r *Relation, old *Tuple, new *Tuple) error {
+	for c, fks := range tx.e.referencing(r) {
+		for _, fk := range fks {
+			values := make([]interface{}, len(fk.references))
+			changed := new == nil
+			for i, a := range fk.references {
+				values[i] = old.Values[a]
+				if new != nil && !equal(old.Values[a], new.Values[a]) {
+					changed = true
+				}
+			}
+			if !changed {
+				continue
+			}
+
+			action := fk.onDelete
+			if new != nil {
+				action = fk.onUpdate
+			}
+
+			rows := c.rows
+			if positions, ok := c.search(fk.attributes, values); ok {
+				rows = c.heads(positions)
+			}
+
+			for _, row := range rows {
+				// Referencing rows written by a concurrent transaction
+				// would be left without the row they reference
+				if !tx.sees(row.createdBy) && row.deletedBy == nil && matches(row, fk.attributes, values) {
+					return tx.conflict(c, row.createdBy)
+				}
+				if row = tx.visible(row); row == nil || !matches(row, fk.attributes, values) {
+					continue
+				}
+
+				switch action {
+				case noAction, restrict:
+					return fmt.Errorf("update or delete on table \"%s\" violates foreign key constraint \"%s\" on table \"%s\"", r.table.name, fk.name, c.table.name)
+				case cascade:
+					if new == nil {
+						if err := tx.delete(c, row); err != nil {
+							return err
+						}
+						continue
+					}
+				}
+
+				updated := make([]interface{}, len(row.Values))
+				copy(updated, row.Values)
+				for j, a := range fk.attributes {
+					switch action {
+					case cascade:
+						updated[a] = new.Values[fk.references[j]]
+					case setNull:
+						updated[a] = nil
+					case setDefault:
+						v, err := c.table.attributes[a].computeDefault(tx)
+						if err != nil {
+							return err
+						}
+						updated[a] = v
+					}
+				}
+				n, err := tx.update(c, -1, row, updated)
+				if err != nil {
+					return err
+				}
+				// Default value may be the deleted key itself
+				if action == setDefault {
+					if err := c.checkReference(tx, fk, n, nil); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// dropReferences removes foreign keys referencing relation r, restored
+// if tx rolls back
+func (tx *Transaction) dropReferences(r *Relation) {
+	for c := range tx.e.referencing(r) {
+		if c == r {
+			continue
+		}
+		t := c.table
+		fks := t.foreignKeys
+		var left []*foreignKey
+		for _, fk := range fks {
+			if fk.table != r.table.name {
+				left = append(left, fk)
+			}
+		}
+		t.foreignKeys = left
+		tx.onRollback(func() {
+			t.foreignKeys = fks
+		})
+	}
+}
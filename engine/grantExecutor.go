@@ -5,6 +5,6 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func grantExecutor(e *Engine, decl *parser.Decl, conn protocol.EngineConn) error {
+func grantExecutor(e *Engine, tx *Transaction, decl *parser.Decl, conn protocol.EngineConn) error {
 	return conn.WriteResult(0, 0)
 }
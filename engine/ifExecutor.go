@@ -7,14 +7,14 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func ifExecutor(e *Engine, ifDecl *parser.Decl, conn protocol.EngineConn) error {
+func ifExecutor(e *Engine, tx *Transaction, ifDecl *parser.Decl, conn protocol.EngineConn) error {
 
 	if len(ifDecl.Decl) == 0 {
 		return fmt.Errorf("malformed condition")
 	}
 
 	if e.opsExecutors[ifDecl.Decl[0].Token] != nil {
-		return e.opsExecutors[ifDecl.Decl[0].Token](e, ifDecl.Decl[0], conn)
+		return e.opsExecutors[ifDecl.Decl[0].Token](e, tx, ifDecl.Decl[0], conn)
 	}
 
 	return fmt.Errorf("error near %v, unknown keyword", ifDecl.Decl[0].Lexeme)
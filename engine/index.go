@@ -0,0 +1,755 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// Index methods
+const (
+	btreeIndex = iota
+	hashIndex
+)
+
+// index gives access to the row versions of a relation by the values of
+// some of their attributes, its key. Every version of a row is indexed,
+// visibility being checked once found. Versions holding a NULL in their
+// key are not indexed, since no predicate run through an index matches
+// NULL.
+type index struct {
+	name       string
+	attributes []int
+	method     int
+	// unique is true for indexes created with CREATE UNIQUE INDEX,
+	// enforced by a unique constraint of the same name
+	unique bool
+	// constraint is true for indexes backing a PRIMARY KEY or UNIQUE
+	// constraint, they are dropped with it
+	constraint bool
+	storage    indexStorage
+}
+
+// indexStorage holds index entries
+type indexStorage interface {
+	insert(key []interface{}, t *Tuple)
+	remove(key []interface{}, t *Tuple)
+	// get returns versions whose key starts with given values
+	get(values []interface{}) []*Tuple
+	clear()
+}
+
+func newIndex(name string, attributes []int, method int) *index {
+	i := &index{
+		name:       name,
+		attributes: attributes,
+		method:     method,
+	}
+
+	switch method {
+	case hashIndex:
+		i.storage = make(hashStorage)
+	default:
+		i.storage = &btreeStorage{}
+	}
+
+	return i
+}
+
+// key returns the key of row version t, false if it holds a NULL value
+func (i *index) key(t *Tuple) ([]interface{}, bool) {
+	key := make([]interface{}, len(i.attributes))
+	for j, a := range i.attributes {
+		if a >= len(t.Values) || t.Values[a] == nil {
+			return nil, false
+		}
+		key[j] = t.Values[a]
+	}
+
+	return key, true
+}
+
+func (i *index) insert(t *Tuple) {
+	if key, ok := i.key(t); ok {
+		i.storage.insert(key, t)
+	}
+}
+
+func (i *index) remove(t *Tuple) {
+	if key, ok := i.key(t); ok {
+		i.storage.remove(key, t)
+	}
+}
+
+// get returns versions whose key starts with given values. Hash indexes
+// can only be searched with a complete key.
+func (i *index) get(values []interface{}) ([]*Tuple, bool) {
+	if len(values) == 0 || len(values) > len(i.attributes) {
+		return nil, false
+	}
+	if i.method == hashIndex && len(values) != len(i.attributes) {
+		return nil, false
+	}
+
+	return i.storage.get(values), true
+}
+
+// between returns versions whose first key attribute is between given
+// bounds. Only ordered indexes can be searched by range.
+func (i *index) between(min *bound, max *bound) ([]*Tuple, bool) {
+	s, ok := i.storage.(*btreeStorage)
+	if !ok {
+		return nil, false
+	}
+
+	return s.between(min, max), true
+}
+
+// index adds row version t to indexes of relation r
+func (r *Relation) index(t *Tuple) {
+	for _, i := range r.indexes {
+		i.insert(t)
+	}
+}
+
+// unindex removes row version t from indexes of relation r
+func (r *Relation) unindex(t *Tuple) {
+	for _, i := range r.indexes {
+		i.remove(t)
+	}
+}
+
+// build fills index i with every row version of relation r
+func (r *Relation) build(i *index) {
+	i.storage.clear()
+	for _, row := range r.rows {
+		for v := row; v != nil; v = v.previous {
+			i.insert(v)
+		}
+	}
+}
+
+// reindex rebuilds indexes of relation r, i.e once row values were
+// rewritten by ALTER TABLE
+func (r *Relation) reindex() {
+	for _, i := range r.indexes {
+		r.build(i)
+	}
+}
+
+// removeIndex removes index i from relation r
+func (r *Relation) removeIndex(i *index) {
+	var indexes []*index
+	for _, j := range r.indexes {
+		if j != i {
+			indexes = append(indexes, j)
+		}
+	}
+	r.indexes = indexes
+}
+
+// indexNamed returns the index of relation r with given name, nil if
+// there is none
+func (r *Relation) indexNamed(name string) *index {
+	for _, i := range r.indexes {
+		if i.name == name {
+			return i
+		}
+	}
+
+	return nil
+}
+
+// syncKeyIndexes creates the indexes backing primary key and unique
+// constraints of relation r, and drops those of dropped constraints.
+// A unique index created by CREATE UNIQUE INDEX backs its own constraint.
+func (r *Relation) syncKeyIndexes() {
+	t := r.table
+	keys := t.uniqueKeys
+	if t.primaryKey != nil {
+		keys = append([]*uniqueKey{t.primaryKey}, keys...)
+	}
+
+	var indexes []*index
+	for _, i := range r.indexes {
+		if !i.constraint {
+			indexes = append(indexes, i)
+		}
+	}
+
+	for _, k := range keys {
+		i := r.indexNamed(k.name)
+		if i != nil && !i.constraint {
+			continue
+		}
+		if i == nil || !reflect.DeepEqual(i.attributes, k.attributes) {
+			i = newIndex(k.name, k.attributes, btreeIndex)
+			i.constraint = true
+			r.build(i)
+		}
+		indexes = append(indexes, i)
+	}
+
+	r.indexes = indexes
+}
+
+// findIndex returns the index with given name and its relation
+func (e *Engine) findIndex(name string) (*Relation, *index) {
+	for _, r := range e.relations {
+		if i := r.indexNamed(name); i != nil {
+			return r, i
+		}
+	}
+
+	return nil, nil
+}
+
+// indexValue converts v to the type of attribute a, as found in indexes.
+// It returns false if rows equal to v may hold values converting to
+// another one, i.e 1 and '1.0' for an integer, which indexes cannot find.
+func (r *Relation) indexValue(a int, v interface{}) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	c, err := coerce(r.table.attributes[a], v)
+	if err != nil || c == nil {
+		return nil, false
+	}
+	if _, ok := c.([]byte); ok {
+		return nil, false
+	}
+	if _, ok := v.(string); !ok && reflect.TypeOf(c) != reflect.TypeOf(v) {
+		return nil, false
+	}
+
+	return c, true
+}
+
+// match returns row versions of relation r holding given values, by
+// attribute, using the index whose key starts with most of them. It
+// returns false if there is none.
+func (r *Relation) match(values map[int]interface{}) ([]*Tuple, bool) {
+	var best *index
+	var key []interface{}
+	for _, i := range r.indexes {
+		var prefix []interface{}
+		for _, a := range i.attributes {
+			v, ok := values[a]
+			if !ok {
+				break
+			}
+			prefix = append(prefix, v)
+		}
+		if len(prefix) == 0 || len(prefix) <= len(key) {
+			continue
+		}
+		if i.method == hashIndex && len(prefix) != len(i.attributes) {
+			continue
+		}
+		best, key = i, prefix
+	}
+
+	if best == nil {
+		return nil, false
+	}
+	return best.get(key)
+}
+
+// positions returns the sorted positions in r.rows of rows holding given
+// versions
+func (r *Relation) positions(versions []*Tuple) []int {
+	var positions []int
+	seen := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		if seen[v.seq] {
+			continue
+		}
+		seen[v.seq] = true
+		if i := r.position(v.seq); i >= 0 {
+			positions = append(positions, i)
+		}
+	}
+
+	sort.Ints(positions)
+	return positions
+}
+
+// heads returns the rows of relation r at given positions
+func (r *Relation) heads(positions []int) []*Tuple {
+	rows := make([]*Tuple, len(positions))
+	for j, i := range positions {
+		rows[j] = r.rows[i]
+	}
+
+	return rows
+}
+
+// search returns the positions in r.rows of rows which may hold given
+// values in given attributes, found with an index. It returns false if
+// no index applies.
+func (r *Relation) search(attributes []int, values []interface{}) ([]int, bool) {
+	equalities := make(map[int]interface{}, len(attributes))
+	for j, a := range attributes {
+		v, ok := r.indexValue(a, values[j])
+		if !ok {
+			return nil, false
+		}
+		equalities[a] = v
+	}
+
+	versions, ok := r.match(equalities)
+	if !ok {
+		return nil, false
+	}
+	return r.positions(versions), true
+}
+
+// lookup returns the positions in r.rows of rows which may satisfy every
+// given predicate, found with an index: equalities first, then IN lists,
+// then ranges. It returns false if no index applies, rows must then be
+// scanned. Predicates must still be evaluated on rows found.
+func (r *Relation) lookup(predicates []*Predicate) ([]int, bool) {
+	equalities := make(map[int]interface{})
+	var lists []*Predicate
+	var ranges []*Predicate
+	for _, p := range predicates {
+		if p.True || p.LeftValue.table != r.table.name {
+			continue
+		}
+		a := r.table.attributeIndex(p.LeftValue.lexeme)
+		if a < 0 {
+			continue
+		}
+
+		switch p.kind {
+		case parser.EqualityToken:
+			value := p.RightValue.v
+			if value == nil {
+				value = p.RightValue.lexeme
+			}
+			if v, ok := r.indexValue(a, value); ok {
+				equalities[a] = v
+			}
+		case parser.InToken:
+			lists = append(lists, p)
+		case parser.LeftDipleToken, parser.RightDipleToken, parser.LessOrEqualToken, parser.GreaterOrEqualToken:
+			ranges = append(ranges, p)
+		}
+	}
+
+	if versions, ok := r.match(equalities); ok {
+		return r.positions(versions), true
+	}
+
+	for _, p := range lists {
+		if versions, ok := r.matchList(p); ok {
+			return r.positions(versions), true
+		}
+	}
+
+	for _, p := range ranges {
+		if versions, ok := r.matchRange(p, ranges); ok {
+			return r.positions(versions), true
+		}
+	}
+
+	return nil, false
+}
+
+// matchList returns row versions of relation r holding one of the values
+// of IN predicate p
+func (r *Relation) matchList(p *Predicate) ([]*Tuple, bool) {
+	a := r.table.attributeIndex(p.LeftValue.lexeme)
+	values, ok := p.RightValue.v.([]string)
+	if !ok {
+		return nil, false
+	}
+
+	var versions []*Tuple
+	for _, lexeme := range values {
+		v, ok := r.indexValue(a, lexeme)
+		if !ok {
+			return nil, false
+		}
+		found, ok := r.match(map[int]interface{}{a: v})
+		if !ok {
+			return nil, false
+		}
+		versions = append(versions, found...)
+	}
+
+	return versions, true
+}
+
+// matchRange returns row versions of relation r within the bounds set on
+// the attribute of range predicate p by all given range predicates.
+// Operators compare numbers and dates only, so do ordered indexes here.
+func (r *Relation) matchRange(p *Predicate, ranges []*Predicate) ([]*Tuple, bool) {
+	a := r.table.attributeIndex(p.LeftValue.lexeme)
+
+	var i *index
+	for _, j := range r.indexes {
+		if j.method == btreeIndex && j.attributes[0] == a {
+			i = j
+			break
+		}
+	}
+	if i == nil {
+		return nil, false
+	}
+
+	kind := typeKind(r.table.attributes[a].typeName)
+	if kind != integerType && kind != floatType && kind != timestampType && kind != dateType {
+		return nil, false
+	}
+
+	var min, max *bound
+	for _, q := range ranges {
+		if q.LeftValue.lexeme != p.LeftValue.lexeme {
+			continue
+		}
+
+		var v interface{}
+		if kind == integerType || kind == floatType {
+			f, err := convToFloat(q.RightValue.lexeme)
+			if err != nil {
+				continue
+			}
+			v = f
+		} else {
+			d, err := convToDate(q.RightValue.lexeme)
+			if err != nil {
+				continue
+			}
+			v = *d
+		}
+
+		b := &bound{value: v, inclusive: q.kind == parser.GreaterOrEqualToken || q.kind == parser.LessOrEqualToken}
+		switch q.kind {
+		case parser.RightDipleToken, parser.GreaterOrEqualToken:
+			if b.tighter(min, 1) {
+				min = b
+			}
+		case parser.LeftDipleToken, parser.LessOrEqualToken:
+			if b.tighter(max, -1) {
+				max = b
+			}
+		}
+	}
+	if min == nil && max == nil {
+		return nil, false
+	}
+
+	return i.between(min, max)
+}
+
+// scan calls f on each row of relation r which may satisfy every given
+// predicate, with its position, using an index if possible
+func (r *Relation) scan(predicates []*Predicate, f func(i int, t *Tuple) error) error {
+	positions, ok := r.lookup(predicates)
+	if !ok {
+		for i, t := range r.rows {
+			if err := f(i, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, i := range positions {
+		if err := f(i, r.rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashStorage maps keys to the set of versions holding them
+type hashStorage map[string]map[*Tuple]struct{}
+
+func hashKey(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		switch t := v.(type) {
+		case time.Time:
+			v = t.UnixNano()
+		case []byte:
+			v = string(t)
+		}
+		fmt.Fprintf(&b, "%T:%v\x00", v, v)
+	}
+
+	return b.String()
+}
+
+func (s hashStorage) insert(key []interface{}, t *Tuple) {
+	k := hashKey(key)
+	if s[k] == nil {
+		s[k] = make(map[*Tuple]struct{})
+	}
+	s[k][t] = struct{}{}
+}
+
+func (s hashStorage) remove(key []interface{}, t *Tuple) {
+	k := hashKey(key)
+	delete(s[k], t)
+	if len(s[k]) == 0 {
+		delete(s, k)
+	}
+}
+
+func (s hashStorage) get(values []interface{}) []*Tuple {
+	var rows []*Tuple
+	for t := range s[hashKey(values)] {
+		rows = append(rows, t)
+	}
+
+	return rows
+}
+
+func (s hashStorage) clear() {
+	for k := range s {
+		delete(s, k)
+	}
+}
+
+// btreeStorage keeps entries ordered by key in pages of bounded size, so
+// insertion and removal only move entries of a single page, and ranges
+// are found by binary search.
+type btreeStorage struct {
+	pages [][]indexEntry
+}
+
+type indexEntry struct {
+	key []interface{}
+	row *Tuple
+}
+
+// btreePageSize is the number of entries above which a page is split
+const btreePageSize = 256
+
+// less orders entries by key, then by row so versions of a row are
+// stored next to each other
+func (e indexEntry) less(other indexEntry) bool {
+	if c := compareKeys(e.key, other.key); c != 0 {
+		return c < 0
+	}
+
+	return e.row.seq < other.row.seq
+}
+
+// search returns the position of the first entry for which f, false then
+// true over ordered entries, is true
+func (s *btreeStorage) search(f func(e indexEntry) bool) (int, int) {
+	p := sort.Search(len(s.pages), func(i int) bool {
+		page := s.pages[i]
+		return f(page[len(page)-1])
+	})
+	if p == len(s.pages) {
+		return p, 0
+	}
+
+	page := s.pages[p]
+	return p, sort.Search(len(page), func(i int) bool {
+		return f(page[i])
+	})
+}
+
+func (s *btreeStorage) insert(key []interface{}, t *Tuple) {
+	e := indexEntry{key: key, row: t}
+	if len(s.pages) == 0 {
+		s.pages = [][]indexEntry{{e}}
+		return
+	}
+
+	p, i := s.search(func(other indexEntry) bool {
+		return e.less(other)
+	})
+	if p == len(s.pages) {
+		p = len(s.pages) - 1
+		i = len(s.pages[p])
+	}
+
+	page := append(s.pages[p], indexEntry{})
+	copy(page[i+1:], page[i:])
+	page[i] = e
+	s.pages[p] = page
+
+	// Split full pages
+	if len(page) > btreePageSize {
+		half := len(page) / 2
+		right := make([]indexEntry, len(page)-half, btreePageSize+1)
+		copy(right, page[half:])
+		s.pages[p] = page[:half:half]
+		s.pages = append(s.pages, nil)
+		copy(s.pages[p+2:], s.pages[p+1:])
+		s.pages[p+1] = right
+	}
+}
+
+func (s *btreeStorage) remove(key []interface{}, t *Tuple) {
+	p, i := s.search(func(other indexEntry) bool {
+		return compareKeys(key, other.key) <= 0
+	})
+
+	for p < len(s.pages) {
+		page := s.pages[p]
+		for ; i < len(page); i++ {
+			if compareKeys(key, page[i].key) != 0 {
+				return
+			}
+			if page[i].row != t {
+				continue
+			}
+
+			copy(page[i:], page[i+1:])
+			page[len(page)-1] = indexEntry{}
+			s.pages[p] = page[:len(page)-1]
+			if len(s.pages[p]) == 0 {
+				s.pages = append(s.pages[:p], s.pages[p+1:]...)
+			}
+			return
+		}
+		p, i = p+1, 0
+	}
+}
+
+// ascend calls f on entries from position p, i until f returns false
+func (s *btreeStorage) ascend(p int, i int, f func(e indexEntry) bool) {
+	for ; p < len(s.pages); p, i = p+1, 0 {
+		for _, e := range s.pages[p][i:] {
+			if !f(e) {
+				return
+			}
+		}
+	}
+}
+
+func (s *btreeStorage) get(values []interface{}) []*Tuple {
+	var rows []*Tuple
+
+	p, i := s.search(func(e indexEntry) bool {
+		return compareKeys(values, e.key[:len(values)]) <= 0
+	})
+	s.ascend(p, i, func(e indexEntry) bool {
+		if compareKeys(values, e.key[:len(values)]) != 0 {
+			return false
+		}
+		rows = append(rows, e.row)
+		return true
+	})
+
+	return rows
+}
+
+// bound is a limit of a range of values
+type bound struct {
+	value     interface{}
+	inclusive bool
+}
+
+// tighter returns true if bound b restricts a range more than bound
+// other, sign being 1 for lower bounds and -1 for upper ones
+func (b *bound) tighter(other *bound, sign int) bool {
+	if other == nil {
+		return true
+	}
+
+	c := compareValues(b.value, other.value) * sign
+	return c > 0 || (c == 0 && !b.inclusive)
+}
+
+func (s *btreeStorage) between(min *bound, max *bound) []*Tuple {
+	var rows []*Tuple
+
+	p, i := 0, 0
+	if min != nil {
+		p, i = s.search(func(e indexEntry) bool {
+			c := compareValues(e.key[0], min.value)
+			return c > 0 || (c == 0 && min.inclusive)
+		})
+	}
+	s.ascend(p, i, func(e indexEntry) bool {
+		if max != nil {
+			c := compareValues(e.key[0], max.value)
+			if c > 0 || (c == 0 && !max.inclusive) {
+				return false
+			}
+		}
+		rows = append(rows, e.row)
+		return true
+	})
+
+	return rows
+}
+
+func (s *btreeStorage) clear() {
+	s.pages = nil
+}
+
+// compareKeys compares keys attribute by attribute
+func compareKeys(a []interface{}, b []interface{}) int {
+	for i := range a {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareValues returns -1, 0 or 1 whether a is lower, equal or greater
+// than b. Numbers compare as such, whatever their type.
+func compareValues(a interface{}, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a < b, a > b)
+		case float64:
+			return compareOrdered(float64(a) < b, float64(a) > b)
+		}
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a < float64(b), a > float64(b))
+		case float64:
+			return compareOrdered(a < b, a > b)
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			return compareOrdered(!a && b, a && !b)
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return compareOrdered(a.Before(b), a.After(b))
+		}
+	case []byte:
+		if b, ok := b.([]byte); ok {
+			return bytes.Compare(a, b)
+		}
+	}
+
+	return strings.Compare(format(a), format(b))
+}
+
+func compareOrdered(lower bool, greater bool) int {
+	switch {
+	case lower:
+		return -1
+	case greater:
+		return 1
+	}
+
+	return 0
+}
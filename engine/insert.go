@@ -3,8 +3,6 @@ package engine
 import (
 	"errors"
 	"fmt"
-	"strconv"
-	"time"
 
 	"github.com/kokizzu/ramsql/engine/log"
 	"github.com/kokizzu/ramsql/engine/parser"
@@ -32,70 +30,124 @@ func getRelation(e *Engine, intoDecl *parser.Decl) (*Relation, []*parser.Decl, e
 		}
 	}
 
-	return r, intoDecl.Decl[0].Decl, nil
+	// Without column list, values are those of all columns in table order
+	attributes := intoDecl.Decl[0].Decl
+	if len(attributes) == 0 {
+		for _, attr := range r.table.attributes {
+			attributes = append(attributes, parser.NewDecl(parser.Token{Token: parser.StringToken, Lexeme: attr.name}))
+		}
+	}
+
+	return r, attributes, nil
 }
 
-func insert(r *Relation, attributes []*parser.Decl, values []*parser.Decl, returnedID string) (int64, error) {
+// defaultValue stands for the DEFAULT keyword in an inserted row
+type defaultValue struct{}
+
+// rowValues evaluates the values of an inserted row
+func rowValues(tx *Transaction, values []*parser.Decl) ([]interface{}, error) {
+	row := make([]interface{}, len(values))
+	for x, decl := range values {
+		if decl.Token == parser.DefaultToken {
+			row[x] = defaultValue{}
+			continue
+		}
+
+		// Before adding value in tuple, check it's not a builtin func or arithmetic operation
+		v, err := tx.value(decl)
+		if err != nil {
+			return nil, err
+		}
+		row[x] = v
+	}
+
+	return row, nil
+}
+
+// newRow returns the tuple holding a row of values, given in the order of
+// attributes, and the id reported once it is inserted
+func newRow(r *Relation, tx *Transaction, attributes []*parser.Decl, row []interface{}) (*Tuple, int64, error) {
 	var assigned = false
 	var id int64
-	var valuesindex int
+
+	if len(row) > len(attributes) {
+		return nil, 0, fmt.Errorf("INSERT has more expressions than target columns")
+	}
+	if len(row) < len(attributes) {
+		return nil, 0, fmt.Errorf("INSERT has more target columns than expressions")
+	}
 
 	// Create tuple
 	t := NewTuple()
-	for attrindex, attr := range r.table.attributes {
+	for _, attr := range r.table.attributes {
 		assigned = false
 
 		for x, decl := range attributes {
 
-			if attr.name == decl.Lexeme && attr.autoIncrement == false {
-				// Before adding value in tuple, check it's not a builtin func or arithmetic operation
-				switch values[x].Token {
-				case parser.NowToken:
-					t.Append(time.Now().Format(parser.DateLongFormat))
-				default:
-					t.Append(values[x].Lexeme)
+			// DEFAULT keyword stands for the default value
+			if _, ok := row[x].(defaultValue); attr.name != decl.Lexeme || ok {
+				continue
+			}
+
+			if attr.generatedAlways {
+				return nil, 0, fmt.Errorf("cannot insert a non-DEFAULT value into column \"%s\"", attr.name)
+			}
+
+			// Check value matches attribute type
+			v, err := coerce(attr, row[x])
+			if err != nil {
+				return nil, 0, err
+			}
 
+			// Auto-incremented attribute is only computed if NULL, its sequence
+			// must not return explicit values afterwards
+			if attr.autoIncrement {
+				n, ok := v.(int64)
+				if !ok {
+					break
 				}
-				valuesindex = x
-				assigned = true
-
-				if returnedID == attr.name {
-					var err error
-					id, err = strconv.ParseInt(values[x].Lexeme, 10, 64)
-					if err != nil {
-						return 0, err
-					}
+				if s := tx.e.sequences[attr.sequence]; s != nil {
+					s.advance(n)
 				}
+				id = n
 			}
-		}
-
-		// If attribute is AUTO INCREMENT then compute and assign it
-		if attr.autoIncrement {
-			id = int64(len(r.rows) + 1)
-			t.Append(id)
 
+			t.Append(v)
 			assigned = true
 		}
 
-		// If attribute is UNIQUE then validate it is so
-		if attr.unique {
-			for i := range r.rows { // check all value already in relation (yup, no index tree)
-				if r.rows[i].Values[attrindex].(string) == string(values[valuesindex].Lexeme) {
-					return 0, fmt.Errorf("UNIQUE constraint violation")
-				}
+		// If attribute is AUTO INCREMENT or takes its default value from a
+		// sequence, then compute and assign it
+		if !assigned && attr.sequence != "" {
+			n, err := tx.nextval(attr.sequence)
+			if err != nil {
+				return nil, 0, err
 			}
+			v, err := coerce(attr, n)
+			if err != nil {
+				return nil, 0, err
+			}
+			t.Append(v)
+			if attr.autoIncrement {
+				id = n
+			}
+
+			assigned = true
 		}
 
 		// If value was not explictly set (or implicitly computed) then use the default value
 		if assigned == false {
 			switch val := attr.defaultValue.(type) {
-			case func() interface{}:
-				v := (func() interface{})(val)()
+			case func(*Transaction) interface{}:
+				v, err := coerce(attr, val(tx))
+				if err != nil {
+					return nil, 0, err
+				}
 				log.Debug("Setting func value '%v' to %s\n", v, attr.name)
 				t.Append(v)
 			default:
 				if val == nil && !attr.isNullable {
-					return 0, fmt.Errorf("Field '%s' with constraint 'NOT NULL' doesn't have a default value", attr.name)
+					return nil, 0, fmt.Errorf("Field '%s' with constraint 'NOT NULL' doesn't have a default value", attr.name)
 				}
 				log.Debug("Setting default value '%v' to %s\n", val, attr.name)
 				t.Append(attr.defaultValue)
@@ -105,11 +157,5 @@ func insert(r *Relation, attributes []*parser.Decl, values []*parser.Decl, retur
 
 	log.Info("New tuple : %v", t)
 
-	// Insert tuple
-	err := r.Insert(t)
-	if err != nil {
-		return 0, err
-	}
-
-	return id, nil
+	return t, id, nil
 }
@@ -1,8 +1,6 @@
 package engine
 
 import (
-	"fmt"
-
 	"github.com/kokizzu/ramsql/engine/parser"
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
@@ -18,8 +16,43 @@ import (
         |-> Roullon
         |-> Pierre
         |-> pierre.roullon@gmail.com
+    |-> VALUES
+        |-> Doe
+        |-> John
+        |-> john.doe@gmail.com
+
+|-> INSERT
+    |-> INTO
+        |-> user
+            |-> last_name
+    |-> SELECT
+        |-> name
+        |-> FROM
+            |-> customer
+
+|-> INSERT
+    |-> INTO
+        |-> user
+            |-> email
+    |-> VALUES
+        |-> john.doe@gmail.com
+    |-> conflict
+        |-> key
+            |-> email
+        |-> nothing
 */
-func insertIntoTableExecutor(e *Engine, insertDecl *parser.Decl, conn protocol.EngineConn) error {
+func insertIntoTableExecutor(e *Engine, tx *Transaction, insertDecl *parser.Decl, conn protocol.EngineConn) error {
+
+	// Rows inserted from a query are selected before the table is locked,
+	// since they may be selected from it
+	var rows [][]interface{}
+	if insertDecl.Decl[1].Token == parser.SelectToken {
+		result := &queryResult{}
+		if err := selectExecutor(e, tx, insertDecl.Decl[1], result); err != nil {
+			return err
+		}
+		rows = result.rows
+	}
 
 	// Get table and concerned attributes and write lock it
 	r, attributes, err := getRelation(e, insertDecl.Decl[0])
@@ -29,30 +62,64 @@ func insertIntoTableExecutor(e *Engine, insertDecl *parser.Decl, conn protocol.E
 	r.Lock()
 	defer r.Unlock()
 
-	// Check for RETURNING clause
-	var returnedID string
-	if len(insertDecl.Decl) > 2 {
-		for i := range insertDecl.Decl {
-			if insertDecl.Decl[i].Token == parser.ReturningToken {
-				returnedID = insertDecl.Decl[i].Lexeme
-				break
-			}
-		}
+	// Values of written rows may be returned
+	ret, err := newReturning(r, insertDecl)
+	if err != nil {
+		return err
 	}
 
-	// Create a new tuple with values
-	id, err := insert(r, attributes, insertDecl.Decl[1].Decl, returnedID)
+	// Rows holding an existing key may be skipped or update it
+	c, err := newConflict(r, insertDecl)
 	if err != nil {
 		return err
 	}
 
-	// if RETURNING decl is not present
-	if returnedID != "" {
-		conn.WriteRowHeader([]string{returnedID})
-		conn.WriteRow([]string{fmt.Sprintf("%v", id)})
-		conn.WriteRowEnd()
-	} else {
-		conn.WriteResult(id, 1)
+	if insertDecl.Decl[1].Token != parser.SelectToken {
+		for _, valuesDecl := range insertDecl.Decl[1:] {
+			if valuesDecl.Token != parser.ValuesToken {
+				continue
+			}
+			row, err := rowValues(tx, valuesDecl.Decl)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	// Without column list, values are those of the first columns
+	implicit := len(insertDecl.Decl[0].Decl[0].Decl) == 0
+
+	// Create new tuples with values
+	var lastID, affected int64
+	for _, row := range rows {
+		columns := attributes
+		if implicit && len(row) < len(columns) {
+			columns = columns[:len(row)]
+		}
+		t, id, err := newRow(r, tx, columns, row)
+		if err != nil {
+			return err
+		}
+		written, n, err := c.insert(tx, r, t)
+		if err != nil {
+			return err
+		}
+		affected += n
+		if written == nil {
+			continue
+		}
+		if written != t {
+			id = rowID(r, written)
+		}
+		lastID = id
+		if err := ret.add(written); err != nil {
+			return err
+		}
+	}
+
+	if ret != nil {
+		return ret.write(conn)
 	}
-	return nil
+	return conn.WriteResult(lastID, affected)
 }
@@ -28,8 +28,11 @@ func (v virtualRow) String() string {
 // INNER, LEFT, RIGHT, FULL
 // with NATURAL option
 type joiner interface {
-	Evaluate(virtualRow, *Relation, int) (bool, error)
+	Evaluate(virtualRow, *Relation, *Tuple) (bool, error)
 	On() string
+	// lookup returns positions of rows of relation which may join
+	// virtual row, false if an index cannot find them
+	lookup(virtualRow, *Relation) ([]int, bool)
 }
 
 // default joiner implementation
@@ -43,7 +46,7 @@ func (i *inner) On() string {
 	return i.table
 }
 
-func (i *inner) Evaluate(row virtualRow, r *Relation, index int) (bool, error) {
+func (i *inner) Evaluate(row virtualRow, r *Relation, t *Tuple) (bool, error) {
 	var t1, t2 Value
 
 	// I want t1 to be the attribute already in the virtual row
@@ -67,7 +70,7 @@ func (i *inner) Evaluate(row virtualRow, r *Relation, index int) (bool, error) {
 	for attrIndex, attr := range r.table.attributes {
 		if attr.name == i.t2Value.lexeme {
 			t2 = Value{
-				v:      r.rows[index].Values[attrIndex],
+				v:      t.Values[attrIndex],
 				lexeme: attr.name,
 				table:  r.table.name,
 				valid:  true,
@@ -80,16 +83,51 @@ func (i *inner) Evaluate(row virtualRow, r *Relation, index int) (bool, error) {
 	}
 
 	// let's say for now the only operator is '='
-	if fmt.Sprintf("%v", t1.v) == fmt.Sprintf("%v", t2.v) {
+	if equal(t1.v, t2.v) {
 		return true, nil
 	}
 
 	return false, nil
 }
 
+func (i *inner) lookup(row virtualRow, r *Relation) ([]int, bool) {
+	t1, t2 := i.t1Value, i.t2Value
+	if t1.table == r.table.name {
+		t1, t2 = t2, t1
+	}
+
+	val, ok := row[t1.table+"."+t1.lexeme]
+	if !ok || t2.table != r.table.name {
+		return nil, false
+	}
+	a := r.table.attributeIndex(t2.lexeme)
+	if a < 0 {
+		return nil, false
+	}
+
+	return r.search([]int{a}, []interface{}{val.v})
+}
+
+// cross joins every row of a table, filtered by WHERE afterwards
+type cross struct {
+	table string
+}
+
+func (c *cross) On() string {
+	return c.table
+}
+
+func (c *cross) Evaluate(row virtualRow, r *Relation, t *Tuple) (bool, error) {
+	return true, nil
+}
+
+func (c *cross) lookup(row virtualRow, r *Relation) ([]int, bool) {
+	return nil, false
+}
+
 // The optional WHERE, GROUP BY, and HAVING clauses in the table expression specify a pipeline of successive transformations performed on the table derived in the FROM clause.
 // All these transformations produce a virtual table that provides the rows that are passed to the select list to compute the output rows of the query.
-func generateVirtualRows(e *Engine, attr []Attribute, conn protocol.EngineConn, t1Name string, joinPredicates []joiner, selectPredicates []PredicateLinker, functors []selectFunctor) error {
+func generateVirtualRows(e *Engine, tx *Transaction, attr []Attribute, conn protocol.EngineConn, t1Name string, joinPredicates []joiner, selectPredicates []PredicateLinker, functors []selectFunctor) error {
 
 	// get t1 and lock it
 	t1 := e.relation(t1Name)
@@ -98,6 +136,7 @@ func generateVirtualRows(e *Engine, attr []Attribute, conn protocol.EngineConn,
 	}
 	t1.RLock()
 	defer t1.RUnlock()
+	tx.reads(t1)
 
 	// all joined tables in a map of relation
 	relations := make(map[string]*Relation)
@@ -108,14 +147,15 @@ func generateVirtualRows(e *Engine, attr []Attribute, conn protocol.EngineConn,
 		}
 		r.RLock()
 		defer r.RUnlock()
+		tx.reads(r)
 		relations[j.On()] = r
 	}
 
-	// Generate fully-qualified attribute names and aliases
+	// Generate fully-qualified attribute names and result columns
 	var header []string
-	var alias []string
+	var columns []protocol.Column
 	for _, a := range attr {
-		alias = append(alias, a.Alias())
+		columns = append(columns, a.column())
 		if strings.Contains(a.name, ".") == false {
 			a.name = t1Name + "." + a.name
 		}
@@ -124,18 +164,31 @@ func generateVirtualRows(e *Engine, attr []Attribute, conn protocol.EngineConn,
 
 	// Initialize functors here
 	for i := range functors {
-		if err := functors[i].Init(e, conn, header, alias); err != nil {
+		if err := functors[i].Init(e, conn, header, columns); err != nil {
 			return err
 		}
 	}
 
+	// Rows of t1 are looked up with an index if WHERE allows it
+	var conditions []*Predicate
+	for _, p := range selectPredicates {
+		conditions = append(conditions, conjuncts(p)...)
+	}
+
 	// for each row in t1
-	for i := range t1.rows {
+	err := t1.scan(conditions, func(_ int, t *Tuple) error {
+		if err := tx.interrupted(); err != nil {
+			return err
+		}
+		if t = tx.visible(t); t == nil {
+			return nil
+		}
+
 		// create virtualrow
 		row := make(virtualRow)
-		for index := range t1.rows[i].Values {
+		for index := range t.Values {
 			v := Value{
-				v:      t1.rows[i].Values[index],
+				v:      t.Values[index],
 				valid:  true,
 				lexeme: t1.table.attributes[index].name,
 				table:  t1Name,
@@ -144,11 +197,10 @@ func generateVirtualRows(e *Engine, attr []Attribute, conn protocol.EngineConn,
 		}
 
 		// for first join predicates
-		err := join(row, relations, joinPredicates, 0, selectPredicates, functors)
-		if err != nil {
-			return err
-		}
-
+		return join(tx, row, relations, joinPredicates, 0, selectPredicates, functors)
+	})
+	if err != nil {
+		return err
 	}
 
 	for i := range functors {
@@ -161,7 +213,7 @@ func generateVirtualRows(e *Engine, attr []Attribute, conn protocol.EngineConn,
 }
 
 // Recursive virtual row creation
-func join(row virtualRow, relations map[string]*Relation, predicates []joiner, predicateIndex int, selectPredicates []PredicateLinker, functors []selectFunctor) error {
+func join(tx *Transaction, row virtualRow, relations map[string]*Relation, predicates []joiner, predicateIndex int, selectPredicates []PredicateLinker, functors []selectFunctor) error {
 
 	// Skip directly to selectRows if there is no joiner to run
 	if len(predicates) == 0 {
@@ -177,10 +229,21 @@ func join(row virtualRow, relations map[string]*Relation, predicates []joiner, p
 		last = true
 	}
 
-	// for each row in relations[pred.Table()]
+	// for each row in relations[pred.Table()], or only those an index finds
 	r := relations[predicate.On()]
-	for i := range r.rows {
-		ok, err := predicate.Evaluate(row, r, i)
+	rows := r.rows
+	if positions, ok := predicate.lookup(row, r); ok {
+		rows = r.heads(positions)
+	}
+	for _, t := range rows {
+		if err := tx.interrupted(); err != nil {
+			return err
+		}
+		if t = tx.visible(t); t == nil {
+			continue
+		}
+
+		ok, err := predicate.Evaluate(row, r, t)
 		if err != nil {
 			return err
 		}
@@ -190,9 +253,9 @@ func join(row virtualRow, relations map[string]*Relation, predicates []joiner, p
 		}
 
 		// combine columns to existing virtual row
-		for index := range r.rows[i].Values {
+		for index := range t.Values {
 			v := Value{
-				v:      r.rows[i].Values[index],
+				v:      t.Values[index],
 				valid:  true,
 				lexeme: r.table.attributes[index].name,
 				table:  r.table.name,
@@ -204,7 +267,7 @@ func join(row virtualRow, relations map[string]*Relation, predicates []joiner, p
 		if last {
 			err = selectRows(row, selectPredicates, functors)
 		} else {
-			err = join(row, relations, predicates, predicateIndex+1, selectPredicates, functors)
+			err = join(tx, row, relations, predicates, predicateIndex+1, selectPredicates, functors)
 		}
 		if err != nil {
 			return err
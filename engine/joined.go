@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// joined finds the rows of a relation written by UPDATE ... FROM, UPDATE
+// ... JOIN or DELETE ... USING, by joining them with the rows of other
+// tables as SELECT does
+type joined struct {
+	// tables holds the written table first, then the joined ones
+	tables     []*Table
+	relations  map[string]*Relation
+	joiners    []joiner
+	predicates []PredicateLinker
+}
+
+// newJoined returns the tables joined to relation r by statement decl, nil
+// if there is none
+func newJoined(e *Engine, r *Relation, decl *parser.Decl) (*joined, error) {
+	j := &joined{
+		tables:    []*Table{r.table},
+		relations: make(map[string]*Relation),
+	}
+
+	var whereDecl *parser.Decl
+	for i, d := range decl.Decl {
+		switch d.Token {
+		case parser.JoinToken:
+			jn, err := joinExecutor(d)
+			if err != nil {
+				return nil, err
+			}
+			if err := j.add(e, jn.On()); err != nil {
+				return nil, err
+			}
+			j.joiners = append(j.joiners, jn)
+		case parser.FromToken, parser.UsingToken:
+			// FROM of DELETE holds the written table
+			if i == 0 {
+				continue
+			}
+			for _, t := range d.Decl {
+				if err := j.add(e, t.Lexeme); err != nil {
+					return nil, err
+				}
+				j.joiners = append(j.joiners, &cross{table: t.Lexeme})
+			}
+		case parser.WhereToken:
+			whereDecl = d
+		}
+	}
+	if len(j.tables) == 1 {
+		return nil, nil
+	}
+
+	// Columns joined on must exist
+	for _, jn := range j.joiners {
+		i, ok := jn.(*inner)
+		if !ok {
+			continue
+		}
+		for _, v := range []Value{i.t1Value, i.t2Value} {
+			columnDecl := &parser.Decl{Token: parser.ColumnToken, Lexeme: v.lexeme}
+			columnDecl.Add(&parser.Decl{Token: parser.StringToken, Lexeme: v.table})
+			if _, err := columnAttribute(columnDecl, j.tables); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if whereDecl == nil {
+		return j, nil
+	}
+	if len(whereDecl.Decl) != 1 {
+		return nil, fmt.Errorf("parsing failed, malformed WHERE clause")
+	}
+	condDecl := whereDecl.Decl[0]
+	if _, err := expressionAttribute(condDecl, j.tables); err != nil {
+		return nil, err
+	}
+	j.predicates = []PredicateLinker{&condition{decl: condDecl}}
+
+	// Rows of tables listed in FROM or USING are looked up with an index
+	// if WHERE compares their columns to those of tables already joined
+	done := map[string]bool{r.table.name: true}
+	for i, jn := range j.joiners {
+		if c, ok := jn.(*cross); ok {
+			if eq := joinEquality(condDecl, c.table, done); eq != nil {
+				j.joiners[i] = eq
+			}
+		}
+		done[jn.On()] = true
+	}
+
+	return j, nil
+}
+
+// add joins table name
+func (j *joined) add(e *Engine, name string) error {
+	r := e.relation(name)
+	if r == nil {
+		return fmt.Errorf("Table %s does not exists", name)
+	}
+	for _, t := range j.tables {
+		if t.name == name {
+			return fmt.Errorf("table name \"%s\" specified more than once", name)
+		}
+	}
+
+	j.relations[name] = r
+	j.tables = append(j.tables, r.table)
+	return nil
+}
+
+// lock read locks the joined relations, and returns the function releasing
+// them
+func (j *joined) lock(tx *Transaction) func() {
+	for _, t := range j.tables[1:] {
+		j.relations[t.name].RLock()
+		tx.reads(j.relations[t.name])
+	}
+
+	return func() {
+		for _, t := range j.tables[1:] {
+			j.relations[t.name].RUnlock()
+		}
+	}
+}
+
+// match returns the first row joined to row t of relation r, nil if none
+// is
+func (j *joined) match(tx *Transaction, r *Relation, t *Tuple) (virtualRow, error) {
+	f := &firstRow{}
+	err := join(tx, r.virtualRow(t), j.relations, j.joiners, 0, j.predicates, []selectFunctor{f})
+	if err != nil {
+		return nil, err
+	}
+
+	return f.row, nil
+}
+
+// joinEquality returns a joiner of table on the equality of one of its
+// columns with a column of an already joined table, found among the AND
+// operands of condition decl. It returns nil if there is none.
+func joinEquality(decl *parser.Decl, table string, joined map[string]bool) *inner {
+	switch decl.Token {
+	case parser.AndToken:
+		for _, d := range decl.Decl {
+			if i := joinEquality(d, table, joined); i != nil {
+				return i
+			}
+		}
+	case parser.EqualityToken:
+		var values []Value
+		for _, d := range decl.Decl {
+			if d.Token != parser.ColumnToken || len(d.Decl) != 1 {
+				return nil
+			}
+			values = append(values, Value{valid: true, lexeme: d.Lexeme, table: d.Decl[0].Lexeme})
+		}
+		if len(values) != 2 {
+			return nil
+		}
+		if values[0].table == table {
+			values[0], values[1] = values[1], values[0]
+		}
+		if values[1].table == table && joined[values[0].table] {
+			return &inner{table: table, t1Value: values[0], t2Value: values[1]}
+		}
+	}
+
+	return nil
+}
+
+// condition is a WHERE clause evaluated on joined rows
+type condition struct {
+	decl *parser.Decl
+}
+
+func (c *condition) Eval(row virtualRow) (bool, error) {
+	return evaluateCondition(c.decl, row)
+}
+
+// firstRow keeps a copy of the first joined row it is fed
+type firstRow struct {
+	row virtualRow
+}
+
+func (f *firstRow) Init(e *Engine, conn protocol.EngineConn, attr []string, columns []protocol.Column) error {
+	return nil
+}
+
+func (f *firstRow) FeedVirtualRow(row virtualRow) error {
+	if f.row != nil {
+		return nil
+	}
+
+	f.row = make(virtualRow, len(row))
+	for k, v := range row {
+		f.row[k] = v
+	}
+	return nil
+}
+
+func (f *firstRow) Done() error {
+	return nil
+}
@@ -21,9 +21,15 @@ func limitedConn(conn protocol.EngineConn, l int) protocol.EngineConn {
 }
 
 // Not needed
-func (l *limit) ReadStatement() (string, error) {
+func (l *limit) ReadStatement() (protocol.Statement, error) {
 	log.Debug("limit.ReadStatement: should not be used\n")
-	return "", nil
+	return protocol.Statement{}, nil
+}
+
+// Not needed
+func (l *limit) WritePrepared(id int64, numInput int) error {
+	log.Debug("limit.WritePrepared: should not be used\n")
+	return nil
 }
 
 // Not needed
@@ -36,11 +42,11 @@ func (l *limit) WriteError(err error) error {
 	return l.realConn.WriteError(err)
 }
 
-func (l *limit) WriteRowHeader(header []string) error {
+func (l *limit) WriteRowHeader(header []protocol.Column) error {
 	return l.realConn.WriteRowHeader(header)
 }
 
-func (l *limit) WriteRow(row []string) error {
+func (l *limit) WriteRow(row []interface{}) error {
 	if l.current == l.limit {
 		// We are done here
 		return nil
@@ -68,9 +74,15 @@ func offsetedConn(conn protocol.EngineConn, o int) protocol.EngineConn {
 }
 
 // Not needed
-func (l *offset) ReadStatement() (string, error) {
+func (l *offset) ReadStatement() (protocol.Statement, error) {
 	log.Debug("limit.ReadStatement: should not be used\n")
-	return "", nil
+	return protocol.Statement{}, nil
+}
+
+// Not needed
+func (l *offset) WritePrepared(id int64, numInput int) error {
+	log.Debug("limit.WritePrepared: should not be used\n")
+	return nil
 }
 
 // Not needed
@@ -83,11 +95,11 @@ func (l *offset) WriteError(err error) error {
 	return l.realConn.WriteError(err)
 }
 
-func (l *offset) WriteRowHeader(header []string) error {
+func (l *offset) WriteRowHeader(header []protocol.Column) error {
 	return l.realConn.WriteRowHeader(header)
 }
 
-func (l *offset) WriteRow(row []string) error {
+func (l *offset) WriteRow(row []interface{}) error {
 	if l.current < l.offset {
 		// skip this line
 		l.current++
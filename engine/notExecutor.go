@@ -5,6 +5,6 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func notExecutor(e *Engine, tableDecl *parser.Decl, conn protocol.EngineConn) error {
+func notExecutor(e *Engine, tx *Transaction, tableDecl *parser.Decl, conn protocol.EngineConn) error {
 	return nil
 }
@@ -35,6 +35,8 @@ func convToDate(t interface{}) (*time.Time, error) {
 	default:
 		log.Debug("convToDate> unexpected type %T\n", t)
 		return &time.Time{}, fmt.Errorf("unexpected internal type %T", t)
+	case time.Time:
+		return &t, nil
 	case string:
 		d, err := parser.ParseDate(string(t))
 		if err != nil {
@@ -175,12 +177,11 @@ func lessThanOperator(leftValue Value, rightValue Value) bool {
 
 // EqualityOperator checks if given value are equal
 func equalityOperator(leftValue Value, rightValue Value) bool {
-
-	if fmt.Sprintf("%v", leftValue.v) == rightValue.lexeme {
-		return true
+	if rightValue.v != nil {
+		return equal(leftValue.v, rightValue.v)
 	}
 
-	return false
+	return equal(leftValue.v, rightValue.lexeme)
 }
 
 // TrueOperator always returns true
@@ -198,7 +199,7 @@ func inOperator(leftValue Value, rightValue Value) bool {
 
 	for i := range values {
 		log.Debug("InOperator: Testing %v against %s", leftValue.v, values[i])
-		if fmt.Sprintf("%v", leftValue.v) == values[i] {
+		if equal(leftValue.v, values[i]) {
 			return true
 		}
 	}
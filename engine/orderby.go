@@ -16,7 +16,7 @@ import (
 //        |-> desc
 func orderbyExecutor(attr *parser.Decl, tables []*Table) (selectFunctor, error) {
 	f := &orderbyFunctor{}
-	f.buffer = make(map[int64][][]string)
+	f.buffer = make(map[int64][][]interface{})
 
 	// first subdecl should be attribute
 	if len(attr.Decl) < 1 {
@@ -49,20 +49,20 @@ type orderbyFunctor struct {
 	e          *Engine
 	conn       protocol.EngineConn
 	attributes []string
-	alias      []string
+	columns    []protocol.Column
 	orderby    string
 	asc        bool
-	buffer     map[int64][][]string
+	buffer     map[int64][][]interface{}
 	order      orderer
 }
 
-func (f *orderbyFunctor) Init(e *Engine, conn protocol.EngineConn, attr []string, alias []string) error {
+func (f *orderbyFunctor) Init(e *Engine, conn protocol.EngineConn, attr []string, columns []protocol.Column) error {
 	f.e = e
 	f.conn = conn
 	f.attributes = attr
-	f.alias = alias
+	f.columns = columns
 
-	return f.conn.WriteRowHeader(f.alias)
+	return f.conn.WriteRowHeader(f.columns)
 }
 
 func (f *orderbyFunctor) FeedVirtualRow(vrow virtualRow) error {
@@ -129,19 +129,20 @@ type orderer interface {
 
 func initOrderer(val Value, attr []string) (orderer, error) {
 	log.Debug("initOrder: %v\n", val)
-	_, err := strconv.ParseInt(fmt.Sprintf("%v", val.v), 10, 64)
-	if err == nil {
-		log.Debug("initOrderer> key is in fact an integer\n")
-		i := &intOrderer{}
-		i.init(attr)
-		return i, nil
-	}
 
 	/* OK SO
-	 * Is the key an integer, a string or a date ?
+	 * Is the key an integer, a float, a string or a date ?
 	 */
 	switch v := val.v.(type) {
 	case string:
+		// Columns of unknown type hold strings, maybe integers
+		_, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			log.Debug("initOrderer> key is in fact an integer\n")
+			i := &intOrderer{}
+			i.init(attr)
+			return i, nil
+		}
 		s := &stringOrderer{}
 		s.init(attr)
 		return s, nil
@@ -149,40 +150,54 @@ func initOrderer(val Value, attr []string) (orderer, error) {
 		i := &intOrderer{}
 		i.init(attr)
 		return i, nil
-	/*case time.Time:
-	d := dateOrderer{}
-	d.init()
-	return d*/
+	case float64:
+		f := &floatOrderer{}
+		f.init(attr)
+		return f, nil
+	case time.Time:
+		d := &dateOrderer{}
+		d.init(attr)
+		return d, nil
 	default:
 		return nil, fmt.Errorf("cannot order %T with value %v", val.v, v)
 	}
 }
 
+// orderedRow returns selected attributes of a virtual row
+func orderedRow(attributes []string, vrow virtualRow) ([]interface{}, error) {
+	var row []interface{}
+
+	for _, attr := range attributes {
+		val, ok := vrow[attr]
+		if !ok {
+			return nil, fmt.Errorf("could not select attribute %s", attr)
+		}
+		row = append(row, val.v)
+	}
+
+	return row, nil
+}
+
 type stringOrderer struct {
-	buffer     map[string][][]string
+	buffer     map[string][][]interface{}
 	attributes []string
 	keys       []string
 }
 
 func (i *stringOrderer) init(attr []string) {
-	i.buffer = make(map[string][][]string)
+	i.buffer = make(map[string][][]interface{})
 	i.attributes = attr
 }
 
 func (i *stringOrderer) Feed(val Value, vrow virtualRow) error {
-	var row []string
-
 	key, ok := val.v.(string)
 	if !ok {
 		return fmt.Errorf("error ordering because of value %v", val.v)
 	}
 
-	for _, attr := range i.attributes {
-		val, ok := vrow[attr]
-		if !ok {
-			return fmt.Errorf("could not select attribute %s", attr)
-		}
-		row = append(row, fmt.Sprintf("%v", val.v))
+	row, err := orderedRow(i.attributes, vrow)
+	if err != nil {
+		return err
 	}
 
 	// now instead of writing row, we will find the ordering key and put in in our buffer
@@ -231,18 +246,17 @@ func (i *stringOrderer) Write(conn protocol.EngineConn) error {
 }
 
 type intOrderer struct {
-	buffer     map[int64][][]string
+	buffer     map[int64][][]interface{}
 	attributes []string
 	keys       []int64
 }
 
 func (i *intOrderer) init(attr []string) {
-	i.buffer = make(map[int64][][]string)
+	i.buffer = make(map[int64][][]interface{})
 	i.attributes = attr
 }
 
 func (i *intOrderer) Feed(val Value, vrow virtualRow) error {
-	var row []string
 	var key int64
 	var err error
 
@@ -251,12 +265,9 @@ func (i *intOrderer) Feed(val Value, vrow virtualRow) error {
 		return fmt.Errorf("error ordering because of value %v", val.v)
 	}
 
-	for _, attr := range i.attributes {
-		val, ok := vrow[attr]
-		if !ok {
-			return fmt.Errorf("could not select attribute %s", attr)
-		}
-		row = append(row, fmt.Sprintf("%v", val.v))
+	row, err := orderedRow(i.attributes, vrow)
+	if err != nil {
+		return err
 	}
 
 	// now instead of writing row, we will find the ordering key and put in in our buffer
@@ -301,6 +312,129 @@ func (i *intOrderer) Write(conn protocol.EngineConn) error {
 	return nil
 }
 
+type floatOrderer struct {
+	buffer     map[float64][][]interface{}
+	attributes []string
+	keys       []float64
+}
+
+func (i *floatOrderer) init(attr []string) {
+	i.buffer = make(map[float64][][]interface{})
+	i.attributes = attr
+}
+
+func (i *floatOrderer) Feed(val Value, vrow virtualRow) error {
+	key, err := convToFloat(val.v)
+	if err != nil {
+		return fmt.Errorf("error ordering because of value %v", val.v)
+	}
+
+	row, err := orderedRow(i.attributes, vrow)
+	if err != nil {
+		return err
+	}
+
+	// now instead of writing row, we will find the ordering key and put in in our buffer
+	i.buffer[key] = append(i.buffer[key], row)
+	return nil
+}
+
+func (i *floatOrderer) Sort() error {
+	// now we have to sort our key
+	i.keys = make([]float64, 0, len(i.buffer))
+	for k := range i.buffer {
+		i.keys = append(i.keys, k)
+	}
+
+	sort.Float64s(i.keys)
+	return nil
+}
+
+func (i *floatOrderer) SortReverse() error {
+	// now we have to sort our key
+	i.keys = make([]float64, 0, len(i.buffer))
+	for k := range i.buffer {
+		i.keys = append(i.keys, k)
+	}
+
+	sort.Sort(sort.Reverse(sort.Float64Slice(i.keys)))
+	return nil
+}
+
+func (i *floatOrderer) Write(conn protocol.EngineConn) error {
+	// now write ordered rows
+	for _, key := range i.keys {
+		rows := i.buffer[key]
+		for index := range rows {
+			err := conn.WriteRow(rows[index])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 type dateOrderer struct {
-	buffer map[time.Time][][]string
+	buffer     map[int64][][]interface{}
+	attributes []string
+	keys       []int64
+}
+
+func (i *dateOrderer) init(attr []string) {
+	i.buffer = make(map[int64][][]interface{})
+	i.attributes = attr
+}
+
+func (i *dateOrderer) Feed(val Value, vrow virtualRow) error {
+	date, ok := val.v.(time.Time)
+	if !ok {
+		return fmt.Errorf("error ordering because of value %v", val.v)
+	}
+	key := date.UnixNano()
+
+	row, err := orderedRow(i.attributes, vrow)
+	if err != nil {
+		return err
+	}
+
+	// now instead of writing row, we will find the ordering key and put in in our buffer
+	i.buffer[key] = append(i.buffer[key], row)
+	return nil
+}
+
+func (i *dateOrderer) Sort() error {
+	// now we have to sort our key
+	i.keys = make([]int64, 0, len(i.buffer))
+	for k := range i.buffer {
+		i.keys = append(i.keys, k)
+	}
+
+	sort.Sort(sortSlice(i.keys))
+	return nil
+}
+
+func (i *dateOrderer) SortReverse() error {
+	// now we have to sort our key
+	i.keys = make([]int64, 0, len(i.buffer))
+	for k := range i.buffer {
+		i.keys = append(i.keys, k)
+	}
+
+	sort.Sort(sort.Reverse(sortSlice(i.keys)))
+	return nil
+}
+
+func (i *dateOrderer) Write(conn protocol.EngineConn) error {
+	// now write ordered rows
+	for _, key := range i.keys {
+		rows := i.buffer[key]
+		for index := range rows {
+			err := conn.WriteRow(rows[index])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// bind returns a copy of given instructions with parameters replaced by
+// their argument value. Arguments never go through the lexer, so any
+// value is handled safely.
+func bind(instructions []parser.Instruction, args []protocol.Argument) ([]parser.Instruction, error) {
+	bound := make([]parser.Instruction, len(instructions))
+
+	for i, instruction := range instructions {
+		for _, decl := range instruction.Decls {
+			d, err := bindDecl(decl, args)
+			if err != nil {
+				return nil, err
+			}
+			bound[i].Decls = append(bound[i].Decls, d)
+		}
+	}
+
+	return bound, nil
+}
+
+func bindDecl(decl *parser.Decl, args []protocol.Argument) (*parser.Decl, error) {
+	if decl.Token == parser.ParameterToken {
+		arg, err := argument(decl.Lexeme, args)
+		if err != nil {
+			return nil, err
+		}
+		return argumentDecl(arg.Value), nil
+	}
+
+	d := &parser.Decl{
+		Token:  decl.Token,
+		Lexeme: decl.Lexeme,
+	}
+	for _, sub := range decl.Decl {
+		s, err := bindDecl(sub, args)
+		if err != nil {
+			return nil, err
+		}
+		d.Add(s)
+	}
+
+	return d, nil
+}
+
+// bindNow binds NOW() and CURRENT_TIMESTAMP in decl to time now, so that
+// every row of a statement holds the same time
+func bindNow(decl *parser.Decl, now time.Time) {
+	if (decl.Token == parser.NowToken || decl.Token == parser.LocalTimestampToken) && len(decl.Decl) == 0 {
+		decl.Add(parser.NewDecl(parser.Token{Token: parser.StringToken, Lexeme: now.Format(time.RFC3339Nano)}))
+		return
+	}
+
+	for _, d := range decl.Decl {
+		bindNow(d, now)
+	}
+}
+
+// argument finds the argument bound to parameter $n, :name or @name
+func argument(parameter string, args []protocol.Argument) (protocol.Argument, error) {
+	if parameter[0] == '$' {
+		n, err := strconv.Atoi(parameter[1:])
+		if err != nil {
+			return protocol.Argument{}, fmt.Errorf("invalid parameter %s", parameter)
+		}
+		for _, arg := range args {
+			if arg.Name == "" && arg.Ordinal == n {
+				return arg, nil
+			}
+		}
+		return protocol.Argument{}, fmt.Errorf("there is no parameter %s", parameter)
+	}
+
+	for _, arg := range args {
+		if arg.Name == parameter[1:] {
+			return arg, nil
+		}
+	}
+
+	return protocol.Argument{}, fmt.Errorf("no value given for parameter %s", parameter)
+}
+
+// argumentDecl returns the literal decl of an argument value
+func argumentDecl(v interface{}) *parser.Decl {
+	token := parser.StringToken
+
+	switch v := v.(type) {
+	case nil:
+		token = parser.NullToken
+	case bool:
+		token = parser.FalseToken
+		if v {
+			token = parser.TrueToken
+		}
+	case int64, float64:
+		token = parser.NumberToken
+	case []byte:
+		// Raw bytes, not to be read as a '\x' hex literal
+		token = parser.ByteaToken
+	case time.Time:
+		token = parser.TimestampToken
+	}
+
+	return &parser.Decl{
+		Token:  token,
+		Lexeme: format(v),
+	}
+}
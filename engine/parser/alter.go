@@ -0,0 +1,394 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseAlter parses a table alteration, made of one or more actions
+// ALTER TABLE [ IF EXISTS ] <TABLE-NAME> <ACTION> [, <ACTION>]*
+// or a sequence alteration, see parseAlterSequence
+//
+//	|-> alter
+//	    |-> table
+//	        |-> if
+//	            |-> exists
+//	        |-> <TABLE-NAME>
+//	    |-> <ACTION>
+func (p *parser) parseAlter() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: ALTER
+	alterDecl, err := p.consumeToken(AlterToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, alterDecl)
+
+	// ALTER SEQUENCE
+	if p.isWord("sequence") {
+		if err := p.parseAlterSequence(alterDecl); err != nil {
+			return nil, err
+		}
+		return i, nil
+	}
+
+	// Required: TABLE
+	tableDecl, err := p.consumeToken(TableToken)
+	if err != nil {
+		return nil, err
+	}
+	alterDecl.Add(tableDecl)
+
+	// Optional: IF EXISTS
+	if p.is(IfToken) {
+		ifDecl, err := p.consumeToken(IfToken)
+		if err != nil {
+			return nil, err
+		}
+		existsDecl, err := p.consumeToken(ExistsToken)
+		if err != nil {
+			return nil, err
+		}
+		ifDecl.Add(existsDecl)
+		tableDecl.Add(ifDecl)
+	}
+
+	// Required: <TABLE-NAME>
+	nameDecl, err := p.parseTableName()
+	if err != nil {
+		return nil, err
+	}
+	tableDecl.Add(nameDecl)
+
+	// Required: <ACTION> [, <ACTION>]*
+	for {
+		actionDecl, err := p.parseAlterAction()
+		if err != nil {
+			return nil, err
+		}
+		alterDecl.Add(actionDecl)
+
+		if !p.is(CommaToken) {
+			break
+		}
+		p.next()
+	}
+
+	if !p.is(SemicolonToken) {
+		return nil, p.syntaxError()
+	}
+
+	return i, nil
+}
+
+// parseAlterAction parses a single ALTER TABLE action:
+//
+//	ADD [ COLUMN ] [ IF NOT EXISTS ] <COLUMN-DEFINITION>
+//	ADD <TABLE-CONSTRAINT>
+//	DROP [ COLUMN ] [ IF EXISTS ] <COLUMN-NAME> [ CASCADE | RESTRICT ]
+//	DROP CONSTRAINT [ IF EXISTS ] <CONSTRAINT-NAME> [ CASCADE | RESTRICT ]
+//	RENAME [ COLUMN ] <COLUMN-NAME> TO <NEW-COLUMN-NAME>
+//	RENAME TO <NEW-TABLE-NAME>
+//	ALTER [ COLUMN ] <COLUMN-NAME> { SET DEFAULT <VALUE> | DROP DEFAULT }
+//	ALTER [ COLUMN ] <COLUMN-NAME> { SET | DROP } NOT NULL
+//	ALTER [ COLUMN ] <COLUMN-NAME> [ SET DATA ] TYPE <TYPE>
+func (p *parser) parseAlterAction() (*Decl, error) {
+	switch {
+	case p.isWord("add"):
+		return p.parseAlterAdd()
+	case p.is(DropToken):
+		return p.parseAlterDrop()
+	case p.isWord("rename"):
+		return p.parseAlterRename()
+	case p.is(AlterToken):
+		return p.parseAlterColumn()
+	}
+
+	return nil, p.syntaxError()
+}
+
+// parseAlterAdd parses ADD [ COLUMN ] [ IF NOT EXISTS ] <COLUMN-DEFINITION>
+// or ADD <TABLE-CONSTRAINT>
+//
+//	|-> add
+//	    |-> column
+//	        |-> if
+//	            |-> not
+//	                |-> exists
+//	        |-> <COLUMN-NAME>
+//	            |-> <TYPE>
+//
+//	|-> add
+//	    |-> <TABLE-CONSTRAINT>
+func (p *parser) parseAlterAdd() (*Decl, error) {
+	if _, err := p.consumeWord("add"); err != nil {
+		return nil, err
+	}
+	addDecl := NewDecl(Token{Token: AddToken, Lexeme: "add"})
+
+	var d *Decl
+	var err error
+	switch p.cur().Token {
+	case ConstraintToken:
+		d, err = p.parseTableConstraint()
+	case PrimaryToken:
+		d, err = p.parsePrimaryKey()
+	case UniqueToken:
+		d, err = p.parseTableUnique()
+	case ForeignToken:
+		d, err = p.parseTableForeignKey()
+	case CheckToken:
+		d, err = p.parseCheck()
+	default:
+		// Optional: COLUMN
+		if p.isWord("column") {
+			p.next()
+		}
+		d = NewDecl(Token{Token: ColumnToken, Lexeme: "column"})
+		// Optional: IF NOT EXISTS
+		if p.is(IfToken) {
+			ifDecl, err := p.parseIfNotExists()
+			if err != nil {
+				return nil, err
+			}
+			d.Add(ifDecl)
+		}
+		var columnDecl *Decl
+		columnDecl, err = p.parseColumn()
+		d.Add(columnDecl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	addDecl.Add(d)
+
+	return addDecl, nil
+}
+
+// parseAlterDrop parses DROP [ COLUMN ] <COLUMN-NAME> or DROP CONSTRAINT <CONSTRAINT-NAME>
+//
+//	|-> drop
+//	    |-> column
+//	        |-> if
+//	            |-> exists
+//	        |-> <COLUMN-NAME>
+//	        |-> cascade
+func (p *parser) parseAlterDrop() (*Decl, error) {
+	dropDecl, err := p.consumeToken(DropToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var d *Decl
+	if p.is(ConstraintToken) {
+		d, err = p.consumeToken(ConstraintToken)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Optional: COLUMN
+		if p.isWord("column") {
+			p.next()
+		}
+		d = NewDecl(Token{Token: ColumnToken, Lexeme: "column"})
+	}
+	dropDecl.Add(d)
+
+	// Optional: IF EXISTS
+	if p.is(IfToken) {
+		ifDecl, err := p.consumeToken(IfToken)
+		if err != nil {
+			return nil, err
+		}
+		existsDecl, err := p.consumeToken(ExistsToken)
+		if err != nil {
+			return nil, err
+		}
+		ifDecl.Add(existsDecl)
+		d.Add(ifDecl)
+	}
+
+	// Required: <NAME>
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	d.Add(nameDecl)
+
+	// Optional: CASCADE | RESTRICT
+	if p.is(CascadeToken, RestrictToken) {
+		optionDecl, err := p.consumeToken(CascadeToken, RestrictToken)
+		if err != nil {
+			return nil, err
+		}
+		d.Add(optionDecl)
+	}
+
+	return dropDecl, nil
+}
+
+// parseAlterRename parses RENAME [ COLUMN ] <COLUMN-NAME> TO <NEW-NAME> or RENAME TO <NEW-NAME>
+//
+//	|-> rename
+//	    |-> column
+//	        |-> <COLUMN-NAME>
+//	        |-> <NEW-COLUMN-NAME>
+//
+//	|-> rename
+//	    |-> to
+//	        |-> <NEW-TABLE-NAME>
+func (p *parser) parseAlterRename() (*Decl, error) {
+	if _, err := p.consumeWord("rename"); err != nil {
+		return nil, err
+	}
+	renameDecl := NewDecl(Token{Token: RenameToken, Lexeme: "rename"})
+
+	// RENAME TO <NEW-TABLE-NAME>
+	if p.isWord("to") {
+		p.next()
+		toDecl := NewDecl(Token{Token: ToToken, Lexeme: "to"})
+		nameDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		toDecl.Add(nameDecl)
+		renameDecl.Add(toDecl)
+		return renameDecl, nil
+	}
+
+	// Optional: COLUMN
+	if p.isWord("column") {
+		p.next()
+	}
+	columnDecl := NewDecl(Token{Token: ColumnToken, Lexeme: "column"})
+	renameDecl.Add(columnDecl)
+
+	// Required: <COLUMN-NAME> TO <NEW-COLUMN-NAME>
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	columnDecl.Add(nameDecl)
+
+	if _, err := p.consumeWord("to"); err != nil {
+		return nil, err
+	}
+
+	newNameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	columnDecl.Add(newNameDecl)
+
+	return renameDecl, nil
+}
+
+// parseAlterColumn parses ALTER [ COLUMN ] <COLUMN-NAME> followed by the
+// column alteration
+//
+//	|-> alter
+//	    |-> <COLUMN-NAME>
+//	        |-> set
+//	            |-> default
+//	                |-> <VALUE>
+//
+//	|-> alter
+//	    |-> <COLUMN-NAME>
+//	        |-> drop
+//	            |-> not
+//	                |-> null
+//
+//	|-> alter
+//	    |-> <COLUMN-NAME>
+//	        |-> type
+//	            |-> <TYPE>
+//	            |-> using
+//	                |-> <EXPRESSION>
+func (p *parser) parseAlterColumn() (*Decl, error) {
+	alterDecl, err := p.consumeToken(AlterToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Optional: COLUMN
+	if p.isWord("column") {
+		p.next()
+	}
+
+	// Required: <COLUMN-NAME>
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	alterDecl.Add(nameDecl)
+
+	// [ SET DATA ] TYPE <TYPE>
+	if p.is(SetToken) && p.hasNext() && strings.EqualFold(p.peekForward().Lexeme, "data") {
+		p.next()
+		p.next()
+	}
+	if p.isWord("type") {
+		p.next()
+		typeDecl := NewDecl(Token{Token: TypeToken, Lexeme: "type"})
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		typeDecl.Add(t)
+		nameDecl.Add(typeDecl)
+
+		// Optional: USING <EXPRESSION>
+		if p.is(UsingToken) {
+			usingDecl, err := p.consumeToken(UsingToken)
+			if err != nil {
+				return nil, err
+			}
+			exprDecl, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			usingDecl.Add(exprDecl)
+			typeDecl.Add(usingDecl)
+		}
+		return alterDecl, nil
+	}
+
+	// Required: SET | DROP
+	d, err := p.consumeToken(SetToken, DropToken)
+	if err != nil {
+		return nil, err
+	}
+	nameDecl.Add(d)
+
+	switch p.cur().Token {
+	case DefaultToken: // { SET | DROP } DEFAULT
+		defaultDecl, err := p.consumeToken(DefaultToken)
+		if err != nil {
+			return nil, err
+		}
+		d.Add(defaultDecl)
+		if d.Token == DropToken {
+			break
+		}
+
+		// Required: <VALUE>
+		if err := p.parseDefault(defaultDecl); err != nil {
+			return nil, err
+		}
+	case NotToken: // { SET | DROP } NOT NULL
+		notDecl, err := p.consumeToken(NotToken)
+		if err != nil {
+			return nil, err
+		}
+		nullDecl, err := p.consumeToken(NullToken)
+		if err != nil {
+			return nil, err
+		}
+		notDecl.Add(nullDecl)
+		d.Add(notDecl)
+	default:
+		return nil, p.syntaxError()
+	}
+
+	return alterDecl, nil
+}
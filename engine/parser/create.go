@@ -13,11 +13,14 @@ func (p *parser) parseCreate() (*Instruction, error) {
 	i.Decls = append(i.Decls, createDecl)
 
 	// After create token, should be either
-	// TABLE
+	// [TEMP | TEMPORARY] TABLE
 	// INDEX
+	// SEQUENCE
+	// [OR REPLACE] VIEW
+	// SCHEMA
 	// ...
 	if !p.hasNext() {
-		return nil, fmt.Errorf("CREATE token must be followed by TABLE, INDEX")
+		return nil, fmt.Errorf("CREATE token must be followed by TABLE, INDEX, SEQUENCE, VIEW, SCHEMA")
 	}
 	p.index++
 
@@ -29,6 +32,37 @@ func (p *parser) parseCreate() (*Instruction, error) {
 		}
 		createDecl.Add(d)
 		break
+	case IndexToken, UniqueToken:
+		d, err := p.parseIndex()
+		if err != nil {
+			return nil, err
+		}
+		createDecl.Add(d)
+	case OrToken:
+		d, err := p.parseView()
+		if err != nil {
+			return nil, err
+		}
+		createDecl.Add(d)
+	case StringToken:
+		var d *Decl
+		var err error
+		switch {
+		case p.isWord("sequence"):
+			d, err = p.parseSequence()
+		case p.isWord("view"):
+			d, err = p.parseView()
+		case p.isWord("schema"):
+			d, err = p.parseSchema()
+		case p.isWord("temp", "temporary"):
+			d, err = p.parseTemporaryTable()
+		default:
+			return nil, fmt.Errorf("Parsing error near <%s>", p.cur().Lexeme)
+		}
+		if err != nil {
+			return nil, err
+		}
+		createDecl.Add(d)
 	default:
 		return nil, fmt.Errorf("Parsing error near <%s>", p.cur().Lexeme)
 	}
@@ -36,6 +70,232 @@ func (p *parser) parseCreate() (*Instruction, error) {
 	return i, nil
 }
 
+// parseIndex processes tokens that should define a standalone index
+// [ UNIQUE ] INDEX [ IF NOT EXISTS ] [ <INDEX-NAME> ] [ USING { BTREE | HASH } ] ON <TABLE-NAME> [ USING { BTREE | HASH } ] '(' <INDEX-KEY> [, ...] ')'
+//
+//	|-> index
+//	    |-> unique
+//	    |-> if
+//	        |-> not
+//	            |-> exists
+//	    |-> <INDEX-NAME>
+//	    |-> on
+//	        |-> <TABLE-NAME>
+//	    |-> using
+//	        |-> { btree | hash }
+//	    |-> key
+//	        |-> <INDEX-KEY>
+func (p *parser) parseIndex() (*Decl, error) {
+	var uniqueDecl *Decl
+	if p.is(UniqueToken) {
+		d, err := p.consumeToken(UniqueToken)
+		if err != nil {
+			return nil, err
+		}
+		uniqueDecl = d
+	}
+
+	// Required: INDEX
+	indexDecl, err := p.consumeToken(IndexToken)
+	if err != nil {
+		return nil, err
+	}
+	if uniqueDecl != nil {
+		indexDecl.Add(uniqueDecl)
+	}
+
+	// Optional: IF NOT EXISTS
+	if p.is(IfToken) {
+		ifDecl, err := p.parseIfNotExists()
+		if err != nil {
+			return nil, err
+		}
+		indexDecl.Add(ifDecl)
+	}
+
+	// Optional: <INDEX-NAME>
+	if p.isNot(OnToken, UsingToken) {
+		nameDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		indexDecl.Add(nameDecl)
+	}
+
+	// Optional: USING { BTREE | HASH }, MySQL style
+	var usingDecl *Decl
+	if p.is(UsingToken) {
+		usingDecl, err = p.parseIndexMethod()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Required: ON <TABLE-NAME>
+	onDecl, err := p.consumeToken(OnToken)
+	if err != nil {
+		return nil, err
+	}
+	tableDecl, err := p.parseTableName()
+	if err != nil {
+		return nil, err
+	}
+	onDecl.Add(tableDecl)
+	indexDecl.Add(onDecl)
+
+	// Optional: USING { BTREE | HASH }
+	if p.is(UsingToken) {
+		usingDecl, err = p.parseIndexMethod()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if usingDecl != nil {
+		indexDecl.Add(usingDecl)
+	}
+
+	// Required: '(' <INDEX-KEY> [, ...] ')'
+	keyDecl, err := p.parseIndexKey()
+	if err != nil {
+		return nil, err
+	}
+	indexDecl.Add(keyDecl)
+
+	return indexDecl, nil
+}
+
+// parseIfNotExists parses IF NOT EXISTS
+//
+//	|-> if
+//	    |-> not
+//	        |-> exists
+func (p *parser) parseIfNotExists() (*Decl, error) {
+	ifDecl, err := p.consumeToken(IfToken)
+	if err != nil {
+		return nil, err
+	}
+	notDecl, err := p.consumeToken(NotToken)
+	if err != nil {
+		return nil, err
+	}
+	existsDecl, err := p.consumeToken(ExistsToken)
+	if err != nil {
+		return nil, err
+	}
+	notDecl.Add(existsDecl)
+	ifDecl.Add(notDecl)
+
+	return ifDecl, nil
+}
+
+// parseTemporaryTable parses a table only visible to the connection
+// creating it
+// { TEMP | TEMPORARY } TABLE <TABLE-DEFINITION> [ ON COMMIT { PRESERVE ROWS | DROP } ]
+//
+//	|-> table
+//	    |-> <TABLE-DEFINITION>
+//	    |-> on
+//	        |-> commit
+//	            |-> { preserve | drop }
+//	    |-> temporary
+func (p *parser) parseTemporaryTable() (*Decl, error) {
+	// Required: TEMP | TEMPORARY
+	if _, err := p.consumeWord("temp", "temporary"); err != nil {
+		return nil, err
+	}
+
+	if !p.is(TableToken) {
+		return nil, p.syntaxError()
+	}
+	tableDecl, err := p.parseTable()
+	if err != nil {
+		return nil, err
+	}
+	tableDecl.Add(NewDecl(Token{Token: TemporaryToken, Lexeme: "temporary"}))
+
+	return tableDecl, nil
+}
+
+// parseOnCommit parses what happens to a temporary table when
+// the transaction ends
+// ON COMMIT { PRESERVE ROWS | DROP }
+//
+//	|-> on
+//	    |-> commit
+//	        |-> { preserve | drop }
+func (p *parser) parseOnCommit() (*Decl, error) {
+	onDecl, err := p.consumeToken(OnToken)
+	if err != nil {
+		return nil, err
+	}
+	commitDecl, err := p.consumeToken(CommitToken)
+	if err != nil {
+		return nil, err
+	}
+	onDecl.Add(commitDecl)
+
+	if p.is(DropToken) {
+		dropDecl, err := p.consumeToken(DropToken)
+		if err != nil {
+			return nil, err
+		}
+		commitDecl.Add(dropDecl)
+		return onDecl, nil
+	}
+
+	preserveDecl, err := p.consumeWord("preserve")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeWord("rows"); err != nil {
+		return nil, err
+	}
+	commitDecl.Add(preserveDecl)
+
+	return onDecl, nil
+}
+
+// parseTableAs parses the query defining the table being created
+// [ ON COMMIT { PRESERVE ROWS | DROP } ] AS <SELECT>
+//
+//	|-> table
+//	    |-> <TABLE-NAME>
+//	    |-> on
+//	        |-> commit
+//	            |-> { preserve | drop }
+//	    |-> as
+//	        |-> select
+func (p *parser) parseTableAs(tableDecl *Decl) (*Decl, error) {
+	if p.is(OnToken) {
+		onDecl, err := p.parseOnCommit()
+		if err != nil {
+			return nil, err
+		}
+		tableDecl.Add(onDecl)
+	}
+
+	asDecl, err := p.consumeToken(AsToken)
+	if err != nil {
+		return nil, err
+	}
+	tableDecl.Add(asDecl)
+
+	if !p.is(SelectToken) {
+		return nil, p.syntaxError()
+	}
+	i, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	asDecl.Add(i.Decls[0])
+
+	if !p.is(SemicolonToken) {
+		return nil, p.syntaxError()
+	}
+
+	return tableDecl, nil
+}
+
 func (p *parser) parseTable() (*Decl, error) {
 	var err error
 	tableDecl := NewDecl(p.cur())
@@ -67,12 +327,17 @@ func (p *parser) parseTable() (*Decl, error) {
 	}
 
 	// Required: <TABLE-NAME>
-	nameTable, err := p.parseAttribute()
+	nameTable, err := p.parseTableName()
 	if err != nil {
 		return nil, p.syntaxError()
 	}
 	tableDecl.Add(nameTable)
 
+	// Optional: [ ON COMMIT ... ] AS <SELECT>, columns being those selected
+	if p.is(OnToken, AsToken) {
+		return p.parseTableAs(tableDecl)
+	}
+
 	// Required: '(' (Opening Parenthesis)
 	if !p.hasNext() || p.cur().Token != BracketOpeningToken {
 		return nil, fmt.Errorf("Table name token must be followed by table definition")
@@ -90,164 +355,59 @@ func (p *parser) parseTable() (*Decl, error) {
 
 		// (CONSTRAINT <CONSTRAINT-NAME>?)? ...
 		if p.cur().Token == ConstraintToken {
-			_, err := p.parseTableConstraint()
+			constraintDecl, err := p.parseTableConstraint()
 			if err != nil {
 				return nil, err
 			}
+			tableDecl.Add(constraintDecl)
 
 			// PRIMARY KEY ( <INDEX-KEY> [, ...] )
 		} else if p.cur().Token == PrimaryToken {
-			_, err := p.parsePrimaryKey()
+			primaryDecl, err := p.parsePrimaryKey()
 			if err != nil {
 				return nil, err
 			}
+			tableDecl.Add(primaryDecl)
 
 			// UNIQUE [INDEX | KEY] ...
 		} else if p.cur().Token == UniqueToken {
-			_, err := p.consumeToken(UniqueToken)
-			if err != nil {
-				return nil, err
-			}
-
-			_, err = p.parseTableIndex()
+			uniqueDecl, err := p.parseTableUnique()
 			if err != nil {
 				return nil, err
 			}
+			tableDecl.Add(uniqueDecl)
 
 			// { INDEX | KEY } [ index_name ] [?:index_type USING { BTREE | HASH } ] '(' { col_name [ '(' length ')' ] | '(' expr ')' } [ ASC | DESC ] ',' ... ')' [?:index_option ... ]
 		} else if p.cur().Token == IndexToken || p.cur().Token == KeyToken {
-			_, err := p.parseTableIndex()
+			indexDecl, err := p.parseTableIndex(false)
 			if err != nil {
 				return nil, err
 			}
+			tableDecl.Add(indexDecl)
 
 			// FOREIGN KEY ...
 		} else if p.cur().Token == ForeignToken {
-			_, err := p.parseTableForeignKey()
+			foreignDecl, err := p.parseTableForeignKey()
 			if err != nil {
 				return nil, err
 			}
+			tableDecl.Add(foreignDecl)
 
-			// <TABLE-ATTRIBUTE>
-		} else {
-			// New attribute name
-			newAttribute, err := p.parseQuotedToken()
+			// CHECK ( <CONDITION> )
+		} else if p.cur().Token == CheckToken {
+			checkDecl, err := p.parseCheck()
 			if err != nil {
 				return nil, err
 			}
-			tableDecl.Add(newAttribute)
+			tableDecl.Add(checkDecl)
 
-			newAttributeType, err := p.parseType()
+			// <TABLE-ATTRIBUTE>
+		} else {
+			newAttribute, err := p.parseColumn()
 			if err != nil {
 				return nil, err
 			}
-			newAttribute.Add(newAttributeType)
-
-			// All the following tokens until bracket or comma are column constraints.
-			// Column constraints can be listed in any order.
-			for p.isNot(BracketClosingToken, CommaToken) {
-				switch p.cur().Token {
-				case UniqueToken: // UNIQUE
-					uniqueDecl, err := p.consumeToken(UniqueToken)
-					if err != nil {
-						return nil, err
-					}
-					newAttribute.Add(uniqueDecl)
-				case NotToken: // NOT NULL
-					if _, err = p.isNext(NullToken); err == nil {
-						notDecl, err := p.consumeToken(NotToken)
-						if err != nil {
-							return nil, err
-						}
-						newAttribute.Add(notDecl)
-						nullDecl, err := p.consumeToken(NullToken)
-						if err != nil {
-							return nil, err
-						}
-						notDecl.Add(nullDecl)
-					}
-				case NullToken: // NULL
-					nullDecl, err := p.consumeToken(NullToken)
-					if err != nil {
-						return nil, err
-					}
-
-					newAttribute.Add(nullDecl)
-				case PrimaryToken: // PRIMARY KEY
-					if _, err = p.isNext(KeyToken); err == nil {
-						newPrimary := NewDecl(p.cur())
-						newAttribute.Add(newPrimary)
-
-						if err = p.next(); err != nil {
-							return nil, fmt.Errorf("Unexpected end")
-						}
-
-						newKey := NewDecl(p.cur())
-						newPrimary.Add(newKey)
-
-						if err = p.next(); err != nil {
-							return nil, fmt.Errorf("Unexpected end")
-						}
-					}
-				case AutoincrementToken:
-					autoincDecl, err := p.consumeToken(AutoincrementToken)
-					if err != nil {
-						return nil, err
-					}
-					newAttribute.Add(autoincDecl)
-				case WithToken: // WITH TIME ZONE
-					if strings.ToLower(newAttributeType.Lexeme) == "timestamp" {
-						withDecl, err := p.consumeToken(WithToken)
-						if err != nil {
-							return nil, err
-						}
-						timeDecl, err := p.consumeToken(TimeToken)
-						if err != nil {
-							return nil, err
-						}
-						zoneDecl, err := p.consumeToken(ZoneToken)
-						if err != nil {
-							return nil, err
-						}
-						newAttributeType.Add(withDecl)
-						withDecl.Add(timeDecl)
-						timeDecl.Add(zoneDecl)
-					}
-				case DefaultToken: // DEFAULT <VALUE>
-					defaultDecl, err := p.consumeToken(DefaultToken)
-					if err != nil {
-						return nil, err
-					}
-					newAttribute.Add(defaultDecl)
-					valueDecl, err := p.consumeToken(FalseToken, StringToken, NumberToken, LocalTimestampToken, NullToken)
-					if err != nil {
-						return nil, err
-					}
-					defaultDecl.Add(valueDecl)
-				case OnToken: // ON UPDATE <VALUE>
-					onDecl, err := p.consumeToken(OnToken)
-					if err != nil {
-						return nil, err
-					}
-
-					updateDecl, err := p.consumeToken(UpdateToken)
-					if err != nil {
-						return nil, err
-					}
-
-					vDecl, err := p.consumeToken(FalseToken, StringToken, NumberToken, LocalTimestampToken)
-					if err != nil {
-						return nil, err
-					}
-
-					onDecl.Add(updateDecl)
-					updateDecl.Add(vDecl)
-					newAttribute.Add(onDecl)
-				default:
-					// Unknown column constraint
-					return nil, p.syntaxError()
-				}
-			}
+			tableDecl.Add(newAttribute)
 		}
 
 		// Comma means continue to next table column
@@ -387,6 +547,13 @@ tableOptions:
 			setDecl.Add(vDecl)
 			// TODO: tableDecl.Add(charDecl)
 
+		case OnToken: // ON COMMIT { PRESERVE ROWS | DROP }
+			onDecl, err := p.parseOnCommit()
+			if err != nil {
+				return nil, err
+			}
+			tableDecl.Add(onDecl)
+
 		case SemicolonToken: // semicolon means end of instruction
 			// Important NOT to consume the semicolon token
 
@@ -400,6 +567,253 @@ tableOptions:
 	return tableDecl, nil
 }
 
+// parseColumn processes tokens that should define a table column
+// <COLUMN-NAME> <TYPE> [ <COLUMN-CONSTRAINT> ]*
+func (p *parser) parseColumn() (*Decl, error) {
+	// New attribute name
+	newAttribute, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	newAttributeType, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	newAttribute.Add(newAttributeType)
+
+	// All the following tokens until bracket or comma are column constraints.
+	// Column constraints can be listed in any order.
+	for p.isNot(BracketClosingToken, CommaToken, SemicolonToken) {
+		switch p.cur().Token {
+		case UniqueToken: // UNIQUE
+			uniqueDecl, err := p.consumeToken(UniqueToken)
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(uniqueDecl)
+		case NotToken: // NOT NULL
+			if _, err = p.isNext(NullToken); err == nil {
+				notDecl, err := p.consumeToken(NotToken)
+				if err != nil {
+					return nil, err
+				}
+				newAttribute.Add(notDecl)
+				nullDecl, err := p.consumeToken(NullToken)
+				if err != nil {
+					return nil, err
+				}
+				notDecl.Add(nullDecl)
+			}
+		case NullToken: // NULL
+			nullDecl, err := p.consumeToken(NullToken)
+			if err != nil {
+				return nil, err
+			}
+
+			newAttribute.Add(nullDecl)
+		case PrimaryToken: // PRIMARY KEY
+			if _, err = p.isNext(KeyToken); err == nil {
+				newPrimary := NewDecl(p.cur())
+				newAttribute.Add(newPrimary)
+
+				if err = p.next(); err != nil {
+					return nil, fmt.Errorf("Unexpected end")
+				}
+
+				newKey := NewDecl(p.cur())
+				newPrimary.Add(newKey)
+
+				if err = p.next(); err != nil {
+					return nil, fmt.Errorf("Unexpected end")
+				}
+			}
+		case CheckToken: // CHECK ( <CONDITION> )
+			checkDecl, err := p.parseCheck()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(checkDecl)
+		case ReferencesToken: // REFERENCES <TABLE-NAME> ...
+			referencesDecl, err := p.parseTableReference()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(referencesDecl)
+		case ConstraintToken: // CONSTRAINT <CONSTRAINT-NAME> ...
+			constraintDecl, err := p.parseColumnConstraint()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(constraintDecl)
+		case AutoincrementToken:
+			autoincDecl, err := p.consumeToken(AutoincrementToken)
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(autoincDecl)
+		case WithToken: // WITH TIME ZONE
+			if strings.ToLower(newAttributeType.Lexeme) == "timestamp" {
+				withDecl, err := p.consumeToken(WithToken)
+				if err != nil {
+					return nil, err
+				}
+				timeDecl, err := p.consumeToken(TimeToken)
+				if err != nil {
+					return nil, err
+				}
+				zoneDecl, err := p.consumeToken(ZoneToken)
+				if err != nil {
+					return nil, err
+				}
+				newAttributeType.Add(withDecl)
+				withDecl.Add(timeDecl)
+				timeDecl.Add(zoneDecl)
+			}
+		case DefaultToken: // DEFAULT <VALUE>
+			defaultDecl, err := p.consumeToken(DefaultToken)
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(defaultDecl)
+			if err := p.parseDefault(defaultDecl); err != nil {
+				return nil, err
+			}
+		case OnToken: // ON UPDATE <VALUE>
+			onDecl, err := p.consumeToken(OnToken)
+			if err != nil {
+				return nil, err
+			}
+
+			updateDecl, err := p.consumeToken(UpdateToken)
+			if err != nil {
+				return nil, err
+			}
+
+			vDecl, err := p.consumeToken(FalseToken, StringToken, NumberToken, LocalTimestampToken)
+			if err != nil {
+				return nil, err
+			}
+
+			onDecl.Add(updateDecl)
+			updateDecl.Add(vDecl)
+			newAttribute.Add(onDecl)
+		case StringToken: // GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY
+			if !p.isWord("generated") {
+				return nil, p.syntaxError()
+			}
+			generatedDecl, err := p.parseIdentity()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(generatedDecl)
+		default:
+			// Unknown column constraint
+			return nil, p.syntaxError()
+		}
+	}
+
+	return newAttribute, nil
+}
+
+// parseColumnConstraint processes tokens that should define a named
+// column constraint
+// CONSTRAINT <CONSTRAINT-NAME> { CHECK | NOT NULL | UNIQUE | PRIMARY KEY | REFERENCES } ...
+//
+//	|-> constraint
+//	    |-> <CONSTRAINT-NAME>
+//	    |-> check
+//	        |-> <CONDITION>
+func (p *parser) parseColumnConstraint() (*Decl, error) {
+	constraintDecl, err := p.consumeToken(ConstraintToken)
+	if err != nil {
+		return nil, err
+	}
+
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	constraintDecl.Add(nameDecl)
+
+	switch p.cur().Token {
+	case CheckToken:
+		checkDecl, err := p.parseCheck()
+		if err != nil {
+			return nil, err
+		}
+		constraintDecl.Add(checkDecl)
+	case NotToken:
+		notDecl, err := p.consumeToken(NotToken)
+		if err != nil {
+			return nil, err
+		}
+		nullDecl, err := p.consumeToken(NullToken)
+		if err != nil {
+			return nil, err
+		}
+		notDecl.Add(nullDecl)
+		constraintDecl.Add(notDecl)
+	case UniqueToken:
+		uniqueDecl, err := p.consumeToken(UniqueToken)
+		if err != nil {
+			return nil, err
+		}
+		constraintDecl.Add(uniqueDecl)
+	case PrimaryToken:
+		primaryDecl, err := p.consumeToken(PrimaryToken)
+		if err != nil {
+			return nil, err
+		}
+		keyDecl, err := p.consumeToken(KeyToken)
+		if err != nil {
+			return nil, err
+		}
+		primaryDecl.Add(keyDecl)
+		constraintDecl.Add(primaryDecl)
+	case ReferencesToken:
+		referencesDecl, err := p.parseTableReference()
+		if err != nil {
+			return nil, err
+		}
+		constraintDecl.Add(referencesDecl)
+	default:
+		// Unknown column constraint
+		return nil, p.syntaxError()
+	}
+
+	return constraintDecl, nil
+}
+
+// parseDefault parses the value of a DEFAULT clause into defaultDecl:
+// a literal, quoted or not, or a call to a sequence function
+func (p *parser) parseDefault(defaultDecl *Decl) error {
+	if p.isSequenceFunc() {
+		funcDecl, err := p.parseSequenceFunc()
+		if err != nil {
+			return err
+		}
+		defaultDecl.Add(funcDecl)
+		return nil
+	}
+
+	if p.is(SimpleQuoteToken) {
+		valueDecl, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		defaultDecl.Add(valueDecl)
+		return nil
+	}
+
+	valueDecl, err := p.consumeToken(FalseToken, TrueToken, StringToken, NumberToken, LocalTimestampToken, NowToken, NullToken)
+	if err != nil {
+		return err
+	}
+	defaultDecl.Add(valueDecl)
+	return nil
+}
+
 // parseTableConstraint processes tokens that should define a table constraint
 // CONSTRAINT <CONSTRAINT-NAME>? ...
 func (p *parser) parseTableConstraint() (*Decl, error) {
@@ -410,33 +824,38 @@ func (p *parser) parseTableConstraint() (*Decl, error) {
 
 	// Optional: <CONSTRAINT-NAME>
 	if p.is(StringToken) {
-		_, err := p.consumeToken(StringToken)
+		nameDecl, err := p.consumeToken(StringToken)
 		if err != nil {
 			return nil, err
 		}
+		constraintDecl.Add(nameDecl)
 	}
 
 	switch p.cur().Token {
 	case PrimaryToken:
-		_, err := p.parsePrimaryKey()
+		primaryDecl, err := p.parsePrimaryKey()
 		if err != nil {
 			return nil, err
 		}
+		constraintDecl.Add(primaryDecl)
 	case UniqueToken:
-		_, err := p.consumeToken(UniqueToken)
+		uniqueDecl, err := p.parseTableUnique()
 		if err != nil {
 			return nil, err
 		}
-
-		_, err = p.parseTableIndex()
+		constraintDecl.Add(uniqueDecl)
+	case ForeignToken:
+		foreignDecl, err := p.parseTableForeignKey()
 		if err != nil {
 			return nil, err
 		}
-	case ForeignToken:
-		_, err := p.parseTableForeignKey()
+		constraintDecl.Add(foreignDecl)
+	case CheckToken:
+		checkDecl, err := p.parseCheck()
 		if err != nil {
 			return nil, err
 		}
+		constraintDecl.Add(checkDecl)
 	default:
 		// Unknown constraint type
 		return nil, p.syntaxError()
@@ -445,6 +864,38 @@ func (p *parser) parseTableConstraint() (*Decl, error) {
 	return constraintDecl, nil
 }
 
+// parseCheck processes tokens that should define a check constraint
+// CHECK '(' <CONDITION> ')'
+//
+//	|-> check
+//	    |-> <CONDITION>
+func (p *parser) parseCheck() (*Decl, error) {
+	checkDecl, err := p.consumeToken(CheckToken)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consumeToken(BracketOpeningToken)
+	if err != nil {
+		return nil, err
+	}
+
+	conditionDecl, err := p.parseSearchCondition()
+	if err != nil {
+		return nil, err
+	}
+	checkDecl.Add(conditionDecl)
+
+	_, err = p.consumeToken(BracketClosingToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkDecl, nil
+}
+
+// parsePrimaryKey processes tokens that should define a table primary key
+// PRIMARY KEY '(' <INDEX-KEY> [, <INDEX-KEY>]* ')'
 func (p *parser) parsePrimaryKey() (*Decl, error) {
 	primaryDecl, err := p.consumeToken(PrimaryToken)
 	if err != nil {
@@ -467,6 +918,7 @@ func (p *parser) parsePrimaryKey() (*Decl, error) {
 		if err != nil {
 			return nil, err
 		}
+		keyDecl.Add(d)
 
 		d, err = p.consumeToken(CommaToken, BracketClosingToken)
 		if err != nil {
@@ -480,9 +932,34 @@ func (p *parser) parsePrimaryKey() (*Decl, error) {
 	return primaryDecl, nil
 }
 
+// parseTableUnique processes tokens that should define a table unique constraint
+// UNIQUE [ INDEX | KEY ] [ index_name ] ... '(' <INDEX-KEY> [, ...] ')'
+func (p *parser) parseTableUnique() (*Decl, error) {
+	uniqueDecl, err := p.consumeToken(UniqueToken)
+	if err != nil {
+		return nil, err
+	}
+
+	indexDecl, err := p.parseTableIndex(true)
+	if err != nil {
+		return nil, err
+	}
+	uniqueDecl.Add(indexDecl)
+
+	return uniqueDecl, nil
+}
+
 // parseTableIndex processes tokens that should define a table index
 // { INDEX | KEY } [ index_name ] [?:index_type USING { BTREE | HASH } ] '(' { col_name [ '(' length ')' ] | '(' expr ')' } [ ASC | DESC ] ',' ... ')' [?:index_option ... ]
-func (p *parser) parseTableIndex() (*Decl, error) {
+// INDEX or KEY is optional after UNIQUE.
+//
+//	|-> index
+//	    |-> <INDEX-NAME>
+//	    |-> using
+//	        |-> { btree | hash }
+//	    |-> key
+//	        |-> <INDEX-KEY>
+func (p *parser) parseTableIndex(unique bool) (*Decl, error) {
 	indexDecl := NewDecl(Token{Token: IndexToken, Lexeme: "index"})
 
 	// Required: { INDEX | KEY }
@@ -493,52 +970,80 @@ func (p *parser) parseTableIndex() (*Decl, error) {
 			return nil, err
 		}
 	default:
-		return nil, fmt.Errorf("Table INDEX definition must start with INDEX or KEY")
+		if !unique {
+			return nil, fmt.Errorf("Table INDEX definition must start with INDEX or KEY")
+		}
 	}
 
 	// Optional: <INDEX-NAME>
 	if p.is(StringToken) {
-		_, err := p.consumeToken(StringToken)
+		nameDecl, err := p.consumeToken(StringToken)
 		if err != nil {
 			return nil, err
 		}
+		indexDecl.Add(nameDecl)
 	}
 
 	// Optional: <INDEX-TYPE> := USING { BTREE | HASH }
 	if p.is(UsingToken) {
-		_, err := p.consumeToken(UsingToken)
+		usingDecl, err := p.parseIndexMethod()
 		if err != nil {
 			return nil, err
 		}
+		indexDecl.Add(usingDecl)
+	}
 
-		if p.is(BtreeToken) {
-			_, err := p.consumeToken(BtreeToken)
-			if err != nil {
-				return nil, err
-			}
-		} else if p.is(HashToken) {
-			_, err := p.consumeToken(HashToken)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, p.syntaxError()
-		}
+	keyDecl, err := p.parseIndexKey()
+	if err != nil {
+		return nil, err
+	}
+	indexDecl.Add(keyDecl)
+
+	return indexDecl, nil
+}
+
+// parseIndexMethod processes tokens that should define an index type
+// USING { BTREE | HASH }
+//
+//	|-> using
+//	    |-> { btree | hash }
+func (p *parser) parseIndexMethod() (*Decl, error) {
+	usingDecl, err := p.consumeToken(UsingToken)
+	if err != nil {
+		return nil, err
 	}
 
+	methodDecl, err := p.consumeToken(BtreeToken, HashToken)
+	if err != nil {
+		return nil, err
+	}
+	usingDecl.Add(methodDecl)
+
+	return usingDecl, nil
+}
+
+// parseIndexKey processes tokens that should define the attributes of an index
+// '(' <INDEX-KEY> [ ASC | DESC ] [, <INDEX-KEY> [ ASC | DESC ] ]* ')'
+//
+//	|-> key
+//	    |-> <INDEX-KEY>
+func (p *parser) parseIndexKey() (*Decl, error) {
 	// Required: '('
 	_, err := p.consumeToken(BracketOpeningToken)
 	if err != nil {
 		return nil, err
 	}
 
+	keyDecl := NewDecl(Token{Token: KeyToken, Lexeme: "key"})
+
 	// Required: <INDEX-KEY> [ ASC | DESC ] [, <INDEX-KEY> [ ASC | DESC ] ]* ')'
 	for {
 		// Required: <INDEX-KEY>
-		_, err := p.consumeToken(StringToken)
+		d, err := p.parseQuotedToken()
 		if err != nil {
 			return nil, err
 		}
+		keyDecl.Add(d)
 
 		// Optional: 'ASC' | 'DESC'
 		if p.is(AscToken) {
@@ -563,11 +1068,16 @@ func (p *parser) parseTableIndex() (*Decl, error) {
 		}
 	}
 
-	return indexDecl, nil
+	return keyDecl, nil
 }
 
 // parseTableForeignKey processes tokens that should define a table foreign key
 // FOREIGN KEY ...
+//
+//	|-> foreign
+//	    |-> key
+//	        |-> <FK-INDEX>
+//	    |-> references
 func (p *parser) parseTableForeignKey() (*Decl, error) {
 	// Required: FOREIGN
 	foreignDecl, err := p.consumeToken(ForeignToken)
@@ -598,10 +1108,11 @@ func (p *parser) parseTableForeignKey() (*Decl, error) {
 
 	// Required: <FK-INDEX> [, <FK-INDEX>]* ')'
 	for {
-		_, err := p.consumeToken(StringToken)
+		d, err := p.parseQuotedToken()
 		if err != nil {
 			return nil, err
 		}
+		keyDecl.Add(d)
 
 		n, err := p.consumeToken(CommaToken, BracketClosingToken)
 		if err != nil {
@@ -614,10 +1125,11 @@ func (p *parser) parseTableForeignKey() (*Decl, error) {
 
 	// Optional: REFERENCES ...
 	if p.is(ReferencesToken) {
-		_, err := p.parseTableReference()
+		referencesDecl, err := p.parseTableReference()
 		if err != nil {
 			return nil, err
 		}
+		foreignDecl.Add(referencesDecl)
 	}
 
 	return foreignDecl, nil
@@ -625,6 +1137,14 @@ func (p *parser) parseTableForeignKey() (*Decl, error) {
 
 // parseTableReference processes tokens that should define a table reference
 // REFERENCES ...
+// Referenced columns default to the primary key of referenced table.
+//
+//	|-> references
+//	    |-> <TABLE-NAME>
+//	        |-> <KEY-PART>
+//	    |-> on
+//	        |-> delete
+//	            |-> <REFERENCE-OPTION>
 func (p *parser) parseTableReference() (*Decl, error) {
 	// Required: REFERENCES
 	referencesDecl, err := p.consumeToken(ReferencesToken)
@@ -633,30 +1153,33 @@ func (p *parser) parseTableReference() (*Decl, error) {
 	}
 
 	// Required: <TABLE-NAME>
-	_, err = p.consumeToken(StringToken)
+	tableDecl, err := p.parseTableName()
 	if err != nil {
 		return nil, err
 	}
+	referencesDecl.Add(tableDecl)
 
-	// Required: '('
-	_, err = p.consumeToken(BracketOpeningToken)
-	if err != nil {
-		return nil, err
-	}
-
-	// Required: <KEY-PART> [, <KEY-PART>]* ')'
-	for {
-		_, err := p.consumeToken(StringToken)
+	// Optional: '(' <KEY-PART> [, <KEY-PART>]* ')'
+	if p.is(BracketOpeningToken) {
+		_, err = p.consumeToken(BracketOpeningToken)
 		if err != nil {
 			return nil, err
 		}
 
-		n, err := p.consumeToken(CommaToken, BracketClosingToken)
-		if err != nil {
-			return nil, err
-		}
-		if n.Token == BracketClosingToken {
-			break
+		for {
+			d, err := p.parseQuotedToken()
+			if err != nil {
+				return nil, err
+			}
+			tableDecl.Add(d)
+
+			n, err := p.consumeToken(CommaToken, BracketClosingToken)
+			if err != nil {
+				return nil, err
+			}
+			if n.Token == BracketClosingToken {
+				break
+			}
 		}
 	}
 
@@ -693,33 +1216,38 @@ func (p *parser) parseTableReference() (*Decl, error) {
 	if p.is(OnToken) {
 		for {
 			// Required: ON
-			_, err := p.consumeToken(OnToken)
+			onDecl, err := p.consumeToken(OnToken)
 			if err != nil {
 				return nil, err
 			}
+			referencesDecl.Add(onDecl)
 
 			// Required: (UPDATE | DELETE) <REFERENCE-OPTION>
 			switch p.cur().Token {
 			case UpdateToken:
-				_, err := p.consumeToken(UpdateToken)
+				updateDecl, err := p.consumeToken(UpdateToken)
 				if err != nil {
 					return nil, err
 				}
+				onDecl.Add(updateDecl)
 
-				_, err = p.parseTableReferenceOption()
+				optionDecl, err := p.parseTableReferenceOption()
 				if err != nil {
 					return nil, err
 				}
+				updateDecl.Add(optionDecl)
 			case DeleteToken:
-				_, err := p.consumeToken(DeleteToken)
+				deleteDecl, err := p.consumeToken(DeleteToken)
 				if err != nil {
 					return nil, err
 				}
+				onDecl.Add(deleteDecl)
 
-				_, err = p.parseTableReferenceOption()
+				optionDecl, err := p.parseTableReferenceOption()
 				if err != nil {
 					return nil, err
 				}
+				deleteDecl.Add(optionDecl)
 			default:
 				// Unknown on reference option type
 				return nil, p.syntaxError()
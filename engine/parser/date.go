@@ -8,6 +8,12 @@ import (
 // DateLongFormat is same as time.RFC3339Nano
 const DateLongFormat = time.RFC3339Nano
 
+// DateTimeFormat is the SQL timestamp format without time zone
+const DateTimeFormat = "2006-01-02 15:04:05.999999999"
+
+// DateTimeZoneFormat is the SQL timestamp format with numeric time zone
+const DateTimeZoneFormat = "2006-01-02 15:04:05.999999999-07"
+
 // DateShortFormat is a short date format with human-readable month element
 const DateShortFormat = "2006-Jan-02"
 
@@ -31,6 +37,16 @@ func ParseDate(data string) (*time.Time, error) {
 		return &t, nil
 	}
 
+	t, err = time.Parse(DateTimeFormat, data)
+	if err == nil {
+		return &t, nil
+	}
+
+	t, err = time.Parse(DateTimeZoneFormat, data)
+	if err == nil {
+		return &t, nil
+	}
+
 	t, err = time.Parse(DateShortFormat, data)
 	if err == nil {
 		return &t, nil
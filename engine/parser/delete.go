@@ -1,5 +1,18 @@
 package parser
 
+// parseDelete parses DELETE, whose rows may be joined with those of other
+// tables
+// DELETE FROM <TABLE-NAME> [ [AS] <ALIAS> ] [ USING <TABLE-NAME> [, ...] ] [ WHERE ... ] [ RETURNING ... ]
+//
+//	|-> delete
+//	    |-> from
+//	        |-> <TABLE-NAME>
+//	            |-> as
+//	                |-> <ALIAS>
+//	    |-> using
+//	        |-> <TABLE-NAME>
+//	    |-> where
+//	    |-> returning
 func (p *parser) parseDelete() (*Instruction, error) {
 	i := &Instruction{}
 
@@ -17,11 +30,14 @@ func (p *parser) parseDelete() (*Instruction, error) {
 	}
 	deleteDecl.Add(fromDecl)
 
-	// Should be a table name
-	nameDecl, err := p.parseQuotedToken()
+	// Should be a table name, maybe aliased
+	nameDecl, err := p.parseTableName()
 	if err != nil {
 		return nil, err
 	}
+	if err := p.parseTableAlias(nameDecl); err != nil {
+		return nil, err
+	}
 	fromDecl.Add(nameDecl)
 
 	// MAY be WHERE  here
@@ -30,9 +46,34 @@ func (p *parser) parseDelete() (*Instruction, error) {
 		return i, nil
 	}
 
-	err = p.parseWhere(deleteDecl)
-	if err != nil {
-		return nil, err
+	// Optional: USING <TABLE-NAME> [, ...], rows are deleted if they join
+	// those of other tables
+	if p.is(UsingToken) {
+		usingDecl, err := p.parseTableList(UsingToken)
+		if err != nil {
+			return nil, err
+		}
+		deleteDecl.Add(usingDecl)
+		if p.is(WhereToken) {
+			if err := p.parseWhereCondition(deleteDecl); err != nil {
+				return nil, err
+			}
+		}
+	} else if p.is(ReturningToken) {
+		// Every row is deleted one by one if their values are returned
+		addImplicitWhereAll(deleteDecl)
+	} else {
+		err = p.parseWhere(deleteDecl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Optional: RETURNING ...
+	if p.is(ReturningToken) {
+		if err := p.parseReturning(deleteDecl); err != nil {
+			return nil, err
+		}
 	}
 
 	return i, nil
@@ -15,11 +15,23 @@ func (p *parser) parseDrop() (*Instruction, error) {
 	}
 	i.Decls = append(i.Decls, dropDecl)
 
-	// Required: TABLE
-	tableDecl, err := p.consumeToken(TableToken)
-	if err != nil {
-		log.Debug("Consume table !\n")
-		return nil, err
+	// Required: TABLE | INDEX | SEQUENCE | VIEW | SCHEMA
+	var tableDecl *Decl
+	if p.isWord("schema") {
+		tableDecl = NewDecl(Token{Token: SchemaToken, Lexeme: "schema"})
+		p.next()
+	} else if p.isWord("sequence") {
+		tableDecl = NewDecl(Token{Token: SequenceToken, Lexeme: "sequence"})
+		p.next()
+	} else if p.isWord("view") {
+		tableDecl = NewDecl(Token{Token: ViewToken, Lexeme: "view"})
+		p.next()
+	} else {
+		tableDecl, err = p.consumeToken(TableToken, IndexToken)
+		if err != nil {
+			log.Debug("Consume table !\n")
+			return nil, err
+		}
 	}
 	dropDecl.Add(tableDecl)
 
@@ -43,13 +55,36 @@ func (p *parser) parseDrop() (*Instruction, error) {
 		ifDecl.Add(existsDecl)
 	}
 
-	// Required: <TABLE-NAME>
-	nameDecl, err := p.parseQuotedToken()
+	// Required: <TABLE-NAME> | <INDEX-NAME> | <SEQUENCE-NAME> | <VIEW-NAME> | <SCHEMA-NAME>
+	nameDecl, err := p.parseTableName()
 	if err != nil {
 		log.Debug("UH ?\n")
 		return nil, err
 	}
 	tableDecl.Add(nameDecl)
 
+	// Optional: ON <TABLE-NAME>, MySQL style index table
+	if tableDecl.Token == IndexToken && p.is(OnToken) {
+		onDecl, err := p.consumeToken(OnToken)
+		if err != nil {
+			return nil, err
+		}
+		onTableDecl, err := p.parseTableName()
+		if err != nil {
+			return nil, err
+		}
+		onDecl.Add(onTableDecl)
+		tableDecl.Add(onDecl)
+	}
+
+	// Optional: CASCADE | RESTRICT
+	if p.is(CascadeToken) || p.is(RestrictToken) {
+		d, err := p.consumeToken(CascadeToken, RestrictToken)
+		if err != nil {
+			return nil, err
+		}
+		tableDecl.Add(d)
+	}
+
 	return i, nil
 }
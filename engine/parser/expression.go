@@ -0,0 +1,448 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseExpression parses a value computed from the columns of a row, i.e
+// price * quantity. Operators are left associative, multiplicative ones
+// binding tighter than additive ones and concatenation.
+// <TERM> [ { + | - | '||' } <TERM> ...]
+//
+//	|-> { + | - | || }
+//	    |-> <EXPRESSION>
+//	    |-> <EXPRESSION>
+func (p *parser) parseExpression() (*Decl, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is(PlusToken, MinusToken, ConcatToken) {
+		opDecl, err := p.consumeToken(PlusToken, MinusToken, ConcatToken)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		opDecl.Add(left)
+		opDecl.Add(right)
+		left = opDecl
+	}
+
+	return left, nil
+}
+
+// parseTerm parses the operands of additive operators
+// <FACTOR> [ { * | / | % } <FACTOR> ...]
+func (p *parser) parseTerm() (*Decl, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is(StarToken, SlashToken, PercentToken) {
+		opDecl, err := p.consumeToken(StarToken, SlashToken, PercentToken)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		opDecl.Add(left)
+		opDecl.Add(right)
+		left = opDecl
+	}
+
+	return left, nil
+}
+
+// parseFactor parses the operands of multiplicative operators: a value, a
+// column, a function call, a CASE, a scalar subquery, the VALUES() of an
+// upsert or a parenthesized expression, possibly negated
+//
+//	|-> -
+//	    |-> <EXPRESSION>
+//
+//	|-> column
+//	    |-> <TABLE-NAME>
+func (p *parser) parseFactor() (*Decl, error) {
+	switch {
+	case p.is(BracketOpeningToken):
+		p.next()
+		// Scalar subquery
+		if p.is(SelectToken) {
+			selectInstruction, err := p.parseSelect()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consumeToken(BracketClosingToken); err != nil {
+				return nil, err
+			}
+			return selectInstruction.Decls[0], nil
+		}
+		exprDecl, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+		return exprDecl, nil
+	case p.is(MinusToken, PlusToken):
+		signDecl, err := p.consumeToken(MinusToken, PlusToken)
+		if err != nil {
+			return nil, err
+		}
+		exprDecl, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		signDecl.Add(exprDecl)
+		return signDecl, nil
+	case p.is(SimpleQuoteToken):
+		return p.parseValue()
+	case p.is(NumberToken, TrueToken, FalseToken, NowToken, LocalTimestampToken, NullToken, ParameterToken):
+		return p.consumeToken(NumberToken, TrueToken, FalseToken, NowToken, LocalTimestampToken, NullToken, ParameterToken)
+	case p.isWord("true"):
+		// TRUE is not a keyword for the lexer
+		trueDecl, err := p.consumeWord("true")
+		if err != nil {
+			return nil, err
+		}
+		trueDecl.Token = TrueToken
+		return trueDecl, nil
+	case p.isWord("case"):
+		return p.parseCase()
+	case p.is(ValuesToken):
+		return p.parseExcludedValue()
+	case p.is(StringToken) && p.hasNext() && p.peekForward().Token == BracketOpeningToken:
+		return p.parseFunction()
+	}
+
+	return p.parseColumnRef()
+}
+
+// parseColumnRef parses a column reference, which may be qualified by its
+// table name, or all columns of a table
+// [ [ <SCHEMA-NAME> '.' ] <TABLE-NAME> '.' ] { <COLUMN-NAME> | * }
+func (p *parser) parseColumnRef() (*Decl, error) {
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{nameDecl.Lexeme}
+
+	for p.is(PeriodToken) {
+		p.next()
+		if p.is(StarToken) {
+			starDecl, err := p.consumeToken(StarToken)
+			if err != nil {
+				return nil, err
+			}
+			starDecl.Add(NewDecl(Token{Token: StringToken, Lexeme: strings.Join(names, ".")}))
+			return starDecl, nil
+		}
+		nameDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, nameDecl.Lexeme)
+	}
+
+	columnDecl := NewDecl(Token{Token: ColumnToken, Lexeme: names[len(names)-1]})
+	if len(names) > 1 {
+		columnDecl.Add(NewDecl(Token{Token: StringToken, Lexeme: strings.Join(names[:len(names)-1], ".")}))
+	}
+
+	return columnDecl, nil
+}
+
+// parseFunction parses the call of a scalar function, i.e lower(name)
+// <FUNCTION-NAME> '(' [ <EXPRESSION> [, ...] ] ')'
+//
+//	|-> <FUNCTION-NAME>
+//	    |-> <EXPRESSION>
+func (p *parser) parseFunction() (*Decl, error) {
+	nameDecl, err := p.consumeToken(StringToken)
+	if err != nil {
+		return nil, err
+	}
+	funcDecl := NewDecl(Token{Token: FunctionToken, Lexeme: strings.ToLower(nameDecl.Lexeme)})
+
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	for p.isNot(BracketClosingToken) {
+		argDecl, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		funcDecl.Add(argDecl)
+
+		if !p.is(CommaToken) {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+
+	return funcDecl, nil
+}
+
+// parseCase parses a CASE expression. The simple form, comparing a value
+// to those of each WHEN, is rewritten as the searched one.
+// CASE [ <EXPRESSION> ] WHEN <CONDITION> THEN <EXPRESSION> [...] [ ELSE <EXPRESSION> ] END
+//
+//	|-> case
+//	    |-> when
+//	        |-> <CONDITION>
+//	        |-> <EXPRESSION>
+//	    |-> else
+//	        |-> <EXPRESSION>
+func (p *parser) parseCase() (*Decl, error) {
+	caseDecl, err := p.consumeWord("case")
+	if err != nil {
+		return nil, err
+	}
+	caseDecl.Token = CaseToken
+
+	// Optional: value compared
+	var valueDecl *Decl
+	if !p.isWord("when") {
+		valueDecl, err = p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for p.isWord("when") {
+		whenDecl, err := p.consumeWord("when")
+		if err != nil {
+			return nil, err
+		}
+		whenDecl.Token = WhenToken
+
+		var condDecl *Decl
+		if valueDecl != nil {
+			otherDecl, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			condDecl = NewDecl(Token{Token: EqualityToken, Lexeme: "="})
+			condDecl.Add(valueDecl)
+			condDecl.Add(otherDecl)
+		} else {
+			condDecl, err = p.parseSearchCondition()
+			if err != nil {
+				return nil, err
+			}
+		}
+		whenDecl.Add(condDecl)
+
+		if _, err := p.consumeWord("then"); err != nil {
+			return nil, err
+		}
+		resultDecl, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		whenDecl.Add(resultDecl)
+		caseDecl.Add(whenDecl)
+	}
+	if len(caseDecl.Decl) == 0 {
+		return nil, p.syntaxError()
+	}
+
+	// Optional: ELSE
+	if p.isWord("else") {
+		elseDecl, err := p.consumeWord("else")
+		if err != nil {
+			return nil, err
+		}
+		elseDecl.Token = ElseToken
+		resultDecl, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		elseDecl.Add(resultDecl)
+		caseDecl.Add(elseDecl)
+	}
+
+	// Required: END
+	if _, err := p.consumeWord("end"); err != nil {
+		return nil, err
+	}
+
+	return caseDecl, nil
+}
+
+// parseSearchCondition parses a condition on expressions, AND binding
+// tighter than OR
+// <PREDICATE> [ { AND | OR } <PREDICATE> ...]
+//
+//	|-> { and | or }
+//	    |-> <CONDITION>
+//	    |-> <CONDITION>
+func (p *parser) parseSearchCondition() (*Decl, error) {
+	left, err := p.parseConjunction()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is(OrToken) {
+		orDecl, err := p.consumeToken(OrToken)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.parseConjunction()
+		if err != nil {
+			return nil, err
+		}
+		orDecl.Add(left)
+		orDecl.Add(right)
+		left = orDecl
+	}
+
+	return left, nil
+}
+
+// parseConjunction parses the operands of OR
+func (p *parser) parseConjunction() (*Decl, error) {
+	left, err := p.parsePredicate()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.is(AndToken) {
+		andDecl, err := p.consumeToken(AndToken)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		andDecl.Add(left)
+		andDecl.Add(right)
+		left = andDecl
+	}
+
+	return left, nil
+}
+
+// parsePredicate parses a comparison of expressions, a NULL test, a list
+// membership test or a boolean expression, possibly negated
+// [ NOT ] <EXPRESSION> [ { = | < | > | <= | >= } <EXPRESSION> | IS [ NOT ] NULL | [ NOT ] IN '(' <EXPRESSION> [, ...] ')' ]
+//
+//	|-> { = | < | > | <= | >= }
+//	    |-> <EXPRESSION>
+//	    |-> <EXPRESSION>
+//
+//	|-> in
+//	    |-> <EXPRESSION>
+//	    |-> <EXPRESSION>
+//	    |-> ...
+//
+//	|-> not
+//	    |-> is
+//	        |-> <EXPRESSION>
+//	        |-> null
+func (p *parser) parsePredicate() (*Decl, error) {
+	if p.is(NotToken) {
+		notDecl, err := p.consumeToken(NotToken)
+		if err != nil {
+			return nil, err
+		}
+		condDecl, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		notDecl.Add(condDecl)
+		return notDecl, nil
+	}
+
+	left, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.is(EqualityToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken):
+		opDecl, err := p.consumeToken(EqualityToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken)
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		opDecl.Add(left)
+		opDecl.Add(right)
+		return opDecl, nil
+	case p.is(IsToken):
+		isDecl, err := p.consumeToken(IsToken)
+		if err != nil {
+			return nil, err
+		}
+		var notDecl *Decl
+		if p.is(NotToken) {
+			if notDecl, err = p.consumeToken(NotToken); err != nil {
+				return nil, err
+			}
+		}
+		nullDecl, err := p.consumeToken(NullToken)
+		if err != nil {
+			return nil, err
+		}
+		isDecl.Add(left)
+		isDecl.Add(nullDecl)
+		if notDecl != nil {
+			notDecl.Add(isDecl)
+			return notDecl, nil
+		}
+		return isDecl, nil
+	case p.is(InToken) || p.is(NotToken) && p.hasNext() && p.peekForward().Token == InToken:
+		var notDecl *Decl
+		if p.is(NotToken) {
+			if notDecl, err = p.consumeToken(NotToken); err != nil {
+				return nil, err
+			}
+		}
+		inDecl, err := p.consumeToken(InToken)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+			return nil, err
+		}
+		inDecl.Add(left)
+		for {
+			valueDecl, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			inDecl.Add(valueDecl)
+
+			if !p.is(CommaToken) {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+		if notDecl != nil {
+			notDecl.Add(inDecl)
+			return notDecl, nil
+		}
+		return inDecl, nil
+	}
+
+	return left, nil
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"errors"
+	"strconv"
 )
 
 // ParseInstruction calls lexer and parser, then return Decl tree for each instruction
@@ -25,3 +26,49 @@ func ParseInstruction(instruction string) ([]Instruction, error) {
 
 	return instructions, nil
 }
+
+// NumParameters returns the number of parameters of given instructions:
+// the highest positional parameter ($n, ?) or the number of distinct
+// named parameters (:name, @name). It returns -1 if it cannot tell,
+// i.e when both kinds are used.
+func NumParameters(instructions []Instruction) int {
+	positional := 0
+	named := make(map[string]bool)
+
+	var walk func(d *Decl) bool
+	walk = func(d *Decl) bool {
+		if d.Token == ParameterToken {
+			if d.Lexeme[0] != '$' {
+				named[d.Lexeme[1:]] = true
+				return true
+			}
+			n, err := strconv.Atoi(d.Lexeme[1:])
+			if err != nil {
+				return false
+			}
+			if n > positional {
+				positional = n
+			}
+		}
+		for _, sub := range d.Decl {
+			if !walk(sub) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, i := range instructions {
+		for _, d := range i.Decls {
+			if !walk(d) {
+				return -1
+			}
+		}
+	}
+
+	if positional > 0 && len(named) > 0 {
+		return -1
+	}
+
+	return positional + len(named)
+}
@@ -12,35 +12,56 @@ type lexer struct {
 	instruction    []byte
 	instructionLen int
 	pos            int
+	// count of ODBC parameter markers met so far
+	markers int
 }
 
 // SQL Tokens
 const (
 	ActionToken         = iota // Second-order
+	AddToken                   // Non-reserved
+	AlterToken                 // First-order
+	AlwaysToken                // Non-reserved
 	AndToken                   // Second-order
 	AsToken                    // Second-order
 	AscToken                   // Second-order
 	AutoincrementToken         // Second-order
 	BacktickToken              // Punctuation
+	BeginToken                 // First-order
 	BracketClosingToken        // Punctuation
 	BracketOpeningToken        // Punctuation
 	BtreeToken                 // Second-order
 	ByToken                    // Second-order
+	ByteaToken                 // Type
+	CacheToken                 // Non-reserved
 	CascadeToken               // Second-order
+	CaseToken                  // Non-reserved
 	CharacterToken             // Second-order
 	CharsetToken               // Second-order
+	CheckToken                 // Second-order
+	ColumnToken                // Non-reserved
 	CommaToken                 // Punctuation
+	CommitToken                // First-order
+	ConcatToken                // Punctuation
+	ConflictToken              // Non-reserved
 	ConstraintToken            // Second-order
+	ContinueToken              // Non-reserved
 	CountToken                 // Second-order
 	CreateToken                // First-order
+	CurrvalToken               // Non-reserved
+	CycleToken                 // Non-reserved
 	DateToken                  // Type
 	DefaultToken               // Second-order
 	DeleteToken                // First-order
 	DescToken                  // Second-order
 	DoubleQuoteToken           // Quote
 	DropToken                  // First-order
+	DuplicateToken             // Non-reserved
+	ElseToken                  // Non-reserved
+	EndToken                   // Non-reserved
 	EngineToken                // Second-order
 	EqualityToken              // Quote
+	ExcludedToken              // Non-reserved
 	ExistsToken                // Second-order
 	ExplainToken               // First-order
 	FalseToken                 // Second-order
@@ -48,11 +69,15 @@ const (
 	ForeignToken               // Second-order
 	FromToken                  // Second-order
 	FullToken                  // Second-order
+	FunctionToken              // Non-reserved
+	GeneratedToken             // Non-reserved
 	GrantToken                 // First-order
 	GreaterOrEqualToken        // Punctuation
 	HashToken                  // Second-order
+	IdentityToken              // Non-reserved
 	IfToken                    // Second-order
 	InToken                    // Second-order
+	IncrementToken             // Non-reserved
 	IndexToken                 // Second-order
 	InnerToken                 // Second-order
 	InsertToken                // First-order
@@ -67,7 +92,12 @@ const (
 	LimitToken                 // Second-order
 	LocalTimestampToken        // Second-order
 	MatchToken                 // Second-order
+	MaxvalueToken              // Non-reserved
+	MinusToken                 // Punctuation
+	MinvalueToken              // Non-reserved
+	NextvalToken               // Non-reserved
 	NoToken                    // Second-order
+	NothingToken               // Non-reserved
 	NotToken                   // Second-order
 	NowToken                   // Second-order
 	NullToken                  // Second-order
@@ -77,31 +107,52 @@ const (
 	OrToken                    // Second-order
 	OrderToken                 // Second-order
 	OuterToken                 // Second-order
+	ParameterToken             // Type
 	PartialToken               // Quote
+	PercentToken               // Punctuation
 	PeriodToken                // Quote
+	PlusToken                  // Punctuation
 	PrimaryToken               // Type
 	ReferencesToken            // Second-order
+	ReleaseToken               // Non-reserved
+	RenameToken                // Non-reserved
+	ReplaceToken               // Non-reserved
+	RestartToken               // Non-reserved
 	ReturningToken             // Second-order
 	RestrictToken              // Second-order
 	RightToken                 // Second-order
 	RightDipleToken            // Punctuation
+	RollbackToken              // First-order
+	SavepointToken             // Non-reserved
+	SchemaToken                // Non-reserved
 	SelectToken                // First-order
 	SemicolonToken             // Punctuation
+	SequenceToken              // Non-reserved
 	SetToken                   // Second-order
+	SetvalToken                // Non-reserved
 	SimpleToken                // Second-order
 	SimpleQuoteToken           // Quote
+	SlashToken                 // Punctuation
 	SpaceToken                 // Punctuation
 	StarToken                  // Quote
+	StartToken                 // Non-reserved
 	StringToken                // Type
 	TableToken                 // Second-order
+	TemporaryToken             // Non-reserved
 	TextToken                  // Type
+	ThenToken                  // Non-reserved
 	TimeToken                  // Second-order
+	TimestampToken             // Type
+	ToToken                    // Non-reserved
 	TrueToken                  // Second-order
 	TruncateToken              // First-order
+	TypeToken                  // Non-reserved
 	UniqueToken                // Second-order
 	UpdateToken                // First-order
 	UsingToken                 // Second-order
 	ValuesToken                // Second-order
+	ViewToken                  // Non-reserved
+	WhenToken                  // Non-reserved
 	WhereToken                 // Second-order
 	WithToken                  // Second-order
 	ZoneToken                  // Second-order
@@ -112,11 +163,13 @@ type Matcher func() bool
 
 //go:generate go run ../../utils/lexer-generate-matcher.go --init
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "action"
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "alter"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "and"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "as"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "asc"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "autoincrement" --lexeme "auto_increment"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "`" --name Backtick
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "begin"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme ")" --name BracketClosing
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "(" --name BracketOpening
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "btree"
@@ -124,7 +177,10 @@ type Matcher func() bool
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "cascade"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "character"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "charset"
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "check"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "," --name Comma
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "commit"
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "||" --name Concat
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "constraint"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "count"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "create"
@@ -158,6 +214,7 @@ type Matcher func() bool
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "limit"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "localtimestamp" --lexeme "current_timestamp" --name LocalTimestamp
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "match"
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "-" --name Minus
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "no"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "not"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "now()" --name Now
@@ -168,17 +225,21 @@ type Matcher func() bool
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "order"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "outer"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "partial"
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "%" --name Percent
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "." --name Period
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "+" --name Plus
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "primary"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "references"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "restrict"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "returning"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "right"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme ">" --name RightDiple
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "rollback"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "select"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme ";" --name Semicolon
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "set"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "simple"
+//go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "/" --name Slash
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "*" --name Star
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "table"
 //go:generate go run ../../utils/lexer-generate-matcher.go --lexeme "time"
@@ -197,6 +258,7 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	l.tokens = nil
 	l.instruction = instruction
 	l.pos = 0
+	l.markers = 0
 	securityPos := 0
 
 	var matchers []Matcher
@@ -216,12 +278,22 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	matchers = append(matchers, l.MatchGreaterOrEqualToken)
 	matchers = append(matchers, l.MatchRightDipleToken)
 	matchers = append(matchers, l.MatchBacktickToken)
+	matchers = append(matchers, l.MatchParameterToken)
+	matchers = append(matchers, l.MatchPlusToken)
+	matchers = append(matchers, l.MatchMinusToken)
+	matchers = append(matchers, l.MatchSlashToken)
+	matchers = append(matchers, l.MatchPercentToken)
+	matchers = append(matchers, l.MatchConcatToken)
 	// First order Matcher
+	matchers = append(matchers, l.MatchAlterToken)
+	matchers = append(matchers, l.MatchBeginToken)
+	matchers = append(matchers, l.MatchCommitToken)
 	matchers = append(matchers, l.MatchCreateToken)
 	matchers = append(matchers, l.MatchDeleteToken)
 	matchers = append(matchers, l.MatchDropToken)
 	matchers = append(matchers, l.MatchGrantToken)
 	matchers = append(matchers, l.MatchInsertToken)
+	matchers = append(matchers, l.MatchRollbackToken)
 	matchers = append(matchers, l.MatchSelectToken)
 	matchers = append(matchers, l.MatchTruncateToken)
 	matchers = append(matchers, l.MatchUpdateToken)
@@ -236,6 +308,7 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	matchers = append(matchers, l.MatchCascadeToken)
 	matchers = append(matchers, l.MatchCharacterToken)
 	matchers = append(matchers, l.MatchCharsetToken)
+	matchers = append(matchers, l.MatchCheckToken)
 	matchers = append(matchers, l.MatchConstraintToken)
 	matchers = append(matchers, l.MatchCountToken)
 	matchers = append(matchers, l.MatchDefaultToken)
@@ -363,6 +436,14 @@ func (l *lexer) MatchNumberToken() bool {
 		i++
 	}
 
+	// Decimal part
+	if i != l.pos && i+1 < l.instructionLen && l.instruction[i] == '.' && unicode.IsDigit(rune(l.instruction[i+1])) {
+		i++
+		for i < l.instructionLen && unicode.IsDigit(rune(l.instruction[i])) {
+			i++
+		}
+	}
+
 	if i != l.pos {
 		t := Token{
 			Token:  NumberToken,
@@ -430,6 +511,54 @@ func (l *lexer) MatchDoubleQuoteToken() bool {
 	return false
 }
 
+// MatchParameterToken matches statement parameters, either
+// positional ($1, ?) or named (:name, @name).
+// ODBC markers are numbered so they are handled as $n.
+func (l *lexer) MatchParameterToken() bool {
+	i := l.pos
+	c := l.instruction[i]
+
+	if c == '?' {
+		l.markers++
+		t := Token{
+			Token:  ParameterToken,
+			Lexeme: fmt.Sprintf("$%d", l.markers),
+		}
+		l.tokens = append(l.tokens, t)
+		l.pos++
+		return true
+	}
+
+	if c != '$' && c != ':' && c != '@' {
+		return false
+	}
+	i++
+
+	for i < l.instructionLen {
+		r := rune(l.instruction[i])
+		if c == '$' && !unicode.IsDigit(r) {
+			break
+		}
+		if c != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		i++
+	}
+
+	// Named parameters cannot start with a digit
+	if i == l.pos+1 || (c != '$' && unicode.IsDigit(rune(l.instruction[l.pos+1]))) {
+		return false
+	}
+
+	t := Token{
+		Token:  ParameterToken,
+		Lexeme: string(l.instruction[l.pos:i]),
+	}
+	l.tokens = append(l.tokens, t)
+	l.pos = i
+	return true
+}
+
 func (l *lexer) MatchEscapedStringToken() bool {
 	i := l.pos
 	if l.instruction[i] != '$' || l.instruction[i+1] != '$' {
@@ -551,7 +680,7 @@ func (l *lexer) MatchSingle(char byte, token int) bool {
 
 func (l *lexer) Match(str []byte, token int) bool {
 
-	if l.pos+len(str)-1 > l.instructionLen {
+	if l.pos+len(str) > l.instructionLen {
 		return false
 	}
 
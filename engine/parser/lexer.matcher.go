@@ -4,6 +4,10 @@ func (l *lexer) MatchActionToken() bool {
 	return l.Match([]byte("action"), ActionToken)
 }
 
+func (l *lexer) MatchAlterToken() bool {
+	return l.Match([]byte("alter"), AlterToken)
+}
+
 func (l *lexer) MatchAndToken() bool {
 	return l.Match([]byte("and"), AndToken)
 }
@@ -25,6 +29,10 @@ func (l *lexer) MatchBacktickToken() bool {
 	return l.MatchSingle('`', BacktickToken)
 }
 
+func (l *lexer) MatchBeginToken() bool {
+	return l.Match([]byte("begin"), BeginToken)
+}
+
 func (l *lexer) MatchBracketClosingToken() bool {
 	return l.MatchSingle(')', BracketClosingToken)
 }
@@ -53,10 +61,22 @@ func (l *lexer) MatchCharsetToken() bool {
 	return l.Match([]byte("charset"), CharsetToken)
 }
 
+func (l *lexer) MatchCheckToken() bool {
+	return l.Match([]byte("check"), CheckToken)
+}
+
 func (l *lexer) MatchCommaToken() bool {
 	return l.MatchSingle(',', CommaToken)
 }
 
+func (l *lexer) MatchCommitToken() bool {
+	return l.Match([]byte("commit"), CommitToken)
+}
+
+func (l *lexer) MatchConcatToken() bool {
+	return l.Match([]byte("||"), ConcatToken)
+}
+
 func (l *lexer) MatchConstraintToken() bool {
 	return l.Match([]byte("constraint"), ConstraintToken)
 }
@@ -190,6 +210,10 @@ func (l *lexer) MatchMatchToken() bool {
 	return l.Match([]byte("match"), MatchToken)
 }
 
+func (l *lexer) MatchMinusToken() bool {
+	return l.MatchSingle('-', MinusToken)
+}
+
 func (l *lexer) MatchNoToken() bool {
 	return l.Match([]byte("no"), NoToken)
 }
@@ -230,10 +254,18 @@ func (l *lexer) MatchPartialToken() bool {
 	return l.Match([]byte("partial"), PartialToken)
 }
 
+func (l *lexer) MatchPercentToken() bool {
+	return l.MatchSingle('%', PercentToken)
+}
+
 func (l *lexer) MatchPeriodToken() bool {
 	return l.MatchSingle('.', PeriodToken)
 }
 
+func (l *lexer) MatchPlusToken() bool {
+	return l.MatchSingle('+', PlusToken)
+}
+
 func (l *lexer) MatchPrimaryToken() bool {
 	return l.Match([]byte("primary"), PrimaryToken)
 }
@@ -258,6 +290,10 @@ func (l *lexer) MatchRightDipleToken() bool {
 	return l.MatchSingle('>', RightDipleToken)
 }
 
+func (l *lexer) MatchRollbackToken() bool {
+	return l.Match([]byte("rollback"), RollbackToken)
+}
+
 func (l *lexer) MatchSelectToken() bool {
 	return l.Match([]byte("select"), SelectToken)
 }
@@ -274,6 +310,10 @@ func (l *lexer) MatchSimpleToken() bool {
 	return l.Match([]byte("simple"), SimpleToken)
 }
 
+func (l *lexer) MatchSlashToken() bool {
+	return l.MatchSingle('/', SlashToken)
+}
+
 func (l *lexer) MatchStarToken() bool {
 	return l.MatchSingle('*', StarToken)
 }
@@ -89,3 +89,23 @@ func TestLexerOtherAutoIncrement(t *testing.T) {
 		t.Fatalf("Lexing failed, expected %d tokens, got %d", nrExpectedDecls, len(decls))
 	}
 }
+
+func TestLexerArithmeticOperators(t *testing.T) {
+	query := `price*quantity+1-2/3%4||'x'`
+
+	lexer := lexer{}
+	decls, err := lexer.lex([]byte(query))
+	if err != nil {
+		t.Fatalf("Cannot lex <%s> string", query)
+	}
+
+	expected := []int{StringToken, StarToken, StringToken, PlusToken, NumberToken, MinusToken, NumberToken, SlashToken, NumberToken, PercentToken, NumberToken, ConcatToken, SimpleQuoteToken, StringToken, SimpleQuoteToken}
+	if len(decls) != len(expected) {
+		t.Fatalf("Lexing failed, expected %d tokens, got %d", len(expected), len(decls))
+	}
+	for i := range expected {
+		if decls[i].Token != expected[i] {
+			t.Fatalf("Lexing failed, expected token %d at %d, got %d (%s)", expected[i], i, decls[i].Token, decls[i].Lexeme)
+		}
+	}
+}
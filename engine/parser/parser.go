@@ -5,6 +5,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/kokizzu/ramsql/engine/log"
 )
@@ -26,6 +27,11 @@ func NewParser(tokens []Token) parser {
 		i:      []Instruction{},
 	}
 
+	// Terminate last instruction so single token statements (i.e COMMIT)
+	// can be parsed as well
+	if len(p.tokens) > 0 && p.tokens[len(p.tokens)-1].Token != SemicolonToken {
+		p.tokens = append(p.tokens, Token{Token: SemicolonToken, Lexeme: ";"})
+	}
 	p.tokenLen = len(p.tokens)
 
 	return p
@@ -50,7 +56,8 @@ func (p *parser) parse() ([]Instruction, error) {
 		// Now,
 		// Create a logical tree of all tokens
 		// We start with first order query
-		// CREATE, SELECT, INSERT, UPDATE, DELETE, TRUNCATE, DROP, EXPLAIN
+		// CREATE, SELECT, INSERT, UPDATE, DELETE, TRUNCATE, DROP, ALTER, EXPLAIN
+		// BEGIN, COMMIT, ROLLBACK, SAVEPOINT, RELEASE, SET, REPLACE
 		switch p.cur().Token {
 		case CreateToken:
 			i, err := p.parseCreate()
@@ -102,13 +109,65 @@ func (p *parser) parse() ([]Instruction, error) {
 			}
 			p.i = append(p.i, *i)
 			break
+		case BeginToken:
+			i, err := p.parseBegin()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
+			break
+		case CommitToken:
+			i, err := p.parseCommit()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
+			break
+		case RollbackToken:
+			i, err := p.parseRollback()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
+			break
+		case AlterToken:
+			i, err := p.parseAlter()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
+			break
 		case ExplainToken:
 			break
+		case SetToken:
+			i, err := p.parseSet()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
 		case GrantToken:
 			i := &Instruction{}
 			i.Decls = append(i.Decls, NewDecl(Token{Token: GrantToken}))
 			p.i = append(p.i, *i)
 			return p.i, nil
+		case StringToken:
+			// Non-reserved keywords, so they can be used as names elsewhere
+			var i *Instruction
+			var err error
+			switch {
+			case p.isWord("savepoint"):
+				i, err = p.parseSavepoint()
+			case p.isWord("release"):
+				i, err = p.parseRelease()
+			case p.isWord("replace"):
+				i, err = p.parseInsert()
+			default:
+				return nil, fmt.Errorf("Parsing error near <%s>", p.cur().Lexeme)
+			}
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
 		default:
 			return nil, fmt.Errorf("Parsing error near <%s>", p.cur().Lexeme)
 		}
@@ -117,6 +176,23 @@ func (p *parser) parse() ([]Instruction, error) {
 	return p.i, nil
 }
 
+// parseUpdate parses UPDATE, whose rows may be joined with those of other
+// tables, either PostgreSQL or MySQL style
+// UPDATE <TABLE-NAME> [ [AS] <ALIAS> ] [ [INNER] JOIN ... ] SET <COLUMN-NAME> = <EXPRESSION> [, ...] [ FROM <TABLE-NAME> [, ...] ] [ WHERE ... ] [ RETURNING ... ]
+//
+//	|-> update
+//	    |-> <TABLE-NAME>
+//	        |-> as
+//	            |-> <ALIAS>
+//	    |-> set
+//	        |-> <COLUMN-NAME>
+//	            |-> =
+//	            |-> <EXPRESSION>
+//	    |-> join
+//	    |-> from
+//	        |-> <TABLE-NAME>
+//	    |-> where
+//	    |-> returning
 func (p *parser) parseUpdate() (*Instruction, error) {
 	i := &Instruction{}
 
@@ -128,12 +204,32 @@ func (p *parser) parseUpdate() (*Instruction, error) {
 	i.Decls = append(i.Decls, updateDecl)
 
 	// should be table name
-	nameDecl, err := p.parseQuotedToken()
+	nameDecl, err := p.parseTableName()
 	if err != nil {
 		return nil, err
 	}
+	if err := p.parseTableAlias(nameDecl); err != nil {
+		return nil, err
+	}
 	updateDecl.Add(nameDecl)
 
+	// Optional: [INNER] JOIN ..., MySQL style. Rows of the table are only
+	// updated if joined, outer joins are not supported.
+	var joinDecls []*Decl
+	for p.is(InnerToken, JoinToken, LeftToken, RightToken, OuterToken) {
+		if p.is(LeftToken, RightToken, OuterToken) {
+			return nil, fmt.Errorf("%s JOIN is not supported in UPDATE, only [INNER] JOIN is", strings.ToUpper(p.cur().Lexeme))
+		}
+		if p.is(InnerToken) {
+			p.next()
+		}
+		joinDecl, err := p.parseJoin()
+		if err != nil {
+			return nil, err
+		}
+		joinDecls = append(joinDecls, joinDecl)
+	}
+
 	// should be SET
 	setDecl, err := p.consumeToken(SetToken)
 	if err != nil {
@@ -141,38 +237,96 @@ func (p *parser) parseUpdate() (*Instruction, error) {
 	}
 	updateDecl.Add(setDecl)
 
-	// should be a list of equality
-	gotClause := false
-	for p.cur().Token != WhereToken {
+	// should be a list of assignments, whose values are computed from
+	// the row as it was before the update
+	for {
+		attributeDecl, err := p.parseAttribute()
+		if err != nil {
+			return nil, err
+		}
+		equalDecl, err := p.consumeToken(EqualityToken)
+		if err != nil {
+			return nil, err
+		}
+		attributeDecl.Add(equalDecl)
+		valueDecl, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		attributeDecl.Add(valueDecl)
+		setDecl.Add(attributeDecl)
 
-		if !p.hasNext() && gotClause {
+		if !p.is(CommaToken) {
 			break
 		}
+		p.next()
+	}
 
-		attributeDecl, err := p.parseCondition()
+	// Joined tables follow the assignments
+	for _, joinDecl := range joinDecls {
+		updateDecl.Add(joinDecl)
+	}
+
+	// Optional: FROM <TABLE-NAME> [, ...], PostgreSQL style
+	if p.is(FromToken) {
+		fromDecl, err := p.parseTableList(FromToken)
 		if err != nil {
 			return nil, err
 		}
-		setDecl.Add(attributeDecl)
-		p.consumeToken(CommaToken)
+		updateDecl.Add(fromDecl)
+		joinDecls = append(joinDecls, fromDecl)
+	}
 
-		// Got at least one clause
-		gotClause = true
+	// Optional: WHERE, every row is updated otherwise. Rows of joined
+	// tables may be compared to each other.
+	if len(joinDecls) > 0 {
+		if p.is(WhereToken) {
+			if err := p.parseWhereCondition(updateDecl); err != nil {
+				return nil, err
+			}
+		}
+	} else if p.is(WhereToken) {
+		err = p.parseWhere(updateDecl)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		addImplicitWhereAll(updateDecl)
 	}
 
-	err = p.parseWhere(updateDecl)
-	if err != nil {
-		return nil, err
+	// Optional: RETURNING ...
+	if p.is(ReturningToken) {
+		if err := p.parseReturning(updateDecl); err != nil {
+			return nil, err
+		}
 	}
 
 	return i, nil
 }
 
+// parseInsert parses INSERT, and REPLACE which deletes the rows holding
+// the same keys as those inserted, MySQL style
+//
+//	|-> insert
+//	    |-> into
+//	        |-> <TABLE-NAME>
+//	            |-> <ATTRIBUTE-NAME>
+//	    |-> { values | select }
+//	    |-> { conflict | duplicate | replace }
+//	    |-> returning
 func (p *parser) parseInsert() (*Instruction, error) {
 	i := &Instruction{}
 
-	// Required: INSERT
-	insertDecl, err := p.consumeToken(InsertToken)
+	// Required: INSERT | REPLACE
+	var insertDecl, replaceDecl *Decl
+	var err error
+	if p.isWord("replace") {
+		replaceDecl, err = p.consumeWord("replace")
+		replaceDecl.Token = ReplaceToken
+		insertDecl = NewDecl(Token{Token: InsertToken, Lexeme: "insert"})
+	} else {
+		insertDecl, err = p.consumeToken(InsertToken)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -186,90 +340,201 @@ func (p *parser) parseInsert() (*Instruction, error) {
 	insertDecl.Add(intoDecl)
 
 	// Required: <TABLE-NAME>
-	tableDecl, err := p.parseQuotedToken()
+	tableDecl, err := p.parseTableName()
 	if err != nil {
 		return nil, err
 	}
 	intoDecl.Add(tableDecl)
 
-	// Required: '('
-	_, err = p.consumeToken(BracketOpeningToken)
-	if err != nil {
-		return nil, err
-	}
+	// Optional: '(' <ATTRIBUTE-NAME> [, <ATTRIBUTE-NAME>]* ')', columns
+	// defaulting to all those of the table
+	if p.is(BracketOpeningToken) {
+		p.next()
+		for {
+			decl, err := p.parseQuotedToken()
+			if err != nil {
+				return nil, err
+			}
+			tableDecl.Add(decl)
 
-	// Required: <ATTRIBUTE-NAME> [, <ATTRIBUTE-NAME>]* ')'
-	for {
-		decl, err := p.parseQuotedToken()
-		if err != nil {
-			return nil, err
-		}
-		tableDecl.Add(decl)
+			if p.is(BracketClosingToken) {
+				if _, err = p.consumeToken(BracketClosingToken); err != nil {
+					return nil, err
+				}
 
-		if p.is(BracketClosingToken) {
-			if _, err = p.consumeToken(BracketClosingToken); err != nil {
+				break
+			}
+
+			_, err = p.consumeToken(CommaToken)
+			if err != nil {
 				return nil, err
 			}
+		}
+	}
 
-			break
+	// Required: VALUES ... | SELECT ...
+	if p.is(SelectToken) {
+		selectInstruction, err := p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+		insertDecl.Add(selectInstruction.Decls[0])
+	} else {
+		rows, err := p.parseValues()
+		if err != nil {
+			return nil, err
+		}
+		for _, valuesDecl := range rows {
+			insertDecl.Add(valuesDecl)
 		}
+	}
 
-		_, err = p.consumeToken(CommaToken)
+	// Optional: ON CONFLICT ... | ON DUPLICATE KEY UPDATE ...
+	if replaceDecl != nil {
+		insertDecl.Add(replaceDecl)
+	} else if p.is(OnToken) {
+		conflictDecl, err := p.parseOnConflict()
 		if err != nil {
 			return nil, err
 		}
+		insertDecl.Add(conflictDecl)
 	}
 
-	// Required: VALUES
-	valuesDecl, err := p.consumeToken(ValuesToken)
-	if err != nil {
-		return nil, err
+	// Optional: RETURNING ...
+	if p.is(ReturningToken) {
+		if err := p.parseReturning(insertDecl); err != nil {
+			return nil, err
+		}
 	}
-	insertDecl.Add(valuesDecl)
 
-	// Required: '('
-	_, err = p.consumeToken(BracketOpeningToken)
+	return i, nil
+}
+
+// parseReturning parses the values computed from each row written by
+// INSERT, UPDATE or DELETE
+// RETURNING { * | <EXPRESSION> [ [ AS ] <ALIAS> ] } [, ...]
+//
+//	|-> returning
+//	    |-> *
+//	    |-> <EXPRESSION>
+//	    |-> as
+//	        |-> <EXPRESSION>
+//	        |-> <ALIAS>
+func (p *parser) parseReturning(decl *Decl) error {
+	retDecl, err := p.consumeToken(ReturningToken)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	decl.Add(retDecl)
 
-	// Required: <ATTRIBUTE-VALUE> [, <ATTRIBUTE-VALUE>]* ')'
 	for {
-		decl, err := p.parseListElement()
-		if err != nil {
-			return nil, err
+		if p.is(StarToken) {
+			starDecl, err := p.consumeToken(StarToken)
+			if err != nil {
+				return err
+			}
+			retDecl.Add(starDecl)
+		} else {
+			exprDecl, err := p.parseExpression()
+			if err != nil {
+				return err
+			}
+
+			// Optional: [ AS ] <ALIAS>
+			if p.is(AsToken, StringToken, DoubleQuoteToken, BacktickToken) {
+				asDecl := NewDecl(Token{Token: AsToken, Lexeme: "as"})
+				if p.is(AsToken) {
+					p.next()
+				}
+				aliasDecl, err := p.parseQuotedToken()
+				if err != nil {
+					return err
+				}
+				asDecl.Add(exprDecl)
+				asDecl.Add(aliasDecl)
+				exprDecl = asDecl
+			}
+			retDecl.Add(exprDecl)
 		}
-		valuesDecl.Add(decl)
 
-		if p.is(BracketClosingToken) {
-			p.consumeToken(BracketClosingToken)
+		if !p.is(CommaToken) {
 			break
 		}
+		p.next()
+	}
 
-		_, err = p.consumeToken(CommaToken)
-		if err != nil {
-			return nil, err
-		}
+	return nil
+}
+
+// parseValues parses the rows of values inserted, each one in its own decl
+// VALUES '(' <ATTRIBUTE-VALUE> [, ...] ')' [, ...]
+//
+//	|-> values
+//	    |-> <ATTRIBUTE-VALUE>
+//	|-> values
+//	    |-> <ATTRIBUTE-VALUE>
+func (p *parser) parseValues() ([]*Decl, error) {
+	// Required: VALUES
+	valuesToken := p.cur()
+	if _, err := p.consumeToken(ValuesToken); err != nil {
+		return nil, err
 	}
 
-	// Optional: RETURNING ...
-	if retDecl, err := p.consumeToken(ReturningToken); err == nil {
-		insertDecl.Add(retDecl)
-		attrDecl, err := p.parseAttribute()
-		if err != nil {
+	var rows []*Decl
+	for {
+		valuesDecl := NewDecl(valuesToken)
+		rows = append(rows, valuesDecl)
+
+		// Required: '('
+		if _, err := p.consumeToken(BracketOpeningToken); err != nil {
 			return nil, err
 		}
-		retDecl.Add(attrDecl)
+
+		// Required: <ATTRIBUTE-VALUE> [, <ATTRIBUTE-VALUE>]* ')'
+		for {
+			decl, err := p.parseListElement()
+			if err != nil {
+				return nil, err
+			}
+			valuesDecl.Add(decl)
+
+			if p.is(BracketClosingToken) {
+				p.consumeToken(BracketClosingToken)
+				break
+			}
+
+			_, err = p.consumeToken(CommaToken)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Optional: ',' '(' ... ')', another row
+		if !p.is(CommaToken) {
+			break
+		}
+		p.next()
 	}
 
-	return i, nil
+	return rows, nil
 }
 
 func (p *parser) parseType() (*Decl, error) {
-	typeDecl, err := p.consumeToken(StringToken)
+	typeDecl, err := p.consumeToken(StringToken, CharacterToken)
 	if err != nil {
 		return nil, err
 	}
+	typeDecl.Token = StringToken
+
+	// Multi-words types: DOUBLE PRECISION, CHARACTER VARYING
+	if strings.EqualFold(typeDecl.Lexeme, "double") && p.isWord("precision") {
+		typeDecl.Lexeme = "double precision"
+		p.next()
+	}
+	if strings.EqualFold(typeDecl.Lexeme, "character") && p.isWord("varying") {
+		typeDecl.Lexeme = "character varying"
+		p.next()
+	}
 
 	// Maybe a complex type
 	if p.is(BracketOpeningToken) {
@@ -282,6 +547,15 @@ func (p *parser) parseType() (*Decl, error) {
 			return nil, err
 		}
 		typeDecl.Add(sizeDecl)
+		// Scale of decimal types, i.e NUMERIC(10, 2)
+		if p.is(CommaToken) {
+			p.next()
+			scaleDecl, err := p.consumeToken(NumberToken)
+			if err != nil {
+				return nil, err
+			}
+			typeDecl.Add(scaleDecl)
+		}
 		_, err = p.consumeToken(BracketClosingToken)
 		if err != nil {
 			return nil, err
@@ -355,7 +629,7 @@ func (p *parser) parseWhere(selectDecl *Decl) error {
 			break
 		}
 
-		if p.is(OrderToken, LimitToken, ForToken) {
+		if p.is(OrderToken, LimitToken, ForToken, ReturningToken, BracketClosingToken) {
 			break
 		}
 
@@ -380,6 +654,57 @@ func (p *parser) parseWhere(selectDecl *Decl) error {
 	return nil
 }
 
+// parseWhereCondition parses the WHERE clause of statements joining
+// tables, whose condition may compare columns of different tables
+//
+//	|-> where
+//	    |-> <CONDITION>
+func (p *parser) parseWhereCondition(decl *Decl) error {
+	whereDecl, err := p.consumeToken(WhereToken)
+	if err != nil {
+		return err
+	}
+
+	condDecl, err := p.parseSearchCondition()
+	if err != nil {
+		return err
+	}
+	whereDecl.Add(condDecl)
+	decl.Add(whereDecl)
+
+	return nil
+}
+
+// parseTableList parses a list of tables introduced by given keyword, i.e
+// FROM or USING
+//
+//	|-> { from | using }
+//	    |-> <TABLE-NAME>
+func (p *parser) parseTableList(token int) (*Decl, error) {
+	listDecl, err := p.consumeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tableDecl, err := p.parseTableName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.parseTableAlias(tableDecl); err != nil {
+			return nil, err
+		}
+		listDecl.Add(tableDecl)
+
+		if !p.is(CommaToken) {
+			break
+		}
+		p.next()
+	}
+
+	return listDecl, nil
+}
+
 // parseBuiltinFunc looks for COUNT,MAX,MIN
 func (p *parser) parseBuiltinFunc() (*Decl, error) {
 	var d *Decl
@@ -462,6 +787,16 @@ func (p *parser) parseAttribute() (*Decl, error) {
 			return nil, err
 		}
 
+		// Optional: '.', the table name was qualified by its schema
+		if attrDecl.Token == StringToken && p.is(PeriodToken) {
+			p.next()
+			tableDecl.Lexeme += "." + attrDecl.Lexeme
+			attrDecl, err = p.consumeToken(StringToken, StarToken)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		attrDecl.Add(tableDecl)
 	}
 
@@ -518,6 +853,56 @@ func (p *parser) parseQuotedToken() (*Decl, error) {
 	return decl, nil
 }
 
+// parseTableName parses the name of a table, or of another object of a
+// schema, which may be qualified by its schema name
+// [ <SCHEMA-NAME> '.' ] <TABLE-NAME>
+//
+// A qualified name is a single decl, i.e billing.invoices
+func (p *parser) parseTableName() (*Decl, error) {
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Optional: '.' <TABLE-NAME>
+	if p.is(PeriodToken) {
+		p.next()
+		d, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		nameDecl.Lexeme += "." + d.Lexeme
+	}
+
+	return nameDecl, nil
+}
+
+// parseTableAlias parses the optional alias of a table, which columns may
+// be qualified by instead of the table name
+// [ [AS] <ALIAS> ]
+//
+//	|-> <TABLE-NAME>
+//	    |-> as
+//	        |-> <ALIAS>
+func (p *parser) parseTableAlias(tableDecl *Decl) error {
+	if !p.is(AsToken, StringToken, DoubleQuoteToken, BacktickToken) {
+		return nil
+	}
+
+	asDecl := NewDecl(Token{Token: AsToken, Lexeme: "as"})
+	if p.is(AsToken) {
+		p.next()
+	}
+	aliasDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return err
+	}
+	asDecl.Add(aliasDecl)
+	tableDecl.Add(asDecl)
+
+	return nil
+}
+
 func (p *parser) parseCondition() (*Decl, error) {
 
 	// We may have the WHERE 1 condition
@@ -582,6 +967,18 @@ func (p *parser) parseCondition() (*Decl, error) {
 		return attributeDecl, nil
 	}
 
+	// Column of another table, i.e of the outer query of a subquery
+	if p.is(StringToken) {
+		if _, err := p.isNext(PeriodToken); err == nil {
+			columnDecl, err := p.parseAttribute()
+			if err != nil {
+				return nil, err
+			}
+			attributeDecl.Add(columnDecl)
+			return attributeDecl, nil
+		}
+	}
+
 	// Value
 	valueDecl, err := p.parseValue()
 	if err != nil {
@@ -644,7 +1041,7 @@ func (p *parser) parseValue() (*Decl, error) {
 		}
 	}
 
-	valueDecl, err := p.consumeToken(StringToken, NumberToken, TrueToken, FalseToken, DateToken, NowToken, LocalTimestampToken, NullToken)
+	valueDecl, err := p.consumeToken(StringToken, NumberToken, TrueToken, FalseToken, DateToken, NowToken, LocalTimestampToken, NullToken, ParameterToken)
 	if err != nil {
 		debug("parseValue: Wasn't expecting %v\n", p.cur())
 		return nil, err
@@ -672,10 +1069,13 @@ func (p *parser) parseJoin() (*Decl, error) {
 	}
 
 	// TABLE NAME
-	tableDecl, err := p.parseAttribute()
+	tableDecl, err := p.parseTableName()
 	if err != nil {
 		return nil, err
 	}
+	if err := p.parseTableAlias(tableDecl); err != nil {
+		return nil, err
+	}
 	joinDecl.Add(tableDecl)
 
 	// ON
@@ -722,13 +1122,18 @@ func (p *parser) parseListElement() (*Decl, error) {
 		return v, nil
 	}
 
+	// Or a sequence function, i.e nextval('account_id_seq')
+	if p.isSequenceFunc() {
+		return p.parseSequenceFunc()
+	}
+
 	if p.is(SimpleQuoteToken) || p.is(DoubleQuoteToken) {
 		quoted = true
 		p.next()
 	}
 
 	var valueDecl *Decl
-	valueDecl, err := p.consumeToken(StringToken, NumberToken, NullToken, DateToken, NowToken, FalseToken)
+	valueDecl, err := p.consumeToken(StringToken, NumberToken, NullToken, DateToken, NowToken, FalseToken, ParameterToken)
 	if err != nil {
 		return nil, err
 	}
@@ -785,6 +1190,35 @@ func (p *parser) isNot(tokenTypes ...int) bool {
 	return !p.is(tokenTypes...)
 }
 
+// isWord checks if current token is a string matching one of given words.
+// Non-reserved keywords are matched this way so they can still be used as
+// table or attribute names.
+func (p *parser) isWord(words ...string) bool {
+	if !p.is(StringToken) {
+		return false
+	}
+
+	for _, w := range words {
+		if strings.EqualFold(p.cur().Lexeme, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// consumeWord is the consumeToken counterpart of isWord
+func (p *parser) consumeWord(words ...string) (*Decl, error) {
+
+	if !p.isWord(words...) {
+		return nil, p.syntaxError()
+	}
+
+	decl := NewDecl(p.cur())
+	p.next()
+	return decl, nil
+}
+
 func (p *parser) isNext(tokenTypes ...int) (t Token, err error) {
 
 	if !p.hasNext() {
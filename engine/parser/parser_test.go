@@ -305,6 +305,47 @@ func TestSelectWithAs(t *testing.T) {
 	parse(query, 1, t)
 }
 
+func TestSelectCompareColumns(t *testing.T) {
+	i := parse(`SELECT amount FROM bonus WHERE bonus.id = counter.id`, 1, t)
+	var where *Decl
+	for _, d := range i[0].Decls[0].Decl {
+		if d.Token == WhereToken {
+			where = d
+		}
+	}
+	if where == nil || len(where.Decl) != 1 || len(where.Decl[0].Decl) != 3 {
+		t.Fatalf("Expected a single comparison, got %v", where)
+	}
+	column := where.Decl[0].Decl[2]
+	if column.Lexeme != "id" || len(column.Decl) != 1 || column.Decl[0].Lexeme != "counter" {
+		t.Fatalf("Expected column counter.id, got %v", column)
+	}
+}
+
+func TestSelectTableAlias(t *testing.T) {
+	queries := []string{
+		`SELECT id FROM account AS a`,
+		`SELECT a.id FROM account a WHERE a.id = 1`,
+		`SELECT a.id, p.name FROM account AS a JOIN project AS p ON p.owner_id = a.id`,
+		`SELECT a.id, p.name FROM account a INNER JOIN project p ON p.owner_id = a.id WHERE p.name = 'foo'`,
+	}
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`SELECT a.id FROM account AS a`, 1, t)
+	var table *Decl
+	for _, d := range i[0].Decls[0].Decl {
+		if d.Token == FromToken {
+			table = d.Decl[0]
+		}
+	}
+	if table == nil || table.Lexeme != "account" || len(table.Decl) != 1 ||
+		table.Decl[0].Token != AsToken || table.Decl[0].Decl[0].Lexeme != "a" {
+		t.Fatalf("Expected table account aliased as a, got %v", table)
+	}
+}
+
 func TestInsertMinimal(t *testing.T) {
 	query := `INSERT INTO account ('email', 'password', 'age') VALUES ('foo@bar.com', 'tititoto', '4')`
 	parse(query, 1, t)
@@ -340,10 +381,10 @@ func TestInsertStringWithSimpleQuote(t *testing.T) {
 	parse(query, 1, t)
 }
 
-// func TestInsertImplicitAttributes(t *testing.T) {
-// 	query := `INSERT INTO account VALUES ('foo@bar.com', 'tititoto', 4)`
-// 	parse(query, 1, t)
-// }
+func TestInsertImplicitAttributes(t *testing.T) {
+	query := `INSERT INTO account VALUES ('foo@bar.com', 'tititoto', 4)`
+	parse(query, 1, t)
+}
 
 func TestParseDelete(t *testing.T) {
 	query := `delete from "posts"`
@@ -393,6 +434,12 @@ func TestCreateDefaultNumerical(t *testing.T) {
 	parse(query, 1, t)
 }
 
+func TestCreateDefaultQuoted(t *testing.T) {
+	query := `CREATE TABLE foo (bar BIGINT, riri TEXT DEFAULT 'loulou', fifi DATE DEFAULT '2020-01-02')`
+
+	parse(query, 1, t)
+}
+
 func TestCreateWithTimestamp(t *testing.T) {
 	query := `CREATE TABLE IF NOT EXISTS "pokemon" (id BIGSERIAL PRIMARY KEY, name TEXT, type TEXT, seen TIMESTAMP WITH TIME ZONE)`
 
@@ -429,6 +476,73 @@ func TestUnique(t *testing.T) {
 	}
 }
 
+func TestTransaction(t *testing.T) {
+	queries := []string{
+		`BEGIN`,
+		`BEGIN TRANSACTION`,
+		`COMMIT`,
+		`COMMIT WORK;`,
+		`ROLLBACK`,
+		`ROLLBACK TRANSACTION`,
+		`BEGIN ISOLATION LEVEL SERIALIZABLE`,
+		`BEGIN TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY`,
+		`BEGIN ISOLATION LEVEL READ COMMITTED READ WRITE`,
+		`BEGIN READ ONLY ISOLATION LEVEL READ UNCOMMITTED`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	parse(`BEGIN; INSERT INTO account (id) VALUES (1); COMMIT;`, 3, t)
+}
+
+func TestSavepoint(t *testing.T) {
+	queries := []string{
+		`SAVEPOINT foo`,
+		`ROLLBACK TO SAVEPOINT foo`,
+		`ROLLBACK TO foo`,
+		`ROLLBACK TRANSACTION TO SAVEPOINT "foo"`,
+		`RELEASE SAVEPOINT foo`,
+		`RELEASE foo`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	queries := []string{
+		`SELECT * FROM account WHERE email = $1 AND name = $2`,
+		`SELECT * FROM account WHERE email = ? AND name = '?' LIMIT ? OFFSET ?`,
+		`SELECT * FROM account WHERE id IN ($1, $2, $3)`,
+		`INSERT INTO account (email, name) VALUES (:email, @name)`,
+		`UPDATE account SET name = $1 WHERE email = '$1'`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	// ODBC markers are numbered in order, quoted ones are literals
+	i := parse(`SELECT * FROM account WHERE email = ? AND name = '?' AND age = ?`, 1, t)
+	var parameters []string
+	var walk func(d *Decl)
+	walk = func(d *Decl) {
+		if d.Token == ParameterToken {
+			parameters = append(parameters, d.Lexeme)
+		}
+		for _, sub := range d.Decl {
+			walk(sub)
+		}
+	}
+	walk(i[0].Decls[0])
+	if len(parameters) != 2 || parameters[0] != "$1" || parameters[1] != "$2" {
+		t.Fatalf("Expected parameters [$1 $2], got %v", parameters)
+	}
+}
+
 func parse(query string, instructionCount int, t *testing.T) []Instruction {
 	log.UseTestLogger(t)
 
@@ -450,3 +564,552 @@ func parse(query string, instructionCount int, t *testing.T) []Instruction {
 
 	return instructions
 }
+
+func TestParserCreateTableWithNamedCompositePrimaryKey(t *testing.T) {
+	query := `CREATE TABLE membership (account_id BIGINT, team TEXT, CONSTRAINT membership_pk PRIMARY KEY (account_id, team))`
+	i := parse(query, 1, t)
+
+	tableDecl := i[0].Decls[0].Decl[0]
+	constraintDecl := tableDecl.Decl[len(tableDecl.Decl)-1]
+	if constraintDecl.Token != ConstraintToken || constraintDecl.Decl[0].Lexeme != "membership_pk" {
+		t.Fatalf("Expected named constraint, got %v", constraintDecl)
+	}
+
+	keyDecl := constraintDecl.Decl[1].Decl[0]
+	if len(keyDecl.Decl) != 2 || keyDecl.Decl[0].Lexeme != "account_id" || keyDecl.Decl[1].Lexeme != "team" {
+		t.Fatalf("Expected key on account_id and team, got %v", keyDecl.Decl)
+	}
+}
+
+func TestParserCreateTableWithUniqueConstraints(t *testing.T) {
+	query := `CREATE TABLE address (street TEXT, town TEXT, UNIQUE (street, town), CONSTRAINT street_town UNIQUE KEY (street, town))`
+	parse(query, 1, t)
+}
+
+func TestParserCreateTableWithCheckConstraints(t *testing.T) {
+	query := `CREATE TABLE product (id BIGINT, price INT CHECK (price > 0), stock INT, CHECK (stock >= 0 OR stock IS NULL), CHECK (stock < price * 10), CHECK (status NOT IN ('a', 'b')), CONSTRAINT cheap CHECK (price < 1000 AND price >= 1))`
+	i := parse(query, 1, t)
+
+	tableDecl := i[0].Decls[0].Decl[0]
+	checkDecl := tableDecl.Decl[len(tableDecl.Decl)-1].Decl[1]
+	if checkDecl.Token != CheckToken || len(checkDecl.Decl) != 1 || checkDecl.Decl[0].Token != AndToken || len(checkDecl.Decl[0].Decl) != 2 {
+		t.Fatalf("Expected check with 2 conditions, got %v", checkDecl)
+	}
+}
+
+func TestParserCreateTableWithNamedColumnConstraints(t *testing.T) {
+	query := `CREATE TABLE member (id BIGINT CONSTRAINT member_id PRIMARY KEY, email TEXT CONSTRAINT email_required NOT NULL CONSTRAINT "email_taken" UNIQUE, age INT CONSTRAINT adult CHECK (age >= 18), team_id BIGINT CONSTRAINT member_team REFERENCES team (id))`
+	i := parse(query, 1, t)
+
+	tableDecl := i[0].Decls[0].Decl[0]
+	emailDecl := tableDecl.Decl[2]
+	if len(emailDecl.Decl) != 3 || emailDecl.Decl[2].Token != ConstraintToken || emailDecl.Decl[2].Decl[0].Lexeme != "email_taken" || emailDecl.Decl[2].Decl[1].Token != UniqueToken {
+		t.Fatalf("Expected named unique constraint, got %v", emailDecl)
+	}
+	ageDecl := tableDecl.Decl[3]
+	if len(ageDecl.Decl) != 2 || ageDecl.Decl[1].Token != ConstraintToken || ageDecl.Decl[1].Decl[1].Token != CheckToken {
+		t.Fatalf("Expected named check constraint, got %v", ageDecl)
+	}
+
+	failures := []string{
+		`CREATE TABLE member (id BIGINT CONSTRAINT PRIMARY KEY)`,
+		`CREATE TABLE member (id BIGINT CONSTRAINT member_id)`,
+		`CREATE TABLE member (email TEXT CONSTRAINT email_required NOT)`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserAlterTable(t *testing.T) {
+	queries := []string{
+		`ALTER TABLE account ADD COLUMN age INT DEFAULT 18 NOT NULL`,
+		`ALTER TABLE account ADD age INT, ADD CONSTRAINT account_age_check CHECK (age > 0)`,
+		`ALTER TABLE IF EXISTS account ADD COLUMN IF NOT EXISTS age INT`,
+		`ALTER TABLE account ADD IF NOT EXISTS age INT`,
+		`ALTER TABLE account ADD UNIQUE (email), ADD FOREIGN KEY (team_id) REFERENCES team (id) ON DELETE CASCADE`,
+		`ALTER TABLE account DROP COLUMN IF EXISTS age CASCADE`,
+		`ALTER TABLE account DROP age`,
+		`ALTER TABLE account DROP CONSTRAINT account_pkey`,
+		`ALTER TABLE account RENAME COLUMN email TO mail`,
+		`ALTER TABLE account RENAME email TO mail`,
+		`ALTER TABLE account RENAME TO member`,
+		`ALTER TABLE account ALTER COLUMN age SET DEFAULT 21`,
+		`ALTER TABLE account ALTER COLUMN email SET DEFAULT 'foo@bar.com'`,
+		`ALTER TABLE account ADD COLUMN nick TEXT DEFAULT 'anonymous'`,
+		`ALTER TABLE account ALTER age DROP DEFAULT`,
+		`ALTER TABLE account ALTER COLUMN age SET NOT NULL, ALTER COLUMN email DROP NOT NULL`,
+		`ALTER TABLE account ALTER COLUMN age TYPE BIGINT`,
+		`ALTER TABLE account ALTER COLUMN age TYPE BIGINT USING age * 12, ALTER COLUMN email TYPE TEXT USING lower(email)`,
+		`ALTER TABLE account ALTER COLUMN email SET DATA TYPE VARCHAR(255)`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`ALTER TABLE account RENAME email TO mail, DROP COLUMN age`, 1, t)
+	alterDecl := i[0].Decls[0]
+	if len(alterDecl.Decl) != 3 || alterDecl.Decl[1].Token != RenameToken || alterDecl.Decl[2].Token != DropToken {
+		t.Fatalf("Expected rename and drop actions, got %v", alterDecl.Decl)
+	}
+
+	i = parse(`ALTER TABLE IF EXISTS account ADD COLUMN IF NOT EXISTS age INT`, 1, t)
+	alterDecl = i[0].Decls[0]
+	if len(alterDecl.Decl[0].Decl) != 2 || alterDecl.Decl[0].Decl[0].Token != IfToken || alterDecl.Decl[0].Decl[1].Lexeme != "account" {
+		t.Fatalf("Expected IF EXISTS before table name, got %v", alterDecl.Decl[0].Decl)
+	}
+	columnDecl := alterDecl.Decl[1].Decl[0]
+	if len(columnDecl.Decl) != 2 || columnDecl.Decl[0].Token != IfToken || columnDecl.Decl[1].Lexeme != "age" {
+		t.Fatalf("Expected IF NOT EXISTS before column definition, got %v", columnDecl.Decl)
+	}
+}
+
+func TestParserIndex(t *testing.T) {
+	queries := []string{
+		`CREATE INDEX account_email_idx ON account (email)`,
+		`CREATE INDEX ON account (email, name DESC)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS account_email_idx ON account USING HASH (email)`,
+		`CREATE INDEX account_email_idx USING BTREE ON account (email)`,
+		`DROP INDEX account_email_idx`,
+		`DROP INDEX IF EXISTS account_email_idx CASCADE`,
+		`DROP INDEX account_email_idx ON account`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`CREATE UNIQUE INDEX account_email_idx ON account USING HASH (email, name)`, 1, t)
+	indexDecl := i[0].Decls[0].Decl[0]
+	if indexDecl.Token != IndexToken || len(indexDecl.Decl) != 5 {
+		t.Fatalf("Expected index declaration, got %v", indexDecl)
+	}
+	if key := indexDecl.Decl[4]; key.Token != KeyToken || len(key.Decl) != 2 {
+		t.Fatalf("Expected 2 attributes in index key, got %v", key)
+	}
+}
+
+func TestParserSequence(t *testing.T) {
+	queries := []string{
+		`CREATE SEQUENCE account_id_seq`,
+		`CREATE SEQUENCE IF NOT EXISTS account_id_seq AS INTEGER INCREMENT BY 2 MINVALUE 10 NO MAXVALUE START WITH 10 CACHE 1 NO CYCLE`,
+		`CREATE SEQUENCE "account_id_seq" INCREMENT 5 MAXVALUE 100 START 20 CYCLE`,
+		`ALTER SEQUENCE account_id_seq RESTART`,
+		`ALTER SEQUENCE account_id_seq RESTART WITH 100 INCREMENT BY 10`,
+		`DROP SEQUENCE account_id_seq`,
+		`DROP SEQUENCE IF EXISTS account_id_seq CASCADE`,
+		`SELECT nextval('account_id_seq')`,
+		`SELECT currval('account_id_seq'), setval('account_id_seq', 10, false)`,
+		`INSERT INTO account (id, email) VALUES (nextval('account_id_seq'), 'foo@bar.com')`,
+		`CREATE TABLE account (id INT DEFAULT nextval('account_id_seq'), email TEXT)`,
+		`CREATE TABLE account (id SERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE account (id BIGINT GENERATED ALWAYS AS IDENTITY, email TEXT)`,
+		`CREATE TABLE account (id BIGINT GENERATED BY DEFAULT AS IDENTITY (START WITH 100 INCREMENT BY 10) PRIMARY KEY, email TEXT)`,
+		`TRUNCATE account`,
+		`TRUNCATE TABLE account RESTART IDENTITY`,
+		`TRUNCATE account CONTINUE IDENTITY`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`ALTER SEQUENCE account_id_seq RESTART WITH 100`, 1, t)
+	alterDecl := i[0].Decls[0]
+	if len(alterDecl.Decl) != 2 || alterDecl.Decl[0].Token != SequenceToken || alterDecl.Decl[1].Token != RestartToken {
+		t.Fatalf("Expected sequence restart, got %v", alterDecl.Decl)
+	}
+
+	i = parse(`SELECT setval('account_id_seq', 10, false)`, 1, t)
+	funcDecl := i[0].Decls[0].Decl[0]
+	if funcDecl.Token != SetvalToken || len(funcDecl.Decl) != 3 {
+		t.Fatalf("Expected setval call with 3 arguments, got %v", funcDecl)
+	}
+
+	failures := []string{
+		`CREATE SEQUENCE account_id_seq RESTART`,
+		`ALTER SEQUENCE account_id_seq`,
+		`SELECT nextval('account_id_seq'), id`,
+		`SELECT setval('account_id_seq')`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserView(t *testing.T) {
+	queries := []string{
+		`CREATE VIEW active_account AS SELECT * FROM account WHERE active = true`,
+		`CREATE OR REPLACE VIEW active_account AS SELECT id, email FROM account WHERE active = true ORDER BY email LIMIT 10`,
+		`CREATE VIEW "account_address" (account_id, city) AS SELECT account.id, address.city FROM account JOIN address ON account.id = address.account_id`,
+		`DROP VIEW active_account`,
+		`DROP VIEW IF EXISTS active_account CASCADE`,
+		`SELECT * FROM active_account WHERE email = 'foo@bar.com'`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`CREATE OR REPLACE VIEW v (a, b) AS SELECT id, email FROM account`, 1, t)
+	viewDecl := i[0].Decls[0].Decl[0]
+	if viewDecl.Token != ViewToken || len(viewDecl.Decl) != 3 {
+		t.Fatalf("Expected view with OR REPLACE, name and AS, got %v", viewDecl.Decl)
+	}
+	if len(viewDecl.Decl[1].Decl) != 2 {
+		t.Fatalf("Expected 2 view columns, got %v", viewDecl.Decl[1].Decl)
+	}
+	if viewDecl.Decl[2].Decl[0].Token != SelectToken {
+		t.Fatalf("Expected view query, got %v", viewDecl.Decl[2].Decl)
+	}
+
+	failures := []string{
+		`CREATE VIEW active_account`,
+		`CREATE VIEW active_account AS UPDATE account SET active = false`,
+		`CREATE OR VIEW active_account AS SELECT * FROM account`,
+		`CREATE VIEW active_account () AS SELECT * FROM account`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserSchema(t *testing.T) {
+	queries := []string{
+		`CREATE SCHEMA billing`,
+		`CREATE SCHEMA IF NOT EXISTS billing AUTHORIZATION admin`,
+		`CREATE SCHEMA AUTHORIZATION admin`,
+		`DROP SCHEMA IF EXISTS billing CASCADE`,
+		`SET search_path TO billing, public`,
+		`SET SESSION search_path = "billing"`,
+		`SET search_path TO DEFAULT`,
+		`CREATE TABLE billing.invoices (id INT PRIMARY KEY, user_id INT REFERENCES auth.users (id))`,
+		`INSERT INTO billing.invoices (id, user_id) VALUES (1, 1)`,
+		`UPDATE billing.invoices SET user_id = 2 WHERE id = 1`,
+		`DELETE FROM billing.invoices WHERE id = 1`,
+		`TRUNCATE billing.invoices`,
+		`ALTER TABLE billing.invoices ADD COLUMN total INT`,
+		`DROP TABLE billing.invoices`,
+		`CREATE INDEX invoices_user_idx ON billing.invoices (user_id)`,
+		`SELECT billing.invoices.id, users.email FROM billing.invoices JOIN auth.users ON users.id = invoices.user_id`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`SELECT billing.invoices.id FROM billing.invoices`, 1, t)
+	selectDecl := i[0].Decls[0]
+	if selectDecl.Decl[0].Lexeme != "id" || selectDecl.Decl[0].Decl[0].Lexeme != "billing.invoices" {
+		t.Fatalf("Expected column id of billing.invoices, got %v", selectDecl.Decl[0])
+	}
+	if selectDecl.Decl[1].Decl[0].Lexeme != "billing.invoices" {
+		t.Fatalf("Expected table billing.invoices, got %v", selectDecl.Decl[1].Decl[0])
+	}
+
+	failures := []string{
+		`CREATE SCHEMA`,
+		`SET search_path`,
+		`SET timezone TO 'UTC'`,
+		`SELECT * FROM billing.`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserTemporaryTable(t *testing.T) {
+	queries := []string{
+		`CREATE TEMP TABLE staging (id INT, note TEXT)`,
+		`CREATE TEMPORARY TABLE IF NOT EXISTS staging (id SERIAL PRIMARY KEY)`,
+		`CREATE TEMPORARY TABLE staging (id INT) ON COMMIT DROP`,
+		`CREATE TEMP TABLE staging (id INT) ON COMMIT PRESERVE ROWS`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	failures := []string{
+		`CREATE TEMP staging (id INT)`,
+		`CREATE TEMPORARY TABLE staging (id INT) ON COMMIT`,
+		`CREATE TEMPORARY TABLE staging (id INT) ON COMMIT PRESERVE`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserInsertSelect(t *testing.T) {
+	queries := []string{
+		`INSERT INTO account (id, email) SELECT id, email FROM legacy_account`,
+		`INSERT INTO account (id, email) SELECT id, email FROM legacy_account WHERE active = true`,
+		`INSERT INTO account (id) SELECT id FROM legacy_account RETURNING id`,
+		`INSERT INTO account SELECT * FROM legacy_account`,
+		`CREATE TABLE active_account AS SELECT id, email FROM account WHERE active = true`,
+		`CREATE TEMP TABLE active_account ON COMMIT DROP AS SELECT * FROM account`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`INSERT INTO account (id) SELECT id FROM legacy_account`, 1, t)
+	if i[0].Decls[0].Decl[1].Token != SelectToken {
+		t.Fatalf("Expected SELECT, got %v", i[0].Decls[0].Decl[1])
+	}
+
+	failures := []string{
+		`INSERT INTO account (id) SELECT`,
+		`CREATE TABLE active_account AS`,
+		`CREATE TABLE active_account AS VALUES (1)`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserInsertMultipleRows(t *testing.T) {
+	i := parse(`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com'), (2, 'bar@bar.com'), (3, DEFAULT) RETURNING id`, 1, t)
+
+	var rows int
+	for _, d := range i[0].Decls[0].Decl {
+		if d.Token == ValuesToken {
+			rows++
+			if len(d.Decl) != 2 {
+				t.Fatalf("Expected 2 values per row, got %d", len(d.Decl))
+			}
+		}
+	}
+	if rows != 3 {
+		t.Fatalf("Expected 3 rows, got %d", rows)
+	}
+
+	failures := []string{
+		`INSERT INTO account (id) VALUES (1),`,
+		`INSERT INTO account (id) VALUES (1) (2)`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserUpsert(t *testing.T) {
+	queries := []string{
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') ON CONFLICT DO NOTHING`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') ON CONFLICT (id, email) DO NOTHING RETURNING id`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') ON CONFLICT ON CONSTRAINT account_pkey DO UPDATE SET email = EXCLUDED.email, visits = 1`,
+		`INSERT INTO account (id, email) SELECT id, email FROM legacy ON CONFLICT (id) DO UPDATE SET email = $1`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') ON DUPLICATE KEY UPDATE email = VALUES(email)`,
+		`INSERT INTO account (id, visits) VALUES (1, 1) ON CONFLICT (id) DO UPDATE SET visits = account.visits + EXCLUDED.visits WHERE account.visits < 10`,
+		`INSERT INTO account (id, visits) VALUES (1, 1) ON CONFLICT (id) DO UPDATE SET visits = visits + 1 WHERE excluded.visits > 0 RETURNING id`,
+		`INSERT INTO account (id, visits) VALUES (1, 1) ON DUPLICATE KEY UPDATE visits = visits + 1, email = email || '!'`,
+		`REPLACE INTO account (id, email) VALUES (1, 'foo@bar.com'), (2, 'bar@bar.com')`,
+	}
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') ON CONFLICT (id) DO UPDATE SET email = EXCLUDED.email`, 1, t)
+	conflictDecl := i[0].Decls[0].Decl[2]
+	if conflictDecl.Token != ConflictToken || len(conflictDecl.Decl) != 2 {
+		t.Fatalf("Expected conflict with key and update, got %v", conflictDecl)
+	}
+	assignment := conflictDecl.Decl[1].Decl[0]
+	if v := assignment.Decl[1]; assignment.Lexeme != "email" || v.Token != ColumnToken || v.Lexeme != "email" || v.Decl[0].Token != ExcludedToken {
+		t.Fatalf("Expected email = excluded.email, got %v", assignment)
+	}
+
+	i = parse(`INSERT INTO account (id, visits) VALUES (1, 1) ON DUPLICATE KEY UPDATE visits = visits + VALUES(visits)`, 1, t)
+	assignment = i[0].Decls[0].Decl[2].Decl[0].Decl[0]
+	if v := assignment.Decl[1]; v.Token != PlusToken || v.Decl[1].Token != ColumnToken || v.Decl[1].Decl[0].Token != ExcludedToken {
+		t.Fatalf("Expected visits = visits + excluded.visits, got %v", assignment)
+	}
+
+	failures := []string{
+		`INSERT INTO account (id) VALUES (1) ON CONFLICT`,
+		`INSERT INTO account (id) VALUES (1) ON CONFLICT (id) DO`,
+		`INSERT INTO account (id) VALUES (1) ON CONFLICT (id) DO UPDATE email = 1`,
+		`INSERT INTO account (id) VALUES (1) ON CONFLICT () DO NOTHING`,
+		`INSERT INTO account (id) VALUES (1) ON DUPLICATE UPDATE id = 1`,
+		`REPLACE INTO account (id) VALUES (1) ON CONFLICT DO NOTHING`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserReturning(t *testing.T) {
+	queries := []string{
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') RETURNING *`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') RETURNING id, email AS address, account.created_at`,
+		`INSERT INTO account (id, email) VALUES (1, 'foo@bar.com') RETURNING id * 2 + 1 doubled, 'id: ' || id, -(id % 3), NOW()`,
+		`UPDATE account SET email = 'foo@bar.com' WHERE id = 1 RETURNING account.*`,
+		`UPDATE account SET email = 'foo@bar.com' RETURNING id, (price - 1) / 2 AS half`,
+		`DELETE FROM account WHERE id = 1 RETURNING id, email`,
+		`DELETE FROM account RETURNING *`,
+	}
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`DELETE FROM account RETURNING price * quantity AS total`, 1, t)
+	deleteDecl := i[0].Decls[0]
+	if len(deleteDecl.Decl) != 3 || deleteDecl.Decl[1].Token != WhereToken {
+		t.Fatalf("Expected implicit WHERE, got %v", deleteDecl)
+	}
+	asDecl := deleteDecl.Decl[2].Decl[0]
+	if asDecl.Token != AsToken || asDecl.Decl[1].Lexeme != "total" {
+		t.Fatalf("Expected aliased expression, got %v", asDecl)
+	}
+	if exprDecl := asDecl.Decl[0]; exprDecl.Token != StarToken || exprDecl.Decl[0].Token != ColumnToken || exprDecl.Decl[1].Lexeme != "quantity" {
+		t.Fatalf("Expected price * quantity, got %v", exprDecl)
+	}
+
+	// Multiplicative operators bind tighter than additive ones
+	i = parse(`INSERT INTO account (id) VALUES (1) RETURNING 1 + 2 * 3 - 4`, 1, t)
+	retDecl := i[0].Decls[0].Decl[2]
+	minusDecl := retDecl.Decl[0]
+	if minusDecl.Token != MinusToken || minusDecl.Decl[0].Token != PlusToken || minusDecl.Decl[0].Decl[1].Token != StarToken {
+		t.Fatalf("Expected (1 + (2 * 3)) - 4, got %v", minusDecl)
+	}
+
+	failures := []string{
+		`INSERT INTO account (id) VALUES (1) RETURNING`,
+		`INSERT INTO account (id) VALUES (1) RETURNING id +`,
+		`INSERT INTO account (id) VALUES (1) RETURNING (id`,
+		`UPDATE account SET id = 1 RETURNING id,`,
+		`DELETE FROM account WHERE id = 1 RETURNING id AS`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserUpdateExpressions(t *testing.T) {
+	queries := []string{
+		`UPDATE counter SET n = n + 1`,
+		`UPDATE counter SET updated_at = NOW(), total = price * qty WHERE id = 1`,
+		`UPDATE counter SET "n" = counter.n * 2, label = 'count: ' || n`,
+		`UPDATE counter SET n = COALESCE(n, 0), label = lower(name)`,
+		`UPDATE counter SET label = CASE WHEN n IS NULL THEN 'none' WHEN n > 1 AND NOT qty <= 2 THEN 'many' ELSE 'few' END`,
+		`UPDATE counter SET label = CASE n WHEN 1 THEN 'one' WHEN 2 THEN 'two' END`,
+		`UPDATE counter SET n = (SELECT amount FROM bonus WHERE id = 1) + 1 WHERE id = 2`,
+		`UPDATE counter SET n = (SELECT COUNT(*) FROM bonus)`,
+		`UPDATE counter SET enabled = true, n = $1 WHERE id = $2`,
+	}
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`UPDATE counter SET n = n + 1, qty = 2 WHERE id = 1`, 1, t)
+	setDecl := i[0].Decls[0].Decl[1]
+	if len(setDecl.Decl) != 2 {
+		t.Fatalf("Expected 2 assignments, got %v", setDecl)
+	}
+	if exprDecl := setDecl.Decl[0].Decl[1]; exprDecl.Token != PlusToken || exprDecl.Decl[0].Token != ColumnToken {
+		t.Fatalf("Expected n + 1, got %v", exprDecl)
+	}
+
+	// Simple CASE is rewritten as a searched one
+	i = parse(`UPDATE counter SET label = CASE n WHEN 1 THEN 'one' ELSE 'other' END`, 1, t)
+	caseDecl := i[0].Decls[0].Decl[1].Decl[0].Decl[1]
+	if caseDecl.Token != CaseToken || len(caseDecl.Decl) != 2 || caseDecl.Decl[1].Token != ElseToken {
+		t.Fatalf("Expected CASE with WHEN and ELSE, got %v", caseDecl)
+	}
+	if whenDecl := caseDecl.Decl[0]; whenDecl.Token != WhenToken || whenDecl.Decl[0].Token != EqualityToken {
+		t.Fatalf("Expected n = 1 condition, got %v", whenDecl)
+	}
+
+	// Subqueries are kept whole
+	i = parse(`UPDATE counter SET n = (SELECT amount FROM bonus WHERE id = 1) WHERE id = 2`, 1, t)
+	updateDecl := i[0].Decls[0]
+	if updateDecl.Decl[1].Decl[0].Decl[1].Token != SelectToken || updateDecl.Decl[2].Token != WhereToken {
+		t.Fatalf("Expected subquery then WHERE, got %v", updateDecl)
+	}
+
+	failures := []string{
+		`UPDATE counter SET`,
+		`UPDATE counter SET n =`,
+		`UPDATE counter SET n = n +`,
+		`UPDATE counter SET n = 1,`,
+		`UPDATE counter SET n = CASE WHEN n > 1 THEN 1`,
+		`UPDATE counter SET n = CASE END`,
+		`UPDATE counter SET n = lower(name`,
+		`UPDATE counter SET n = (SELECT amount FROM bonus`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
+
+func TestParserJoinedWrites(t *testing.T) {
+	queries := []string{
+		`UPDATE orders SET status = 'vip' FROM customers WHERE orders.customer_id = customers.id AND customers.vip`,
+		`UPDATE orders SET amount = amount - customers.discount FROM customers, coupons WHERE orders.customer_id = customers.id OR coupons.id = 1 RETURNING orders.id, customers.name`,
+		`UPDATE orders SET status = 'done' FROM customers`,
+		`UPDATE orders JOIN customers ON orders.customer_id = customers.id SET orders.status = 'vip' WHERE customers.vip`,
+		`UPDATE orders INNER JOIN customers ON orders.customer_id = customers.id SET status = 'vip'`,
+		`DELETE FROM sessions USING users WHERE sessions.user_id = users.id AND users.disabled`,
+		`DELETE FROM sessions USING users, groups WHERE sessions.user_id = users.id AND NOT users.group_id = groups.id RETURNING sessions.id`,
+		`DELETE FROM sessions USING users`,
+		`UPDATE orders o JOIN customers c ON o.customer_id = c.id SET o.status = 'vip'`,
+		`UPDATE orders AS o SET status = 'vip' FROM customers c WHERE o.customer_id = c.id`,
+		`DELETE FROM sessions s USING users u WHERE s.user_id = u.id`,
+		`DELETE FROM sessions AS s WHERE s.id = 1`,
+	}
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	i := parse(`UPDATE orders JOIN customers ON orders.customer_id = customers.id SET status = 'vip' FROM coupons WHERE customers.vip`, 1, t)
+	updateDecl := i[0].Decls[0]
+	if len(updateDecl.Decl) != 5 || updateDecl.Decl[1].Token != SetToken || updateDecl.Decl[2].Token != JoinToken || updateDecl.Decl[3].Token != FromToken {
+		t.Fatalf("Expected SET, JOIN and FROM, got %v", updateDecl)
+	}
+	if whereDecl := updateDecl.Decl[4]; whereDecl.Token != WhereToken || len(whereDecl.Decl) != 1 || whereDecl.Decl[0].Token != ColumnToken {
+		t.Fatalf("Expected WHERE condition, got %v", whereDecl)
+	}
+
+	i = parse(`DELETE FROM sessions USING users WHERE sessions.user_id = users.id AND users.disabled`, 1, t)
+	deleteDecl := i[0].Decls[0]
+	if len(deleteDecl.Decl) != 3 || deleteDecl.Decl[1].Token != UsingToken || deleteDecl.Decl[2].Decl[0].Token != AndToken {
+		t.Fatalf("Expected USING and WHERE condition, got %v", deleteDecl)
+	}
+
+	failures := []string{
+		`UPDATE orders SET status = 'vip' FROM`,
+		`UPDATE orders SET status = 'vip' FROM customers,`,
+		`UPDATE orders SET status = 'vip' FROM customers WHERE`,
+		`UPDATE orders JOIN customers SET status = 'vip'`,
+		`UPDATE orders LEFT JOIN customers ON orders.customer_id = customers.id SET status = 'vip'`,
+		`DELETE FROM sessions USING`,
+		`DELETE FROM sessions USING users WHERE users.id =`,
+	}
+	for _, q := range failures {
+		if _, err := ParseInstruction(q); err == nil {
+			t.Fatalf("Expected error parsing %s", q)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseSchema parses a schema definition
+// SCHEMA [ IF NOT EXISTS ] <SCHEMA-NAME> [ AUTHORIZATION <ROLE-NAME> ]
+// SCHEMA [ IF NOT EXISTS ] AUTHORIZATION <ROLE-NAME>
+//
+// The schema is named after the role if its name is omitted. Roles are
+// not checked.
+//
+//	|-> schema
+//	    |-> if
+//	        |-> not
+//	            |-> exists
+//	    |-> <SCHEMA-NAME>
+func (p *parser) parseSchema() (*Decl, error) {
+	if _, err := p.consumeWord("schema"); err != nil {
+		return nil, err
+	}
+	schemaDecl := NewDecl(Token{Token: SchemaToken, Lexeme: "schema"})
+
+	// Optional: IF NOT EXISTS
+	if p.is(IfToken) {
+		ifDecl, err := p.parseIfNotExists()
+		if err != nil {
+			return nil, err
+		}
+		schemaDecl.Add(ifDecl)
+	}
+
+	// Required: <SCHEMA-NAME> and/or AUTHORIZATION <ROLE-NAME>
+	var nameDecl *Decl
+	if !p.isWord("authorization") {
+		d, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		nameDecl = d
+	}
+	if p.isWord("authorization") {
+		p.next()
+		roleDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		if nameDecl == nil {
+			nameDecl = roleDecl
+		}
+	}
+	schemaDecl.Add(nameDecl)
+
+	if !p.is(SemicolonToken) {
+		return nil, p.syntaxError()
+	}
+
+	return schemaDecl, nil
+}
+
+// parseSet parses the change of a session setting. Only search_path,
+// the list of schemas where unqualified names are looked up, is supported.
+// SET [ SESSION ] SEARCH_PATH { TO | = } { <SCHEMA-NAME> [, ...] | DEFAULT }
+//
+//	|-> set
+//	    |-> search_path
+//	        |-> <SCHEMA-NAME>
+//
+//	|-> set
+//	    |-> search_path
+//	        |-> default
+func (p *parser) parseSet() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: SET
+	setDecl, err := p.consumeToken(SetToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, setDecl)
+
+	// Optional: SESSION
+	if p.isWord("session") {
+		p.next()
+	}
+
+	// Required: SEARCH_PATH
+	paramDecl, err := p.consumeWord("search_path")
+	if err != nil {
+		return nil, err
+	}
+	paramDecl.Lexeme = strings.ToLower(paramDecl.Lexeme)
+	setDecl.Add(paramDecl)
+
+	// Required: TO | =
+	if p.is(EqualityToken) {
+		p.next()
+	} else if _, err := p.consumeWord("to"); err != nil {
+		return nil, err
+	}
+
+	// Required: DEFAULT | <SCHEMA-NAME> [, ...]
+	if p.is(DefaultToken) {
+		defaultDecl, err := p.consumeToken(DefaultToken)
+		if err != nil {
+			return nil, err
+		}
+		paramDecl.Add(defaultDecl)
+	} else {
+		for {
+			nameDecl, err := p.parseQuotedToken()
+			if err != nil {
+				return nil, err
+			}
+			paramDecl.Add(nameDecl)
+
+			if !p.is(CommaToken) {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if !p.is(SemicolonToken) {
+		return nil, p.syntaxError()
+	}
+
+	return i, nil
+}
@@ -16,10 +16,12 @@ func (p *parser) parseSelect() (*Instruction, error) {
 	// a StarToken
 	// a list of table names + (StarToken Or Attribute)
 	// a builtin func (COUNT, MAX, ...)
+	// a sequence func (NEXTVAL, CURRVAL, SETVAL)
 	if err = p.next(); err != nil {
 		return nil, fmt.Errorf("SELECT token must be followed by attributes to select")
 	}
 
+	funcs := true
 	for {
 		if p.is(CountToken) {
 			attrDecl, err := p.parseBuiltinFunc()
@@ -27,7 +29,15 @@ func (p *parser) parseSelect() (*Instruction, error) {
 				return nil, err
 			}
 			selectDecl.Add(attrDecl)
+			funcs = false
+		} else if p.isSequenceFunc() {
+			funcDecl, err := p.parseSequenceFunc()
+			if err != nil {
+				return nil, err
+			}
+			selectDecl.Add(funcDecl)
 		} else {
+			funcs = false
 			attrDecl, err := p.parseAttribute()
 			if err != nil {
 				return nil, err
@@ -45,6 +55,11 @@ func (p *parser) parseSelect() (*Instruction, error) {
 		break
 	}
 
+	// Optional: FROM, if only sequence functions are called
+	if funcs && p.is(SemicolonToken) {
+		return i, nil
+	}
+
 	// Required: FROM
 	if p.cur().Token != FromToken {
 		return nil, fmt.Errorf("Syntax error near %v", p.cur())
@@ -58,10 +73,13 @@ func (p *parser) parseSelect() (*Instruction, error) {
 		if err = p.next(); err != nil {
 			return nil, fmt.Errorf("Unexpected end. Syntax error near %v", p.cur())
 		}
-		tableNameDecl, err := p.parseAttribute()
+		tableNameDecl, err := p.parseTableName()
 		if err != nil {
 			return nil, err
 		}
+		if err := p.parseTableAlias(tableNameDecl); err != nil {
+			return nil, err
+		}
 		fromDecl.Add(tableNameDecl)
 
 		// If no next, then it's implicit where
@@ -145,7 +163,7 @@ func (p *parser) parseSelect() (*Instruction, error) {
 				return nil, err
 			}
 			selectDecl.Add(limitDecl)
-			numDecl, err := p.consumeToken(NumberToken)
+			numDecl, err := p.consumeToken(NumberToken, ParameterToken)
 			if err != nil {
 				return nil, err
 			}
@@ -156,7 +174,7 @@ func (p *parser) parseSelect() (*Instruction, error) {
 				return nil, err
 			}
 			selectDecl.Add(offsetDecl)
-			offsetValue, err := p.consumeToken(NumberToken)
+			offsetValue, err := p.consumeToken(NumberToken, ParameterToken)
 			if err != nil {
 				return nil, err
 			}
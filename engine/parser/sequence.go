@@ -0,0 +1,292 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseSequence parses a sequence definition
+// SEQUENCE [ IF NOT EXISTS ] <SEQUENCE-NAME> [ <SEQUENCE-OPTION> ... ]
+//
+//	|-> sequence
+//	    |-> if
+//	        |-> not
+//	            |-> exists
+//	    |-> <SEQUENCE-NAME>
+//	    |-> <SEQUENCE-OPTION>
+func (p *parser) parseSequence() (*Decl, error) {
+	if _, err := p.consumeWord("sequence"); err != nil {
+		return nil, err
+	}
+	sequenceDecl := NewDecl(Token{Token: SequenceToken, Lexeme: "sequence"})
+
+	// Optional: IF NOT EXISTS
+	if p.is(IfToken) {
+		ifDecl, err := p.parseIfNotExists()
+		if err != nil {
+			return nil, err
+		}
+		sequenceDecl.Add(ifDecl)
+	}
+
+	// Required: <SEQUENCE-NAME>
+	nameDecl, err := p.parseTableName()
+	if err != nil {
+		return nil, err
+	}
+	sequenceDecl.Add(nameDecl)
+
+	// Optional: <SEQUENCE-OPTION> ...
+	if err := p.parseSequenceOptions(sequenceDecl, false); err != nil {
+		return nil, err
+	}
+
+	return sequenceDecl, nil
+}
+
+// parseAlterSequence parses a sequence alteration, once ALTER is consumed
+// SEQUENCE <SEQUENCE-NAME> <SEQUENCE-OPTION> [ <SEQUENCE-OPTION> ... ]
+//
+//	|-> sequence
+//	    |-> <SEQUENCE-NAME>
+//	|-> <SEQUENCE-OPTION>
+func (p *parser) parseAlterSequence(alterDecl *Decl) error {
+	if _, err := p.consumeWord("sequence"); err != nil {
+		return err
+	}
+	sequenceDecl := NewDecl(Token{Token: SequenceToken, Lexeme: "sequence"})
+	alterDecl.Add(sequenceDecl)
+
+	// Required: <SEQUENCE-NAME>
+	nameDecl, err := p.parseTableName()
+	if err != nil {
+		return err
+	}
+	sequenceDecl.Add(nameDecl)
+
+	// Required: <SEQUENCE-OPTION> [ <SEQUENCE-OPTION> ... ]
+	if err := p.parseSequenceOptions(alterDecl, true); err != nil {
+		return err
+	}
+	if len(alterDecl.Decl) == 1 || !p.is(SemicolonToken) {
+		return p.syntaxError()
+	}
+
+	return nil
+}
+
+// parseSequenceOptions parses the options of a sequence, or of an identity
+// column, adding them to given decl. RESTART is only allowed on alteration.
+//
+//	AS <TYPE>
+//	INCREMENT [ BY ] <N>
+//	{ MINVALUE <N> | NO MINVALUE }
+//	{ MAXVALUE <N> | NO MAXVALUE }
+//	START [ WITH ] <N>
+//	RESTART [ [ WITH ] <N> ]
+//	CACHE <N>
+//	[ NO ] CYCLE
+//
+//	|-> { increment | minvalue | maxvalue | start | restart | cache }
+//	    |-> <N>
+//	|-> no
+//	    |-> { minvalue | maxvalue | cycle }
+//	|-> cycle
+//	|-> as
+//	    |-> <TYPE>
+func (p *parser) parseSequenceOptions(decl *Decl, alter bool) error {
+	for {
+		var optionDecl *Decl
+		var err error
+
+		switch {
+		case p.is(AsToken):
+			optionDecl, err = p.consumeToken(AsToken)
+			if err != nil {
+				return err
+			}
+			typeDecl, err := p.parseType()
+			if err != nil {
+				return err
+			}
+			optionDecl.Add(typeDecl)
+		case p.isWord("increment"):
+			optionDecl, err = p.parseSequenceOption(IncrementToken, ByToken, false)
+		case p.isWord("minvalue"):
+			optionDecl, err = p.parseSequenceOption(MinvalueToken, -1, false)
+		case p.isWord("maxvalue"):
+			optionDecl, err = p.parseSequenceOption(MaxvalueToken, -1, false)
+		case p.isWord("start"):
+			optionDecl, err = p.parseSequenceOption(StartToken, WithToken, false)
+		case p.isWord("restart") && alter:
+			optionDecl, err = p.parseSequenceOption(RestartToken, WithToken, true)
+		case p.isWord("cache"):
+			optionDecl, err = p.parseSequenceOption(CacheToken, -1, false)
+		case p.isWord("cycle"):
+			optionDecl = NewDecl(Token{Token: CycleToken, Lexeme: "cycle"})
+			p.next()
+		case p.is(NoToken):
+			optionDecl, err = p.consumeToken(NoToken)
+			if err != nil {
+				return err
+			}
+			wordDecl, err := p.consumeWord("minvalue", "maxvalue", "cycle")
+			if err != nil {
+				return err
+			}
+			wordDecl.Token = sequenceOptionToken(wordDecl.Lexeme)
+			optionDecl.Add(wordDecl)
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		decl.Add(optionDecl)
+	}
+}
+
+// parseSequenceOption parses a sequence option made of a word, an optional
+// noise word and a number, which may be omitted if optional is true
+func (p *parser) parseSequenceOption(token int, noise int, optional bool) (*Decl, error) {
+	optionDecl := NewDecl(Token{Token: token, Lexeme: strings.ToLower(p.cur().Lexeme)})
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.is(noise) {
+		p.next()
+	} else if optional && p.isNot(NumberToken) {
+		return optionDecl, nil
+	}
+
+	valueDecl, err := p.consumeToken(NumberToken)
+	if err != nil {
+		return nil, err
+	}
+	optionDecl.Add(valueDecl)
+
+	return optionDecl, nil
+}
+
+func sequenceOptionToken(word string) int {
+	switch strings.ToLower(word) {
+	case "minvalue":
+		return MinvalueToken
+	case "maxvalue":
+		return MaxvalueToken
+	}
+
+	return CycleToken
+}
+
+// parseIdentity parses an identity column constraint
+// GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY [ '(' <SEQUENCE-OPTION> ... ')' ]
+//
+//	|-> generated
+//	    |-> { always | default }
+//	    |-> <SEQUENCE-OPTION>
+func (p *parser) parseIdentity() (*Decl, error) {
+	if _, err := p.consumeWord("generated"); err != nil {
+		return nil, err
+	}
+	generatedDecl := NewDecl(Token{Token: GeneratedToken, Lexeme: "generated"})
+
+	// Required: ALWAYS | BY DEFAULT
+	if p.isWord("always") {
+		generatedDecl.Add(NewDecl(Token{Token: AlwaysToken, Lexeme: "always"}))
+		p.next()
+	} else {
+		if _, err := p.consumeToken(ByToken); err != nil {
+			return nil, err
+		}
+		defaultDecl, err := p.consumeToken(DefaultToken)
+		if err != nil {
+			return nil, err
+		}
+		generatedDecl.Add(defaultDecl)
+	}
+
+	// Required: AS IDENTITY
+	if _, err := p.consumeToken(AsToken); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeWord("identity"); err != nil {
+		return nil, err
+	}
+
+	// Optional: '(' <SEQUENCE-OPTION> ... ')'
+	if p.is(BracketOpeningToken) {
+		p.next()
+		if err := p.parseSequenceOptions(generatedDecl, false); err != nil {
+			return nil, err
+		}
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+	}
+
+	return generatedDecl, nil
+}
+
+// isSequenceFunc checks if current token is a call to a sequence function
+func (p *parser) isSequenceFunc() bool {
+	return p.isWord("nextval", "currval", "setval") && p.hasNext() && p.peekForward().Token == BracketOpeningToken
+}
+
+// parseSequenceFunc parses a call to a sequence function
+// { NEXTVAL | CURRVAL } '(' <SEQUENCE-NAME> ')'
+// SETVAL '(' <SEQUENCE-NAME> ',' <VALUE> [ ',' { TRUE | FALSE } ] ')'
+//
+//	|-> { nextval | currval | setval }
+//	    |-> <SEQUENCE-NAME>
+//	    |-> <VALUE>
+//	    |-> { true | false }
+func (p *parser) parseSequenceFunc() (*Decl, error) {
+	name := strings.ToLower(p.cur().Lexeme)
+	funcDecl := NewDecl(Token{Token: NextvalToken, Lexeme: name})
+	switch name {
+	case "currval":
+		funcDecl.Token = CurrvalToken
+	case "setval":
+		funcDecl.Token = SetvalToken
+	}
+	p.next()
+
+	// Required: '(' <SEQUENCE-NAME>
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	funcDecl.Add(nameDecl)
+
+	// Required for SETVAL: ',' <VALUE> [ ',' { TRUE | FALSE } ]
+	if funcDecl.Token == SetvalToken {
+		if _, err := p.consumeToken(CommaToken); err != nil {
+			return nil, err
+		}
+		valueDecl, err := p.consumeToken(NumberToken, ParameterToken)
+		if err != nil {
+			return nil, err
+		}
+		funcDecl.Add(valueDecl)
+
+		if p.is(CommaToken) {
+			p.next()
+			calledDecl, err := p.consumeToken(TrueToken, FalseToken)
+			if err != nil {
+				return nil, err
+			}
+			funcDecl.Add(calledDecl)
+		}
+	}
+
+	// Required: ')'
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+
+	return funcDecl, nil
+}
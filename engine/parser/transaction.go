@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseBegin parses a transaction start
+// BEGIN [ TRANSACTION | WORK ] [ transaction_mode [, ...] ]
+//
+// where transaction_mode is one of:
+//
+//	ISOLATION LEVEL { SERIALIZABLE | REPEATABLE READ | READ COMMITTED | READ UNCOMMITTED }
+//	READ WRITE | READ ONLY
+func (p *parser) parseBegin() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: BEGIN
+	beginDecl, err := p.consumeToken(BeginToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, beginDecl)
+
+	// Optional: TRANSACTION | WORK
+	p.consumeWord("transaction", "work")
+
+	// Optional: transaction_mode [, ...]
+	for {
+		var modeDecl *Decl
+		switch {
+		case p.isWord("isolation"):
+			modeDecl, err = p.parseIsolationLevel()
+		case p.isWord("read"):
+			modeDecl, err = p.parseAccessMode()
+		default:
+			return i, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		beginDecl.Add(modeDecl)
+
+		if p.is(CommaToken) {
+			p.next()
+		}
+	}
+}
+
+// parseIsolationLevel parses a transaction isolation level
+// ISOLATION LEVEL { SERIALIZABLE | REPEATABLE READ | READ COMMITTED | READ UNCOMMITTED }
+func (p *parser) parseIsolationLevel() (*Decl, error) {
+	var level string
+
+	// Required: ISOLATION LEVEL
+	if _, err := p.consumeWord("isolation"); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeWord("level"); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isWord("serializable"):
+		level = "serializable"
+		p.next()
+	case p.isWord("repeatable"):
+		p.next()
+		if _, err := p.consumeWord("read"); err != nil {
+			return nil, err
+		}
+		level = "repeatable read"
+	case p.isWord("read"):
+		p.next()
+		levelDecl, err := p.consumeWord("committed", "uncommitted")
+		if err != nil {
+			return nil, err
+		}
+		level = "read " + strings.ToLower(levelDecl.Lexeme)
+	default:
+		return nil, p.syntaxError()
+	}
+
+	isolationDecl := NewDecl(Token{Token: StringToken, Lexeme: "isolation"})
+	isolationDecl.Add(NewDecl(Token{Token: StringToken, Lexeme: level}))
+	return isolationDecl, nil
+}
+
+// parseAccessMode parses a transaction access mode
+// READ WRITE | READ ONLY
+func (p *parser) parseAccessMode() (*Decl, error) {
+
+	// Required: READ
+	if _, err := p.consumeWord("read"); err != nil {
+		return nil, err
+	}
+
+	// Required: WRITE | ONLY
+	modeDecl, err := p.consumeWord("write", "only")
+	if err != nil {
+		return nil, err
+	}
+
+	readDecl := NewDecl(Token{Token: StringToken, Lexeme: "read"})
+	readDecl.Add(NewDecl(Token{Token: StringToken, Lexeme: strings.ToLower(modeDecl.Lexeme)}))
+	return readDecl, nil
+}
+
+// parseCommit parses a transaction end
+// COMMIT [ TRANSACTION | WORK ]
+func (p *parser) parseCommit() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: COMMIT
+	commitDecl, err := p.consumeToken(CommitToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, commitDecl)
+
+	// Optional: TRANSACTION | WORK
+	p.consumeWord("transaction", "work")
+
+	return i, nil
+}
+
+// parseRollback parses a transaction abort
+// ROLLBACK [ TRANSACTION | WORK ] [ TO [ SAVEPOINT ] savepoint_name ]
+func (p *parser) parseRollback() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: ROLLBACK
+	rollbackDecl, err := p.consumeToken(RollbackToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, rollbackDecl)
+
+	// Optional: TRANSACTION | WORK
+	p.consumeWord("transaction", "work")
+
+	// Optional: TO [ SAVEPOINT ] savepoint_name
+	if !p.isWord("to") {
+		return i, nil
+	}
+	p.next()
+
+	savepointDecl := NewDecl(Token{Token: SavepointToken, Lexeme: "savepoint"})
+	if p.isWord("savepoint") {
+		p.next()
+	}
+
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	savepointDecl.Add(nameDecl)
+	rollbackDecl.Add(savepointDecl)
+
+	return i, nil
+}
+
+// parseSavepoint parses a savepoint definition
+// SAVEPOINT savepoint_name
+func (p *parser) parseSavepoint() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: SAVEPOINT
+	if _, err := p.consumeWord("savepoint"); err != nil {
+		return nil, err
+	}
+	savepointDecl := NewDecl(Token{Token: SavepointToken, Lexeme: "savepoint"})
+	i.Decls = append(i.Decls, savepointDecl)
+
+	// Required: savepoint_name
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	savepointDecl.Add(nameDecl)
+
+	return i, nil
+}
+
+// parseRelease parses a savepoint destruction
+// RELEASE [ SAVEPOINT ] savepoint_name
+func (p *parser) parseRelease() (*Instruction, error) {
+	i := &Instruction{}
+
+	// Required: RELEASE
+	if _, err := p.consumeWord("release"); err != nil {
+		return nil, err
+	}
+	releaseDecl := NewDecl(Token{Token: ReleaseToken, Lexeme: "release"})
+	i.Decls = append(i.Decls, releaseDecl)
+
+	// Optional: SAVEPOINT
+	if p.isWord("savepoint") {
+		p.next()
+	}
+
+	// Required: savepoint_name
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	releaseDecl.Add(nameDecl)
+
+	return i, nil
+}
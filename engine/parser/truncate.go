@@ -10,12 +10,31 @@ func (p *parser) parseTruncate() (*Instruction, error) {
 	}
 	i.Decls = append(i.Decls, trDecl)
 
+	// Optional: TABLE
+	if p.is(TableToken) {
+		p.next()
+	}
+
 	// Should be a table name
-	nameDecl, err := p.parseQuotedToken()
+	nameDecl, err := p.parseTableName()
 	if err != nil {
 		return nil, err
 	}
 	trDecl.Add(nameDecl)
 
+	// Optional: RESTART IDENTITY | CONTINUE IDENTITY
+	if p.isWord("restart", "continue") {
+		restart := p.isWord("restart")
+		p.next()
+		if _, err := p.consumeWord("identity"); err != nil {
+			return nil, err
+		}
+		if restart {
+			restartDecl := NewDecl(Token{Token: RestartToken, Lexeme: "restart"})
+			restartDecl.Add(NewDecl(Token{Token: IdentityToken, Lexeme: "identity"}))
+			trDecl.Add(restartDecl)
+		}
+	}
+
 	return i, nil
 }
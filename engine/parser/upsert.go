@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseOnConflict parses what happens when an inserted row holds the same
+// key as an existing one, either PostgreSQL or MySQL style
+// ON CONFLICT [ '(' <COLUMN-NAME> [, ...] ')' | ON CONSTRAINT <CONSTRAINT-NAME> ] DO { NOTHING | UPDATE SET <ASSIGNMENT> [, ...] [ WHERE <CONDITION> ] }
+// ON DUPLICATE KEY UPDATE <ASSIGNMENT> [, ...]
+//
+//	|-> conflict
+//	    |-> key
+//	        |-> <COLUMN-NAME>
+//	    |-> constraint
+//	        |-> <CONSTRAINT-NAME>
+//	    |-> { nothing | update }
+//	        |-> <ASSIGNMENT>
+//	        |-> where
+//	            |-> <CONDITION>
+//
+//	|-> duplicate
+//	    |-> update
+//	        |-> <ASSIGNMENT>
+func (p *parser) parseOnConflict() (*Decl, error) {
+	// Required: ON
+	if _, err := p.consumeToken(OnToken); err != nil {
+		return nil, err
+	}
+
+	// ON DUPLICATE KEY UPDATE
+	if p.isWord("duplicate") {
+		duplicateDecl, err := p.consumeWord("duplicate")
+		if err != nil {
+			return nil, err
+		}
+		duplicateDecl.Token = DuplicateToken
+		if _, err := p.consumeToken(KeyToken); err != nil {
+			return nil, err
+		}
+		updateDecl, err := p.consumeToken(UpdateToken)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.parseAssignments(updateDecl); err != nil {
+			return nil, err
+		}
+		duplicateDecl.Add(updateDecl)
+		return duplicateDecl, nil
+	}
+
+	// Required: CONFLICT
+	conflictDecl, err := p.consumeWord("conflict")
+	if err != nil {
+		return nil, err
+	}
+	conflictDecl.Token = ConflictToken
+
+	// Optional: '(' <COLUMN-NAME> [, ...] ')'
+	if p.is(BracketOpeningToken) {
+		p.next()
+		keyDecl := NewDecl(Token{Token: KeyToken, Lexeme: "key"})
+		for {
+			columnDecl, err := p.parseQuotedToken()
+			if err != nil {
+				return nil, err
+			}
+			keyDecl.Add(columnDecl)
+
+			if !p.is(CommaToken) {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+		conflictDecl.Add(keyDecl)
+	} else if p.is(OnToken) {
+		// Optional: ON CONSTRAINT <CONSTRAINT-NAME>
+		p.next()
+		constraintDecl, err := p.consumeToken(ConstraintToken)
+		if err != nil {
+			return nil, err
+		}
+		nameDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		constraintDecl.Add(nameDecl)
+		conflictDecl.Add(constraintDecl)
+	}
+
+	// Required: DO
+	if _, err := p.consumeWord("do"); err != nil {
+		return nil, err
+	}
+
+	// Required: NOTHING | UPDATE SET ...
+	if p.isWord("nothing") {
+		nothingDecl, err := p.consumeWord("nothing")
+		if err != nil {
+			return nil, err
+		}
+		nothingDecl.Token = NothingToken
+		conflictDecl.Add(nothingDecl)
+		return conflictDecl, nil
+	}
+
+	updateDecl, err := p.consumeToken(UpdateToken)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(SetToken); err != nil {
+		return nil, err
+	}
+	if err := p.parseAssignments(updateDecl); err != nil {
+		return nil, err
+	}
+
+	// Optional: WHERE <CONDITION>
+	if p.is(WhereToken) {
+		if err := p.parseWhereCondition(updateDecl); err != nil {
+			return nil, err
+		}
+		excluded(updateDecl.Decl[len(updateDecl.Decl)-1])
+	}
+	conflictDecl.Add(updateDecl)
+
+	return conflictDecl, nil
+}
+
+// parseAssignments parses the columns updated by an upsert, set to
+// expressions of the existing row. Values of the row which could not be
+// inserted are designated as EXCLUDED.<COLUMN-NAME>, or VALUES(<COLUMN-NAME>)
+// in MySQL.
+// <COLUMN-NAME> = <EXPRESSION> [, ...]
+//
+//	|-> update
+//	    |-> <COLUMN-NAME>
+//	        |-> =
+//	        |-> <EXPRESSION>
+//
+//	|-> column
+//	    |-> excluded
+func (p *parser) parseAssignments(updateDecl *Decl) error {
+	for {
+		columnDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return err
+		}
+		equalDecl, err := p.consumeToken(EqualityToken)
+		if err != nil {
+			return err
+		}
+		columnDecl.Add(equalDecl)
+
+		exprDecl, err := p.parseExpression()
+		if err != nil {
+			return err
+		}
+		excluded(exprDecl)
+		columnDecl.Add(exprDecl)
+		updateDecl.Add(columnDecl)
+
+		if !p.is(CommaToken) {
+			break
+		}
+		p.next()
+	}
+
+	return nil
+}
+
+// parseExcludedValue parses the MySQL designation of a value of the row
+// which could not be inserted
+// VALUES '(' <COLUMN-NAME> ')'
+//
+//	|-> column
+//	    |-> excluded
+func (p *parser) parseExcludedValue() (*Decl, error) {
+	if _, err := p.consumeToken(ValuesToken); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+
+	columnDecl := NewDecl(Token{Token: ColumnToken, Lexeme: nameDecl.Lexeme})
+	columnDecl.Add(NewDecl(Token{Token: ExcludedToken, Lexeme: "excluded"}))
+	return columnDecl, nil
+}
+
+// excluded marks columns of expression decl qualified by EXCLUDED, so that
+// they are not mistaken for columns of a table of that name
+func excluded(decl *Decl) {
+	if decl.Token == SelectToken {
+		return
+	}
+	if decl.Token == ColumnToken && len(decl.Decl) == 1 && strings.EqualFold(decl.Decl[0].Lexeme, "excluded") {
+		decl.Decl[0].Token = ExcludedToken
+		decl.Decl[0].Lexeme = "excluded"
+	}
+
+	for _, d := range decl.Decl {
+		excluded(d)
+	}
+}
@@ -0,0 +1,86 @@
+package parser
+
+// parseView parses a view definition
+// [ OR REPLACE ] VIEW <VIEW-NAME> [ '(' <COLUMN-NAME> [, ...] ')' ] AS <SELECT>
+//
+//	|-> view
+//	    |-> or
+//	        |-> replace
+//	    |-> <VIEW-NAME>
+//	        |-> <COLUMN-NAME>
+//	    |-> as
+//	        |-> select
+func (p *parser) parseView() (*Decl, error) {
+	var orDecl *Decl
+	if p.is(OrToken) {
+		d, err := p.consumeToken(OrToken)
+		if err != nil {
+			return nil, err
+		}
+		replaceDecl, err := p.consumeWord("replace")
+		if err != nil {
+			return nil, err
+		}
+		replaceDecl.Token = ReplaceToken
+		d.Add(replaceDecl)
+		orDecl = d
+	}
+
+	// Required: VIEW
+	if _, err := p.consumeWord("view"); err != nil {
+		return nil, err
+	}
+	viewDecl := NewDecl(Token{Token: ViewToken, Lexeme: "view"})
+	if orDecl != nil {
+		viewDecl.Add(orDecl)
+	}
+
+	// Required: <VIEW-NAME>
+	nameDecl, err := p.parseTableName()
+	if err != nil {
+		return nil, err
+	}
+	viewDecl.Add(nameDecl)
+
+	// Optional: '(' <COLUMN-NAME> [, ...] ')'
+	if p.is(BracketOpeningToken) {
+		p.next()
+		for {
+			columnDecl, err := p.parseQuotedToken()
+			if err != nil {
+				return nil, err
+			}
+			nameDecl.Add(columnDecl)
+
+			if !p.is(CommaToken) {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+	}
+
+	// Required: AS <SELECT>
+	asDecl, err := p.consumeToken(AsToken)
+	if err != nil {
+		return nil, err
+	}
+	viewDecl.Add(asDecl)
+
+	if !p.is(SelectToken) {
+		return nil, p.syntaxError()
+	}
+	i, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	asDecl.Add(i.Decls[0])
+
+	if !p.is(SemicolonToken) {
+		return nil, p.syntaxError()
+	}
+
+	return viewDecl, nil
+}
@@ -75,6 +75,9 @@ type Predicate struct {
 	Operator   Operator
 	RightValue Value
 	True       bool
+
+	// kind is the operator token, telling which rows an index may find
+	kind int
 }
 
 func (p Predicate) String() string {
@@ -139,3 +142,20 @@ func (p *Predicate) Evaluate(t *Tuple, table *Table) (bool, error) {
 	p.LeftValue.v = t.Values[i]
 	return p.Operator(p.LeftValue, p.RightValue), nil
 }
+
+// conjuncts returns the predicates of p which must all be true for p to
+// be true
+func conjuncts(p PredicateLinker) []*Predicate {
+	switch p := p.(type) {
+	case *andOperator:
+		var preds []*Predicate
+		for _, pred := range p.pred {
+			preds = append(preds, conjuncts(pred)...)
+		}
+		return preds
+	case *Predicate:
+		return []*Predicate{p}
+	}
+
+	return nil
+}
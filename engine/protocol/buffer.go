@@ -9,12 +9,10 @@ import (
 // UnlimitedRowsChannel buffers incomming message from bufferThis channel and forward them to
 // returned channel.
 // ONLY CREATED CHANNEL IS CLOSED HERE.
-func UnlimitedRowsChannel(bufferThis chan message, firstMessage message) chan []string {
-	driverChannel := make(chan []string)
+func UnlimitedRowsChannel(bufferThis chan message) chan []interface{} {
+	driverChannel := make(chan []interface{})
 	rowList := list.New()
 
-	rowList.PushBack(firstMessage.Value)
-
 	go func() {
 		for {
 			// If nothing comes in and nothing is going out...
@@ -27,9 +25,9 @@ func UnlimitedRowsChannel(bufferThis chan message, firstMessage message) chan []
 			// We can disable the case in select with a nil channel and get a chance
 			// to fetch new data on bufferThis channel
 			driverChannelNullable := driverChannel
-			var nextRow []string
+			var nextRow []interface{}
 			if rowList.Len() != 0 {
-				nextRow = rowList.Front().Value.([]string)
+				nextRow = rowList.Front().Value.([]interface{})
 			} else {
 				driverChannelNullable = nil
 			}
@@ -66,7 +64,7 @@ func UnlimitedRowsChannel(bufferThis chan message, firstMessage message) chan []
 					return
 				} else {
 					// Everything is ok, buffering new value
-					rowList.PushBack(newRow.Value)
+					rowList.PushBack(newRow.Row)
 				}
 			case exit := <-driverChannel:
 				// this means driverChannel is closed
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -14,11 +15,27 @@ const (
 	rowHeaderMessage = "ROWHEAD"
 	rowValueMessage  = "ROWVAL"
 	rowEndMessage    = "ROWEND"
+	beginMessage     = "BEGIN"
+	commitMessage    = "COMMIT"
+	rollbackMessage  = "ROLLBACK"
+	prepareMessage   = "PREPARE"
+	preparedMessage  = "PREPARED"
+	executeMessage   = "EXECUTE"
+	closeMessage     = "CLOSE"
 )
 
 type message struct {
 	Type  string
 	Value []string
+	Row   []interface{}
+	// Columns is only set on row header
+	Columns []Column
+	// Args is only set on statements
+	Args []Argument
+	// ID of prepared statement
+	ID int64
+	// Context of the statement, since channel backend runs in process
+	Context context.Context
 }
 
 // ChannelDriverConn implements DriverConn for channel backend
@@ -104,15 +121,39 @@ func NewChannelEngineConn(newConn chan message) EngineConn {
 	return cec
 }
 
-// ReadStatement get SQL statements from client
-func (cec *ChannelEngineConn) ReadStatement() (string, error) {
+// ReadStatement get SQL statements from client, along with arguments
+// bound to their parameters
+func (cec *ChannelEngineConn) ReadStatement() (Statement, error) {
 	message, ok := <-cec.conn
 	if !ok {
 		cec.conn = nil
-		return "", io.EOF
+		return Statement{}, io.EOF
 	}
 
-	return message.Value[0], nil
+	stmt := Statement{
+		ID:      message.ID,
+		Args:    message.Args,
+		Prepare: message.Type == prepareMessage,
+		Close:   message.Type == closeMessage,
+		Context: message.Context,
+	}
+	if len(message.Value) > 0 {
+		stmt.Query = message.Value[0]
+	}
+
+	return stmt, nil
+}
+
+// WritePrepared answers to a statement preparation with its identifier
+// and number of parameters
+func (cec *ChannelEngineConn) WritePrepared(id int64, numInput int) error {
+	m := message{
+		Type:  preparedMessage,
+		Value: []string{fmt.Sprintf("%d %d", id, numInput)},
+	}
+
+	cec.conn <- m
+	return nil
 }
 
 // WriteResult is used to answer to statements other than SELECT
@@ -139,10 +180,10 @@ func (cec *ChannelEngineConn) WriteError(err error) error {
 }
 
 // WriteRowHeader indicates that rows are coming next
-func (cec *ChannelEngineConn) WriteRowHeader(header []string) error {
+func (cec *ChannelEngineConn) WriteRowHeader(header []Column) error {
 	m := message{
-		Type:  rowHeaderMessage,
-		Value: header,
+		Type:    rowHeaderMessage,
+		Columns: header,
 	}
 
 	cec.conn <- m
@@ -150,11 +191,12 @@ func (cec *ChannelEngineConn) WriteRowHeader(header []string) error {
 
 }
 
-// WriteRow must be called after WriteRowHeader and before WriteRowEnd
-func (cec *ChannelEngineConn) WriteRow(row []string) error {
+// WriteRow must be called after WriteRowHeader and before WriteRowEnd.
+// Values are sent typed, either nil, int64, float64, bool, string, []byte or time.Time
+func (cec *ChannelEngineConn) WriteRow(row []interface{}) error {
 	m := message{
-		Type:  rowValueMessage,
-		Value: row,
+		Type: rowValueMessage,
+		Row:  row,
 	}
 
 	cec.conn <- m
@@ -172,14 +214,16 @@ func (cec *ChannelEngineConn) WriteRowEnd() error {
 }
 
 // WriteQuery allows client to query the RamSQL server
-func (cdc *ChannelDriverConn) WriteQuery(query string) error {
+func (cdc *ChannelDriverConn) WriteQuery(ctx context.Context, query string, args []Argument) error {
 	if cdc.conn == nil {
 		return fmt.Errorf("connection closed")
 	}
 
 	m := message{
-		Type:  queryMessage,
-		Value: []string{query},
+		Type:    queryMessage,
+		Value:   []string{query},
+		Args:    args,
+		Context: ctx,
 	}
 
 	cdc.conn <- m
@@ -187,13 +231,122 @@ func (cdc *ChannelDriverConn) WriteQuery(query string) error {
 }
 
 // WriteExec allows client to manipulate the RamSQL server
-func (cdc *ChannelDriverConn) WriteExec(statement string) error {
+func (cdc *ChannelDriverConn) WriteExec(ctx context.Context, statement string, args []Argument) error {
+	if cdc.conn == nil {
+		return fmt.Errorf("connection closed")
+	}
+
+	m := message{
+		Type:    execMessage,
+		Value:   []string{statement},
+		Args:    args,
+		Context: ctx,
+	}
+
+	cdc.conn <- m
+	return nil
+}
+
+// WritePrepare asks the RamSQL server to prepare a statement
+func (cdc *ChannelDriverConn) WritePrepare(query string) error {
+	if cdc.conn == nil {
+		return fmt.Errorf("connection closed")
+	}
+
+	m := message{
+		Type:  prepareMessage,
+		Value: []string{query},
+	}
+
+	cdc.conn <- m
+	return nil
+}
+
+// ReadPrepared when Prepare has been used
+func (cdc *ChannelDriverConn) ReadPrepared() (id int64, numInput int, err error) {
+	if cdc.conn == nil {
+		return 0, 0, fmt.Errorf("connection closed")
+	}
+
+	m := <-cdc.conn
+	if m.Type != preparedMessage {
+		if m.Type == errMessage {
+			return 0, 0, errors.New(m.Value[0])
+		}
+		return 0, 0, fmt.Errorf("Protocal error: ReadPrepared received %v", m)
+	}
+
+	_, err = fmt.Sscanf(m.Value[0], "%d %d", &id, &numInput)
+	return id, numInput, err
+}
+
+// WriteExecute runs a prepared statement with given arguments.
+// Answer is read with either ReadResult or ReadRows.
+func (cdc *ChannelDriverConn) WriteExecute(ctx context.Context, id int64, args []Argument) error {
+	if cdc.conn == nil {
+		return fmt.Errorf("connection closed")
+	}
+
+	m := message{
+		Type:    executeMessage,
+		ID:      id,
+		Args:    args,
+		Context: ctx,
+	}
+
+	cdc.conn <- m
+	return nil
+}
+
+// WriteClose releases a prepared statement on the RamSQL server
+func (cdc *ChannelDriverConn) WriteClose(id int64) error {
 	if cdc.conn == nil {
 		return fmt.Errorf("connection closed")
 	}
 
 	m := message{
-		Type:  execMessage,
+		Type: closeMessage,
+		ID:   id,
+	}
+
+	cdc.conn <- m
+	return nil
+}
+
+// WriteBegin starts a transaction on the RamSQL server.
+// Isolation level is one of READ UNCOMMITTED, READ COMMITTED,
+// REPEATABLE READ or SERIALIZABLE, empty for server default.
+func (cdc *ChannelDriverConn) WriteBegin(isolationLevel string, readOnly bool) error {
+	stmt := beginMessage
+	if isolationLevel != "" {
+		stmt += " ISOLATION LEVEL " + isolationLevel
+	}
+	if readOnly {
+		stmt += " READ ONLY"
+	}
+
+	return cdc.writeTransaction(beginMessage, stmt)
+}
+
+// WriteCommit commits current transaction on the RamSQL server
+func (cdc *ChannelDriverConn) WriteCommit() error {
+	return cdc.writeTransaction(commitMessage, commitMessage)
+}
+
+// WriteRollback aborts current transaction on the RamSQL server
+func (cdc *ChannelDriverConn) WriteRollback() error {
+	return cdc.writeTransaction(rollbackMessage, rollbackMessage)
+}
+
+// Transaction boundaries are sent along with their SQL statement,
+// so the engine handles them like any other statement
+func (cdc *ChannelDriverConn) writeTransaction(boundary string, statement string) error {
+	if cdc.conn == nil {
+		return fmt.Errorf("connection closed")
+	}
+
+	m := message{
+		Type:  boundary,
 		Value: []string{statement},
 	}
 
@@ -220,19 +373,19 @@ func (cdc *ChannelDriverConn) ReadResult() (lastInsertedID int64, rowsAffected i
 }
 
 // ReadRows when Query has been used
-func (cdc *ChannelDriverConn) ReadRows() (chan []string, error) {
+func (cdc *ChannelDriverConn) ReadRows() ([]Column, chan []interface{}, error) {
 	if cdc.conn == nil {
-		return nil, fmt.Errorf("connection closed")
+		return nil, nil, fmt.Errorf("connection closed")
 	}
 
 	m := <-cdc.conn
 	if m.Type == errMessage {
-		return nil, errors.New(m.Value[0])
+		return nil, nil, errors.New(m.Value[0])
 	}
 
 	if m.Type != rowHeaderMessage {
-		return nil, errors.New("not a rows header")
+		return nil, nil, errors.New("not a rows header")
 	}
 
-	return UnlimitedRowsChannel(cdc.conn, m), nil
+	return m.Columns, UnlimitedRowsChannel(cdc.conn), nil
 }
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -21,12 +22,12 @@ func TestQuery(t *testing.T) {
 			}
 
 			_ = st
-			err = engineConn.WriteRowHeader([]string{"foo", "bar"})
+			err = engineConn.WriteRowHeader([]Column{{Name: "foo"}, {Name: "bar"}})
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			err = engineConn.WriteRow([]string{"hello", "world"})
+			err = engineConn.WriteRow([]interface{}{"hello", int64(42)})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -43,31 +44,26 @@ func TestQuery(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = driverConn.WriteQuery("toto")
+	err = driverConn.WriteQuery(context.Background(), "toto", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	channel, err := driverConn.ReadRows()
+	header, channel, err := driverConn.ReadRows()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	header, ok := <-channel
-	if !ok {
-		t.Fatal("Cannot read rows")
-	}
-
 	if len(header) != 2 {
 		t.Fatalf("Expected 2 columns, got %d", len(header))
 	}
 
-	if header[0] != "foo" {
-		t.Fatalf("Expected first columns name to be <foo>, got <%s>", header[0])
+	if header[0].Name != "foo" {
+		t.Fatalf("Expected first columns name to be <foo>, got <%s>", header[0].Name)
 	}
 
-	if header[1] != "bar" {
-		t.Fatalf("Expected second columns name to be <bar>, got <%s>", header[1])
+	if header[1].Name != "bar" {
+		t.Fatalf("Expected second columns name to be <bar>, got <%s>", header[1].Name)
 	}
 
 	rows, ok := <-channel
@@ -83,8 +79,8 @@ func TestQuery(t *testing.T) {
 		t.Fatalf("Expected first column value to be <hello>, got <%s>", rows[0])
 	}
 
-	if rows[1] != "world" {
-		t.Fatalf("Expected first columns value to be <world>, got <%s>", rows[1])
+	if rows[1] != int64(42) {
+		t.Fatalf("Expected second column value to be <42>, got <%v>", rows[1])
 	}
 
 }
@@ -114,7 +110,7 @@ func TestExecAndResult(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = driverConn.WriteExec("toto")
+	err = driverConn.WriteExec(context.Background(), "toto", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -157,7 +153,7 @@ func TestError(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = driverConn.WriteExec("toto")
+	err = driverConn.WriteExec(context.Background(), "toto", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1,23 +1,72 @@
 package protocol
 
+import (
+	"context"
+	"reflect"
+)
+
+// Column describes a column of a result set
+type Column struct {
+	Name     string
+	TypeName string
+	Nullable bool
+	// Length of variable length types, 0 if not applicable
+	Length int64
+	// Precision and scale of decimal types, 0 if not applicable
+	Precision int64
+	Scale     int64
+	// ScanType is the Go type of column values
+	ScanType reflect.Type
+}
+
+// Argument is a value bound to a statement parameter,
+// either by position ($1, ?) or by name (:name, @name)
+type Argument struct {
+	Name    string
+	Ordinal int
+	Value   interface{}
+}
+
+// Statement is a request read by the engine. Either Query is set, to be run
+// or prepared, or ID refers to a statement prepared earlier on the connection.
+type Statement struct {
+	Query string
+	ID    int64
+	Args  []Argument
+	// Prepare asks to parse and cache Query, answered with WritePrepared
+	Prepare bool
+	// Close asks to release prepared statement ID
+	Close bool
+	// Context of the statement, cancelling it once done
+	Context context.Context
+}
+
 // DriverConn is a networking helper hiding implementation
 // either with channels or network sockets.
 type DriverConn interface {
-	WriteQuery(query string) error
-	WriteExec(stmt string) error
+	WriteQuery(ctx context.Context, query string, args []Argument) error
+	WriteExec(ctx context.Context, stmt string, args []Argument) error
+	WritePrepare(query string) error
+	ReadPrepared() (id int64, numInput int, err error)
+	WriteExecute(ctx context.Context, id int64, args []Argument) error
+	WriteClose(id int64) error
+	WriteBegin(isolationLevel string, readOnly bool) error
+	WriteCommit() error
+	WriteRollback() error
 	ReadResult() (lastInsertedID int64, rowsAffected int64, err error)
-	ReadRows() (chan []string, error)
+	ReadRows() (header []Column, rows chan []interface{}, err error)
 	Close()
 }
 
 // EngineConn is a networking helper hiding implementation
 // either with channels or network sockets.
 type EngineConn interface {
-	ReadStatement() (string, error)
+	ReadStatement() (Statement, error)
+	WritePrepared(id int64, numInput int) error
 	WriteResult(lastInsertedID int64, rowsAffected int64) error
 	WriteError(err error) error
-	WriteRowHeader(header []string) error
-	WriteRow(row []string) error
+	WriteRowHeader(header []Column) error
+	WriteRow(row []interface{}) error
 	WriteRowEnd() error
 }
 
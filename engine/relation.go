@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"sort"
 	"sync"
 )
 
@@ -10,6 +11,15 @@ type Relation struct {
 	sync.RWMutex
 	table *Table
 	rows  []*Tuple
+
+	// indexes give access to rows by attribute values, see index
+	indexes []*index
+	// seq numbers inserted rows, so rows stay ordered by seq
+	seq int64
+
+	// modifiedAt is the commit sequence of the last transaction
+	// which modified relation rows
+	modifiedAt int64
 }
 
 // NewRelation initializes a new Relation struct
@@ -24,7 +34,81 @@ func NewRelation(t *Table) *Relation {
 // Insert a tuple in relation
 func (r *Relation) Insert(t *Tuple) error {
 	// Maybe do somthing like lock read/write here
-	// Maybe index
+	r.seq++
+	t.seq = r.seq
 	r.rows = append(r.rows, t)
+	r.index(t)
 	return nil
 }
+
+// remove a tuple from relation, starting from the end since
+// it is mostly used to cancel the latest insertions
+func (r *Relation) remove(t *Tuple) {
+	for i := len(r.rows) - 1; i >= 0; i-- {
+		if r.rows[i] == t {
+			r.rows = append(r.rows[:i], r.rows[i+1:]...)
+			r.unindex(t)
+			return
+		}
+	}
+}
+
+// replace the row holding tuple old by tuple new. Index i is a hint
+// of where old was last seen.
+func (r *Relation) replace(i int, old *Tuple, new *Tuple) {
+	if i < 0 || i >= len(r.rows) || r.rows[i] != old {
+		i = r.position(old.seq)
+	}
+
+	if i >= 0 && r.rows[i] == old {
+		r.rows[i] = new
+	}
+}
+
+// position returns the index in r.rows of the row numbered seq, -1 if
+// there is none
+func (r *Relation) position(seq int64) int {
+	i := sort.Search(len(r.rows), func(i int) bool {
+		return r.rows[i].seq >= seq
+	})
+	if i < len(r.rows) && r.rows[i].seq == seq {
+		return i
+	}
+
+	return -1
+}
+
+// vacuum drops rows deleted before horizon
+func (r *Relation) vacuum(horizon int64) {
+	rows := r.rows[:0]
+	for _, t := range r.rows {
+		if t.deletedBy != nil && t.deletedBy.committedBefore(horizon) {
+			for v := t; v != nil; v = v.previous {
+				r.unindex(v)
+			}
+			continue
+		}
+		rows = append(rows, t)
+	}
+
+	// Clear dropped pointers for the garbage collector
+	for i := len(rows); i < len(r.rows); i++ {
+		r.rows[i] = nil
+	}
+	r.rows = rows
+}
+
+// virtualRow returns the values of row t, named after the table
+func (r *Relation) virtualRow(t *Tuple) virtualRow {
+	row := make(virtualRow)
+	for i, attr := range r.table.attributes {
+		row[r.table.name+"."+attr.name] = Value{
+			v:      t.Values[i],
+			valid:  true,
+			lexeme: attr.name,
+			table:  r.table.name,
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// returning collects the values computed from each row written by INSERT,
+// UPDATE or DELETE ... RETURNING, once defaults and ON UPDATE values are
+// applied:
+//
+//	|-> returning
+//	    |-> *
+//	    |-> <EXPRESSION>
+//	    |-> as
+//	        |-> <EXPRESSION>
+//	        |-> <ALIAS>
+type returning struct {
+	relation    *Relation
+	expressions []*parser.Decl
+	columns     []protocol.Column
+	rows        [][]interface{}
+}
+
+// newReturning returns the RETURNING clause of statement decl, which writes
+// rows of relation r, possibly joined with given tables, nil if there is
+// none
+func newReturning(r *Relation, decl *parser.Decl, joined ...*Table) (*returning, error) {
+	var returningDecl *parser.Decl
+	for _, d := range decl.Decl {
+		if d.Token == parser.ReturningToken {
+			returningDecl = d
+		}
+	}
+	if returningDecl == nil {
+		return nil, nil
+	}
+
+	tables := append([]*Table{r.table}, joined...)
+	ret := &returning{relation: r}
+	for _, d := range returningDecl.Decl {
+		// * returns every column, possibly qualified by the table name
+		if d.Token == parser.StarToken && len(d.Decl) < 2 {
+			matched := false
+			for _, t := range tables {
+				if len(d.Decl) == 1 && d.Decl[0].Lexeme != t.name {
+					continue
+				}
+				matched = true
+				for _, attr := range t.attributes {
+					columnDecl := &parser.Decl{Token: parser.ColumnToken, Lexeme: attr.name}
+					columnDecl.Add(&parser.Decl{Token: parser.StringToken, Lexeme: t.name})
+					ret.expressions = append(ret.expressions, columnDecl)
+					ret.columns = append(ret.columns, attr.column())
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("missing FROM-clause entry for table \"%s\"", d.Decl[0].Lexeme)
+			}
+			continue
+		}
+
+		exprDecl, alias := d, ""
+		if d.Token == parser.AsToken {
+			if len(d.Decl) != 2 {
+				return nil, fmt.Errorf("parsing failed, malformed RETURNING clause")
+			}
+			exprDecl, alias = d.Decl[0], d.Decl[1].Lexeme
+		}
+		attr, err := expressionAttribute(exprDecl, tables)
+		if err != nil {
+			return nil, err
+		}
+		attr.selectAs = alias
+		ret.expressions = append(ret.expressions, exprDecl)
+		ret.columns = append(ret.columns, attr.column())
+	}
+
+	return ret, nil
+}
+
+// add computes the values returned for row t
+func (ret *returning) add(t *Tuple) error {
+	return ret.addJoined(t, nil)
+}
+
+// addJoined computes the values returned for row t, joined with the rows
+// of other tables
+func (ret *returning) addJoined(t *Tuple, joinedRow virtualRow) error {
+	if ret == nil {
+		return nil
+	}
+
+	row := ret.relation.virtualRow(t)
+	for k, v := range joinedRow {
+		if _, ok := row[k]; !ok {
+			row[k] = v
+		}
+	}
+	values := make([]interface{}, len(ret.expressions))
+	for i, exprDecl := range ret.expressions {
+		v, err := evaluate(exprDecl, row)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	ret.rows = append(ret.rows, values)
+
+	return nil
+}
+
+// write sends the returned rows to conn
+func (ret *returning) write(conn protocol.EngineConn) error {
+	if err := conn.WriteRowHeader(ret.columns); err != nil {
+		return err
+	}
+	for _, row := range ret.rows {
+		if err := conn.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	return conn.WriteRowEnd()
+}
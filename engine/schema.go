@@ -0,0 +1,651 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// Tables, views, sequences and indexes belong to a schema. Those of the
+// public schema are named as is, the others are named after their schema,
+// i.e billing.invoices, which is how the parser reads qualified names.
+//
+// Statements are resolved before being executed: names they hold are
+// replaced by the names of the objects they designate, unqualified names
+// being looked up in the schemas of the session search_path.
+const publicSchema = "public"
+
+// tempSchemaPrefix names the schemas holding temporary tables, one per
+// session. The temporary schema of a session is searched first, so that
+// its tables shadow the others, and is dropped with the session.
+const tempSchemaPrefix = "pg_temp_"
+
+// defaultSearchPath is the search_path of new sessions
+var defaultSearchPath = []string{publicSchema}
+
+// splitName returns the schema and the name of an object,
+// schema being empty if name is not qualified
+func splitName(name string) (string, string) {
+	if dot := strings.Index(name, "."); dot >= 0 {
+		return name[:dot], name[dot+1:]
+	}
+
+	return "", name
+}
+
+// qualifiedName returns the name of object name of schema s
+func qualifiedName(s string, name string) string {
+	if s == publicSchema {
+		return name
+	}
+
+	return s + "." + name
+}
+
+// isTempSchema returns true if s holds the temporary tables of a session
+func isTempSchema(s string) bool {
+	return strings.HasPrefix(s, tempSchemaPrefix)
+}
+
+// tempSchema returns the temporary schema of session s, created on first use
+func (e *Engine) tempSchema(s *session) string {
+	if s.temp == "" {
+		e.temps++
+		s.temp = fmt.Sprintf("%s%d", tempSchemaPrefix, e.temps)
+		e.schemas[s.temp] = true
+	}
+
+	return s.temp
+}
+
+// dropTempSchema drops the temporary tables of session s once closed
+func (e *Engine) dropTempSchema(s *session) {
+	if s.temp == "" {
+		return
+	}
+
+	tx := e.begin()
+	if err := tx.dropSchema(e, s.temp, true); err != nil {
+		tx.rollback()
+		return
+	}
+	tx.commit()
+}
+
+// schemaOf returns the schema of an object
+func schemaOf(name string) string {
+	s, _ := splitName(name)
+	if s == "" {
+		return publicSchema
+	}
+
+	return s
+}
+
+// lookup returns the name of the object designated by name, for which
+// exists returns true. Unqualified names are looked up in the schemas of
+// given search path, in order. Names not found are returned unchanged if
+// public is on the path, qualified by the first schema of the path
+// otherwise, so that objects of the public schema stay hidden.
+func (e *Engine) lookup(path []string, name string, exists func(string) bool) string {
+	s, n := splitName(name)
+	if s != "" {
+		return qualifiedName(s, n)
+	}
+
+	for _, s := range path {
+		if !e.schemas[s] {
+			continue
+		}
+		if candidate := qualifiedName(s, n); exists(candidate) {
+			return candidate
+		}
+	}
+
+	for _, s := range path {
+		if s == publicSchema {
+			return name
+		}
+	}
+	for _, s := range path {
+		if !isTempSchema(s) {
+			return qualifiedName(s, n)
+		}
+	}
+
+	return name
+}
+
+// creation returns the name of an object created as name. Unqualified
+// names are created in the first existing schema of given search path,
+// temporary schemas aside.
+func (e *Engine) creation(path []string, name string) (string, error) {
+	s, n := splitName(name)
+	if s != "" {
+		if !e.schemas[s] {
+			return "", fmt.Errorf("schema \"%s\" does not exist", s)
+		}
+		return qualifiedName(s, n), nil
+	}
+
+	for _, s := range path {
+		if e.schemas[s] && !isTempSchema(s) {
+			return qualifiedName(s, n), nil
+		}
+	}
+
+	return "", fmt.Errorf("no schema has been selected to create in")
+}
+
+// temporaryCreation returns the name of a temporary table created as name
+func (e *Engine) temporaryCreation(s *session, name string) (string, error) {
+	schema, n := splitName(name)
+	if schema != "" && schema != s.temp {
+		return "", fmt.Errorf("cannot create temporary relation in non-temporary schema")
+	}
+
+	return qualifiedName(e.tempSchema(s), n), nil
+}
+
+func (e *Engine) isRelation(name string) bool {
+	return e.relation(name) != nil || e.view(name) != nil
+}
+
+func (e *Engine) isTable(name string) bool {
+	return e.relation(name) != nil
+}
+
+func (e *Engine) isSequence(name string) bool {
+	return e.sequences[name] != nil
+}
+
+func (e *Engine) isIndex(name string) bool {
+	_, i := e.findIndex(name)
+	return i != nil
+}
+
+// resolve replaces the names of tables, views, sequences and indexes of
+// given statement by the names of the objects they designate for session s
+func (e *Engine) resolve(s *session, decl *parser.Decl) error {
+	path := s.path()
+	tables := e.isTable
+
+	switch decl.Token {
+	case parser.SelectToken:
+		return e.resolveSelect(path, decl)
+	case parser.InsertToken:
+		aliases := make(map[string]string)
+		if len(decl.Decl) > 0 && len(decl.Decl[0].Decl) > 0 {
+			if err := e.resolveTable(path, decl.Decl[0].Decl[0], aliases); err != nil {
+				return err
+			}
+		}
+		for _, d := range decl.Decl {
+			switch d.Token {
+			case parser.SelectToken:
+				if err := e.resolveSelect(path, d); err != nil {
+					return err
+				}
+			case parser.ReturningToken, parser.ConflictToken, parser.DuplicateToken:
+				resolveQualifiers(d, aliases)
+			}
+		}
+	case parser.UpdateToken:
+		if len(decl.Decl) > 0 {
+			aliases := make(map[string]string)
+			if err := e.resolveTable(path, decl.Decl[0], aliases); err != nil {
+				return err
+			}
+			if err := e.resolveJoined(path, decl.Decl[1:], aliases); err != nil {
+				return err
+			}
+			for _, d := range decl.Decl[1:] {
+				resolveQualifiers(d, aliases)
+			}
+			return e.resolveSubqueries(path, decl)
+		}
+	case parser.DeleteToken:
+		if len(decl.Decl) > 0 && len(decl.Decl[0].Decl) > 0 {
+			aliases := make(map[string]string)
+			if err := e.resolveTable(path, decl.Decl[0].Decl[0], aliases); err != nil {
+				return err
+			}
+			if err := e.resolveJoined(path, decl.Decl[1:], aliases); err != nil {
+				return err
+			}
+			for _, d := range decl.Decl[1:] {
+				resolveQualifiers(d, aliases)
+			}
+		}
+	case parser.TruncateToken:
+		if len(decl.Decl) > 0 {
+			return e.resolveTable(path, decl.Decl[0], make(map[string]string))
+		}
+	case parser.AlterToken:
+		if len(decl.Decl) > 0 && len(decl.Decl[0].Decl) > 0 {
+			exists := e.isRelation
+			if decl.Decl[0].Token == parser.SequenceToken {
+				exists = e.isSequence
+			}
+			d := decl.Decl[0].Decl[len(decl.Decl[0].Decl)-1]
+			written := d.Lexeme
+			d.Lexeme = e.lookup(path, written, exists)
+			if !exists(d.Lexeme) && decl.Decl[0].Decl[0].Token != parser.IfToken {
+				return fmt.Errorf("relation \"%s\" does not exist", written)
+			}
+		}
+	case parser.DropToken:
+		if len(decl.Decl) > 0 {
+			e.resolveDrop(path, decl.Decl[0])
+		}
+	case parser.CreateToken:
+		if len(decl.Decl) == 0 {
+			break
+		}
+		created, err := e.resolveCreate(s, path, decl.Decl[0])
+		if err != nil {
+			return err
+		}
+		// Tables may reference themselves
+		tables = func(name string) bool {
+			return name == created || e.isTable(name)
+		}
+	}
+
+	e.resolveObjects(path, decl, tables)
+	return nil
+}
+
+// resolveSelect resolves tables of FROM and JOIN clauses, and columns
+// qualified by their name
+func (e *Engine) resolveSelect(path []string, selectDecl *parser.Decl) error {
+	aliases := make(map[string]string)
+	for _, d := range selectDecl.Decl {
+		switch d.Token {
+		case parser.FromToken:
+			for _, t := range d.Decl {
+				if err := e.resolveTable(path, t, aliases); err != nil {
+					return err
+				}
+			}
+		case parser.JoinToken:
+			if len(d.Decl) > 0 {
+				if err := e.resolveTable(path, d.Decl[0], aliases); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Tables selected from more than once are told apart by their alias,
+	// see materializeAliases
+	for _, t := range selfJoined(selectDecl) {
+		if alias := tableAlias(t); alias != "" {
+			aliases[alias] = alias
+		}
+	}
+
+	for _, d := range selectDecl.Decl {
+		if d.Token != parser.FromToken {
+			resolveQualifiers(d, aliases)
+		}
+	}
+
+	return nil
+}
+
+// resolveTable resolves the name of table decl t. Columns may be qualified
+// by the name as written, with or without schema, or by the alias of the
+// table, which aliases records. Like in Postgres, tables not found are
+// reported by the name as written.
+func (e *Engine) resolveTable(path []string, t *parser.Decl, aliases map[string]string) error {
+	written := t.Lexeme
+	t.Lexeme = e.lookup(path, written, e.isRelation)
+	if !e.isRelation(t.Lexeme) {
+		return fmt.Errorf("relation \"%s\" does not exist", written)
+	}
+
+	_, name := splitName(written)
+	aliases[written] = t.Lexeme
+	aliases[name] = t.Lexeme
+	aliases[schemaOf(t.Lexeme)+"."+name] = t.Lexeme
+
+	// Optional: [AS] <ALIAS>
+	for _, d := range t.Decl {
+		if d.Token == parser.AsToken && len(d.Decl) == 1 {
+			aliases[d.Decl[0].Lexeme] = t.Lexeme
+		}
+	}
+
+	return nil
+}
+
+// resolveJoined resolves the tables joined by UPDATE ... JOIN, UPDATE ...
+// FROM or DELETE ... USING clauses found in decls
+func (e *Engine) resolveJoined(path []string, decls []*parser.Decl, aliases map[string]string) error {
+	for _, d := range decls {
+		switch d.Token {
+		case parser.JoinToken:
+			if len(d.Decl) > 0 {
+				if err := e.resolveTable(path, d.Decl[0], aliases); err != nil {
+					return err
+				}
+			}
+		case parser.FromToken, parser.UsingToken:
+			for _, t := range d.Decl {
+				if err := e.resolveTable(path, t, aliases); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveSubqueries resolves the scalar subqueries found in decl
+func (e *Engine) resolveSubqueries(path []string, decl *parser.Decl) error {
+	for _, d := range decl.Decl {
+		if d.Token == parser.SelectToken {
+			if err := e.resolveSelect(path, d); err != nil {
+				return err
+			}
+		}
+		if err := e.resolveSubqueries(path, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveQualifiers replaces the table qualifying columns found in decl,
+// but those of subqueries, which select from their own tables
+func resolveQualifiers(decl *parser.Decl, aliases map[string]string) {
+	if decl.Token == parser.SelectToken {
+		return
+	}
+	if (decl.Token == parser.StringToken || decl.Token == parser.ColumnToken || decl.Token == parser.StarToken) &&
+		len(decl.Decl) > 0 && decl.Decl[0].Token == parser.StringToken {
+		if name, ok := aliases[decl.Decl[0].Lexeme]; ok {
+			decl.Decl[0].Lexeme = name
+		}
+	}
+
+	for _, d := range decl.Decl {
+		resolveQualifiers(d, aliases)
+	}
+}
+
+// resolveDrop resolves the name of the object dropped by DROP decl d
+func (e *Engine) resolveDrop(path []string, d *parser.Decl) {
+	var exists func(string) bool
+	switch d.Token {
+	case parser.TableToken, parser.ViewToken:
+		exists = e.isRelation
+	case parser.SequenceToken:
+		exists = e.isSequence
+	case parser.IndexToken:
+		exists = e.isIndex
+	default:
+		return
+	}
+
+	for _, n := range d.Decl {
+		switch n.Token {
+		case parser.StringToken:
+			n.Lexeme = e.lookup(path, n.Lexeme, exists)
+		case parser.OnToken:
+			if len(n.Decl) == 1 {
+				n.Decl[0].Lexeme = e.lookup(path, n.Decl[0].Lexeme, e.isTable)
+			}
+		}
+	}
+}
+
+// resolveCreate resolves the name of the object created by CREATE decl d,
+// and returns it. Temporary tables are created in the temporary schema of
+// session s.
+func (e *Engine) resolveCreate(s *session, path []string, d *parser.Decl) (string, error) {
+	switch d.Token {
+	case parser.TableToken, parser.SequenceToken, parser.ViewToken:
+	case parser.IndexToken:
+		for _, n := range d.Decl {
+			if n.Token == parser.OnToken && len(n.Decl) == 1 {
+				n.Decl[0].Lexeme = e.lookup(path, n.Decl[0].Lexeme, e.isTable)
+			}
+		}
+		return "", nil
+	default:
+		return "", nil
+	}
+
+	var temporary bool
+	for _, n := range d.Decl {
+		if n.Token == parser.TemporaryToken {
+			temporary = true
+		}
+	}
+
+	var created string
+	var nameDecl *parser.Decl
+	var original string
+	for _, n := range d.Decl {
+		switch n.Token {
+		case parser.StringToken:
+			if created != "" {
+				continue
+			}
+			name, err := e.creation(path, n.Lexeme)
+			if temporary {
+				name, err = e.temporaryCreation(s, n.Lexeme)
+			}
+			if err != nil {
+				return "", err
+			}
+			nameDecl, original = n, n.Lexeme
+			n.Lexeme = name
+			created = name
+		case parser.AsToken:
+			if len(n.Decl) != 1 {
+				continue
+			}
+			if err := e.resolveSelect(path, n.Decl[0]); err != nil {
+				return "", err
+			}
+			// Like in Postgres, views selecting from temporary tables are
+			// temporary, so that they go away with the session
+			if d.Token == parser.ViewToken && nameDecl != nil && selectsTemporary(n.Decl[0]) {
+				name, err := e.temporaryCreation(s, original)
+				if err != nil {
+					return "", err
+				}
+				nameDecl.Lexeme = name
+				created = name
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// resolveObjects resolves the sequences called and the tables referenced
+// by foreign keys in decl
+func (e *Engine) resolveObjects(path []string, decl *parser.Decl, tables func(string) bool) {
+	for _, d := range decl.Decl {
+		switch d.Token {
+		case parser.NextvalToken, parser.CurrvalToken, parser.SetvalToken:
+			if len(d.Decl) > 0 {
+				d.Decl[0].Lexeme = e.lookup(path, d.Decl[0].Lexeme, e.isSequence)
+			}
+		case parser.ReferencesToken:
+			if len(d.Decl) > 0 {
+				d.Decl[0].Lexeme = e.lookup(path, d.Decl[0].Lexeme, tables)
+			}
+		}
+		e.resolveObjects(path, d, tables)
+	}
+}
+
+// createSchemaExecutor creates a schema:
+//
+//	|-> schema
+//	    |-> if
+//	        |-> not
+//	            |-> exists
+//	    |-> <SCHEMA-NAME>
+func createSchemaExecutor(e *Engine, tx *Transaction, schemaDecl *parser.Decl, conn protocol.EngineConn) error {
+	var name string
+	var ifNotExists bool
+	for _, d := range schemaDecl.Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifNotExists = true
+		default:
+			name = d.Lexeme
+		}
+	}
+
+	if e.schemas[name] {
+		if ifNotExists {
+			return conn.WriteResult(0, 0)
+		}
+		return fmt.Errorf("schema \"%s\" already exists", name)
+	}
+
+	e.schemas[name] = true
+	tx.onRollback(func() {
+		delete(e.schemas, name)
+	})
+
+	return conn.WriteResult(0, 1)
+}
+
+// dropSchema drops a schema. Objects it holds are dropped with CASCADE,
+// as well as the objects depending on them.
+//
+//	|-> schema
+//	    |-> if
+//	        |-> exists
+//	    |-> <SCHEMA-NAME>
+//	    |-> cascade
+func dropSchema(e *Engine, tx *Transaction, schemaDecl *parser.Decl, conn protocol.EngineConn) error {
+	var name string
+	var ifExists, cascade bool
+	for _, d := range schemaDecl.Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifExists = true
+		case parser.CascadeToken:
+			cascade = true
+		case parser.RestrictToken:
+		default:
+			name = d.Lexeme
+		}
+	}
+
+	if !e.schemas[name] {
+		if ifExists {
+			return conn.WriteResult(0, 0)
+		}
+		return fmt.Errorf("schema \"%s\" does not exist", name)
+	}
+
+	if err := tx.dropSchema(e, name, cascade); err != nil {
+		return err
+	}
+
+	return conn.WriteResult(0, 1)
+}
+
+// dropSchema removes schema name. Objects it holds are dropped with cascade,
+// as well as the objects depending on them.
+func (tx *Transaction) dropSchema(e *Engine, name string, cascade bool) error {
+	var views []*view
+	var relations []*Relation
+	var sequences []*sequence
+	for n, v := range e.views {
+		if schemaOf(n) == name {
+			views = append(views, v)
+		}
+	}
+	for n, r := range e.relations {
+		if schemaOf(n) == name {
+			relations = append(relations, r)
+		}
+	}
+	for n, s := range e.sequences {
+		if schemaOf(n) == name {
+			sequences = append(sequences, s)
+		}
+	}
+	if len(views)+len(relations)+len(sequences) > 0 && !cascade {
+		return fmt.Errorf("cannot drop schema %s because other objects depend on it", name)
+	}
+
+	// Views and sequences may have been dropped with objects they depend on
+	for _, v := range views {
+		if e.view(v.name) == v {
+			tx.dropView(e, v)
+			tx.dropViews(e, v.name)
+		}
+	}
+	for _, r := range relations {
+		r.Lock()
+		tx.dropTable(e, r)
+		r.Unlock()
+	}
+	for _, s := range sequences {
+		if e.sequences[s.name] != s {
+			continue
+		}
+		if err := tx.unlinkSequence(e, s.name, true); err != nil {
+			return err
+		}
+		tx.dropSequence(e, s)
+	}
+
+	delete(e.schemas, name)
+	tx.onRollback(func() {
+		e.schemas[name] = true
+	})
+
+	return nil
+}
+
+// searchPathExecutor sets the schemas where unqualified names are looked up
+// for the rest of the session, unless tx rolls back:
+//
+//	|-> set
+//	    |-> search_path
+//	        |-> <SCHEMA-NAME>
+func searchPathExecutor(e *Engine, tx *Transaction, setDecl *parser.Decl, conn protocol.EngineConn) error {
+	if len(setDecl.Decl) != 1 {
+		return fmt.Errorf("parsing failed, malformed SET query")
+	}
+	if setDecl.Decl[0].Lexeme != "search_path" {
+		return fmt.Errorf("unrecognized configuration parameter \"%s\"", setDecl.Decl[0].Lexeme)
+	}
+
+	path := defaultSearchPath
+	if len(setDecl.Decl[0].Decl) > 0 && setDecl.Decl[0].Decl[0].Token != parser.DefaultToken {
+		path = nil
+		for _, d := range setDecl.Decl[0].Decl {
+			path = append(path, d.Lexeme)
+		}
+	}
+
+	s := tx.session
+	if s == nil {
+		return fmt.Errorf("cannot set search_path outside of a session")
+	}
+	old := s.searchPath
+	s.searchPath = path
+	tx.onRollback(func() {
+		s.searchPath = old
+	})
+
+	return conn.WriteResult(0, 0)
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/kokizzu/ramsql/engine/log"
 	"github.com/kokizzu/ramsql/engine/parser"
@@ -34,6 +33,32 @@ func attributeExistsInTable(e *Engine, attr string, table string) error {
 	return nil
 }
 
+// describeAttribute copies the type of the column definition matching attr,
+// looked up in given tables unless attr name is qualified
+func describeAttribute(e *Engine, attr *Attribute, tables []string) {
+	name := attr.name
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		tables = []string{name[:dot]}
+		name = name[dot+1:]
+	}
+
+	for _, table := range tables {
+		r := e.relation(table)
+		if r == nil {
+			continue
+		}
+		for _, tAttr := range r.table.attributes {
+			if tAttr.name == name {
+				attr.typeName = tAttr.typeName
+				attr.typeSize = tAttr.typeSize
+				attr.typeScale = tAttr.typeScale
+				attr.isNullable = tAttr.isNullable
+				return
+			}
+		}
+	}
+}
+
 func attributesExistInTables(e *Engine, attributes []Attribute, tables []string) error {
 
 	for _, attr := range attributes {
@@ -41,9 +66,8 @@ func attributesExistInTables(e *Engine, attributes []Attribute, tables []string)
 			continue
 		}
 
-		if strings.Contains(attr.name, ".") {
-			t := strings.Split(attr.name, ".")
-			if err := attributeExistsInTable(e, t[1], t[0]); err != nil {
+		if dot := strings.LastIndex(attr.name, "."); dot >= 0 {
+			if err := attributeExistsInTable(e, attr.name[dot+1:], attr.name[:dot]); err != nil {
 				return err
 			}
 			continue
@@ -69,7 +93,7 @@ func attributesExistInTables(e *Engine, attributes []Attribute, tables []string)
 }
 
 type selectFunctor interface {
-	Init(e *Engine, conn protocol.EngineConn, attr []string, alias []string) error
+	Init(e *Engine, conn protocol.EngineConn, attr []string, columns []protocol.Column) error
 	FeedVirtualRow(row virtualRow) error
 	Done() error
 }
@@ -104,21 +128,21 @@ type defaultSelectFunction struct {
 	e          *Engine
 	conn       protocol.EngineConn
 	attributes []string
-	alias      []string
+	columns    []protocol.Column
 }
 
-func (f *defaultSelectFunction) Init(e *Engine, conn protocol.EngineConn, attr []string, alias []string) error {
+func (f *defaultSelectFunction) Init(e *Engine, conn protocol.EngineConn, attr []string, columns []protocol.Column) error {
 	f.e = e
 	f.conn = conn
 	f.attributes = attr
-	f.alias = alias
+	f.columns = columns
 
-	log.Debug("function:defaultSelectFunction.Init: rowHeader=%v", f.alias)
-	return f.conn.WriteRowHeader(f.alias)
+	log.Debug("function:defaultSelectFunction.Init: rowHeader=%v", f.columns)
+	return f.conn.WriteRowHeader(f.columns)
 }
 
 func (f *defaultSelectFunction) FeedVirtualRow(vrow virtualRow) error {
-	var row []string
+	var row []interface{}
 
 	for _, attr := range f.attributes {
 		val, ok := vrow[attr]
@@ -126,12 +150,7 @@ func (f *defaultSelectFunction) FeedVirtualRow(vrow virtualRow) error {
 			return fmt.Errorf("could not select attribute %s", attr)
 		}
 
-		switch v := val.v.(type) {
-		case time.Time:
-			row = append(row, v.Format(parser.DateLongFormat))
-		default:
-			row = append(row, fmt.Sprintf("%v", v))
-		}
+		row = append(row, val.v)
 	}
 
 	log.Debug("function:defaultSelectFunction.FeedVirtualRow: row=%v", row)
@@ -147,15 +166,15 @@ type countSelectFunction struct {
 	e          *Engine
 	conn       protocol.EngineConn
 	attributes []string
-	alias      []string
+	columns    []protocol.Column
 	Count      int64
 }
 
-func (f *countSelectFunction) Init(e *Engine, conn protocol.EngineConn, attr []string, alias []string) error {
+func (f *countSelectFunction) Init(e *Engine, conn protocol.EngineConn, attr []string, columns []protocol.Column) error {
 	f.e = e
 	f.conn = conn
 	f.attributes = attr
-	f.alias = alias
+	f.columns = columns
 	return nil
 }
 
@@ -165,12 +184,12 @@ func (f *countSelectFunction) FeedVirtualRow(row virtualRow) error {
 }
 
 func (f *countSelectFunction) Done() error {
-	err := f.conn.WriteRowHeader(f.alias)
+	err := f.conn.WriteRowHeader(f.columns)
 	if err != nil {
 		return err
 	}
 
-	err = f.conn.WriteRow([]string{fmt.Sprintf("%d", f.Count)})
+	err = f.conn.WriteRow([]interface{}{f.Count})
 	if err != nil {
 		return err
 	}
@@ -182,6 +201,7 @@ func inExecutor(inDecl *parser.Decl, p *Predicate) error {
 	inDecl.Stringy(0)
 
 	p.Operator = inOperator
+	p.kind = parser.InToken
 
 	// Put everything in a []string
 	var values []string
@@ -197,6 +217,7 @@ func inExecutor(inDecl *parser.Decl, p *Predicate) error {
 func isExecutor(isDecl *parser.Decl, p *Predicate) error {
 	isDecl.Stringy(0)
 
+	p.kind = parser.IsToken
 	if isDecl.Decl[0].Token == parser.NullToken {
 		p.Operator = isNullOperator
 	} else {
@@ -328,7 +349,9 @@ func whereExecutor2(e *Engine, decl []*parser.Decl, fromTableName string) (Predi
 	if err != nil {
 		return nil, err
 	}
+	p.kind = op.Token
 	p.RightValue.lexeme = val.Lexeme
+	p.RightValue.v = declValue(val)
 	p.RightValue.valid = true
 
 	p.LeftValue.table = fromTableName
@@ -411,12 +434,17 @@ func whereExecutor(whereDecl *parser.Decl, fromTableName string) ([]Predicate, e
 		// The first element of the list is then the relation of the attribute
 		op := cond.Decl[0]
 		val := cond.Decl[1]
+		if len(val.Decl) > 0 {
+			return nil, fmt.Errorf("comparing column \"%s\" with column \"%s.%s\" is not supported", cond.Lexeme, val.Decl[0].Lexeme, val.Lexeme)
+		}
 
 		p.Operator, err = NewOperator(op.Token, op.Lexeme)
 		if err != nil {
 			return nil, err
 		}
+		p.kind = op.Token
 		p.RightValue.lexeme = val.Lexeme
+		p.RightValue.v = declValue(val)
 		p.RightValue.valid = true
 
 		p.LeftValue.table = tableName
@@ -466,7 +494,8 @@ func getSelectedAttribute(e *Engine, attr *parser.Decl, tables []*Table) ([]Attr
 		if err != nil && attr.Decl[0].Lexeme != "*" {
 			return nil, err
 		}
-		attribute := NewAttribute("COUNT", "int", false)
+		attribute := NewAttribute("COUNT", "bigint", false)
+		attribute.isNullable = false
 
 		if len(attr.Decl) == 2 {
 			if attr.Decl[1].Token != parser.AsToken {
@@ -514,6 +543,7 @@ func getSelectedAttribute(e *Engine, attr *parser.Decl, tables []*Table) ([]Attr
 		if err := attributesExistInTables(e, []Attribute{attribute}, t); err != nil {
 			return nil, err
 		}
+		describeAttribute(e, &attribute, t)
 
 		attributes = append(attributes, attribute)
 	}
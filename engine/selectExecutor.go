@@ -2,8 +2,11 @@ package engine
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
+	"github.com/kokizzu/ramsql/engine/log"
 	"github.com/kokizzu/ramsql/engine/parser"
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
@@ -18,7 +21,7 @@ import (
 			|-> =
 			|-> foo@bar.com
 */
-func selectExecutor(e *Engine, selectDecl *parser.Decl, conn protocol.EngineConn) error {
+func selectExecutor(e *Engine, tx *Transaction, selectDecl *parser.Decl, conn protocol.EngineConn) error {
 	var attributes []Attribute
 	var tables []*Table
 	var predicates []PredicateLinker
@@ -27,6 +30,31 @@ func selectExecutor(e *Engine, selectDecl *parser.Decl, conn protocol.EngineConn
 	var err error
 
 	selectDecl.Stringy(0)
+
+	// Sequence functions called without FROM, i.e SELECT nextval('seq')
+	from := false
+	for _, d := range selectDecl.Decl {
+		if d.Token == parser.FromToken {
+			from = true
+		}
+	}
+	if !from {
+		return selectSequenceFuncs(tx, selectDecl, conn)
+	}
+
+	// Views are selected from like tables for the statement
+	release, err := e.materializeViews(tx, selectDecl)
+	if err != nil {
+		return err
+	}
+	defer release()
+	releaseAliases, err := e.materializeAliases(tx, selectDecl)
+	if err != nil {
+		return err
+	}
+	defer releaseAliases()
+
+	var joined []string
 	for i := range selectDecl.Decl {
 		switch selectDecl.Decl[i].Token {
 		case parser.FromToken:
@@ -44,6 +72,19 @@ func selectExecutor(e *Engine, selectDecl *parser.Decl, conn protocol.EngineConn
 			if err != nil {
 				return err
 			}
+			// Self-joins would mix rows of the same table, aliased or not
+			name := selectDecl.Decl[i].Decl[0].Lexeme
+			for _, t := range tables {
+				if t.name == name {
+					return fmt.Errorf("table name \"%s\" specified more than once", name)
+				}
+			}
+			for _, t := range joined {
+				if t == name {
+					return fmt.Errorf("table name \"%s\" specified more than once", name)
+				}
+			}
+			joined = append(joined, name)
 			joiners = append(joiners, j)
 		case parser.OrderToken:
 			orderFunctor, err := orderbyExecutor(selectDecl.Decl[i], tables)
@@ -57,6 +98,8 @@ func selectExecutor(e *Engine, selectDecl *parser.Decl, conn protocol.EngineConn
 				return fmt.Errorf("wrong limit value: %s", err)
 			}
 			conn = limitedConn(conn, limit)
+		case parser.NextvalToken, parser.CurrvalToken, parser.SetvalToken:
+			return fmt.Errorf("%s() cannot be selected from a table", selectDecl.Decl[i].Lexeme)
 		case parser.OffsetToken:
 			offset, err := strconv.Atoi(selectDecl.Decl[i].Decl[0].Lexeme)
 			if err != nil {
@@ -90,10 +133,171 @@ func selectExecutor(e *Engine, selectDecl *parser.Decl, conn protocol.EngineConn
 		}
 	}
 
-	err = generateVirtualRows(e, attributes, conn, tables[0].name, joiners, predicates, functors)
+	err = generateVirtualRows(e, tx, attributes, conn, tables[0].name, joiners, predicates, functors)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// materializeAliases registers the tables selected from more than once,
+// i.e self-joins, as relations named after their alias holding their rows
+// as seen by tx, until returned func is called. Columns are qualified by
+// the alias of such tables, see resolveSelect.
+func (e *Engine) materializeAliases(tx *Transaction, selectDecl *parser.Decl) (func(), error) {
+	var names []string
+	release := func() {
+		for _, name := range names {
+			e.drop(name)
+		}
+	}
+
+	for _, d := range selfJoined(selectDecl) {
+		alias := tableAlias(d)
+		r := e.relation(d.Lexeme)
+		if alias == "" || r == nil {
+			continue
+		}
+		if e.relation(alias) != nil {
+			release()
+			return nil, fmt.Errorf("alias \"%s\" of table \"%s\" conflicts with relation \"%s\"", alias, d.Lexeme, alias)
+		}
+
+		t := NewTable(alias)
+		t.attributes = append([]Attribute(nil), r.table.attributes...)
+		a := NewRelation(t)
+		r.RLock()
+		tx.reads(r)
+		for _, row := range r.rows {
+			if v := tx.visible(row); v != nil {
+				a.Insert(NewTuple(v.Values...))
+			}
+		}
+		r.RUnlock()
+
+		e.Lock()
+		e.relations[alias] = a
+		e.Unlock()
+		names = append(names, alias)
+		d.Lexeme = alias
+	}
+
+	return release, nil
+}
+
+// selfJoined returns the decls of tables found more than once in FROM and
+// JOIN clauses of selectDecl
+func selfJoined(selectDecl *parser.Decl) []*parser.Decl {
+	var tables []*parser.Decl
+	for _, d := range selectDecl.Decl {
+		switch d.Token {
+		case parser.FromToken:
+			tables = append(tables, d.Decl...)
+		case parser.JoinToken:
+			if len(d.Decl) > 0 {
+				tables = append(tables, d.Decl[0])
+			}
+		}
+	}
+
+	var joined []*parser.Decl
+	for _, t := range tables {
+		n := 0
+		for _, other := range tables {
+			if other.Lexeme == t.Lexeme {
+				n++
+			}
+		}
+		if n > 1 {
+			joined = append(joined, t)
+		}
+	}
+
+	return joined
+}
+
+// tableAlias returns the alias of table decl t, if any
+func tableAlias(t *parser.Decl) string {
+	for _, d := range t.Decl {
+		if d.Token == parser.AsToken && len(d.Decl) == 1 {
+			return d.Decl[0].Lexeme
+		}
+	}
+
+	return ""
+}
+
+// addResultColumns adds to t the columns of a query result, named as given
+// or as in the result
+func addResultColumns(t *Table, names []string, columns []protocol.Column) error {
+	for i, c := range columns {
+		name := c.Name
+		if i < len(names) {
+			name = names[i]
+		} else if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+		if t.attributeIndex(name) >= 0 {
+			return fmt.Errorf("column \"%s\" specified more than once", name)
+		}
+		attr := NewAttribute(name, strings.ToLower(c.TypeName), false)
+		switch typeKind(attr.typeName) {
+		case textType, byteaType:
+			if c.Length != math.MaxInt64 {
+				attr.typeSize = c.Length
+			}
+		case floatType:
+			attr.typeSize = c.Precision
+			attr.typeScale = c.Scale
+		}
+		if err := t.AddAttribute(attr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryResult collects the rows written by a query run by the engine itself,
+// i.e the query of a view
+type queryResult struct {
+	columns []protocol.Column
+	rows    [][]interface{}
+}
+
+// Not needed
+func (r *queryResult) ReadStatement() (protocol.Statement, error) {
+	log.Debug("queryResult.ReadStatement: should not be used\n")
+	return protocol.Statement{}, nil
+}
+
+// Not needed
+func (r *queryResult) WritePrepared(id int64, numInput int) error {
+	log.Debug("queryResult.WritePrepared: should not be used\n")
+	return nil
+}
+
+// Not needed
+func (r *queryResult) WriteResult(last int64, ra int64) error {
+	log.Debug("queryResult.WriteResult: should not be used\n")
+	return nil
+}
+
+func (r *queryResult) WriteError(err error) error {
+	return err
+}
+
+func (r *queryResult) WriteRowHeader(header []protocol.Column) error {
+	r.columns = header
+	return nil
+}
+
+func (r *queryResult) WriteRow(row []interface{}) error {
+	r.rows = append(r.rows, row)
+	return nil
+}
+
+func (r *queryResult) WriteRowEnd() error {
+	return nil
+}
@@ -0,0 +1,574 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// sequence generates integers, i.e ids of SERIAL and identity columns.
+//
+// As in Postgres, values are never given back: a transaction rolling back
+// does not cancel nextval calls, so a sequence never goes backwards unless
+// restarted.
+type sequence struct {
+	name      string
+	increment int64
+	min       int64
+	max       int64
+	start     int64
+	cycle     bool
+
+	// last is the value last returned by nextval or given to setval.
+	// Until called, nextval returns it as is.
+	last   int64
+	called bool
+
+	// owner is the relation of the identity column using the sequence,
+	// which is dropped along with it
+	owner *Relation
+}
+
+func newSequence(name string) *sequence {
+	return &sequence{
+		name:      name,
+		increment: 1,
+		min:       1,
+		max:       math.MaxInt64,
+		start:     1,
+		last:      1,
+	}
+}
+
+// configure applies options given on creation or alteration of a sequence,
+// or on definition of an identity column
+//
+//	|-> { increment | minvalue | maxvalue | start | restart | cache }
+//	    |-> <N>
+//	|-> no
+//	    |-> { minvalue | maxvalue | cycle }
+//	|-> cycle
+//	|-> as
+//	    |-> <TYPE>
+func (s *sequence) configure(options []*parser.Decl, create bool) error {
+	var start, restart bool
+	var restartValue int64
+
+	for _, d := range options {
+		var n int64
+		if len(d.Decl) == 1 && d.Decl[0].Token == parser.NumberToken {
+			var err error
+			n, err = strconv.ParseInt(d.Decl[0].Lexeme, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for sequence option %s: %s", strings.ToUpper(d.Lexeme), d.Decl[0].Lexeme)
+			}
+		}
+
+		switch d.Token {
+		case parser.AsToken:
+			if len(d.Decl) != 1 {
+				return fmt.Errorf("malformed AS option of sequence %s", s.name)
+			}
+			max, err := sequenceMax(d.Decl[0].Lexeme)
+			if err != nil {
+				return err
+			}
+			s.max = max
+		case parser.IncrementToken:
+			s.increment = n
+		case parser.MinvalueToken:
+			s.min = n
+		case parser.MaxvalueToken:
+			s.max = n
+		case parser.StartToken:
+			s.start = n
+			start = true
+		case parser.RestartToken:
+			restart = true
+			restartValue = s.start
+			if len(d.Decl) == 1 {
+				restartValue = n
+			}
+		case parser.CycleToken:
+			s.cycle = true
+		case parser.NoToken:
+			if len(d.Decl) != 1 {
+				return fmt.Errorf("malformed NO option of sequence %s", s.name)
+			}
+			switch d.Decl[0].Token {
+			case parser.MinvalueToken:
+				s.min = 1
+			case parser.MaxvalueToken:
+				s.max = math.MaxInt64
+			case parser.CycleToken:
+				s.cycle = false
+			}
+		}
+	}
+
+	if s.increment == 0 {
+		return fmt.Errorf("INCREMENT must not be zero")
+	}
+	if s.min >= s.max {
+		return fmt.Errorf("MINVALUE (%d) must be less than MAXVALUE (%d)", s.min, s.max)
+	}
+	if create && !start {
+		s.start = s.min
+	}
+	if s.start < s.min {
+		return fmt.Errorf("START value (%d) cannot be less than MINVALUE (%d)", s.start, s.min)
+	}
+	if s.start > s.max {
+		return fmt.Errorf("START value (%d) cannot be greater than MAXVALUE (%d)", s.start, s.max)
+	}
+
+	if create {
+		s.restart(s.start)
+	}
+	if restart {
+		if restartValue < s.min {
+			return fmt.Errorf("RESTART value (%d) cannot be less than MINVALUE (%d)", restartValue, s.min)
+		}
+		if restartValue > s.max {
+			return fmt.Errorf("RESTART value (%d) cannot be greater than MAXVALUE (%d)", restartValue, s.max)
+		}
+		s.restart(restartValue)
+	}
+
+	return nil
+}
+
+// sequenceMax returns the greatest value a sequence of given type can reach
+func sequenceMax(typeName string) (int64, error) {
+	switch typeString(typeName) {
+	case "smallint":
+		return math.MaxInt16, nil
+	case "integer":
+		return math.MaxInt32, nil
+	case "bigint":
+		return math.MaxInt64, nil
+	}
+
+	return 0, fmt.Errorf("sequence type must be smallint, integer, or bigint")
+}
+
+// next advances the sequence and returns its new value
+func (s *sequence) next() (int64, error) {
+	if !s.called {
+		s.called = true
+		return s.last, nil
+	}
+
+	v := s.last + s.increment
+	if s.increment > 0 && (v > s.max || v < s.last) {
+		if !s.cycle {
+			return 0, fmt.Errorf("nextval: reached maximum value of sequence \"%s\" (%d)", s.name, s.max)
+		}
+		v = s.min
+	}
+	if s.increment < 0 && (v < s.min || v > s.last) {
+		if !s.cycle {
+			return 0, fmt.Errorf("nextval: reached minimum value of sequence \"%s\" (%d)", s.name, s.min)
+		}
+		v = s.max
+	}
+
+	s.last = v
+	return v, nil
+}
+
+// set sets the current value of the sequence. Next call to nextval returns
+// v itself unless called is true.
+func (s *sequence) set(v int64, called bool) error {
+	if v < s.min || v > s.max {
+		return fmt.Errorf("setval: value %d is out of bounds for sequence \"%s\" (%d..%d)", v, s.name, s.min, s.max)
+	}
+
+	s.last = v
+	s.called = called
+	return nil
+}
+
+// restart makes nextval return v next time
+func (s *sequence) restart(v int64) {
+	s.last = v
+	s.called = false
+}
+
+// advance moves an ascending sequence past v, a value given explicitly to
+// an identity column, so nextval never returns it afterwards
+func (s *sequence) advance(v int64) {
+	if s.increment < 0 || v < s.last || (s.called && v == s.last) {
+		return
+	}
+
+	s.last = v
+	s.called = true
+}
+
+// createSequenceExecutor creates a sequence:
+//
+//	|-> sequence
+//	    |-> if
+//	        |-> not
+//	            |-> exists
+//	    |-> <SEQUENCE-NAME>
+//	    |-> <SEQUENCE-OPTION>
+func createSequenceExecutor(e *Engine, tx *Transaction, sequenceDecl *parser.Decl, conn protocol.EngineConn) error {
+	var name string
+	var ifNotExists bool
+	var options []*parser.Decl
+	for _, d := range sequenceDecl.Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifNotExists = true
+		case parser.StringToken:
+			name = d.Lexeme
+		default:
+			options = append(options, d)
+		}
+	}
+
+	if e.sequences[name] != nil || e.relation(name) != nil || e.view(name) != nil {
+		if ifNotExists {
+			return conn.WriteResult(0, 0)
+		}
+		return fmt.Errorf("relation \"%s\" already exists", name)
+	}
+
+	s := newSequence(name)
+	if err := s.configure(options, true); err != nil {
+		return err
+	}
+	tx.createSequence(e, s)
+
+	return conn.WriteResult(0, 1)
+}
+
+// alterSequenceExecutor changes the options of a sequence, or restarts it:
+//
+//	|-> alter
+//	    |-> sequence
+//	        |-> <SEQUENCE-NAME>
+//	    |-> <SEQUENCE-OPTION>
+func alterSequenceExecutor(e *Engine, tx *Transaction, alterDecl *parser.Decl, conn protocol.EngineConn) error {
+	if len(alterDecl.Decl[0].Decl) != 1 {
+		return fmt.Errorf("parsing failed, malformed ALTER SEQUENCE query")
+	}
+
+	name := alterDecl.Decl[0].Decl[0].Lexeme
+	s := e.sequences[name]
+	if s == nil {
+		return fmt.Errorf("relation \"%s\" does not exist", name)
+	}
+
+	options := alterDecl.Decl[1:]
+	restart := false
+	for _, d := range options {
+		if d.Token == parser.RestartToken {
+			restart = true
+		}
+	}
+	tx.saveSequence(s, restart)
+	if err := s.configure(options, false); err != nil {
+		return err
+	}
+
+	return conn.WriteResult(0, 1)
+}
+
+// dropSequence drops a sequence. Columns using it as default value are
+// altered with CASCADE.
+//
+//	|-> sequence
+//	    |-> if
+//	        |-> exists
+//	    |-> <SEQUENCE-NAME>
+//	    |-> cascade
+func dropSequence(e *Engine, tx *Transaction, sequenceDecl *parser.Decl, conn protocol.EngineConn) error {
+	var name string
+	var ifExists, cascade bool
+	for _, d := range sequenceDecl.Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifExists = true
+		case parser.CascadeToken:
+			cascade = true
+		case parser.RestrictToken:
+		default:
+			name = d.Lexeme
+		}
+	}
+
+	s := e.sequences[name]
+	if s == nil {
+		if ifExists {
+			return conn.WriteResult(0, 0)
+		}
+		return fmt.Errorf("sequence \"%s\" does not exist", name)
+	}
+
+	if err := tx.unlinkSequence(e, name, cascade); err != nil {
+		return err
+	}
+
+	tx.dropSequence(e, s)
+	return conn.WriteResult(0, 1)
+}
+
+// unlinkSequence removes sequence name from the defaults of the columns
+// using it, which is only allowed with cascade
+func (tx *Transaction) unlinkSequence(e *Engine, name string, cascade bool) error {
+	for _, r := range e.relations {
+		saved := false
+		for i := range r.table.attributes {
+			attr := &r.table.attributes[i]
+			if attr.sequence != name {
+				continue
+			}
+			if !cascade {
+				return fmt.Errorf("cannot drop sequence %s because other objects depend on it", name)
+			}
+			if !saved {
+				tx.saveTable(r.table)
+				saved = true
+			}
+			attr.sequence = ""
+			attr.autoIncrement = false
+			attr.generatedAlways = false
+		}
+	}
+
+	return nil
+}
+
+// createSequence registers sequence s, removed if tx rolls back
+func (tx *Transaction) createSequence(e *Engine, s *sequence) {
+	e.sequences[s.name] = s
+	tx.onRollback(func() {
+		delete(e.sequences, s.name)
+	})
+}
+
+// dropSequence removes sequence s, registered again if tx rolls back
+func (tx *Transaction) dropSequence(e *Engine, s *sequence) {
+	delete(e.sequences, s.name)
+	tx.onRollback(func() {
+		e.sequences[s.name] = s
+	})
+}
+
+// dropOwnedSequences drops sequences of identity columns of relation r
+func (tx *Transaction) dropOwnedSequences(e *Engine, r *Relation) {
+	for _, s := range e.sequences {
+		if s.owner == r {
+			tx.dropSequence(e, s)
+		}
+	}
+}
+
+// saveSequence restores the definition of sequence s as it is now if tx
+// rolls back. Values obtained meanwhile are kept, unless s is restarted.
+func (tx *Transaction) saveSequence(s *sequence, restart bool) {
+	saved := *s
+	tx.onRollback(func() {
+		last, called := s.last, s.called
+		*s = saved
+		if !restart {
+			s.last, s.called = last, called
+		}
+	})
+}
+
+// createIdentity creates the sequence generating values of identity column
+// attr of relation r, named <table>_<column>_seq as in Postgres
+func (tx *Transaction) createIdentity(e *Engine, r *Relation, attr *Attribute, options []*parser.Decl) error {
+	s := newSequence(e.sequenceName(r.table.name + "_" + attr.name + "_seq"))
+	max, err := sequenceMax(attr.typeName)
+	if err != nil {
+		return fmt.Errorf("identity column type must be smallint, integer, or bigint")
+	}
+	s.max = max
+	if err := s.configure(options, true); err != nil {
+		return err
+	}
+	s.owner = r
+
+	attr.sequence = s.name
+	tx.createSequence(e, s)
+	return nil
+}
+
+// createIdentities creates sequences of identity columns of relation r
+// declared in given column definitions, i.e SERIAL ones
+func (tx *Transaction) createIdentities(e *Engine, r *Relation, columnDecls []*parser.Decl) error {
+	for _, c := range columnDecls {
+		i := r.table.attributeIndex(c.Lexeme)
+		if i < 0 {
+			continue
+		}
+		attr := &r.table.attributes[i]
+		if !attr.autoIncrement || attr.sequence != "" {
+			continue
+		}
+
+		// Options of GENERATED ... AS IDENTITY follow ALWAYS or DEFAULT
+		var options []*parser.Decl
+		for _, d := range c.Decl[1:] {
+			if d.Token == parser.GeneratedToken && len(d.Decl) > 0 {
+				options = d.Decl[1:]
+			}
+		}
+		if err := tx.createIdentity(e, r, attr, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sequenceName returns given sequence name, followed by a number if a
+// sequence or a relation already uses it
+func (e *Engine) sequenceName(name string) string {
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate += strconv.Itoa(n)
+		}
+		if e.sequences[candidate] == nil && e.relation(candidate) == nil {
+			return candidate
+		}
+	}
+}
+
+// restartIdentities restarts sequences of identity columns of relation r,
+// i.e on TRUNCATE ... RESTART IDENTITY
+func (tx *Transaction) restartIdentities(e *Engine, r *Relation) {
+	for _, attr := range r.table.attributes {
+		s := e.sequences[attr.sequence]
+		if !attr.autoIncrement || s == nil || s.owner != r {
+			continue
+		}
+		tx.saveSequence(s, true)
+		s.restart(s.start)
+	}
+}
+
+// nextval advances sequence name and returns its new value, which the
+// session returns from currval afterwards
+func (tx *Transaction) nextval(name string) (int64, error) {
+	s := tx.e.sequences[name]
+	if s == nil {
+		return 0, fmt.Errorf("relation \"%s\" does not exist", name)
+	}
+
+	v, err := s.next()
+	if err != nil {
+		return 0, err
+	}
+	if tx.session != nil {
+		tx.session.currval[s] = v
+	}
+
+	return v, nil
+}
+
+// value returns the value of a literal decl, calling sequence functions
+func (tx *Transaction) value(decl *parser.Decl) (interface{}, error) {
+	switch decl.Token {
+	case parser.NextvalToken, parser.CurrvalToken, parser.SetvalToken:
+		return tx.call(decl)
+	}
+
+	return declValue(decl), nil
+}
+
+// sequenceChange returns the first call to nextval() or setval() in decl,
+// nil if none
+func sequenceChange(decl *parser.Decl) *parser.Decl {
+	if decl.Token == parser.NextvalToken || decl.Token == parser.SetvalToken {
+		return decl
+	}
+
+	for _, d := range decl.Decl {
+		if f := sequenceChange(d); f != nil {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// call runs a sequence function:
+//
+//	|-> { nextval | currval | setval }
+//	    |-> <SEQUENCE-NAME>
+//	    |-> <VALUE>
+//	    |-> { true | false }
+func (tx *Transaction) call(funcDecl *parser.Decl) (int64, error) {
+	if len(funcDecl.Decl) == 0 {
+		return 0, fmt.Errorf("function %s() does not exist", funcDecl.Lexeme)
+	}
+	name := funcDecl.Decl[0].Lexeme
+
+	if funcDecl.Token == parser.NextvalToken {
+		return tx.nextval(name)
+	}
+
+	s := tx.e.sequences[name]
+	if s == nil {
+		return 0, fmt.Errorf("relation \"%s\" does not exist", name)
+	}
+
+	if funcDecl.Token == parser.CurrvalToken {
+		v, ok := int64(0), false
+		if tx.session != nil {
+			v, ok = tx.session.currval[s]
+		}
+		if !ok {
+			return 0, fmt.Errorf("currval of sequence \"%s\" is not yet defined in this session", name)
+		}
+		return v, nil
+	}
+
+	// SETVAL
+	if len(funcDecl.Decl) < 2 {
+		return 0, fmt.Errorf("function setval() requires a value")
+	}
+	v, err := strconv.ParseInt(funcDecl.Decl[1].Lexeme, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input syntax for type bigint: \"%s\"", funcDecl.Decl[1].Lexeme)
+	}
+	called := len(funcDecl.Decl) < 3 || funcDecl.Decl[2].Token == parser.TrueToken
+	if err := s.set(v, called); err != nil {
+		return 0, err
+	}
+	if called && tx.session != nil {
+		tx.session.currval[s] = v
+	}
+
+	return v, nil
+}
+
+// selectSequenceFuncs answers a SELECT without FROM calling sequence
+// functions, i.e SELECT nextval('account_id_seq'), with a single row
+func selectSequenceFuncs(tx *Transaction, selectDecl *parser.Decl, conn protocol.EngineConn) error {
+	var columns []protocol.Column
+	var row []interface{}
+	for _, d := range selectDecl.Decl {
+		v, err := tx.call(d)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, NewAttribute(d.Lexeme, "bigint", false).column())
+		row = append(row, v)
+	}
+
+	conn.WriteRowHeader(columns)
+	conn.WriteRow(row)
+	return conn.WriteRowEnd()
+}
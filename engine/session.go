@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// session holds the state of a connection to the engine
+type session struct {
+	// tx is the transaction started with BEGIN, if any
+	tx *Transaction
+	// ctx is the context of the statement being run
+	ctx context.Context
+	// statements prepared on the connection, by identifier
+	statements map[int64][]parser.Instruction
+	lastID     int64
+	// currval holds the value last returned by nextval in the session,
+	// by sequence
+	currval map[*sequence]int64
+	// searchPath lists the schemas where unqualified names are looked up
+	searchPath []string
+	// temp is the schema holding the temporary tables of the session,
+	// empty until the first one is created
+	temp string
+}
+
+func newSession() *session {
+	return &session{
+		ctx:        context.Background(),
+		statements: make(map[int64][]parser.Instruction),
+		currval:    make(map[*sequence]int64),
+		searchPath: defaultSearchPath,
+	}
+}
+
+// path returns the schemas where unqualified names are looked up,
+// temporary tables first
+func (s *session) path() []string {
+	if s.temp == "" {
+		return s.searchPath
+	}
+
+	return append([]string{s.temp}, s.searchPath...)
+}
+
+// prepare caches parsed instructions, returning their identifier
+func (s *session) prepare(instructions []parser.Instruction) int64 {
+	s.lastID++
+	s.statements[s.lastID] = instructions
+	return s.lastID
+}
+
+// statement returns the instructions of a prepared statement
+func (s *session) statement(id int64) ([]parser.Instruction, error) {
+	instructions, ok := s.statements[id]
+	if !ok {
+		return nil, fmt.Errorf("prepared statement %d does not exist", id)
+	}
+
+	return instructions, nil
+}
+
+// deallocate releases a prepared statement
+func (s *session) deallocate(id int64) error {
+	if _, ok := s.statements[id]; !ok {
+		return fmt.Errorf("prepared statement %d does not exist", id)
+	}
+
+	delete(s.statements, id)
+	return nil
+}
+
+// transaction returns the running explicit transaction,
+// or starts a new implicit one for the current statement
+func (s *session) transaction(e *Engine) *Transaction {
+	if s.tx != nil && s.tx.state == txActive {
+		s.tx.refresh()
+		return s.tx
+	}
+
+	s.tx = e.begin()
+	return s.tx
+}
+
+// end terminates a statement run in tx. Implicit transactions are committed
+// on success. On error, an implicit transaction is rolled back, an explicit one
+// only loses the changes made since mark.
+func (s *session) end(tx *Transaction, mark int, err error) error {
+	if tx.state != txActive {
+		s.tx = nil
+		return err
+	}
+
+	if err != nil {
+		if tx.explicit {
+			tx.rollbackTo(mark)
+			return err
+		}
+
+		tx.rollback()
+		s.tx = nil
+		return err
+	}
+
+	if !tx.explicit {
+		s.tx = nil
+		return tx.commit()
+	}
+
+	return nil
+}
+
+// close rolls back the running transaction, if any,
+// and releases prepared statements
+func (s *session) close() {
+	s.statements = nil
+
+	if s.tx != nil {
+		s.tx.rollback()
+		s.tx = nil
+	}
+}
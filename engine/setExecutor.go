@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"fmt"
+
 	"github.com/kokizzu/ramsql/engine/parser"
 )
 
@@ -8,14 +10,55 @@ import (
 	|-> set
 	      |-> email
 					|-> =
-					|-> roger@gmail.com
+					|-> <EXPRESSION>
 */
-func setExecutor(setDecl *parser.Decl) (map[string]interface{}, error) {
+func setExecutor(setDecl *parser.Decl, tables []*Table) (map[string]*parser.Decl, error) {
 
-	values := make(map[string]interface{})
+	expressions := make(map[string]*parser.Decl)
 
+	// Columns set belong to the first table, those of joined tables
+	// cannot be updated
+	t := tables[0]
 	for _, attr := range setDecl.Decl {
-		values[attr.Lexeme] = attr.Decl[1].Lexeme
+		if len(attr.Decl) < 2 {
+			return nil, fmt.Errorf("parsing failed, malformed SET clause")
+		}
+		// Column may be qualified by its table name
+		if len(attr.Decl) == 3 && attr.Decl[0].Lexeme != t.name {
+			return nil, fmt.Errorf("cannot update column \"%s\" of joined table \"%s\", only columns of \"%s\" can be set", attr.Lexeme, attr.Decl[0].Lexeme, t.name)
+		}
+		if t.attributeIndex(attr.Lexeme) < 0 {
+			for _, joined := range tables[1:] {
+				if joined.attributeIndex(attr.Lexeme) >= 0 {
+					return nil, fmt.Errorf("cannot update column \"%s\" of joined table \"%s\", only columns of \"%s\" can be set", attr.Lexeme, joined.name, t.name)
+				}
+			}
+			return nil, fmt.Errorf("column \"%s\" of relation \"%s\" does not exist", attr.Lexeme, t.name)
+		}
+		if _, ok := expressions[attr.Lexeme]; ok {
+			return nil, fmt.Errorf("multiple assignments to same column \"%s\"", attr.Lexeme)
+		}
+		exprDecl := attr.Decl[len(attr.Decl)-1]
+		if _, err := expressionAttribute(exprDecl, tables); err != nil {
+			return nil, err
+		}
+		expressions[attr.Lexeme] = exprDecl
+	}
+
+	return expressions, nil
+}
+
+// setValues computes the values set from a row. Every expression sees the
+// row as it was before the update.
+func setValues(row virtualRow, expressions map[string]*parser.Decl) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(expressions))
+
+	for name, exprDecl := range expressions {
+		v, err := evaluate(exprDecl, row)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = v
 	}
 
 	return values, nil
@@ -3,8 +3,12 @@ package engine
 // Table is defined by a name and attributes
 // A table with data is called a Relation
 type Table struct {
-	name       string
-	attributes []Attribute
+	name        string
+	attributes  []Attribute
+	primaryKey  *uniqueKey
+	uniqueKeys  []*uniqueKey
+	foreignKeys []*foreignKey
+	checks      []*checkConstraint
 }
 
 // NewTable initializes a new Table
@@ -23,6 +27,17 @@ func (t *Table) AddAttribute(attr Attribute) error {
 	return nil
 }
 
+// attributeIndex returns the index of attribute with given name, -1 if not found
+func (t *Table) attributeIndex(name string) int {
+	for i := range t.attributes {
+		if t.attributes[i].name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // String returns a printable string with table name and attributes
 func (t Table) String() string {
 	stringy := t.name + " ("
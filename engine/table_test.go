@@ -24,14 +24,14 @@ func TestInsertTable(t *testing.T) {
 
 	createTable(e, t)
 
-	query := `INSERT INTO user ('last_name', 'first_name', 'email') VALUES ('Roullon', 'Pierre', 'pierre.roullon@gmail.com')`
+	query := `INSERT INTO user ('id', 'last_name', 'first_name', 'email') VALUES (1, 'Roullon', 'Pierre', 'pierre.roullon@gmail.com')`
 
 	i, err := parser.ParseInstruction(query)
 	if err != nil {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
@@ -56,7 +56,7 @@ func createTable(e *Engine, t *testing.T) {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
@@ -80,7 +80,7 @@ func TestCreateAndInsertTableWithBacktickedKeyword(t *testing.T) {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
@@ -92,7 +92,7 @@ func TestCreateAndInsertTableWithBacktickedKeyword(t *testing.T) {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
@@ -118,7 +118,7 @@ func TestCreateWithAttributeDefaultNull(t *testing.T) {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
@@ -130,6 +130,11 @@ func TestCreateWithIndexKeyAsc(t *testing.T) {
 	e := testEngine(t)
 	defer e.Stop()
 
+	err := parseAndExecuteQuery(t, e, `CREATE TABLE organization (organization_id INT PRIMARY KEY)`)
+	if err != nil {
+		t.Fatalf("Cannot create referenced table: %s", err)
+	}
+
 	query := `CREATE TABLE user (
       id INT PRIMARY KEY AUTO_INCREMENT,
       organization_id INT NOT NULL,
@@ -148,7 +153,7 @@ func TestCreateWithIndexKeyAsc(t *testing.T) {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
@@ -160,6 +165,11 @@ func TestCreateWithForeignKeyOnUpdateNoAction(t *testing.T) {
 	e := testEngine(t)
 	defer e.Stop()
 
+	err := parseAndExecuteQuery(t, e, `CREATE TABLE organization (organization_id INT PRIMARY KEY)`)
+	if err != nil {
+		t.Fatalf("Cannot create referenced table: %s", err)
+	}
+
 	query := `CREATE TABLE user (
       id INT PRIMARY KEY AUTO_INCREMENT,
       organization_id INT NOT NULL,
@@ -178,7 +188,7 @@ func TestCreateWithForeignKeyOnUpdateNoAction(t *testing.T) {
 		t.Fatalf("Cannot parse query %s : %s", query, err)
 	}
 
-	err = e.executeQuery(i[0], &TestEngineConn{})
+	err = e.executeQuery(newSession(), i[0], &TestEngineConn{})
 	if err != nil {
 		t.Fatalf("Cannot execute query: %s", err)
 	}
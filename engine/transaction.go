@@ -0,0 +1,497 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// Transaction states
+const (
+	txActive = iota
+	txCommitted
+	txAborted
+)
+
+// Isolation levels. READ UNCOMMITTED behaves like READ COMMITTED.
+const (
+	readCommitted = iota
+	repeatableRead
+	serializable
+)
+
+// Transaction isolates changes made to relations until they are committed.
+//
+// Rows are versioned: each Tuple knows the transaction which created it and
+// the one which deleted it. An update deletes the current version and links
+// a new one to it, so transactions started before the update still find the
+// row as they saw it. Old versions are freed by Engine.vacuum once no running
+// transaction can see them.
+type Transaction struct {
+	e         *Engine
+	explicit  bool
+	state     int
+	isolation int
+	readOnly  bool
+
+	// snapshot is the engine clock at the time the transaction (or, in
+	// READ COMMITTED, the statement) started. Changes committed after it
+	// are not visible.
+	snapshot  int64
+	commitSeq int64
+
+	// now is the value of NOW() and CURRENT_TIMESTAMP, the time the
+	// transaction started as in PostgreSQL
+	now time.Time
+
+	// undo holds closures cancelling each change, in order
+	undo []func()
+	// deferred holds closures run at commit, before changes become visible
+	deferred []func()
+	// savepoints are marks in undo log
+	savepoints []savepoint
+
+	// relations read and written, to detect serialization anomalies
+	read    map[*Relation]bool
+	written map[*Relation]bool
+	// versions holds every row version created or deleted, to be vacuumed
+	// once commited
+	versions []rowVersion
+
+	// ctx is the context of the statement being run
+	ctx context.Context
+	// session is the connection running the statement
+	session *session
+}
+
+type savepoint struct {
+	name string
+	mark int
+}
+
+type rowVersion struct {
+	relation *Relation
+	tuple    *Tuple
+}
+
+// begin starts a new implicit transaction
+func (e *Engine) begin() *Transaction {
+	tx := &Transaction{
+		e:        e,
+		state:    txActive,
+		snapshot: e.clock,
+		now:      time.Now(),
+	}
+
+	e.active[tx] = true
+	return tx
+}
+
+// refresh takes a new snapshot at the beginning of each statement
+// in READ COMMITTED. Others isolation levels keep the snapshot taken
+// when the transaction started.
+func (tx *Transaction) refresh() {
+	if tx.isolation == readCommitted {
+		tx.snapshot = tx.e.clock
+	}
+}
+
+// setIsolation sets isolation level from its SQL name
+func (tx *Transaction) setIsolation(level string) error {
+	switch level {
+	case "read uncommitted", "read committed":
+		tx.isolation = readCommitted
+	case "repeatable read":
+		tx.isolation = repeatableRead
+	case "serializable":
+		tx.isolation = serializable
+	default:
+		return fmt.Errorf("unknown isolation level %s", level)
+	}
+
+	return nil
+}
+
+// allows returns an error if statement cannot be executed in tx
+func (tx *Transaction) allows(decl *parser.Decl) error {
+	if !tx.readOnly {
+		return nil
+	}
+
+	switch decl.Token {
+	case parser.AlterToken, parser.CreateToken, parser.DeleteToken, parser.DropToken, parser.InsertToken, parser.TruncateToken, parser.UpdateToken:
+		return fmt.Errorf("cannot execute %s in a read-only transaction", strings.ToUpper(decl.Lexeme))
+	}
+
+	// Sequences are changed by nextval() and setval(), even selected
+	if f := sequenceChange(decl); f != nil {
+		return fmt.Errorf("cannot execute %s() in a read-only transaction", f.Lexeme)
+	}
+
+	return nil
+}
+
+// interrupted returns the error of the running statement context
+// once it is cancelled or its deadline exceeded, nil otherwise.
+// Row loops check it so long statements can be aborted.
+func (tx *Transaction) interrupted() error {
+	if tx.ctx == nil {
+		return nil
+	}
+
+	return tx.ctx.Err()
+}
+
+// reads records that tx read rows of relation r
+func (tx *Transaction) reads(r *Relation) {
+	if tx.read == nil {
+		tx.read = make(map[*Relation]bool)
+	}
+	tx.read[r] = true
+}
+
+// writes records that tx modified rows of relation r
+func (tx *Transaction) writes(r *Relation) {
+	if tx.written == nil {
+		tx.written = make(map[*Relation]bool)
+	}
+	tx.written[r] = true
+}
+
+// sees returns true if changes made by other are visible to tx
+func (tx *Transaction) sees(other *Transaction) bool {
+	if other == nil || other == tx {
+		return true
+	}
+
+	return other.state == txCommitted && other.commitSeq <= tx.snapshot
+}
+
+func (tx *Transaction) committedBefore(horizon int64) bool {
+	return tx.state == txCommitted && tx.commitSeq <= horizon
+}
+
+// visible returns the version of given row visible to tx, or nil if
+// the row does not exist for tx
+func (tx *Transaction) visible(t *Tuple) *Tuple {
+	for v := t; v != nil; v = v.previous {
+		if !tx.sees(v.createdBy) {
+			continue
+		}
+
+		if v.deletedBy != nil && tx.sees(v.deletedBy) {
+			return nil
+		}
+		return v
+	}
+
+	return nil
+}
+
+// lock checks that row version t is the latest one and is not being
+// modified by another transaction
+func (tx *Transaction) lock(r *Relation, t *Tuple) error {
+	if t.deletedBy == nil || t.deletedBy == tx {
+		return nil
+	}
+
+	return tx.conflict(r, t.deletedBy)
+}
+
+// conflict returns the error of tx writing a row of relation r also
+// written by transaction other, which tx does not see
+func (tx *Transaction) conflict(r *Relation, other *Transaction) error {
+	if other.state == txActive {
+		return fmt.Errorf("could not obtain lock on row in relation \"%s\"", r.table.name)
+	}
+
+	return fmt.Errorf("could not serialize access due to concurrent update")
+}
+
+// insert appends a new row to relation
+func (tx *Transaction) insert(r *Relation, t *Tuple) error {
+	t.createdBy = tx
+
+	err := r.check(tx, t, nil)
+	if err != nil {
+		return err
+	}
+
+	err = r.Insert(t)
+	if err != nil {
+		return err
+	}
+
+	tx.writes(r)
+	tx.versions = append(tx.versions, rowVersion{r, t})
+	tx.undo = append(tx.undo, func() {
+		r.remove(t)
+	})
+	return nil
+}
+
+// delete marks row version t as deleted by tx
+func (tx *Transaction) delete(r *Relation, t *Tuple) error {
+	err := tx.lock(r, t)
+	if err != nil {
+		return err
+	}
+
+	t.deletedBy = tx
+	tx.writes(r)
+	tx.versions = append(tx.versions, rowVersion{r, t})
+	tx.undo = append(tx.undo, func() {
+		t.deletedBy = nil
+	})
+	return tx.referentialActions(r, t, nil)
+}
+
+// update replaces row version t, found at index i in relation (-1 if
+// unknown), by a new version holding given values
+func (tx *Transaction) update(r *Relation, i int, t *Tuple, values []interface{}) (*Tuple, error) {
+	err := tx.lock(r, t)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Tuple{
+		Values:    values,
+		createdBy: tx,
+		previous:  t,
+		seq:       t.seq,
+	}
+	err = r.check(tx, n, t)
+	if err != nil {
+		return nil, err
+	}
+
+	t.deletedBy = tx
+	r.replace(i, t, n)
+	r.index(n)
+
+	tx.writes(r)
+	tx.versions = append(tx.versions, rowVersion{r, t}, rowVersion{r, n})
+	tx.undo = append(tx.undo, func() {
+		r.unindex(n)
+		r.replace(i, n, t)
+		t.deletedBy = nil
+	})
+	return n, tx.referentialActions(r, t, n)
+}
+
+// rewrite replaces values of every version of every row of relation r by
+// those returned by f, given the row index, i.e once a column is added or
+// dropped. Values are restored if tx rolls back.
+func (tx *Transaction) rewrite(r *Relation, f func(i int, t *Tuple) ([]interface{}, error)) error {
+	saved := make(map[*Tuple][]interface{})
+	tx.onRollback(func() {
+		for t, values := range saved {
+			t.Values = values
+		}
+	})
+
+	for i, row := range r.rows {
+		for v := row; v != nil; v = v.previous {
+			values, err := f(i, v)
+			if err != nil {
+				return err
+			}
+			saved[v] = v.Values
+			v.Values = values
+		}
+	}
+
+	tx.writes(r)
+	return nil
+}
+
+// saveTable restores the definition of table t as it is now if tx rolls
+// back. Constraints are not modified in place but replaced, so copying
+// their slices is enough.
+func (tx *Transaction) saveTable(t *Table) {
+	saved := *t
+	saved.attributes = append([]Attribute(nil), t.attributes...)
+	tx.onRollback(func() {
+		*t = saved
+	})
+}
+
+// onRollback registers a closure cancelling a change not related to rows,
+// i.e a table creation
+func (tx *Transaction) onRollback(f func()) {
+	tx.undo = append(tx.undo, f)
+}
+
+// onCommit registers a change to make when tx commits
+func (tx *Transaction) onCommit(f func()) {
+	tx.deferred = append(tx.deferred, f)
+}
+
+// commit makes all changes visible to other transactions
+func (tx *Transaction) commit() error {
+	if tx.state != txActive {
+		return nil
+	}
+
+	for _, f := range tx.deferred {
+		f()
+	}
+	tx.deferred = nil
+
+	// A SERIALIZABLE transaction writing data based on relations modified
+	// concurrently could produce a result impossible with a serial execution.
+	// Relation level tracking is coarse but never misses an anomaly.
+	if tx.isolation == serializable && len(tx.written) > 0 {
+		for r := range tx.read {
+			if r.modifiedAt > tx.snapshot {
+				tx.rollback()
+				return fmt.Errorf("could not serialize access due to read/write dependencies among transactions")
+			}
+		}
+	}
+
+	tx.e.clock++
+	tx.commitSeq = tx.e.clock
+	tx.state = txCommitted
+	tx.undo = nil
+	for r := range tx.written {
+		r.modifiedAt = tx.commitSeq
+	}
+
+	tx.e.garbage = append(tx.e.garbage, tx.versions...)
+	tx.versions = nil
+	tx.end()
+	return nil
+}
+
+// rollback cancels all changes
+func (tx *Transaction) rollback() {
+	if tx.state != txActive {
+		return
+	}
+
+	tx.rollbackTo(0)
+	tx.state = txAborted
+	tx.versions = nil
+	tx.end()
+}
+
+// rollbackTo cancels changes made after undo log reached mark
+func (tx *Transaction) rollbackTo(mark int) {
+	for i := len(tx.undo) - 1; i >= mark; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:mark]
+}
+
+// savepoint defines a new savepoint at current state of transaction
+func (tx *Transaction) savepoint(name string) error {
+	if !tx.explicit {
+		return fmt.Errorf("SAVEPOINT can only be used in transaction blocks")
+	}
+
+	tx.savepoints = append(tx.savepoints, savepoint{name: name, mark: len(tx.undo)})
+	return nil
+}
+
+// findSavepoint returns the index of the latest savepoint with given name
+func (tx *Transaction) findSavepoint(name string) (int, error) {
+	if !tx.explicit {
+		return 0, fmt.Errorf("savepoints can only be used in transaction blocks")
+	}
+
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("savepoint \"%s\" does not exist", name)
+}
+
+// rollbackToSavepoint cancels changes made since savepoint was defined.
+// The savepoint itself stays defined, later ones are destroyed.
+func (tx *Transaction) rollbackToSavepoint(name string) error {
+	i, err := tx.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+
+	tx.rollbackTo(tx.savepoints[i].mark)
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+// release destroys savepoint and all savepoints defined after it,
+// keeping changes made since
+func (tx *Transaction) release(name string) error {
+	i, err := tx.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+
+	tx.savepoints = tx.savepoints[:i]
+	return nil
+}
+
+func (tx *Transaction) end() {
+	delete(tx.e.active, tx)
+	tx.e.vacuum()
+}
+
+// vacuum frees row versions no running transaction can see anymore
+func (e *Engine) vacuum() {
+	horizon := e.clock
+	for tx := range e.active {
+		if tx.snapshot < horizon {
+			horizon = tx.snapshot
+		}
+	}
+
+	compact := make(map[*Relation]bool)
+	left := e.garbage[:0]
+	for _, v := range e.garbage {
+		var keep bool
+		t := v.tuple
+
+		// Once its creation is visible to everyone, previous versions
+		// of a row are useless
+		if t.createdBy != nil {
+			if t.createdBy.committedBefore(horizon) {
+				for p := t.previous; p != nil; p = p.previous {
+					v.relation.unindex(p)
+				}
+				t.createdBy = nil
+				t.previous = nil
+			} else {
+				keep = true
+			}
+		}
+
+		// Same goes for the row itself once its deletion is visible
+		if t.deletedBy != nil && t.deletedBy.state == txCommitted {
+			if t.deletedBy.committedBefore(horizon) {
+				compact[v.relation] = true
+			} else {
+				keep = true
+			}
+		}
+
+		if keep {
+			left = append(left, v)
+		}
+	}
+
+	// Clear dropped pointers for the garbage collector
+	for i := len(left); i < len(e.garbage); i++ {
+		e.garbage[i] = rowVersion{}
+	}
+	e.garbage = left
+
+	for r := range compact {
+		r.vacuum(horizon)
+	}
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+func beginExecutor(e *Engine, tx *Transaction, beginDecl *parser.Decl, conn protocol.EngineConn) error {
+	if tx.explicit {
+		return fmt.Errorf("there is already a transaction in progress")
+	}
+
+	tx.explicit = true
+
+	// Transaction modes
+	for _, modeDecl := range beginDecl.Decl {
+		switch modeDecl.Lexeme {
+		case "isolation":
+			err := tx.setIsolation(modeDecl.Decl[0].Lexeme)
+			if err != nil {
+				return err
+			}
+		case "read":
+			tx.readOnly = modeDecl.Decl[0].Lexeme == "only"
+		}
+	}
+
+	return conn.WriteResult(0, 0)
+}
+
+func commitExecutor(e *Engine, tx *Transaction, commitDecl *parser.Decl, conn protocol.EngineConn) error {
+	err := tx.commit()
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteResult(0, 0)
+}
+
+func rollbackExecutor(e *Engine, tx *Transaction, rollbackDecl *parser.Decl, conn protocol.EngineConn) error {
+	// ROLLBACK TO SAVEPOINT
+	if len(rollbackDecl.Decl) > 0 {
+		err := tx.rollbackToSavepoint(rollbackDecl.Decl[0].Decl[0].Lexeme)
+		if err != nil {
+			return err
+		}
+		return conn.WriteResult(0, 0)
+	}
+
+	tx.rollback()
+	return conn.WriteResult(0, 0)
+}
+
+func savepointExecutor(e *Engine, tx *Transaction, savepointDecl *parser.Decl, conn protocol.EngineConn) error {
+	err := tx.savepoint(savepointDecl.Decl[0].Lexeme)
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteResult(0, 0)
+}
+
+func releaseExecutor(e *Engine, tx *Transaction, releaseDecl *parser.Decl, conn protocol.EngineConn) error {
+	err := tx.release(releaseDecl.Decl[0].Lexeme)
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteResult(0, 0)
+}
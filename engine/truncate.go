@@ -6,7 +6,7 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func truncateTable(e *Engine, table *Table, conn protocol.EngineConn) error {
+func truncateTable(e *Engine, tx *Transaction, table *Table, conn protocol.EngineConn) error {
 	var rowsDeleted int64
 
 	// get relations and write lock them
@@ -16,11 +16,19 @@ func truncateTable(e *Engine, table *Table, conn protocol.EngineConn) error {
 	}
 	r.Lock()
 	defer r.Unlock()
+	tx.reads(r)
 
-	if r.rows != nil {
-		rowsDeleted = int64(len(r.rows))
+	for _, t := range r.rows {
+		if t = tx.visible(t); t == nil {
+			continue
+		}
+
+		err := tx.delete(r, t)
+		if err != nil {
+			return err
+		}
+		rowsDeleted++
 	}
-	r.rows = make([]*Tuple, 0)
 
 	return conn.WriteResult(0, rowsDeleted)
 }
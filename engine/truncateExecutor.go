@@ -6,11 +6,21 @@ import (
 	"github.com/kokizzu/ramsql/engine/protocol"
 )
 
-func truncateExecutor(e *Engine, trDecl *parser.Decl, conn protocol.EngineConn) error {
+func truncateExecutor(e *Engine, tx *Transaction, trDecl *parser.Decl, conn protocol.EngineConn) error {
 	log.Debug("truncateExecutor")
 
 	// get tables to be deleted
 	table := NewTable(trDecl.Decl[0].Lexeme)
 
-	return truncateTable(e, table, conn)
+	// RESTART IDENTITY resets sequences of identity columns
+	for _, d := range trDecl.Decl[1:] {
+		if d.Token != parser.RestartToken {
+			continue
+		}
+		if r := e.relation(table.name); r != nil {
+			tx.restartIdentities(e, r)
+		}
+	}
+
+	return truncateTable(e, tx, table, conn)
 }
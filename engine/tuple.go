@@ -3,6 +3,13 @@ package engine
 // Tuple is a row in a relation
 type Tuple struct {
 	Values []interface{}
+
+	// Row versioning, see Transaction
+	createdBy *Transaction
+	deletedBy *Transaction
+	previous  *Tuple
+	// seq numbers the row in its relation, all its versions share it
+	seq int64
 }
 
 // NewTuple should check that value are for the right Attribute and match domain
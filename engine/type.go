@@ -0,0 +1,396 @@
+package engine
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// Column types. Values are stored in tuples with the Go type
+// matching their column type:
+//
+//	integerType   int64
+//	floatType     float64
+//	booleanType   bool
+//	textType      string
+//	timestampType time.Time
+//	dateType      time.Time
+//	byteaType     []byte
+//
+// Columns of unknown type (i.e JSON) hold strings as given.
+const (
+	unknownType = iota
+	integerType
+	floatType
+	booleanType
+	textType
+	timestampType
+	dateType
+	byteaType
+)
+
+// typeKind returns the column type of given SQL type name
+func typeKind(typeName string) int {
+	switch strings.ToLower(typeName) {
+	case "int", "integer", "int2", "int4", "int8", "smallint", "bigint", "tinyint", "mediumint",
+		"serial", "smallserial", "bigserial", "serial2", "serial4", "serial8":
+		return integerType
+	case "real", "float", "float4", "float8", "double", "double precision", "numeric", "decimal":
+		return floatType
+	case "bool", "boolean":
+		return booleanType
+	case "text", "varchar", "char", "character", "character varying", "string", "uuid":
+		return textType
+	case "timestamp", "timestamptz", "datetime", "localtimestamp":
+		return timestampType
+	case "date":
+		return dateType
+	case "bytea", "blob", "binary", "varbinary":
+		return byteaType
+	}
+
+	return unknownType
+}
+
+// scanType returns the Go type of values stored in a column of given SQL type
+func scanType(typeName string) reflect.Type {
+	switch typeKind(typeName) {
+	case integerType:
+		return reflect.TypeOf(int64(0))
+	case floatType:
+		return reflect.TypeOf(float64(0))
+	case booleanType:
+		return reflect.TypeOf(false)
+	case timestampType, dateType:
+		return reflect.TypeOf(time.Time{})
+	case byteaType:
+		return reflect.TypeOf([]byte(nil))
+	}
+
+	return reflect.TypeOf("")
+}
+
+// typeString returns the name of the column type as used in error messages
+func typeString(typeName string) string {
+	switch strings.ToLower(typeName) {
+	case "int", "integer", "int4", "serial", "serial4", "mediumint":
+		return "integer"
+	case "int8", "bigint", "bigserial", "serial8":
+		return "bigint"
+	case "int2", "smallint", "tinyint", "smallserial", "serial2":
+		return "smallint"
+	case "real", "float4":
+		return "real"
+	case "float", "float8", "double", "double precision":
+		return "double precision"
+	case "decimal":
+		return "numeric"
+	case "bool":
+		return "boolean"
+	case "varchar":
+		return "character varying"
+	case "char":
+		return "character"
+	case "timestamptz", "datetime", "localtimestamp":
+		return "timestamp"
+	case "blob", "binary", "varbinary":
+		return "bytea"
+	}
+
+	return strings.ToLower(typeName)
+}
+
+// declValue returns the value of a literal decl, i.e in VALUES or SET clauses
+func declValue(decl *parser.Decl) interface{} {
+	switch decl.Token {
+	case parser.NullToken:
+		return nil
+	case parser.NowToken, parser.LocalTimestampToken:
+		// Bound to the time of the transaction, see bindNow
+		if len(decl.Decl) == 1 {
+			if t, err := time.Parse(time.RFC3339Nano, decl.Decl[0].Lexeme); err == nil {
+				return t
+			}
+		}
+		return time.Now()
+	case parser.TrueToken:
+		return true
+	case parser.FalseToken:
+		return false
+	case parser.ByteaToken:
+		// Bound arguments, see argumentDecl
+		return []byte(decl.Lexeme)
+	case parser.TimestampToken:
+		if t, err := time.Parse(parser.DateLongFormat, decl.Lexeme); err == nil {
+			return t
+		}
+	}
+
+	return decl.Lexeme
+}
+
+// coerce converts value v to the type of given attribute,
+// returning an error if v is not a valid input for it
+func coerce(attr Attribute, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var val interface{}
+	var err error
+
+	switch typeKind(attr.typeName) {
+	case integerType:
+		val, err = toInteger(v)
+		if f, ok := v.(float64); ok {
+			val, err = roundInteger(f)
+		}
+		if err == nil {
+			min, max := integerRange(attr.typeName)
+			if i := val.(int64); i < min || i > max {
+				err = errOutOfRange
+			}
+		}
+	case floatType:
+		val, err = toFloat(v)
+	case booleanType:
+		val, err = toBoolean(v)
+	case textType:
+		val, err = toText(v, attr)
+	case timestampType:
+		val, err = toTimestamp(v)
+	case dateType:
+		val, err = toDate(v)
+	case byteaType:
+		val, err = toBytea(v)
+	default:
+		val = format(v)
+	}
+	if err != nil {
+		if err == errInvalidInput {
+			return nil, fmt.Errorf("invalid input syntax for type %s: \"%s\"", typeString(attr.typeName), format(v))
+		}
+		if err == errOutOfRange {
+			return nil, fmt.Errorf("%s out of range", typeString(attr.typeName))
+		}
+		return nil, err
+	}
+
+	return val, nil
+}
+
+var errInvalidInput = fmt.Errorf("invalid input")
+
+var errOutOfRange = fmt.Errorf("out of range")
+
+// integerRange returns the bounds of values of given integer type
+func integerRange(typeName string) (int64, int64) {
+	switch typeString(typeName) {
+	case "integer":
+		return math.MinInt32, math.MaxInt32
+	case "smallint":
+		return math.MinInt16, math.MaxInt16
+	}
+
+	return math.MinInt64, math.MaxInt64
+}
+
+func toInteger(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case bool:
+		// i.e MySQL TINYINT(1)
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err == nil {
+			return i, nil
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, errOutOfRange
+		}
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true":
+			return int64(1), nil
+		case "false":
+			return int64(0), nil
+		}
+		return nil, errInvalidInput
+	}
+
+	return nil, errInvalidInput
+}
+
+// roundInteger rounds a number assigned to an integer column, half away
+// from zero like Postgres does for numeric values
+func roundInteger(f float64) (interface{}, error) {
+	f = math.Round(f)
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil, errOutOfRange
+	}
+
+	return int64(f), nil
+}
+
+func toFloat(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, errInvalidInput
+		}
+		return f, nil
+	}
+
+	return nil, errInvalidInput
+}
+
+func toBoolean(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+	}
+
+	return nil, errInvalidInput
+}
+
+func toText(v interface{}, attr Attribute) (interface{}, error) {
+	s := format(v)
+
+	if attr.typeSize > 0 && int64(utf8.RuneCountInString(s)) > attr.typeSize {
+		return nil, fmt.Errorf("value too long for type %s(%d)", typeString(attr.typeName), attr.typeSize)
+	}
+
+	return s, nil
+}
+
+func toTimestamp(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := parser.ParseDate(strings.TrimSpace(v))
+		if err != nil || t == nil {
+			return nil, errInvalidInput
+		}
+		return *t, nil
+	}
+
+	return nil, errInvalidInput
+}
+
+func toDate(v interface{}) (interface{}, error) {
+	t, err := toTimestamp(v)
+	if err != nil {
+		return nil, err
+	}
+
+	y, m, d := t.(time.Time).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+func toBytea(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		// Hex format, i.e '\x0a0b'
+		if strings.HasPrefix(v, `\x`) {
+			b, err := hex.DecodeString(v[2:])
+			if err != nil {
+				return nil, errInvalidInput
+			}
+			return b, nil
+		}
+		return []byte(v), nil
+	}
+
+	return nil, errInvalidInput
+}
+
+// format returns the string representation of a stored value
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(parser.DateLongFormat)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// equal compares a stored value v with another value, converting the
+// latter to the type of v first. Literals are given as strings.
+func equal(v interface{}, other interface{}) bool {
+	if v == nil || other == nil {
+		return false
+	}
+
+	var o interface{}
+	var err error
+
+	switch v := v.(type) {
+	case int64:
+		if o, err = toInteger(other); err != nil {
+			// Maybe compared to a float
+			if f, err := toFloat(other); err == nil {
+				return float64(v) == f.(float64)
+			}
+			return false
+		}
+		return v == o.(int64)
+	case float64:
+		if o, err = toFloat(other); err != nil {
+			return false
+		}
+		return v == o.(float64)
+	case bool:
+		if o, err = toBoolean(other); err != nil {
+			return false
+		}
+		return v == o.(bool)
+	case time.Time:
+		if o, err = toTimestamp(other); err != nil {
+			return false
+		}
+		return v.Equal(o.(time.Time))
+	}
+
+	return format(v) == format(other)
+}
@@ -1,43 +1,44 @@
 package engine
 
 import (
-	"fmt"
-	"strings"
-	"time"
-
 	"github.com/kokizzu/ramsql/engine/log"
-	"github.com/kokizzu/ramsql/engine/parser"
 )
 
-func updateValues(r *Relation, row int, values map[string]interface{}) error {
+// updateValues replaces row t of relation r, found at index row, by a new
+// version with given attributes set, and returns it
+func updateValues(r *Relation, tx *Transaction, row int, t *Tuple, values map[string]interface{}) (*Tuple, error) {
+	newValues, err := updatedValues(r, tx, t, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.update(r, row, t, newValues)
+}
+
+// updatedValues returns the values of row t once given attributes are set,
+// and those with an ON UPDATE value computed in transaction tx
+func updatedValues(r *Relation, tx *Transaction, t *Tuple, values map[string]interface{}) ([]interface{}, error) {
+	newValues := make([]interface{}, len(t.Values))
+	copy(newValues, t.Values)
+
 	for i := range r.table.attributes {
 		val, ok := values[r.table.attributes[i].name]
 		if !ok {
 			switch onUpdateVal := r.table.attributes[i].onUpdateValue.(type) {
-			case func() interface{}:
-				val = (func() interface{})(onUpdateVal)()
+			case func(*Transaction) interface{}:
+				val = onUpdateVal(tx)
 			default:
 				continue
 			}
-		} else {
-			log.Debug("Type of '%s' is '%s'\n", r.table.attributes[i].name, r.table.attributes[i].typeName)
-			switch strings.ToLower(r.table.attributes[i].typeName) {
-			case "timestamp", "localtimestamp":
-				switch valVal := val.(type) {
-				case func() interface{}:
-					val = (func() interface{})(valVal)()
-				case time.Time:
-					// format time.Time into parsable string
-					val = valVal.Format(parser.DateLongFormat)
-				case string:
-					if valVal == "current_timestamp" || valVal == "now()" {
-						val = time.Now().Format(parser.DateLongFormat)
-					}
-				}
-			}
 		}
-		r.rows[row].Values[i] = fmt.Sprintf("%v", val)
+
+		log.Debug("Type of '%s' is '%s'\n", r.table.attributes[i].name, r.table.attributes[i].typeName)
+		v, err := coerce(r.table.attributes[i], val)
+		if err != nil {
+			return nil, err
+		}
+		newValues[i] = v
 	}
 
-	return nil
+	return newValues, nil
 }
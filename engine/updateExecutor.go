@@ -13,43 +13,83 @@ import (
 	|-> set
 	      |-> email
 					|-> =
-					|-> roger@gmail.com
+					|-> <EXPRESSION>
   |-> where
         |-> id
 					|-> =
 					|-> 2
 */
-func updateExecutor(e *Engine, updateDecl *parser.Decl, conn protocol.EngineConn) error {
+func updateExecutor(e *Engine, tx *Transaction, updateDecl *parser.Decl, conn protocol.EngineConn) error {
 	var num int64
 
 	updateDecl.Stringy(0)
 
+	// Scalar subqueries are evaluated once, before the table is locked
+	// since they may select from it
+	outer := append([]string{updateDecl.Decl[0].Lexeme}, selectedTables(updateDecl)...)
+	if err := evaluateSubqueries(e, tx, updateDecl.Decl[1], outer); err != nil {
+		return err
+	}
+
 	// Fetch table from name and write lock it
 	r := e.relation(updateDecl.Decl[0].Lexeme)
 	if r == nil {
 		return fmt.Errorf("Table %s does not exists", updateDecl.Decl[0].Lexeme)
 	}
 	r.Lock()
-	r.Unlock()
+	defer r.Unlock()
+	tx.reads(r)
+
+	// Rows may be joined with those of other tables, filtered by WHERE
+	tables := []*Table{r.table}
+	j, err := newJoined(e, r, updateDecl)
+	if err != nil {
+		return err
+	}
+	if j != nil {
+		defer j.lock(tx)()
+		tables = j.tables
+	}
 
 	// Set decl
-	values, err := setExecutor(updateDecl.Decl[1])
+	expressions, err := setExecutor(updateDecl.Decl[1], tables)
 	if err != nil {
 		return err
 	}
 
 	// Where decl
-	predicates, err := whereExecutor(updateDecl.Decl[2], r.table.name)
+	var predicates []Predicate
+	if j == nil {
+		predicates, err = whereExecutor(updateDecl.Decl[2], r.table.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Values of updated rows may be returned
+	ret, err := newReturning(r, updateDecl, tables[1:]...)
 	if err != nil {
 		return err
 	}
 
+	conditions := make([]*Predicate, len(predicates))
+	for i := range predicates {
+		conditions[i] = &predicates[i]
+	}
+
 	var ok, res bool
-	for i := range r.rows {
+	err = r.scan(conditions, func(i int, t *Tuple) error {
+		if err := tx.interrupted(); err != nil {
+			return err
+		}
+		if t = tx.visible(t); t == nil {
+			return nil
+		}
+
 		ok = true
 		// If the row validate all predicates, write it
 		for _, predicate := range predicates {
-			if res, err = predicate.Evaluate(r.rows[i], r.table); err != nil {
+			if res, err = predicate.Evaluate(t, r.table); err != nil {
 				return err
 			}
 			if res == false {
@@ -58,14 +98,35 @@ func updateExecutor(e *Engine, updateDecl *parser.Decl, conn protocol.EngineConn
 			}
 		}
 
-		if ok {
-			num++
-			err = updateValues(r, i, values)
-			if err != nil {
+		if !ok {
+			return nil
+		}
+
+		// Joined rows are updated once, from the first row they join
+		row := r.virtualRow(t)
+		if j != nil {
+			if row, err = j.match(tx, r, t); err != nil || row == nil {
 				return err
 			}
 		}
+
+		num++
+		values, err := setValues(row, expressions)
+		if err != nil {
+			return err
+		}
+		n, err := updateValues(r, tx, i, t, values)
+		if err != nil {
+			return err
+		}
+		return ret.addJoined(n, row)
+	})
+	if err != nil {
+		return err
 	}
 
+	if ret != nil {
+		return ret.write(conn)
+	}
 	return conn.WriteResult(0, num)
 }
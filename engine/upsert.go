@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+)
+
+// conflict is what happens to an inserted row holding the same key as an
+// existing one, which is otherwise a unique constraint violation:
+//
+//	|-> conflict
+//	    |-> key
+//	        |-> <COLUMN-NAME>
+//	    |-> constraint
+//	        |-> <CONSTRAINT-NAME>
+//	    |-> { nothing | update }
+//	        |-> where
+//
+//	|-> duplicate
+//	    |-> update
+//
+//	|-> replace
+type conflict struct {
+	// action is ConflictToken for ON CONFLICT, DuplicateToken for
+	// ON DUPLICATE KEY UPDATE and ReplaceToken for REPLACE
+	action int
+	// keys are those an existing row must share with the inserted one
+	keys []*uniqueKey
+	// update holds the expressions assigned to columns of the existing row,
+	// if not DO NOTHING
+	update map[string]*parser.Decl
+	// where is the condition the existing row must meet to be updated
+	where *parser.Decl
+	// written holds rows inserted or updated by the statement
+	written map[*Tuple]bool
+}
+
+// newConflict returns how conflicts are handled when inserting into relation
+// r as required by insertDecl, nil if they are errors
+func newConflict(r *Relation, insertDecl *parser.Decl) (*conflict, error) {
+	var decl *parser.Decl
+	for _, d := range insertDecl.Decl {
+		switch d.Token {
+		case parser.ConflictToken, parser.DuplicateToken, parser.ReplaceToken:
+			decl = d
+		}
+	}
+	if decl == nil {
+		return nil, nil
+	}
+
+	c := &conflict{
+		action:  decl.Token,
+		written: make(map[*Tuple]bool),
+	}
+
+	// Without conflict target, every key is checked
+	t := r.table
+	var keys []*uniqueKey
+	if t.primaryKey != nil {
+		keys = append(keys, t.primaryKey)
+	}
+	keys = append(keys, t.uniqueKeys...)
+	c.keys = keys
+
+	for _, d := range decl.Decl {
+		switch d.Token {
+		case parser.KeyToken:
+			k, err := t.keyOn(d.Decl)
+			if err != nil {
+				return nil, err
+			}
+			c.keys = []*uniqueKey{k}
+		case parser.ConstraintToken:
+			if len(d.Decl) != 1 {
+				return nil, fmt.Errorf("parsing failed, malformed ON CONFLICT clause")
+			}
+			c.keys = nil
+			for _, k := range keys {
+				if k.name == d.Decl[0].Lexeme {
+					c.keys = []*uniqueKey{k}
+				}
+			}
+			if c.keys == nil {
+				return nil, fmt.Errorf("constraint \"%s\" for table \"%s\" does not exist", d.Decl[0].Lexeme, t.name)
+			}
+		case parser.UpdateToken:
+			if err := c.assignments(t, d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.action == parser.ConflictToken && c.update != nil && len(c.keys) == len(keys) && len(decl.Decl) == 1 {
+		return nil, fmt.Errorf("ON CONFLICT DO UPDATE requires inference specification or constraint name")
+	}
+
+	return c, nil
+}
+
+// assignments checks the expressions assigned to columns of table t by
+// updateDecl, and its condition. Unqualified columns are those of the
+// existing row, columns of the inserted one being qualified by EXCLUDED.
+func (c *conflict) assignments(t *Table, updateDecl *parser.Decl) error {
+	excluded := *t
+	excluded.name = "excluded"
+	tables := []*Table{t, &excluded}
+
+	c.update = make(map[string]*parser.Decl)
+	for _, a := range updateDecl.Decl {
+		if a.Token == parser.WhereToken {
+			if len(a.Decl) != 1 {
+				return fmt.Errorf("parsing failed, malformed WHERE clause")
+			}
+			qualifyColumns(a.Decl[0], t.name)
+			if _, err := expressionAttribute(a.Decl[0], tables); err != nil {
+				return err
+			}
+			c.where = a.Decl[0]
+			continue
+		}
+
+		if len(a.Decl) != 2 {
+			return fmt.Errorf("parsing failed, malformed assignment")
+		}
+		if t.attributeIndex(a.Lexeme) < 0 {
+			return fmt.Errorf("column \"%s\" of relation \"%s\" does not exist", a.Lexeme, t.name)
+		}
+		if _, ok := c.update[a.Lexeme]; ok {
+			return fmt.Errorf("multiple assignments to same column \"%s\"", a.Lexeme)
+		}
+		qualifyColumns(a.Decl[1], t.name)
+		if _, err := expressionAttribute(a.Decl[1], tables); err != nil {
+			return err
+		}
+		c.update[a.Lexeme] = a.Decl[1]
+	}
+
+	return nil
+}
+
+// qualifyColumns qualifies the unqualified columns of expression decl by
+// table name
+func qualifyColumns(decl *parser.Decl, name string) {
+	if decl.Token == parser.SelectToken {
+		return
+	}
+	if decl.Token == parser.ColumnToken && len(decl.Decl) == 0 {
+		decl.Add(parser.NewDecl(parser.Token{Token: parser.StringToken, Lexeme: name}))
+		return
+	}
+
+	for _, d := range decl.Decl {
+		qualifyColumns(d, name)
+	}
+}
+
+// keyOn returns the primary key or unique constraint of table t made of
+// given columns, in any order
+func (t *Table) keyOn(columns []*parser.Decl) (*uniqueKey, error) {
+	keys := t.uniqueKeys
+	if t.primaryKey != nil {
+		keys = append([]*uniqueKey{t.primaryKey}, keys...)
+	}
+
+	for _, k := range keys {
+		if len(k.attributes) != len(columns) {
+			continue
+		}
+		match := true
+		for _, c := range columns {
+			found := false
+			for _, a := range k.attributes {
+				if t.attributes[a].name == c.Lexeme {
+					found = true
+				}
+			}
+			match = match && found
+		}
+		if match {
+			return k, nil
+		}
+	}
+
+	return nil, fmt.Errorf("there is no unique or exclusion constraint matching the ON CONFLICT specification")
+}
+
+// insert inserts row t in relation r, handling conflicts with existing rows.
+// It returns the row written, nil if none, and the number of rows affected
+// as reported by PostgreSQL or MySQL.
+func (c *conflict) insert(tx *Transaction, r *Relation, t *Tuple) (*Tuple, int64, error) {
+	if c == nil {
+		return t, 1, tx.insert(r, t)
+	}
+
+	// REPLACE deletes every row holding one of the inserted keys
+	if c.action == parser.ReplaceToken {
+		var deleted int64
+		for _, k := range c.keys {
+			for old := r.duplicate(tx, k, t, nil); old != nil; old = r.duplicate(tx, k, t, nil) {
+				if err := tx.delete(r, old); err != nil {
+					return nil, 0, err
+				}
+				deleted++
+			}
+		}
+		return t, deleted + 1, tx.insert(r, t)
+	}
+
+	var old *Tuple
+	for _, k := range c.keys {
+		if old = r.duplicate(tx, k, t, nil); old != nil {
+			break
+		}
+	}
+	if old == nil {
+		c.written[t] = true
+		return t, 1, tx.insert(r, t)
+	}
+
+	// DO NOTHING
+	if c.update == nil {
+		return nil, 0, nil
+	}
+
+	if c.action == parser.ConflictToken && c.written[old] {
+		return nil, 0, fmt.Errorf("ON CONFLICT DO UPDATE command cannot affect row a second time")
+	}
+
+	// Expressions see the existing row, and the inserted one as EXCLUDED
+	row := r.virtualRow(old)
+	for i, attr := range r.table.attributes {
+		row["excluded."+attr.name] = Value{v: t.Values[i], valid: true, lexeme: attr.name, table: "excluded"}
+	}
+	if c.where != nil {
+		ok, err := evaluateCondition(c.where, row)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			return nil, 0, nil
+		}
+	}
+	values, err := setValues(row, c.update)
+	if err != nil {
+		return nil, 0, err
+	}
+	newValues, err := updatedValues(r, tx, old, values)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// MySQL reports 2 rows affected by an update, none if values are unchanged
+	var affected int64 = 1
+	if c.action == parser.DuplicateToken {
+		affected = 0
+		for i := range newValues {
+			if (newValues[i] != nil || old.Values[i] != nil) && !equal(old.Values[i], newValues[i]) {
+				affected = 2
+			}
+		}
+		if affected == 0 {
+			return old, 0, nil
+		}
+	}
+
+	n, err := tx.update(r, -1, old, newValues)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.written[n] = true
+
+	return n, affected, nil
+}
+
+// rowID returns the id reported for row t of relation r when written by an
+// insert, that is the value of its auto-incremented column
+func rowID(r *Relation, t *Tuple) int64 {
+	for i, attr := range r.table.attributes {
+		if n, ok := t.Values[i].(int64); ok && attr.autoIncrement {
+			return n
+		}
+	}
+
+	return 0
+}
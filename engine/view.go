@@ -0,0 +1,418 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/kokizzu/ramsql/engine/parser"
+	"github.com/kokizzu/ramsql/engine/protocol"
+)
+
+// view is a named SELECT query. Views share their namespace with
+// relations and sequences, and are materialized when selected from.
+type view struct {
+	name string
+	// columns renames the columns of query, if given
+	columns []string
+	query   *parser.Decl
+}
+
+func (e *Engine) view(name string) *view {
+	return e.views[name]
+}
+
+// createViewExecutor stores a view, replacing the existing one with OR REPLACE.
+// The query is run once to check it is valid.
+//
+//	|-> view
+//	    |-> or
+//	        |-> replace
+//	    |-> <VIEW-NAME>
+//	        |-> <COLUMN-NAME>
+//	    |-> as
+//	        |-> select
+func createViewExecutor(e *Engine, tx *Transaction, viewDecl *parser.Decl, conn protocol.EngineConn) error {
+	v := &view{}
+	replace := false
+	for _, d := range viewDecl.Decl {
+		switch d.Token {
+		case parser.OrToken:
+			replace = true
+		case parser.AsToken:
+			if len(d.Decl) != 1 {
+				return fmt.Errorf("parsing failed, malformed CREATE VIEW query")
+			}
+			v.query = d.Decl[0]
+		default:
+			v.name = d.Lexeme
+			for _, c := range d.Decl {
+				v.columns = append(v.columns, c.Lexeme)
+			}
+		}
+	}
+	if v.query == nil {
+		return fmt.Errorf("parsing failed, malformed CREATE VIEW query")
+	}
+
+	old := e.view(v.name)
+	if e.relation(v.name) != nil || e.sequences[v.name] != nil {
+		if replace {
+			return fmt.Errorf("\"%s\" is not a view", v.name)
+		}
+		return fmt.Errorf("relation \"%s\" already exists", v.name)
+	}
+	if old != nil && !replace {
+		return fmt.Errorf("relation \"%s\" already exists", v.name)
+	}
+
+	if e.dependsOn(v.query, v.name) {
+		return fmt.Errorf("infinite recursion detected in rules for relation \"%s\"", v.name)
+	}
+	if _, err := e.materialize(tx, v); err != nil {
+		return err
+	}
+
+	e.views[v.name] = v
+	tx.onRollback(func() {
+		if old != nil {
+			e.views[v.name] = old
+			return
+		}
+		delete(e.views, v.name)
+	})
+
+	return conn.WriteResult(0, 1)
+}
+
+// dropViewExecutor drops a view. Views selecting from it are dropped
+// with CASCADE.
+//
+//	|-> view
+//	    |-> if
+//	        |-> exists
+//	    |-> <VIEW-NAME>
+//	    |-> cascade
+func dropViewExecutor(e *Engine, tx *Transaction, viewDecl *parser.Decl, conn protocol.EngineConn) error {
+	var name string
+	var ifExists, cascade bool
+	for _, d := range viewDecl.Decl {
+		switch d.Token {
+		case parser.IfToken:
+			ifExists = true
+		case parser.CascadeToken:
+			cascade = true
+		case parser.RestrictToken:
+		default:
+			name = d.Lexeme
+		}
+	}
+
+	v := e.view(name)
+	if v == nil {
+		if e.relation(name) != nil {
+			return fmt.Errorf("\"%s\" is not a view", name)
+		}
+		if ifExists {
+			return conn.WriteResult(0, 0)
+		}
+		return fmt.Errorf("view \"%s\" does not exist", name)
+	}
+	if len(e.dependentViews(name)) > 0 && !cascade {
+		return fmt.Errorf("cannot drop view %s because other objects depend on it", name)
+	}
+
+	tx.dropView(e, v)
+	tx.dropViews(e, name)
+
+	return conn.WriteResult(0, 1)
+}
+
+// dropView removes view v, registered again if tx rolls back
+func (tx *Transaction) dropView(e *Engine, v *view) {
+	delete(e.views, v.name)
+	tx.onRollback(func() {
+		e.views[v.name] = v
+	})
+}
+
+// dropViews drops the views selecting from given relation or view,
+// then the views selecting from those
+func (tx *Transaction) dropViews(e *Engine, name string) {
+	for _, v := range e.dependentViews(name) {
+		tx.dropView(e, v)
+		tx.dropViews(e, v.name)
+	}
+}
+
+// dependentViews returns the views directly selecting from given relation or view
+func (e *Engine) dependentViews(name string) []*view {
+	var views []*view
+	for _, v := range e.views {
+		for _, t := range selectedTables(v.query) {
+			if t == name {
+				views = append(views, v)
+				break
+			}
+		}
+	}
+
+	return views
+}
+
+// dependsOn checks if query selects from given relation or view, directly
+// or through other views
+func (e *Engine) dependsOn(query *parser.Decl, name string) bool {
+	for _, t := range selectedTables(query) {
+		if t == name {
+			return true
+		}
+		if v := e.view(t); v != nil && e.dependsOn(v.query, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectedTables returns the names of tables in FROM and JOIN clauses of selectDecl
+func selectedTables(selectDecl *parser.Decl) []string {
+	var names []string
+	for _, d := range selectDecl.Decl {
+		switch d.Token {
+		case parser.FromToken:
+			for _, t := range d.Decl {
+				names = append(names, t.Lexeme)
+			}
+		case parser.JoinToken:
+			if len(d.Decl) > 0 {
+				names = append(names, d.Decl[0].Lexeme)
+			}
+		}
+	}
+
+	return names
+}
+
+// selectsTemporary returns true if selectDecl selects from a temporary
+// table or view
+func selectsTemporary(selectDecl *parser.Decl) bool {
+	for _, t := range selectedTables(selectDecl) {
+		if isTempSchema(schemaOf(t)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// materializeViews registers the views selected from as relations holding
+// their rows as seen by tx, until returned func is called. Statements are
+// executed one at a time so no other statement can see them.
+func (e *Engine) materializeViews(tx *Transaction, selectDecl *parser.Decl) (func(), error) {
+	var names []string
+	release := func() {
+		for _, name := range names {
+			e.drop(name)
+		}
+	}
+
+	for _, name := range selectedTables(selectDecl) {
+		v := e.view(name)
+		if v == nil || e.relation(name) != nil {
+			continue
+		}
+		r, err := e.materialize(tx, v)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		e.relations[name] = r
+		names = append(names, name)
+	}
+
+	return release, nil
+}
+
+// materialize runs the query of view v and returns its result as a relation
+func (e *Engine) materialize(tx *Transaction, v *view) (*Relation, error) {
+	// Executors change the decls they run, so the stored query is kept
+	// as defined
+	result := &queryResult{}
+	if err := selectExecutor(e, tx, copyDecl(v.query), result); err != nil {
+		return nil, err
+	}
+	if len(v.columns) > len(result.columns) {
+		return nil, fmt.Errorf("CREATE VIEW specifies more column names than columns")
+	}
+
+	t := NewTable(v.name)
+	if err := addResultColumns(t, v.columns, result.columns); err != nil {
+		return nil, err
+	}
+	for i, c := range result.columns {
+		t.attributes[i].isNullable = c.Nullable
+	}
+
+	r := NewRelation(t)
+	for _, row := range result.rows {
+		if err := r.Insert(NewTuple(row...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// copyDecl returns a deep copy of decl
+func copyDecl(decl *parser.Decl) *parser.Decl {
+	d := &parser.Decl{
+		Token:  decl.Token,
+		Lexeme: decl.Lexeme,
+	}
+	for _, sub := range decl.Decl {
+		d.Add(copyDecl(sub))
+	}
+
+	return d
+}
+
+// alterView replaces the query of view v by a copy changed by rewrite,
+// restored if tx rolls back
+func (tx *Transaction) alterView(v *view, rewrite func(query *parser.Decl)) {
+	query := v.query
+	v.query = copyDecl(query)
+	rewrite(v.query)
+	tx.onRollback(func() {
+		v.query = query
+	})
+}
+
+// uses returns true if view v reads attribute attr of given table
+func (e *Engine) uses(v *view, table, attr string) bool {
+	if !hasName(selectedTables(v.query), table) {
+		return false
+	}
+
+	for _, d := range v.query.Decl {
+		if d.Token == parser.StarToken && (len(d.Decl) == 0 || d.Decl[0].Lexeme == table) {
+			return true
+		}
+	}
+
+	found := false
+	selectedColumns(v.query, func(column, qualifier *parser.Decl) {
+		found = found || e.refersTo(v.query, column, qualifier, table, attr)
+	})
+	return found
+}
+
+// refersTo returns true if column, qualified by qualifier if not nil,
+// designates attribute attr of given table in selectDecl
+func (e *Engine) refersTo(selectDecl *parser.Decl, column, qualifier *parser.Decl, table, attr string) bool {
+	if column.Lexeme != attr {
+		return false
+	}
+	if qualifier != nil {
+		return qualifier.Lexeme == table
+	}
+
+	// Unqualified, it may be an attribute of another table
+	for _, t := range selectedTables(selectDecl) {
+		if r := e.relation(t); t != table && r != nil && r.table.attributeIndex(attr) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// renameViewColumn renames attribute attr of given table read by view
+// query selectDecl. Selected columns keep their name.
+func (e *Engine) renameViewColumn(selectDecl *parser.Decl, table, attr, name string) {
+	for _, d := range selectDecl.Decl {
+		if d.Token != parser.StringToken && d.Token != parser.ColumnToken {
+			continue
+		}
+		var qualifier *parser.Decl
+		aliased := false
+		for i, c := range d.Decl {
+			switch {
+			case i == 0 && c.Token == parser.StringToken && len(c.Decl) == 0:
+				qualifier = c
+			case c.Token == parser.AsToken:
+				aliased = true
+			}
+		}
+		if !aliased && e.refersTo(selectDecl, d, qualifier, table, attr) {
+			as := parser.NewDecl(parser.Token{Token: parser.AsToken, Lexeme: "as"})
+			as.Add(parser.NewDecl(parser.Token{Token: parser.StringToken, Lexeme: attr}))
+			d.Add(as)
+		}
+	}
+
+	selectedColumns(selectDecl, func(column, qualifier *parser.Decl) {
+		if e.refersTo(selectDecl, column, qualifier, table, attr) {
+			column.Lexeme = name
+		}
+	})
+}
+
+// renameViewTable renames table old read by view query selectDecl, and
+// the qualifiers of its columns
+func renameViewTable(selectDecl *parser.Decl, old, name string) {
+	for _, d := range selectDecl.Decl {
+		switch d.Token {
+		case parser.FromToken:
+			for _, t := range d.Decl {
+				if t.Lexeme == old {
+					t.Lexeme = name
+				}
+			}
+			continue
+		case parser.JoinToken:
+			if len(d.Decl) > 0 && d.Decl[0].Lexeme == old {
+				d.Decl[0].Lexeme = name
+			}
+		}
+		resolveQualifiers(d, map[string]string{old: name})
+	}
+}
+
+// selectedColumns calls f with the columns read by selectDecl, outside of
+// subqueries, and their qualifier if any
+func selectedColumns(selectDecl *parser.Decl, f func(column, qualifier *parser.Decl)) {
+	for _, d := range selectDecl.Decl {
+		switch d.Token {
+		case parser.FromToken, parser.AsToken, parser.InToken, parser.SelectToken:
+		case parser.JoinToken:
+			// Table joined, then ON clause
+			if len(d.Decl) > 0 {
+				selectedColumns(&parser.Decl{Decl: d.Decl[1:]}, f)
+			}
+		case parser.StringToken, parser.ColumnToken:
+			selectedColumn(d, f)
+		default:
+			selectedColumns(d, f)
+		}
+	}
+}
+
+// selectedColumn calls f with column decl and the columns it is compared
+// with. Values it is compared with are skipped.
+func selectedColumn(column *parser.Decl, f func(column, qualifier *parser.Decl)) {
+	var qualifier *parser.Decl
+	for i, d := range column.Decl {
+		switch d.Token {
+		case parser.StringToken, parser.ColumnToken:
+			if i == 0 && len(d.Decl) == 0 {
+				qualifier = d
+			} else if len(d.Decl) > 0 {
+				selectedColumn(d, f)
+			}
+		case parser.AsToken, parser.InToken, parser.SelectToken:
+		default:
+			selectedColumns(d, f)
+		}
+	}
+
+	f(column, qualifier)
+}